@@ -0,0 +1,402 @@
+package xlsx
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/structer/xlsx/formula"
+)
+
+// ErrCircularReference is the cause of the CellError that Recalculate
+// returns for a formula that depends on its own value.
+var ErrCircularReference = errors.New("xlsx: circular reference")
+
+// Recalculate computes the formulas of every sheet of the file and
+// stores their results in the cells, as Excel does when it recalculates
+// a workbook.  A number leaves a cell of type CellTypeFormula, text
+// makes it a CellTypeStringFormula, TRUE or FALSE a CellTypeBool and
+// an error value such as #DIV/0! a CellTypeError.  The formula of the
+// cell is kept in each case.
+//
+// Each formula is computed after the formula cells it refers to,
+// directly or through defined names.  Cells whose formulas depend on
+// their own values keep the values they have, and a formula that can't
+// be parsed gets #NAME?.  The other formulas are computed all the same,
+// and the first such problem is returned as a *CellError whose cause
+// is ErrCircularReference or a *formula.SyntaxError.
+//
+// The functions known to Recalculate are those of the formula
+// package, to which formula.RegisterFunction adds.
+func (f *File) Recalculate() error {
+	for _, sheet := range f.Sheets {
+		if err := sheet.Load(); err != nil {
+			return err
+		}
+	}
+	c := newCalculation(f)
+	for _, cc := range c.sort() {
+		c.evaluate(cc)
+	}
+	return c.err
+}
+
+// calcState is the progress of the recalculation of a formula cell.
+type calcState int
+
+const (
+	calcPending calcState = iota
+	calcActive
+	calcDone
+	calcCircular // on a circular reference; the cell keeps its value
+)
+
+// calcCell is a formula cell being recalculated.
+type calcCell struct {
+	sheet    *Sheet
+	cell     *Cell
+	row, col int // counting from 1
+	node     formula.Node
+	state    calcState
+	value    formula.Value
+}
+
+// ref returns the A1 reference of the cell.
+func (cc *calcCell) ref() string {
+	return getCellIDStringFromCoords(cc.col-1, cc.row-1)
+}
+
+type calcKey struct {
+	sheet    *Sheet
+	row, col int
+}
+
+// calculation is the recalculation of a file.  It is the
+// formula.Context of its evaluator, which reads the cells and defined
+// names of the file through it.
+type calculation struct {
+	file      *File
+	evaluator *formula.Evaluator
+	cells     map[calcKey]*calcCell
+	formulas  map[*Sheet][]*calcCell
+	sizes     map[*Sheet][2]int
+	names     map[*xlsxDefinedName]formula.Node
+	err       error
+}
+
+// newCalculation parses the formulas of the loaded sheets of f.
+func newCalculation(f *File) *calculation {
+	c := &calculation{
+		file:     f,
+		cells:    make(map[calcKey]*calcCell),
+		formulas: make(map[*Sheet][]*calcCell),
+		sizes:    make(map[*Sheet][2]int),
+		names:    make(map[*xlsxDefinedName]formula.Node),
+	}
+	c.evaluator = &formula.Evaluator{
+		Context:  c,
+		Date1904: f.Date1904,
+		Format: func(value float64, format string) string {
+			cell := &Cell{NumFmt: format, date1904: f.Date1904, cellType: CellTypeNumeric}
+			cell.Value = strconv.FormatFloat(value, 'f', -1, 64)
+			s, _ := cell.FormattedValue()
+			return s
+		},
+	}
+	if f.Location != nil {
+		c.evaluator.Now = func() time.Time { return time.Now().In(f.Location) }
+	}
+	for _, sheet := range f.Sheets {
+		cols := 0
+		for y, row := range sheet.Rows {
+			if row == nil {
+				continue
+			}
+			if len(row.Cells) > cols {
+				cols = len(row.Cells)
+			}
+			for x, cell := range row.Cells {
+				if cell == nil || cell.formula == "" {
+					continue
+				}
+				cc := &calcCell{sheet: sheet, cell: cell, row: y + 1, col: x + 1}
+				node, err := formula.Parse(cell.formula)
+				if err != nil {
+					c.fail(newCellError(sheet, cc.ref(), err))
+					cc.value = formula.ErrorValue(formula.ErrName)
+					cc.state = calcDone
+					cc.store()
+				}
+				cc.node = node
+				c.cells[calcKey{sheet, cc.row, cc.col}] = cc
+				c.formulas[sheet] = append(c.formulas[sheet], cc)
+			}
+		}
+		c.sizes[sheet] = [2]int{len(sheet.Rows), cols}
+	}
+	return c
+}
+
+// fail records the first problem of the calculation.
+func (c *calculation) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// sheet returns the sheet of the given name, which is matched without
+// regard to case, or nil.
+func (c *calculation) sheet(name string) *Sheet {
+	for _, sheet := range c.file.Sheets {
+		if strings.EqualFold(sheet.Name, name) {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// sort returns the formula cells in an order in which each comes after
+// those it refers to.  Cells on circular references are left out, as
+// are the cells that couldn't be parsed.
+func (c *calculation) sort() []*calcCell {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	var order, stack []*calcCell
+	marks := make(map[*calcCell]int)
+	var visit func(cc *calcCell)
+	visit = func(cc *calcCell) {
+		switch marks[cc] {
+		case visiting:
+			// The cells from cc to the top of the stack make a
+			// circle.
+			i := len(stack) - 1
+			for stack[i] != cc {
+				i--
+			}
+			for _, on := range stack[i:] {
+				on.state = calcCircular
+			}
+			c.fail(newCellError(cc.sheet, cc.ref(), ErrCircularReference))
+			return
+		case visited:
+			return
+		}
+		marks[cc] = visiting
+		stack = append(stack, cc)
+		for _, p := range c.precedents(cc) {
+			visit(p)
+		}
+		stack = stack[:len(stack)-1]
+		marks[cc] = visited
+		if cc.state == calcPending {
+			order = append(order, cc)
+		}
+	}
+	for _, sheet := range c.file.Sheets {
+		for _, cc := range c.formulas[sheet] {
+			visit(cc)
+		}
+	}
+	return order
+}
+
+// precedents returns the formula cells that the formula of cc refers
+// to, by references and by defined names.
+func (c *calculation) precedents(cc *calcCell) []*calcCell {
+	var cells []*calcCell
+	if cc.node == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var inspect func(n formula.Node)
+	inspect = func(n formula.Node) {
+		formula.Inspect(n, func(n formula.Node) bool {
+			switch n := n.(type) {
+			case *formula.Ref:
+				cells = append(cells, c.formulasIn(n, cc.sheet)...)
+			case *formula.Name:
+				sheet := n.Sheet
+				if sheet == "" {
+					sheet = cc.sheet.Name
+				}
+				key := strings.ToUpper(sheet + "!" + n.Name)
+				if n.Book != "" || seen[key] {
+					return false
+				}
+				seen[key] = true
+				if def := c.Name(sheet, n.Name); def != nil {
+					inspect(def)
+				}
+			}
+			return true
+		})
+	}
+	inspect(cc.node)
+	return cells
+}
+
+// formulasIn returns the formula cells in the range of ref, which
+// stands on sheet unless it names its own.
+func (c *calculation) formulasIn(ref *formula.Ref, sheet *Sheet) []*calcCell {
+	if ref.Invalid || ref.Book != "" || ref.LastSheet != "" {
+		return nil
+	}
+	if ref.Sheet != "" {
+		if sheet = c.sheet(ref.Sheet); sheet == nil {
+			return nil
+		}
+	}
+	top, left, bottom, right := ref.Bounds()
+	if top == bottom && left == right {
+		if cc, ok := c.cells[calcKey{sheet, top, left}]; ok {
+			return []*calcCell{cc}
+		}
+		return nil
+	}
+	var cells []*calcCell
+	for _, cc := range c.formulas[sheet] {
+		if cc.row >= top && cc.row <= bottom && cc.col >= left && cc.col <= right {
+			cells = append(cells, cc)
+		}
+	}
+	return cells
+}
+
+// evaluate computes the formula of cc, unless it has been already,
+// and stores its value in the cell.
+func (c *calculation) evaluate(cc *calcCell) {
+	if cc.state != calcPending {
+		return
+	}
+	cc.state = calcActive
+	cc.value = c.evaluator.Eval(cc.node, cc.sheet.Name, cc.row, cc.col)
+	if cc.state == calcActive {
+		cc.state = calcDone
+		cc.store()
+	}
+}
+
+// store writes the value of the formula of cc into its cell.
+func (cc *calcCell) store() {
+	cell, v := cc.cell, cc.value
+	cell.richText = nil
+	switch v.Type {
+	case formula.ValueNumber:
+		cell.Value = strconv.FormatFloat(v.Number, 'g', -1, 64)
+		cell.cellType = CellTypeFormula
+	case formula.ValueBool:
+		cell.Value = "0"
+		if v.Bool {
+			cell.Value = "1"
+		}
+		cell.cellType = CellTypeBool
+	case formula.ValueError:
+		cell.Value = v.Text
+		cell.cellType = CellTypeError
+	default:
+		cell.Value = v.Text
+		cell.cellType = CellTypeStringFormula
+	}
+}
+
+// Cell returns the value of a cell, computing its formula first if
+// need be.  That is the case for the cells that a formula only reaches
+// through functions such as OFFSET and INDIRECT, and a circular
+// reference can be found that way as well.
+func (c *calculation) Cell(sheet string, row, col int) formula.Value {
+	s := c.sheet(sheet)
+	if s == nil {
+		return formula.Value{}
+	}
+	if cc, ok := c.cells[calcKey{s, row, col}]; ok {
+		switch cc.state {
+		case calcPending:
+			c.evaluate(cc)
+		case calcActive:
+			cc.state = calcCircular
+			c.fail(newCellError(s, cc.ref(), ErrCircularReference))
+		}
+		if cc.state == calcDone {
+			return cc.value
+		}
+	}
+	if row > len(s.Rows) || s.Rows[row-1] == nil || col > len(s.Rows[row-1].Cells) {
+		return formula.Value{}
+	}
+	cell := s.Rows[row-1].Cells[col-1]
+	if cell == nil {
+		return formula.Value{}
+	}
+	return cellValue(cell)
+}
+
+// cellValue returns the value that a cell holds.
+func cellValue(cell *Cell) formula.Value {
+	switch cell.cellType {
+	case CellTypeBool:
+		return formula.BoolValue(cell.Value == "1")
+	case CellTypeError:
+		return formula.ErrorValue(cell.Value)
+	case CellTypeString, CellTypeInline:
+		if cell.Value == "" {
+			return formula.Value{}
+		}
+		return formula.StringValue(cell.Value)
+	case CellTypeStringFormula:
+		return formula.StringValue(cell.Value)
+	}
+	if cell.Value == "" {
+		return formula.Value{}
+	}
+	if f, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+		return formula.NumberValue(f)
+	}
+	return formula.StringValue(cell.Value)
+}
+
+// Size returns the number of rows and columns of a sheet.
+func (c *calculation) Size(sheet string) (rows, cols int, ok bool) {
+	s := c.sheet(sheet)
+	if s == nil {
+		return 0, 0, false
+	}
+	size := c.sizes[s]
+	return size[0], size[1], true
+}
+
+// Name returns the formula of a defined name: the one of the sheet, or
+// else the one of the workbook.
+func (c *calculation) Name(sheet, name string) formula.Node {
+	index := -1
+	for i, s := range c.file.Sheets {
+		if strings.EqualFold(s.Name, sheet) {
+			index = i
+		}
+	}
+	var found *xlsxDefinedName
+	for _, dn := range c.file.DefinedNames {
+		if !strings.EqualFold(dn.Name, name) {
+			continue
+		}
+		if dn.isLocalTo(index) {
+			found = dn
+			break
+		}
+		if dn.isGlobal() && found == nil {
+			found = dn
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	node, ok := c.names[found]
+	if !ok {
+		node, _ = formula.Parse(strings.TrimPrefix(found.Data, "="))
+		c.names[found] = node
+	}
+	return node
+}
@@ -0,0 +1,219 @@
+package xlsx
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/structer/xlsx/formula"
+	. "gopkg.in/check.v1"
+)
+
+type CalcSuite struct{}
+
+var _ = Suite(&CalcSuite{})
+
+// setCells sets the cells of sheet from a map of A1 references to
+// values, where a value starting with "=" is a formula.
+func setCells(c *C, sheet *Sheet, cells map[string]interface{}) {
+	for ref, value := range cells {
+		x, y, err := getCoordsFromCellIDString(ref)
+		c.Assert(err, IsNil)
+		cell := sheet.Cell(y, x)
+		if s, ok := value.(string); ok && len(s) > 1 && s[0] == '=' {
+			cell.SetFormula(s[1:])
+			continue
+		}
+		cell.SetValue(value)
+	}
+}
+
+func (s *CalcSuite) TestRecalculate(c *C) {
+	f := NewFile()
+	data, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	report, err := f.AddSheet("My Report")
+	c.Assert(err, IsNil)
+	setCells(c, data, map[string]interface{}{
+		"A1": 10, "A2": 20, "A3": 30,
+		"B1": "north", "B2": "south", "B3": "north",
+		"C1": "=A1*2", "C2": "=A2*2", "C3": "=A3*2",
+		"D1": "=SUM(C:C)",
+	})
+	setCells(c, report, map[string]interface{}{
+		"A1": "=Data!D1/2",
+		"A2": `=SUMIF(Data!B1:B3,"north",Data!C1:C3)`,
+		"A3": "=A1>A2",
+		"A4": `=Data!B1&"-"&UPPER(Data!B2)`,
+		"A5": "=1/0",
+		"A6": `=IFERROR(A5,"none")`,
+		"A7": "=ISERROR(A5)",
+		"A8": "=VLOOKUP(30,Data!A1:C3,3,FALSE)",
+	})
+	c.Assert(f.Recalculate(), IsNil)
+
+	cases := []struct {
+		ref      string
+		value    string
+		cellType CellType
+	}{
+		{"A1", "60", CellTypeFormula},
+		{"A2", "80", CellTypeFormula},
+		{"A3", "0", CellTypeBool},
+		{"A4", "north-SOUTH", CellTypeStringFormula},
+		{"A5", "#DIV/0!", CellTypeError},
+		{"A6", "none", CellTypeStringFormula},
+		{"A7", "1", CellTypeBool},
+		{"A8", "60", CellTypeFormula},
+	}
+	for _, test := range cases {
+		x, y, err := getCoordsFromCellIDString(test.ref)
+		c.Assert(err, IsNil)
+		cell := report.Cell(y, x)
+		c.Check(cell.Value, Equals, test.value, Commentf(test.ref))
+		c.Check(cell.Type(), Equals, test.cellType, Commentf(test.ref))
+		c.Check(cell.Formula(), Not(Equals), "", Commentf(test.ref))
+	}
+	c.Assert(data.Cell(0, 3).Value, Equals, "120")
+}
+
+func (s *CalcSuite) TestRecalculateOrder(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	// Each formula refers to the cell below it, which is computed
+	// later in reading order.
+	setCells(c, sheet, map[string]interface{}{
+		"A1": "=A2+1",
+		"A2": "=A3+1",
+		"A3": "=Total*2",
+		"B1": 5,
+		"C1": `=INDIRECT("A"&B1)`,
+		"A5": "=SUM(B1)",
+	})
+	f.DefinedNames = append(f.DefinedNames, &xlsxDefinedName{Name: "Total", Data: "Sheet1!$A$5"})
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "12")
+	c.Assert(sheet.Cell(2, 0).Value, Equals, "10")
+	c.Assert(sheet.Cell(0, 2).Value, Equals, "5")
+}
+
+func (s *CalcSuite) TestRecalculateNames(c *C) {
+	f := NewFile()
+	first, err := f.AddSheet("First")
+	c.Assert(err, IsNil)
+	second, err := f.AddSheet("Second")
+	c.Assert(err, IsNil)
+	f.DefinedNames = append(f.DefinedNames,
+		&xlsxDefinedName{Name: "Rate", Data: "0.5"},
+		&xlsxDefinedName{Name: "Rate", Data: "2", LocalSheetID: localSheetID(1)},
+		&xlsxDefinedName{Name: "Broken", Data: "1+"},
+	)
+	setCells(c, first, map[string]interface{}{"A1": "=10*Rate", "A2": "=Broken", "A3": "=Second!Rate"})
+	setCells(c, second, map[string]interface{}{"A1": "=10*rate"})
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(first.Cell(0, 0).Value, Equals, "5")
+	c.Assert(first.Cell(1, 0).Value, Equals, "#NAME?")
+	c.Assert(first.Cell(2, 0).Value, Equals, "2")
+	c.Assert(second.Cell(0, 0).Value, Equals, "20")
+}
+
+func (s *CalcSuite) TestRecalculateCircularReference(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	setCells(c, sheet, map[string]interface{}{
+		"A1": "=B1+1",
+		"B1": "=A1+1",
+		"C1": "=C1",
+		"D1": "=A1*10",
+		"E1": `=SUM(INDIRECT("E2"))`,
+		"E2": "=E1",
+		"F1": "=1+2",
+	})
+	sheet.Cell(0, 0).Value = "7"
+	err = f.Recalculate()
+	c.Assert(err, NotNil)
+	var cellErr *CellError
+	c.Assert(errors.As(err, &cellErr), Equals, true)
+	c.Assert(cellErr.Sheet, Equals, "Sheet1")
+	c.Assert(cellErr.Cell, Equals, "A1")
+	c.Assert(errors.Is(err, ErrCircularReference), Equals, true)
+
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "7")
+	c.Assert(sheet.Cell(0, 3).Value, Equals, "70")
+	c.Assert(sheet.Cell(0, 5).Value, Equals, "3")
+}
+
+func (s *CalcSuite) TestRecalculateSyntaxError(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	setCells(c, sheet, map[string]interface{}{"A1": "=1+", "A2": "=ISERROR(A1)"})
+	err = f.Recalculate()
+	var syntaxErr *formula.SyntaxError
+	c.Assert(errors.As(err, &syntaxErr), Equals, true)
+	c.Assert(err, ErrorMatches, `sheet 'Sheet1', cell A1: formula: .*`)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "#NAME?")
+	c.Assert(sheet.Cell(0, 0).Type(), Equals, CellTypeError)
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "1")
+}
+
+func (s *CalcSuite) TestRecalculateText(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	setCells(c, sheet, map[string]interface{}{
+		"A1": `=TEXT(1234.5,"#,##0.00")`,
+		"A2": `=TEXT(DATE(2020,1,31),"yyyy-mm-dd")`,
+	})
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "1,234.50")
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "2020-01-31")
+}
+
+func (s *CalcSuite) TestRecalculateRoundTrip(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	setCells(c, sheet, map[string]interface{}{
+		"A1": 2,
+		"A2": "=A1^10",
+		"A3": "=A2>1000",
+		"A4": `=REPT("ab",A1)`,
+		"A5": "=A1/0",
+		"A6": `=TEXT(A1,"0.00")`,
+		"A7": "=A6&1",
+	})
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheet.Cell(6, 0).Value, Equals, "2.001")
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	read, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	sheet = read.Sheet["Sheet1"]
+	expected := []struct {
+		value, formula string
+		cellType       CellType
+	}{
+		{"1024", "A1^10", CellTypeFormula},
+		{"1", "A2>1000", CellTypeBool},
+		{"abab", `REPT("ab",A1)`, CellTypeStringFormula},
+		{"#DIV/0!", "A1/0", CellTypeError},
+		{"2.00", `TEXT(A1,"0.00")`, CellTypeStringFormula},
+		{"2.001", "A6&1", CellTypeStringFormula},
+	}
+	for i, test := range expected {
+		cell := sheet.Cell(i+1, 0)
+		c.Check(cell.Value, Equals, test.value)
+		c.Check(cell.Formula(), Equals, test.formula)
+		c.Check(cell.Type(), Equals, test.cellType)
+	}
+
+	sheet.Cell(0, 0).SetInt(3)
+	c.Assert(read.Recalculate(), IsNil)
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "59049")
+	c.Assert(sheet.Cell(3, 0).Value, Equals, "ababab")
+	c.Assert(sheet.Cell(5, 0).Value, Equals, "3.00")
+	c.Assert(sheet.Cell(5, 0).Type(), Equals, CellTypeStringFormula)
+}
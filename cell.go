@@ -4,14 +4,15 @@ import (
 	"fmt"
 	"math"
 	"strconv"
-	"strings"
 	"time"
 )
 
 // CellType is an int type for storing metadata about the data type in the cell.
 type CellType int
 
-// Known types for cell values.
+// Known types for cell values.  A formula whose result is text, such
+// as a cell stored with t="str", is a CellTypeStringFormula and is
+// always written back that way; other formulas are CellTypeFormula.
 const (
 	CellTypeString CellType = iota
 	CellTypeFormula
@@ -21,6 +22,7 @@ const (
 	CellTypeError
 	CellTypeDate
 	CellTypeGeneral
+	CellTypeStringFormula // a formula whose result is text
 )
 
 // Cell is a high level structure intended to provide user access to
@@ -28,10 +30,12 @@ const (
 type Cell struct {
 	Row      *Row
 	Value    string
+	richText []RichTextRun
 	formula  string
 	style    *Style
 	NumFmt   string
 	date1904 bool
+	location *time.Location
 	Hidden   bool
 	HMerge   int
 	VMerge   int
@@ -56,6 +60,8 @@ func (c *Cell) Merge(hcells, vcells int) {
 }
 
 // Type returns the CellType of a cell. See CellType constants for more details.
+// A formula read with a text result gives CellTypeStringFormula rather
+// than CellTypeFormula.
 func (c *Cell) Type() CellType {
 	return c.cellType
 }
@@ -63,10 +69,31 @@ func (c *Cell) Type() CellType {
 // SetString sets the value of a cell to a string.
 func (c *Cell) SetString(s string) {
 	c.Value = s
+	c.richText = nil
 	c.formula = ""
 	c.cellType = CellTypeString
 }
 
+// SetRichText sets the value of a cell to a string made of runs of
+// text with their own fonts.  The Value of the cell is set to the
+// plain text of the runs.
+func (c *Cell) SetRichText(runs []RichTextRun) {
+	c.Value = richTextString(runs)
+	c.richText = append([]RichTextRun(nil), runs...)
+	c.formula = ""
+	c.cellType = CellTypeString
+}
+
+// RichText returns the runs of a rich text cell.  It returns nil if
+// the cell holds a plain string or a value of another type, or if its
+// Value has been changed since the rich text was set.
+func (c *Cell) RichText() []RichTextRun {
+	if c.richText == nil || (c.cellType != CellTypeString && c.cellType != CellTypeInline) || richTextString(c.richText) != c.Value {
+		return nil
+	}
+	return append([]RichTextRun(nil), c.richText...)
+}
+
 // String returns the value of a Cell as a string.
 func (c *Cell) String() (string, error) {
 	return c.FormattedValue()
@@ -109,17 +136,98 @@ func timeToUTCTime(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), timeLocationUTC)
 }
 
-func timeToExcelTime(t time.Time) float64 {
-	return float64(t.Unix())/86400.0 + 25569.0
+// usesDate1904 reports whether the cell's value counts days in the
+// 1904 date system: that of the file the cell belongs to, or that it
+// was read with.
+func (c *Cell) usesDate1904() bool {
+	if c.Row != nil && c.Row.Sheet != nil && c.Row.Sheet.File != nil {
+		return c.Row.Sheet.File.Date1904
+	}
+	return c.date1904
+}
+
+// SetLocation sets the time zone of the cell's dates and times,
+// overriding File.Location.  A nil location gives the cell wall-clock
+// semantics, unless its file has a location.
+func (c *Cell) SetLocation(loc *time.Location) {
+	c.location = loc
+}
+
+// Location returns the time zone of the cell's dates and times: its
+// own, or else that of its file.  It is nil for wall-clock semantics.
+func (c *Cell) Location() *time.Location {
+	if c.location != nil {
+		return c.location
+	}
+	if c.Row != nil && c.Row.Sheet != nil && c.Row.Sheet.File != nil {
+		return c.Row.Sheet.File.Location
+	}
+	return nil
+}
+
+// wallClock returns t as it reads in the cell's location, or t itself
+// for wall-clock semantics.
+func (c *Cell) wallClock(t time.Time) time.Time {
+	if loc := c.Location(); loc != nil {
+		return t.In(loc)
+	}
+	return t
 }
 
-// SetDate sets the value of a cell to a float.
+// SetDate sets the value of a cell to the day of t, in the date
+// system of the cell's file.  The day is that of t in the cell's
+// location, if it has one.
 func (c *Cell) SetDate(t time.Time) {
-	c.SetDateTimeWithFormat(float64(int64(timeToExcelTime(timeToUTCTime(t)))), builtInNumFmt[14])
+	c.SetDateTimeWithFormat(math.Floor(timeToExcelTime(c.wallClock(t), c.usesDate1904())), builtInNumFmt[14])
 }
 
+// SetDateTime sets the value of a cell to the time t, in the date
+// system of the cell's file.  Excel times have no time zone: with a
+// location, the cell holds t as it reads there, and otherwise the
+// wall-clock time of t, whatever its zone.
 func (c *Cell) SetDateTime(t time.Time) {
-	c.SetDateTimeWithFormat(timeToExcelTime(timeToUTCTime(t)), builtInNumFmt[22])
+	c.SetDateTimeWithFormat(timeToExcelTime(c.wallClock(t), c.usesDate1904()), builtInNumFmt[22])
+}
+
+// GetTime returns the date and time in the cell, taking the wall-clock
+// time it holds to be in loc.  If loc is nil the cell's location is
+// used, and UTC for cells without one.
+func (c *Cell) GetTime(loc *time.Location) (time.Time, error) {
+	f, err := c.Float()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.timeIn(f, loc), nil
+}
+
+// timeIn converts the excelTime f, in the date system of the cell, to
+// a time, as GetTime does.
+func (c *Cell) timeIn(f float64, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = c.Location()
+	}
+	t := TimeFromExcelTime(f, c.usesDate1904())
+	if loc == nil {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// SetDuration sets the value of a cell to the length of time d, shown
+// in hours, minutes and seconds.
+func (c *Cell) SetDuration(d time.Duration) {
+	c.SetDateTimeWithFormat(d.Hours()/24, builtInNumFmt[46])
+}
+
+// GetDuration returns the value of the cell as a length of time, for
+// cells holding elapsed times or the time of day.
+func (c *Cell) GetDuration() (time.Duration, error) {
+	f, err := c.Float()
+	if err != nil {
+		return 0, err
+	}
+	// Round to the microsecond, as TimeFromExcelTime does.
+	return time.Duration(math.Floor(f*24*60*60*1e6+0.5)) * time.Microsecond, nil
 }
 
 func (c *Cell) SetDateTimeWithFormat(n float64, format string) {
@@ -202,6 +310,7 @@ func (c *Cell) SetBool(b bool) {
 	} else {
 		c.Value = "0"
 	}
+	c.formula = ""
 	c.cellType = CellTypeBool
 }
 
@@ -250,144 +359,57 @@ func (c *Cell) GetNumberFormat() string {
 	return c.NumFmt
 }
 
-func (c *Cell) formatToFloat(format string) (string, error) {
-	f, err := strconv.ParseFloat(c.Value, 64)
-	if err != nil {
-		return c.Value, err
-	}
-	return fmt.Sprintf(format, f), nil
-}
-
-func (c *Cell) formatToInt(format string) (string, error) {
-	f, err := strconv.ParseFloat(c.Value, 64)
-	if err != nil {
-		return c.Value, err
-	}
-	return fmt.Sprintf(format, int(f)), nil
-}
-
 // FormattedValue returns a value, and possibly an error condition
-// from a Cell.  If it is possible to apply a format to the cell
-// value, it will do so, if not then an error will be returned, along
-// with the raw value of the Cell.
+// from a Cell, formatted for LocaleEnUS.  The value is rendered with the cell's number format as
+// Excel would show it: the format is split into its sections, the
+// section for the value is picked, taking conditions such as [>=100]
+// into account, and the number is laid out with its digit
+// placeholders, separators, literals, fractions or exponent.  If the
+// format is for numbers and the value isn't one, an error is returned,
+// along with the raw value of the Cell.
 func (c *Cell) FormattedValue() (string, error) {
-	var numberFormat = c.GetNumberFormat()
-	if isTimeFormat(numberFormat) {
-		return parseTime(c)
+	return c.FormattedValueWithLocale(LocaleEnUS)
+}
+
+// FormattedValueWithLocale is like FormattedValue, but formats the
+// value for locale: numbers with its decimal and thousands
+// separators, and dates with its names of months and days and its
+// AM/PM designators.  A locale named in the number format, as in
+// [$-407]d mmmm yyyy, takes over the names and designators if it is
+// registered.  A nil locale is taken to be LocaleEnUS.
+func (c *Cell) FormattedValueWithLocale(locale *Locale) (string, error) {
+	if locale == nil {
+		locale = LocaleEnUS
 	}
-	switch numberFormat {
-	case builtInNumFmt[builtInNumFmtIndex_GENERAL], builtInNumFmt[builtInNumFmtIndex_STRING]:
-		return c.Value, nil
-	case builtInNumFmt[builtInNumFmtIndex_INT], "#,##0":
-		return c.formatToInt("%d")
-	case builtInNumFmt[builtInNumFmtIndex_FLOAT], "#,##0.00":
-		return c.formatToFloat("%.2f")
-	case "#,##0 ;(#,##0)", "#,##0 ;[red](#,##0)":
-		f, err := strconv.ParseFloat(c.Value, 64)
-		if err != nil {
+	format := parseNumberFormat(c.GetNumberFormat())
+	isString := c.cellType == CellTypeString || c.cellType == CellTypeInline || c.cellType == CellTypeStringFormula
+	value, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil || isString {
+		switch {
+		case format.text != nil:
+			return format.formatText(c.Value), nil
+		case format.isTextOnly() || c.Value == "":
+			return c.Value, nil
+		case err != nil:
 			return c.Value, err
 		}
-		if f < 0 {
-			i := int(math.Abs(f))
-			return fmt.Sprintf("(%d)", i), nil
-		}
-		i := int(f)
-		return fmt.Sprintf("%d", i), nil
-	case "#,##0.00;(#,##0.00)", "#,##0.00;[red](#,##0.00)":
-		f, err := strconv.ParseFloat(c.Value, 64)
-		if err != nil {
-			return c.Value, err
-		}
-		if f < 0 {
-			return fmt.Sprintf("(%.2f)", f), nil
-		}
-		return fmt.Sprintf("%.2f", f), nil
-	case "0%":
-		f, err := strconv.ParseFloat(c.Value, 64)
-		if err != nil {
-			return c.Value, err
-		}
-		f = f * 100
-		return fmt.Sprintf("%d%%", int(f)), nil
-	case "0.00%":
-		f, err := strconv.ParseFloat(c.Value, 64)
-		if err != nil {
-			return c.Value, err
-		}
-		f = f * 100
-		return fmt.Sprintf("%.2f%%", f), nil
-	case "0.00e+00", "##0.0e+0":
-		return c.formatToFloat("%e")
 	}
-	return c.Value, nil
-
+	section, dropSign := format.section(value)
+	switch {
+	case section == nil:
+		return locale.localizeNumber(formatGeneralNumber(value)), nil
+	case section.isDate:
+		return parseTime(c, section.raw, locale.withNames(section.locale))
+	}
+	return section.formatNumber(value, dropSign, locale), nil
 }
 
-// parseTime returns a string parsed using time.Time
-func parseTime(c *Cell) (string, error) {
+// parseTime returns the value of the cell, formatted as a date or
+// time with format for locale.
+func parseTime(c *Cell, format string, locale *Locale) (string, error) {
 	f, err := strconv.ParseFloat(c.Value, 64)
 	if err != nil {
 		return c.Value, err
 	}
-	val := TimeFromExcelTime(f, c.date1904)
-	format := c.GetNumberFormat()
-
-	// Replace Excel placeholders with Go time placeholders.
-	// For example, replace yyyy with 2006. These are in a specific order,
-	// due to the fact that m is used in month, minute, and am/pm. It would
-	// be easier to fix that with regular expressions, but if it's possible
-	// to keep this simple it would be easier to maintain.
-	// Full-length month and days (e.g. March, Tuesday) have letters in them that would be replaced
-	// by other characters below (such as the 'h' in March, or the 'd' in Tuesday) below.
-	// First we convert them to arbitrary characters unused in Excel Date formats, and then at the end,
-	// turn them to what they should actually be.
-	// Based off: http://www.ozgrid.com/Excel/CustomFormats.htm
-	replacements := []struct{ xltime, gotime string }{
-		{"yyyy", "2006"},
-		{"yy", "06"},
-		{"mmmm", "%%%%"},
-		{"dddd", "&&&&"},
-		{"dd", "02"},
-		{"d", "2"},
-		{"mmm", "Jan"},
-		{"mmss", "0405"},
-		{"ss", "05"},
-		{"hh", "15"},
-		{"h", "3"},
-		{"mm:", "04:"},
-		{":mm", ":04"},
-		{"mm", "01"},
-		{"am/pm", "pm"},
-		{"m/", "1/"},
-		{"%%%%", "January"},
-		{"&&&&", "Monday"},
-	}
-	for _, repl := range replacements {
-		format = strings.Replace(format, repl.xltime, repl.gotime, 1)
-	}
-	// If the hour is optional, strip it out, along with the
-	// possible dangling colon that would remain.
-	if val.Hour() < 1 {
-		format = strings.Replace(format, "]:", "]", 1)
-		format = strings.Replace(format, "[3]", "", 1)
-		format = strings.Replace(format, "[15]", "", 1)
-	} else {
-		format = strings.Replace(format, "[3]", "3", 1)
-		format = strings.Replace(format, "[15]", "15", 1)
-	}
-	return val.Format(format), nil
-}
-
-// isTimeFormat checks whether an Excel format string represents
-// a time.Time.
-func isTimeFormat(format string) bool {
-	dateParts := []string{
-		"yy", "hh", "am", "pm", "ss", "mm", ":",
-	}
-	for _, part := range dateParts {
-		if strings.Contains(format, part) {
-			return true
-		}
-	}
-	return false
+	return formatDateTime(f, format, c.usesDate1904(), locale), nil
 }
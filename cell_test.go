@@ -159,7 +159,7 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	cell := Cell{Value: "37947.7500001"}
 	negativeCell := Cell{Value: "-37947.7500001"}
 	smallCell := Cell{Value: "0.007"}
-	earlyCell := Cell{Value: "2.1"}
+	earlyCell := Cell{Value: "1.1"} // 1 January 1900, 02:24
 
 	fvc := formattedValueChecker{c: c}
 
@@ -168,40 +168,35 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	negativeCell.NumFmt = "general"
 	fvc.Equals(negativeCell, "-37947.7500001")
 
-	// TODO: This test is currently broken.  For a string type cell, I
-	// don't think FormattedValue() should be doing a numeric conversion on the value
-	// before returning the string.
+	// A string cell holding a number is still formatted as one by a
+	// number format, rounding half away from zero as Excel does.
 	cell.NumFmt = "0"
-	fvc.Equals(cell, "37947")
+	fvc.Equals(cell, "37948")
 
-	cell.NumFmt = "#,##0" // For the time being we're not doing
-	// this comma formatting, so it'll fall back to the related
-	// non-comma form.
-	fvc.Equals(cell, "37947")
+	cell.NumFmt = "#,##0"
+	fvc.Equals(cell, "37,948")
 
 	cell.NumFmt = "#,##0.00;(#,##0.00)"
-	fvc.Equals(cell, "37947.75")
+	fvc.Equals(cell, "37,947.75")
 
 	cell.NumFmt = "0.00"
 	fvc.Equals(cell, "37947.75")
 
-	cell.NumFmt = "#,##0.00" // For the time being we're not doing
-	// this comma formatting, so it'll fall back to the related
-	// non-comma form.
-	fvc.Equals(cell, "37947.75")
+	cell.NumFmt = "#,##0.00"
+	fvc.Equals(cell, "37,947.75")
 
 	cell.NumFmt = "#,##0 ;(#,##0)"
-	fvc.Equals(cell, "37947")
+	fvc.Equals(cell, "37,948 ")
 	negativeCell.NumFmt = "#,##0 ;(#,##0)"
-	fvc.Equals(negativeCell, "(37947)")
+	fvc.Equals(negativeCell, "(37,948)")
 
 	cell.NumFmt = "#,##0 ;[red](#,##0)"
-	fvc.Equals(cell, "37947")
+	fvc.Equals(cell, "37,948 ")
 	negativeCell.NumFmt = "#,##0 ;[red](#,##0)"
-	fvc.Equals(negativeCell, "(37947)")
+	fvc.Equals(negativeCell, "(37,948)")
 
 	negativeCell.NumFmt = "#,##0.00;(#,##0.00)"
-	fvc.Equals(negativeCell, "(-37947.75)")
+	fvc.Equals(negativeCell, "(37,947.75)")
 
 	cell.NumFmt = "0%"
 	fvc.Equals(cell, "3794775%")
@@ -210,10 +205,10 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	fvc.Equals(cell, "3794775.00%")
 
 	cell.NumFmt = "0.00e+00"
-	fvc.Equals(cell, "3.794775e+04")
+	fvc.Equals(cell, "3.79e+04")
 
-	cell.NumFmt = "##0.0e+0" // This is wrong, but we'll use it for now.
-	fvc.Equals(cell, "3.794775e+04")
+	cell.NumFmt = "##0.0e+0"
+	fvc.Equals(cell, "37.9e+3")
 
 	cell.NumFmt = "mm-dd-yy"
 	fvc.Equals(cell, "11-22-03")
@@ -241,33 +236,33 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	cell.NumFmt = "hh:mm:ss"
 	fvc.Equals(cell, "18:00:00")
 	smallCell.NumFmt = "h:mm:ss am/pm"
-	fvc.Equals(smallCell, "12:10:04 am")
+	fvc.Equals(smallCell, "12:10:05 am")
 
 	cell.NumFmt = "h:mm"
-	fvc.Equals(cell, "6:00")
+	fvc.Equals(cell, "18:00")
 	smallCell.NumFmt = "h:mm"
-	fvc.Equals(smallCell, "12:10")
+	fvc.Equals(smallCell, "0:10")
 	smallCell.NumFmt = "hh:mm"
 	fvc.Equals(smallCell, "00:10")
 
 	cell.NumFmt = "h:mm:ss"
-	fvc.Equals(cell, "6:00:00")
+	fvc.Equals(cell, "18:00:00")
 	cell.NumFmt = "hh:mm:ss"
 	fvc.Equals(cell, "18:00:00")
 
 	smallCell.NumFmt = "hh:mm:ss"
-	fvc.Equals(smallCell, "00:10:04")
+	fvc.Equals(smallCell, "00:10:05")
 	smallCell.NumFmt = "h:mm:ss"
-	fvc.Equals(smallCell, "12:10:04")
+	fvc.Equals(smallCell, "0:10:05")
 
 	cell.NumFmt = "m/d/yy h:mm"
-	fvc.Equals(cell, "11/22/03 6:00")
+	fvc.Equals(cell, "11/22/03 18:00")
 	cell.NumFmt = "m/d/yy hh:mm"
 	fvc.Equals(cell, "11/22/03 18:00")
 	smallCell.NumFmt = "m/d/yy h:mm"
-	fvc.Equals(smallCell, "12/30/99 12:10")
+	fvc.Equals(smallCell, "1/0/00 0:10")
 	smallCell.NumFmt = "m/d/yy hh:mm"
-	fvc.Equals(smallCell, "12/30/99 00:10")
+	fvc.Equals(smallCell, "1/0/00 00:10")
 	earlyCell.NumFmt = "m/d/yy hh:mm"
 	fvc.Equals(earlyCell, "1/1/00 02:24")
 	earlyCell.NumFmt = "m/d/yy h:mm"
@@ -276,32 +271,30 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	cell.NumFmt = "mm:ss"
 	fvc.Equals(cell, "00:00")
 	smallCell.NumFmt = "mm:ss"
-	fvc.Equals(smallCell, "10:04")
+	fvc.Equals(smallCell, "10:05")
 
+	// Elapsed hours aren't limited to a day.
 	cell.NumFmt = "[hh]:mm:ss"
-	fvc.Equals(cell, "18:00:00")
+	fvc.Equals(cell, "910746:00:00")
 	cell.NumFmt = "[h]:mm:ss"
-	fvc.Equals(cell, "6:00:00")
+	fvc.Equals(cell, "910746:00:00")
 	smallCell.NumFmt = "[h]:mm:ss"
-	fvc.Equals(smallCell, "10:04")
-
-	const (
-		expect1 = "0000.0086"
-		expect2 = "1004.8000"
-		format  = "mmss.0000"
-		tlen    = len(format)
-	)
-
-	for i := 0; i < 3; i++ {
-		tfmt := format[0 : tlen-i]
-		cell.NumFmt = tfmt
-		fvc.Equals(cell, expect1[0:tlen-i])
-		smallCell.NumFmt = tfmt
-		fvc.Equals(smallCell, expect2[0:tlen-i])
+	fvc.Equals(smallCell, "0:10:05")
+
+	// The seconds are rounded to the decimal places shown.
+	for _, sub := range []struct{ format, expect1, expect2 string }{
+		{"mmss.0000", "0000.0086", "1004.8000"},
+		{"mmss.000", "0000.009", "1004.800"},
+		{"mmss.00", "0000.01", "1004.80"},
+	} {
+		cell.NumFmt = sub.format
+		fvc.Equals(cell, sub.expect1)
+		smallCell.NumFmt = sub.format
+		fvc.Equals(smallCell, sub.expect2)
 	}
 
 	cell.NumFmt = "yyyy\\-mm\\-dd"
-	fvc.Equals(cell, "2003\\-11\\-22")
+	fvc.Equals(cell, "2003-11-22")
 
 	cell.NumFmt = "dd/mm/yyyy hh:mm:ss"
 	fvc.Equals(cell, "22/11/2003 18:00:00")
@@ -314,10 +307,10 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	cell.NumFmt = "hh:mm:ss"
 	fvc.Equals(cell, "18:00:00")
 	smallCell.NumFmt = "hh:mm:ss"
-	fvc.Equals(smallCell, "00:10:04")
+	fvc.Equals(smallCell, "00:10:05")
 
 	cell.NumFmt = "dd/mm/yy\\ hh:mm"
-	fvc.Equals(cell, "22/11/03\\ 18:00")
+	fvc.Equals(cell, "22/11/03 18:00")
 
 	cell.NumFmt = "yyyy/mm/dd"
 	fvc.Equals(cell, "2003/11/22")
@@ -347,29 +340,29 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	fvc.Equals(cell, "22/11/2003")
 
 	cell.NumFmt = "mm/dd/yy hh:mm am/pm"
-	fvc.Equals(cell, "11/22/03 18:00 pm")
+	fvc.Equals(cell, "11/22/03 06:00 pm")
 	cell.NumFmt = "mm/dd/yy h:mm am/pm"
 	fvc.Equals(cell, "11/22/03 6:00 pm")
 
 	cell.NumFmt = "mm/dd/yyyy hh:mm:ss"
 	fvc.Equals(cell, "11/22/2003 18:00:00")
 	smallCell.NumFmt = "mm/dd/yyyy hh:mm:ss"
-	fvc.Equals(smallCell, "12/30/1899 00:10:04")
+	fvc.Equals(smallCell, "01/00/1900 00:10:05")
 
 	cell.NumFmt = "yyyy-mm-dd hh:mm:ss"
 	fvc.Equals(cell, "2003-11-22 18:00:00")
 	smallCell.NumFmt = "yyyy-mm-dd hh:mm:ss"
-	fvc.Equals(smallCell, "1899-12-30 00:10:04")
+	fvc.Equals(smallCell, "1900-01-00 00:10:05")
 
 	cell.NumFmt = "mmmm d, yyyy"
 	fvc.Equals(cell, "November 22, 2003")
 	smallCell.NumFmt = "mmmm d, yyyy"
-	fvc.Equals(smallCell, "December 30, 1899")
+	fvc.Equals(smallCell, "January 0, 1900")
 
 	cell.NumFmt = "dddd, mmmm dd, yyyy"
 	fvc.Equals(cell, "Saturday, November 22, 2003")
 	smallCell.NumFmt = "dddd, mmmm dd, yyyy"
-	fvc.Equals(smallCell, "Saturday, December 30, 1899")
+	fvc.Equals(smallCell, "Saturday, January 00, 1900")
 }
 
 // test setters and getters
@@ -485,3 +478,80 @@ func (s *CellSuite) TestSetValue(c *C) {
 	cell.SetValue([]string{"test"})
 	c.Assert(cell.Value, Equals, "[test]")
 }
+
+// Without a location a cell holds the wall-clock time it is set to,
+// and with one it holds the time as it reads in that location.
+func (s *CellSuite) TestTimeZones(c *C) {
+	newYork := time.FixedZone("EST", -5*60*60)
+	tokyo := time.FixedZone("JST", 9*60*60)
+	meeting := time.Date(2013, 1, 1, 9, 0, 0, 0, newYork)
+
+	cell := Cell{}
+	cell.SetDateTime(meeting)
+	c.Assert(cell.Value, Equals, "41275.375")
+	t, err := cell.GetTime(nil)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(2013, 1, 1, 9, 0, 0, 0, time.UTC))
+	t, err = cell.GetTime(tokyo)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(2013, 1, 1, 9, 0, 0, 0, tokyo))
+
+	cell.SetLocation(tokyo)
+	cell.SetDateTime(meeting)
+	c.Assert(cell.Value, Equals, "41275.958333333336")
+	t, err = cell.GetTime(nil)
+	c.Assert(err, IsNil)
+	c.Assert(t.Equal(meeting), Equals, true)
+	c.Assert(t.Location(), Equals, tokyo)
+
+	// The file's location applies to cells without their own.
+	f := NewFile()
+	f.Location = newYork
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	fileCell := sheet.AddRow().AddCell()
+	c.Assert(fileCell.Location(), Equals, newYork)
+	fileCell.SetDate(time.Date(2013, 1, 2, 1, 0, 0, 0, time.UTC))
+	c.Assert(fileCell.Value, Equals, "41275")
+	t, err = fileCell.GetTime(nil)
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(2013, 1, 1, 0, 0, 0, 0, newYork))
+
+	cell.SetString("soon")
+	_, err = cell.GetTime(nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *CellSuite) TestDuration(c *C) {
+	cell := Cell{}
+	cell.SetDuration(27*time.Hour + 25*time.Minute + 45*time.Second)
+	c.Assert(cell.NumFmt, Equals, "[h]:mm:ss")
+	val, err := cell.FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(val, Equals, "27:25:45")
+	d, err := cell.GetDuration()
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, 27*time.Hour+25*time.Minute+45*time.Second)
+
+	cell.SetFloat(-0.5)
+	d, err = cell.GetDuration()
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, -12*time.Hour)
+}
+
+// Rich text is kept only for as long as the cell's value matches it.
+func (s *CellSuite) TestSetRichText(c *C) {
+	cell := Cell{}
+	runs := []RichTextRun{{Text: "Hello, "}, {Font: &Font{Italic: true}, Text: "World"}}
+	cell.SetRichText(runs)
+	c.Assert(cell.Value, Equals, "Hello, World")
+	c.Assert(cell.Type(), Equals, CellTypeString)
+	c.Assert(cell.RichText(), DeepEquals, runs)
+
+	cell.Value = "Goodbye"
+	c.Assert(cell.RichText(), IsNil)
+
+	cell.SetRichText(runs)
+	cell.SetString("Hello, World")
+	c.Assert(cell.RichText(), IsNil)
+}
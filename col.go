@@ -24,7 +24,7 @@ func (c *Col) SetType(cellType CellType) {
 		c.numFmt = builtInNumFmt[builtInNumFmtIndex_INT]
 	case CellTypeDate:
 		c.numFmt = builtInNumFmt[builtInNumFmtIndex_DATE]
-	case CellTypeFormula:
+	case CellTypeFormula, CellTypeStringFormula:
 		c.numFmt = builtInNumFmt[builtInNumFmtIndex_GENERAL]
 	case CellTypeError:
 		c.numFmt = builtInNumFmt[builtInNumFmtIndex_GENERAL] //TEMP
@@ -1,8 +1,34 @@
 package xlsx
 
 import (
+	"bytes"
 	. "gopkg.in/check.v1"
 	"testing"
 )
 
 func Test(t *testing.T) { TestingT(t) }
+
+// newTestFile returns a new file with a sheet for each of names, whose
+// cells are set from the maps of cells in turn, as setCells does.
+func newTestFile(c *C, names []string, cells ...map[string]interface{}) (*File, []*Sheet) {
+	f := NewFile()
+	sheets := make([]*Sheet, len(names))
+	for i, name := range names {
+		sheet, err := f.AddSheet(name)
+		c.Assert(err, IsNil)
+		if i < len(cells) {
+			setCells(c, sheet, cells[i])
+		}
+		sheets[i] = sheet
+	}
+	return f, sheets
+}
+
+// reopenTestFile returns the file read back after writing f.
+func reopenTestFile(c *C, f *File) *File {
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	reopened, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	return reopened
+}
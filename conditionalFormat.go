@@ -0,0 +1,742 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CFType is the type of a conditional format rule.
+type CFType string
+
+// Known types of conditional format rules.
+const (
+	CFTypeCellIs            CFType = "cellIs"
+	CFTypeExpression        CFType = "expression"
+	CFTypeColorScale        CFType = "colorScale"
+	CFTypeDataBar           CFType = "dataBar"
+	CFTypeIconSet           CFType = "iconSet"
+	CFTypeTop10             CFType = "top10"
+	CFTypeAboveAverage      CFType = "aboveAverage"
+	CFTypeDuplicateValues   CFType = "duplicateValues"
+	CFTypeUniqueValues      CFType = "uniqueValues"
+	CFTypeContainsText      CFType = "containsText"
+	CFTypeNotContainsText   CFType = "notContainsText"
+	CFTypeBeginsWith        CFType = "beginsWith"
+	CFTypeEndsWith          CFType = "endsWith"
+	CFTypeContainsBlanks    CFType = "containsBlanks"
+	CFTypeNotContainsBlanks CFType = "notContainsBlanks"
+	CFTypeContainsErrors    CFType = "containsErrors"
+	CFTypeNotContainsErrors CFType = "notContainsErrors"
+	CFTypeTimePeriod        CFType = "timePeriod"
+)
+
+// CFOperator is the comparison made by a cellIs rule, or by one of
+// the text rules.
+type CFOperator string
+
+// Known conditional format operators.
+const (
+	CFOperatorLessThan           CFOperator = "lessThan"
+	CFOperatorLessThanOrEqual    CFOperator = "lessThanOrEqual"
+	CFOperatorEqual              CFOperator = "equal"
+	CFOperatorNotEqual           CFOperator = "notEqual"
+	CFOperatorGreaterThanOrEqual CFOperator = "greaterThanOrEqual"
+	CFOperatorGreaterThan        CFOperator = "greaterThan"
+	CFOperatorBetween            CFOperator = "between"
+	CFOperatorNotBetween         CFOperator = "notBetween"
+	CFOperatorContainsText       CFOperator = "containsText"
+	CFOperatorNotContains        CFOperator = "notContains"
+	CFOperatorBeginsWith         CFOperator = "beginsWith"
+	CFOperatorEndsWith           CFOperator = "endsWith"
+)
+
+// CFValueType says how the threshold of a color scale, data bar or
+// icon set is worked out.
+type CFValueType string
+
+// Known conditional format value types.
+const (
+	CFValueMin        CFValueType = "min"
+	CFValueMax        CFValueType = "max"
+	CFValueNumber     CFValueType = "num"
+	CFValuePercent    CFValueType = "percent"
+	CFValuePercentile CFValueType = "percentile"
+	CFValueFormula    CFValueType = "formula"
+)
+
+// CFTimePeriod is the period of time matched by a timePeriod rule.
+type CFTimePeriod string
+
+// Known time periods.
+const (
+	CFToday     CFTimePeriod = "today"
+	CFYesterday CFTimePeriod = "yesterday"
+	CFTomorrow  CFTimePeriod = "tomorrow"
+	CFLast7Days CFTimePeriod = "last7Days"
+	CFThisWeek  CFTimePeriod = "thisWeek"
+	CFLastWeek  CFTimePeriod = "lastWeek"
+	CFNextWeek  CFTimePeriod = "nextWeek"
+	CFThisMonth CFTimePeriod = "thisMonth"
+	CFLastMonth CFTimePeriod = "lastMonth"
+	CFNextMonth CFTimePeriod = "nextMonth"
+)
+
+// CFValue is a threshold of a color scale, data bar or icon set.
+// Value is not used by the min and max types.
+type CFValue struct {
+	Type  CFValueType
+	Value string
+	// GreaterThan makes an icon set threshold exclusive.  By
+	// default, a cell equal to the threshold reaches it.
+	GreaterThan bool
+}
+
+// ColorScale describes the colours of a colorScale rule: each of
+// the two or three Values has the matching entry of Colors, and the
+// colours of the cells in between are blended.
+type ColorScale struct {
+	Values []CFValue
+	Colors []string
+}
+
+// DataBar describes the bars drawn by a dataBar rule.
+type DataBar struct {
+	Min       CFValue
+	Max       CFValue
+	Color     string
+	MinLength int // Shortest bar, as a percentage of the cell width
+	MaxLength int // Longest bar, as a percentage of the cell width
+	HideValue bool
+}
+
+// IconSet describes the icons shown by an iconSet rule.  Style is
+// the name of the icon set, such as "3TrafficLights1" or "5Arrows",
+// and there is one threshold in Values for each icon.
+type IconSet struct {
+	Style     string
+	Values    []CFValue
+	Reverse   bool
+	HideValue bool
+}
+
+// DifferentialStyle is the formatting applied to a cell when a
+// conditional format rule matches it.  It is applied on top of the
+// cell's own style, and only the parts that are set take effect.
+// For solid fills Excel takes the colour from the BgColor of the Fill.
+type DifferentialStyle struct {
+	Font   *Font
+	Fill   *Fill
+	Border *Border
+	NumFmt string
+}
+
+// ConditionalFormatRule is a conditional format rule.  Type says
+// which of the other fields are used; the New...Rule functions
+// return rules of each type with those fields set.
+type ConditionalFormatRule struct {
+	Type         CFType
+	Operator     CFOperator
+	Formula      []string
+	Style        *DifferentialStyle
+	Priority     int  // Lower values are applied first; 0 assigns the next free priority
+	StopIfTrue   bool // Rules with a lower priority are not applied when this one matches
+	Text         string
+	TimePeriod   CFTimePeriod
+	Rank         int
+	Percent      bool
+	Bottom       bool
+	BelowAverage bool
+	EqualAverage bool
+	StdDev       int
+	ColorScale   *ColorScale
+	DataBar      *DataBar
+	IconSet      *IconSet
+}
+
+// ConditionalFormat applies conditional format rules to the cells of
+// Sqref, a space separated list of cell references and ranges such as
+// "A1:A10 C1:C10".  The rules are kept in the order they were added;
+// the order in which they are evaluated is set by their Priority,
+// across all the rules of the sheet.
+type ConditionalFormat struct {
+	Sqref string
+	Rules []*ConditionalFormatRule
+}
+
+// NewCellIsRule returns a rule that applies style to cells whose
+// value compares to the formulas with operator.  The between and
+// notBetween operators take two formulas, the others one.
+func NewCellIsRule(operator CFOperator, style *DifferentialStyle, formula ...string) *ConditionalFormatRule {
+	return &ConditionalFormatRule{Type: CFTypeCellIs, Operator: operator, Formula: formula, Style: style}
+}
+
+// NewExpressionRule returns a rule that applies style to cells for
+// which formula is true.  References in the formula are relative to
+// the top left cell of the range.
+func NewExpressionRule(formula string, style *DifferentialStyle) *ConditionalFormatRule {
+	return &ConditionalFormatRule{Type: CFTypeExpression, Formula: []string{formula}, Style: style}
+}
+
+// NewColorScaleRule returns a two colour scale rule, shading cells
+// from minColor for the lowest value to maxColor for the highest.
+func NewColorScaleRule(minColor, maxColor string) *ConditionalFormatRule {
+	return &ConditionalFormatRule{
+		Type: CFTypeColorScale,
+		ColorScale: &ColorScale{
+			Values: []CFValue{{Type: CFValueMin}, {Type: CFValueMax}},
+			Colors: []string{minColor, maxColor},
+		},
+	}
+}
+
+// NewThreeColorScaleRule returns a three colour scale rule, with
+// midColor for the median value.
+func NewThreeColorScaleRule(minColor, midColor, maxColor string) *ConditionalFormatRule {
+	return &ConditionalFormatRule{
+		Type: CFTypeColorScale,
+		ColorScale: &ColorScale{
+			Values: []CFValue{{Type: CFValueMin}, {Type: CFValuePercentile, Value: "50"}, {Type: CFValueMax}},
+			Colors: []string{minColor, midColor, maxColor},
+		},
+	}
+}
+
+// NewDataBarRule returns a rule that draws a bar of the given colour
+// in each cell, in proportion to its value.
+func NewDataBarRule(color string) *ConditionalFormatRule {
+	return &ConditionalFormatRule{
+		Type: CFTypeDataBar,
+		DataBar: &DataBar{
+			Min:   CFValue{Type: CFValueMin},
+			Max:   CFValue{Type: CFValueMax},
+			Color: color,
+		},
+	}
+}
+
+// NewIconSetRule returns a rule that shows an icon from the named
+// icon set in each cell, with the icons spread evenly over the range
+// of values.
+func NewIconSetRule(style string) *ConditionalFormatRule {
+	icons := 3
+	if len(style) > 0 && style[0] >= '3' && style[0] <= '5' {
+		icons = int(style[0] - '0')
+	}
+	values := make([]CFValue, icons)
+	for i := range values {
+		values[i] = CFValue{Type: CFValuePercent, Value: strconv.Itoa((100*i + icons/2) / icons)}
+	}
+	return &ConditionalFormatRule{Type: CFTypeIconSet, IconSet: &IconSet{Style: style, Values: values}}
+}
+
+// NewTop10Rule returns a rule that applies style to the cells with
+// the rank highest values.  Set Bottom for the lowest values, and
+// Percent to make rank a percentage of the cells.
+func NewTop10Rule(rank int, style *DifferentialStyle) *ConditionalFormatRule {
+	return &ConditionalFormatRule{Type: CFTypeTop10, Rank: rank, Style: style}
+}
+
+// NewAboveAverageRule returns a rule that applies style to the cells
+// above the average of the range.  Set BelowAverage, EqualAverage
+// and StdDev to match other cells.
+func NewAboveAverageRule(style *DifferentialStyle) *ConditionalFormatRule {
+	return &ConditionalFormatRule{Type: CFTypeAboveAverage, Style: style}
+}
+
+// NewDuplicateValuesRule returns a rule that applies style to the
+// cells whose value appears more than once in the range.
+func NewDuplicateValuesRule(style *DifferentialStyle) *ConditionalFormatRule {
+	return &ConditionalFormatRule{Type: CFTypeDuplicateValues, Style: style}
+}
+
+// NewContainsTextRule returns a rule that applies style to the cells
+// containing text.
+func NewContainsTextRule(text string, style *DifferentialStyle) *ConditionalFormatRule {
+	return &ConditionalFormatRule{Type: CFTypeContainsText, Operator: CFOperatorContainsText, Text: text, Style: style}
+}
+
+// NewTimePeriodRule returns a rule that applies style to the cells
+// holding a date within period.
+func NewTimePeriodRule(period CFTimePeriod, style *DifferentialStyle) *ConditionalFormatRule {
+	return &ConditionalFormatRule{Type: CFTypeTimePeriod, TimePeriod: period, Style: style}
+}
+
+// AddConditionalFormat applies rules to the cells of sqref, a space
+// separated list of cell references and ranges.  If the sheet already
+// has conditional formatting for sqref, the rules are added after its
+// rules.  A rule without a Priority is given a lower priority than
+// every rule already on the sheet, so rules added together are
+// evaluated in the order given.
+func (s *Sheet) AddConditionalFormat(sqref string, rules ...*ConditionalFormatRule) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+	if err := checkSqref(sqref); err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("xlsx: no conditional format rules for '%s'", sqref)
+	}
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	var cf *ConditionalFormat
+	for i := range s.ConditionalFormatting {
+		if s.ConditionalFormatting[i].Sqref == sqref {
+			cf = &s.ConditionalFormatting[i]
+			break
+		}
+	}
+	if cf == nil {
+		s.ConditionalFormatting = append(s.ConditionalFormatting, ConditionalFormat{Sqref: sqref})
+		cf = &s.ConditionalFormatting[len(s.ConditionalFormatting)-1]
+	}
+	for _, rule := range rules {
+		if rule.Priority == 0 {
+			rule.Priority = s.nextCFPriority()
+		}
+		cf.Rules = append(cf.Rules, rule)
+	}
+	return nil
+}
+
+// nextCFPriority returns a priority lower than that of every
+// conditional format rule of the sheet.
+func (s *Sheet) nextCFPriority() int {
+	priority := 0
+	for _, cf := range s.ConditionalFormatting {
+		for _, rule := range cf.Rules {
+			if rule != nil && rule.Priority > priority {
+				priority = rule.Priority
+			}
+		}
+	}
+	return priority + 1
+}
+
+// cfPriorities returns the priority to write for each conditional
+// format rule of the sheet, indexed like s.ConditionalFormatting and
+// its Rules.  Every rule of a sheet needs a priority of its own, so
+// the rules are numbered from 1 in order of their Priority.  Rules
+// with equal priorities keep their order on the sheet, and rules
+// without a priority come last.
+func (s *Sheet) cfPriorities() [][]int {
+	type position struct{ cf, rule, priority int }
+	var positions []position
+	priorities := make([][]int, len(s.ConditionalFormatting))
+	for i, cf := range s.ConditionalFormatting {
+		priorities[i] = make([]int, len(cf.Rules))
+		for j, rule := range cf.Rules {
+			if rule != nil {
+				positions = append(positions, position{i, j, rule.Priority})
+			}
+		}
+	}
+	sort.SliceStable(positions, func(a, b int) bool {
+		pa, pb := positions[a].priority, positions[b].priority
+		if pa == 0 || pb == 0 {
+			return pb == 0 && pa != 0
+		}
+		return pa < pb
+	})
+	for n, p := range positions {
+		priorities[p.cf][p.rule] = n + 1
+	}
+	return priorities
+}
+
+// checkSqref returns an error if sqref isn't a list of valid cell
+// references and ranges.
+func checkSqref(sqref string) error {
+	refs := strings.Fields(sqref)
+	if len(refs) == 0 {
+		return fmt.Errorf("xlsx: empty conditional format range")
+	}
+	for _, ref := range refs {
+		for _, cell := range strings.SplitN(ref, ":", 2) {
+			if _, _, err := getCoordsFromCellIDString(cell); err != nil {
+				return fmt.Errorf("xlsx: invalid conditional format range '%s'", sqref)
+			}
+		}
+	}
+	return nil
+}
+
+// validate returns an error if the fields that the type of the rule
+// relies on are missing.
+func (rule *ConditionalFormatRule) validate() error {
+	switch rule.Type {
+	case CFTypeCellIs:
+		want := 1
+		if rule.Operator == CFOperatorBetween || rule.Operator == CFOperatorNotBetween {
+			want = 2
+		}
+		if rule.Operator == "" || len(rule.Formula) != want {
+			return fmt.Errorf("xlsx: cellIs rule with operator '%s' needs %d formula(s)", rule.Operator, want)
+		}
+	case CFTypeExpression:
+		if len(rule.Formula) != 1 {
+			return fmt.Errorf("xlsx: expression rule needs a formula")
+		}
+	case CFTypeColorScale:
+		if rule.ColorScale == nil || len(rule.ColorScale.Values) < 2 || len(rule.ColorScale.Values) > 3 ||
+			len(rule.ColorScale.Values) != len(rule.ColorScale.Colors) {
+			return fmt.Errorf("xlsx: colorScale rule needs two or three values, each with a colour")
+		}
+	case CFTypeDataBar:
+		if rule.DataBar == nil {
+			return fmt.Errorf("xlsx: dataBar rule needs a DataBar")
+		}
+	case CFTypeIconSet:
+		if rule.IconSet == nil || len(rule.IconSet.Values) < 2 {
+			return fmt.Errorf("xlsx: iconSet rule needs an IconSet with thresholds")
+		}
+	case CFTypeTop10:
+		if rule.Rank < 1 {
+			return fmt.Errorf("xlsx: top10 rule needs a rank")
+		}
+	case CFTypeContainsText, CFTypeNotContainsText, CFTypeBeginsWith, CFTypeEndsWith:
+		if rule.Text == "" {
+			return fmt.Errorf("xlsx: %s rule needs some text", rule.Type)
+		}
+	case CFTypeTimePeriod:
+		if rule.TimePeriod == "" {
+			return fmt.Errorf("xlsx: timePeriod rule needs a time period")
+		}
+	case CFTypeAboveAverage, CFTypeDuplicateValues, CFTypeUniqueValues,
+		CFTypeContainsBlanks, CFTypeNotContainsBlanks, CFTypeContainsErrors, CFTypeNotContainsErrors:
+	default:
+		return fmt.Errorf("xlsx: unknown conditional format rule type '%s'", rule.Type)
+	}
+	return nil
+}
+
+// makeXLSXCfRule returns the XML representation of a rule applied to
+// sqref, with the given priority.  Its differential style is added to
+// styles.
+func (rule *ConditionalFormatRule) makeXLSXCfRule(sqref string, priority int, styles *xlsxStyleSheet) xlsxCfRule {
+	xRule := xlsxCfRule{
+		Type:         string(rule.Type),
+		Priority:     priority,
+		StopIfTrue:   rule.StopIfTrue,
+		Percent:      rule.Percent,
+		Bottom:       rule.Bottom,
+		Operator:     string(rule.Operator),
+		Text:         rule.Text,
+		TimePeriod:   string(rule.TimePeriod),
+		Rank:         rule.Rank,
+		StdDev:       rule.StdDev,
+		EqualAverage: rule.EqualAverage,
+	}
+	for _, formula := range rule.Formula {
+		xRule.Formula = append(xRule.Formula, strings.TrimPrefix(formula, "="))
+	}
+	if len(xRule.Formula) == 0 {
+		if formula := rule.defaultFormula(sqref); formula != "" {
+			xRule.Formula = []string{formula}
+		}
+	}
+	if rule.Type == CFTypeAboveAverage && rule.BelowAverage {
+		aboveAverage := false
+		xRule.AboveAverage = &aboveAverage
+	}
+	if rule.Style != nil {
+		dxfId := styles.addDxf(rule.Style.makeXLSXDxf(styles))
+		xRule.DxfId = &dxfId
+	}
+	if cs := rule.ColorScale; cs != nil && rule.Type == CFTypeColorScale {
+		xRule.ColorScale = &xlsxColorScale{}
+		for i, value := range cs.Values {
+			xRule.ColorScale.Cfvo = append(xRule.ColorScale.Cfvo, value.makeXLSXCfvo())
+			xRule.ColorScale.Color = append(xRule.ColorScale.Color, xlsxColor{RGB: cs.Colors[i]})
+		}
+	}
+	if db := rule.DataBar; db != nil && rule.Type == CFTypeDataBar {
+		xRule.DataBar = &xlsxDataBar{
+			MinLength: db.MinLength,
+			MaxLength: db.MaxLength,
+			Cfvo:      []xlsxCfvo{db.Min.makeXLSXCfvo(), db.Max.makeXLSXCfvo()},
+			Color:     xlsxColor{RGB: db.Color},
+		}
+		if db.HideValue {
+			showValue := false
+			xRule.DataBar.ShowValue = &showValue
+		}
+	}
+	if is := rule.IconSet; is != nil && rule.Type == CFTypeIconSet {
+		xRule.IconSet = &xlsxIconSet{IconSet: is.Style, Reverse: is.Reverse}
+		for _, value := range is.Values {
+			xRule.IconSet.Cfvo = append(xRule.IconSet.Cfvo, value.makeXLSXCfvo())
+		}
+		if is.HideValue {
+			showValue := false
+			xRule.IconSet.ShowValue = &showValue
+		}
+	}
+	return xRule
+}
+
+// defaultFormula returns the formula that Excel stores alongside the
+// text, blank, error and time period rules, which are otherwise
+// described by their attributes alone.
+func (rule *ConditionalFormatRule) defaultFormula(sqref string) string {
+	cell := strings.SplitN(strings.Fields(sqref)[0], ":", 2)[0]
+	text := `"` + strings.Replace(rule.Text, `"`, `""`, -1) + `"`
+	day := "FLOOR(" + cell + ",1)"
+	week := "ROUNDDOWN(" + cell + ",0)"
+	switch rule.Type {
+	case CFTypeContainsText:
+		return "NOT(ISERROR(SEARCH(" + text + "," + cell + ")))"
+	case CFTypeNotContainsText:
+		return "ISERROR(SEARCH(" + text + "," + cell + "))"
+	case CFTypeBeginsWith:
+		return "LEFT(" + cell + ",LEN(" + text + "))=" + text
+	case CFTypeEndsWith:
+		return "RIGHT(" + cell + ",LEN(" + text + "))=" + text
+	case CFTypeContainsBlanks:
+		return "LEN(TRIM(" + cell + "))=0"
+	case CFTypeNotContainsBlanks:
+		return "LEN(TRIM(" + cell + "))>0"
+	case CFTypeContainsErrors:
+		return "ISERROR(" + cell + ")"
+	case CFTypeNotContainsErrors:
+		return "NOT(ISERROR(" + cell + "))"
+	case CFTypeTimePeriod:
+		switch rule.TimePeriod {
+		case CFToday:
+			return day + "=TODAY()"
+		case CFYesterday:
+			return day + "=TODAY()-1"
+		case CFTomorrow:
+			return day + "=TODAY()+1"
+		case CFLast7Days:
+			return "AND(TODAY()-" + day + "<=6," + day + "<=TODAY())"
+		case CFThisWeek:
+			return "AND(TODAY()-" + week + "<=WEEKDAY(TODAY())-1," + week + "-TODAY()<=7-WEEKDAY(TODAY()))"
+		case CFLastWeek:
+			return "AND(TODAY()-" + week + ">=(WEEKDAY(TODAY()))," + "TODAY()-" + week + "<(WEEKDAY(TODAY())+7))"
+		case CFNextWeek:
+			return "AND(" + week + "-TODAY()>(7-WEEKDAY(TODAY()))," + week + "-TODAY()<(15-WEEKDAY(TODAY())))"
+		case CFThisMonth:
+			return "AND(MONTH(" + cell + ")=MONTH(TODAY()),YEAR(" + cell + ")=YEAR(TODAY()))"
+		case CFLastMonth:
+			return "AND(MONTH(" + cell + ")=MONTH(EDATE(TODAY(),0-1)),YEAR(" + cell + ")=YEAR(EDATE(TODAY(),0-1)))"
+		case CFNextMonth:
+			return "AND(MONTH(" + cell + ")=MONTH(EDATE(TODAY(),0+1)),YEAR(" + cell + ")=YEAR(EDATE(TODAY(),0+1)))"
+		}
+	}
+	return ""
+}
+
+// makeConditionalFormatRule returns the rule described by an xlsxCfRule,
+// looking its differential style up in styles.
+func makeConditionalFormatRule(xRule xlsxCfRule, styles *xlsxStyleSheet) *ConditionalFormatRule {
+	if styles == nil {
+		styles = newXlsxStyleSheet(nil)
+	}
+	rule := &ConditionalFormatRule{
+		Type:         CFType(xRule.Type),
+		Operator:     CFOperator(xRule.Operator),
+		Formula:      xRule.Formula,
+		Priority:     xRule.Priority,
+		StopIfTrue:   xRule.StopIfTrue,
+		Text:         xRule.Text,
+		TimePeriod:   CFTimePeriod(xRule.TimePeriod),
+		Rank:         xRule.Rank,
+		Percent:      xRule.Percent,
+		Bottom:       xRule.Bottom,
+		BelowAverage: xRule.AboveAverage != nil && !*xRule.AboveAverage,
+		EqualAverage: xRule.EqualAverage,
+		StdDev:       xRule.StdDev,
+	}
+	if xRule.DxfId != nil && *xRule.DxfId >= 0 && *xRule.DxfId < len(styles.Dxfs.Dxf) {
+		rule.Style = styles.makeDifferentialStyle(styles.Dxfs.Dxf[*xRule.DxfId])
+	}
+	if xcs := xRule.ColorScale; xcs != nil {
+		rule.ColorScale = &ColorScale{}
+		for i, cfvo := range xcs.Cfvo {
+			rule.ColorScale.Values = append(rule.ColorScale.Values, makeCFValue(cfvo))
+			color := ""
+			if i < len(xcs.Color) {
+				color = styles.argbValue(xcs.Color[i])
+			}
+			rule.ColorScale.Colors = append(rule.ColorScale.Colors, color)
+		}
+	}
+	if xdb := xRule.DataBar; xdb != nil {
+		rule.DataBar = &DataBar{
+			Color:     styles.argbValue(xdb.Color),
+			MinLength: xdb.MinLength,
+			MaxLength: xdb.MaxLength,
+			HideValue: xdb.ShowValue != nil && !*xdb.ShowValue,
+		}
+		if len(xdb.Cfvo) == 2 {
+			rule.DataBar.Min = makeCFValue(xdb.Cfvo[0])
+			rule.DataBar.Max = makeCFValue(xdb.Cfvo[1])
+		}
+	}
+	if xis := xRule.IconSet; xis != nil {
+		rule.IconSet = &IconSet{
+			Style:     xis.IconSet,
+			Reverse:   xis.Reverse,
+			HideValue: xis.ShowValue != nil && !*xis.ShowValue,
+		}
+		if rule.IconSet.Style == "" {
+			rule.IconSet.Style = "3TrafficLights1"
+		}
+		for _, cfvo := range xis.Cfvo {
+			rule.IconSet.Values = append(rule.IconSet.Values, makeCFValue(cfvo))
+		}
+	}
+	return rule
+}
+
+func (value CFValue) makeXLSXCfvo() xlsxCfvo {
+	cfvo := xlsxCfvo{Type: string(value.Type), Val: value.Value}
+	if value.GreaterThan {
+		gte := false
+		cfvo.Gte = &gte
+	}
+	return cfvo
+}
+
+func makeCFValue(cfvo xlsxCfvo) CFValue {
+	return CFValue{
+		Type:        CFValueType(cfvo.Type),
+		Value:       cfvo.Val,
+		GreaterThan: cfvo.Gte != nil && !*cfvo.Gte,
+	}
+}
+
+// makeXLSXDxf returns the differential format for a
+// DifferentialStyle.  Its number format, if any, is added to styles.
+func (style *DifferentialStyle) makeXLSXDxf(styles *xlsxStyleSheet) xlsxDxf {
+	xDxf := xlsxDxf{}
+	if style.Font != nil {
+		xDxf.Font = makeXLSXDxfFont(style.Font)
+	}
+	if style.NumFmt != "" {
+		xNumFmt := styles.newNumFmt(style.NumFmt)
+		xDxf.NumFmt = &xNumFmt
+	}
+	if fill := style.Fill; fill != nil {
+		xDxf.Fill.PatternFill.PatternType = fill.PatternType
+		xDxf.Fill.PatternFill.FgColor.RGB = fill.FgColor
+		xDxf.Fill.PatternFill.BgColor.RGB = fill.BgColor
+	}
+	if border := style.Border; border != nil {
+		xDxf.Border = &xlsxBorder{
+			Left:   xlsxLine{Style: border.Left, Color: xlsxColor{RGB: border.LeftColor}},
+			Right:  xlsxLine{Style: border.Right, Color: xlsxColor{RGB: border.RightColor}},
+			Top:    xlsxLine{Style: border.Top, Color: xlsxColor{RGB: border.TopColor}},
+			Bottom: xlsxLine{Style: border.Bottom, Color: xlsxColor{RGB: border.BottomColor}},
+		}
+	}
+	return xDxf
+}
+
+// makeXLSXDxfFont returns the font of a differential format for font.
+// As for the run properties of rich text, a Font read from a file
+// starts from the font it was read from, and only the fields changed
+// since are written anew.
+func makeXLSXDxfFont(font *Font) xlsxFont {
+	xFont, read := xlsxFont{}, Font{}
+	if font.source != nil && font.source.xFont != nil {
+		xFont, read = *font.source.xFont, font.source.read
+	}
+	if font.Size != read.Size {
+		xFont.Sz.Val = ""
+		if font.Size > 0 {
+			xFont.Sz.Val = strconv.Itoa(font.Size)
+		}
+	}
+	if font.Name != read.Name {
+		xFont.Name.Val = font.Name
+	}
+	if font.Family != read.Family {
+		xFont.Family.Val = ""
+		if font.Family > 0 {
+			xFont.Family.Val = strconv.Itoa(font.Family)
+		}
+	}
+	if font.Charset != read.Charset {
+		xFont.Charset.Val = ""
+		if font.Charset > 0 {
+			xFont.Charset.Val = strconv.Itoa(font.Charset)
+		}
+	}
+	if font.Color != read.Color {
+		xFont.Color = xlsxColor{RGB: font.Color}
+	}
+	if font.Bold != read.Bold {
+		xFont.B = nil
+		if font.Bold {
+			xFont.B = &xlsxVal{}
+		}
+	}
+	if font.Italic != read.Italic {
+		xFont.I = nil
+		if font.Italic {
+			xFont.I = &xlsxVal{}
+		}
+	}
+	if font.Underline != read.Underline {
+		xFont.U = nil
+		if font.Underline {
+			xFont.U = &xlsxVal{}
+		}
+	}
+	return xFont
+}
+
+// makeDifferentialStyle returns the DifferentialStyle described by a
+// differential format.
+func (styles *xlsxStyleSheet) makeDifferentialStyle(xDxf xlsxDxf) *DifferentialStyle {
+	style := &DifferentialStyle{}
+	xFont := xDxf.Font
+	if !reflect.DeepEqual(xFont, xlsxFont{}) {
+		style.Font = &Font{Name: xFont.Name.Val, Color: styles.argbValue(xFont.Color)}
+		size, _ := strconv.ParseFloat(xFont.Sz.Val, 64)
+		style.Font.Size = int(size)
+		style.Font.Family, _ = strconv.Atoi(xFont.Family.Val)
+		style.Font.Charset, _ = strconv.Atoi(xFont.Charset.Val)
+		style.Font.Bold = xFont.B != nil && xFont.B.Val != "0"
+		style.Font.Italic = xFont.I != nil && xFont.I.Val != "0"
+		style.Font.Underline = xFont.U != nil && xFont.U.Val != "none"
+		if !reflect.DeepEqual(makeXLSXDxfFont(style.Font), xFont) {
+			style.Font.source = &fontSource{read: *style.Font, xFont: &xFont}
+		}
+	}
+	if xDxf.NumFmt != nil {
+		style.NumFmt = xDxf.NumFmt.FormatCode
+		if style.NumFmt == "" {
+			style.NumFmt = styles.builtinNumberFormat(xDxf.NumFmt.NumFmtId)
+		}
+	}
+	xPatternFill := xDxf.Fill.PatternFill
+	if !reflect.DeepEqual(xPatternFill, xlsxPatternFill{}) {
+		style.Fill = &Fill{
+			PatternType: xPatternFill.PatternType,
+			FgColor:     styles.argbValue(xPatternFill.FgColor),
+			BgColor:     styles.argbValue(xPatternFill.BgColor),
+		}
+	}
+	if xBorder := xDxf.Border; xBorder != nil {
+		style.Border = &Border{
+			Left:        xBorder.Left.Style,
+			LeftColor:   styles.argbValue(xBorder.Left.Color),
+			Right:       xBorder.Right.Style,
+			RightColor:  styles.argbValue(xBorder.Right.Color),
+			Top:         xBorder.Top.Style,
+			TopColor:    styles.argbValue(xBorder.Top.Color),
+			Bottom:      xBorder.Bottom.Style,
+			BottomColor: styles.argbValue(xBorder.Bottom.Color),
+		}
+	}
+	return style
+}
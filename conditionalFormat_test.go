@@ -0,0 +1,215 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type ConditionalFormatSuite struct{}
+
+var _ = Suite(&ConditionalFormatSuite{})
+
+func (s *ConditionalFormatSuite) TestAddConditionalFormat(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	style := &DifferentialStyle{Font: &Font{Bold: true}}
+
+	c.Assert(sheet.AddConditionalFormat("A1:A10", NewCellIsRule(CFOperatorGreaterThan, style, "5")), IsNil)
+	c.Assert(sheet.AddConditionalFormat("B1:B10 D1", NewDataBarRule("FF638EC6")), IsNil)
+	c.Assert(sheet.ConditionalFormatting, HasLen, 2)
+	c.Assert(sheet.ConditionalFormatting[0].Rules[0].Priority, Equals, 1)
+	c.Assert(sheet.ConditionalFormatting[1].Rules[0].Priority, Equals, 2)
+
+	c.Assert(sheet.AddConditionalFormat("", NewDuplicateValuesRule(style)), NotNil)
+	c.Assert(sheet.AddConditionalFormat("A1:Z", NewDuplicateValuesRule(style)), NotNil)
+	c.Assert(sheet.AddConditionalFormat("A1", NewCellIsRule(CFOperatorBetween, style, "1")), NotNil)
+	c.Assert(sheet.AddConditionalFormat("A1", NewContainsTextRule("", style)), NotNil)
+	c.Assert(sheet.AddConditionalFormat("A1", &ConditionalFormatRule{Type: "sparkles"}), NotNil)
+	c.Assert(sheet.AddConditionalFormat("A1"), NotNil)
+	c.Assert(sheet.ConditionalFormatting, HasLen, 2)
+}
+
+// Rules for the same range are kept together, in the order added, and
+// are numbered after the rules already on the sheet.
+func (s *ConditionalFormatSuite) TestAddConditionalFormatRules(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	style := &DifferentialStyle{Font: &Font{Bold: true}}
+
+	c.Assert(sheet.AddConditionalFormat("A1:A10", NewCellIsRule(CFOperatorGreaterThan, style, "5")), IsNil)
+	c.Assert(sheet.AddConditionalFormat("B1:B10", NewDuplicateValuesRule(style)), IsNil)
+	first := NewCellIsRule(CFOperatorLessThan, style, "0")
+	first.StopIfTrue = true
+	c.Assert(sheet.AddConditionalFormat("A1:A10", first, NewDataBarRule("FF638EC6")), IsNil)
+
+	c.Assert(sheet.ConditionalFormatting, HasLen, 2)
+	rules := sheet.ConditionalFormatting[0].Rules
+	c.Assert(rules, HasLen, 3)
+	c.Assert(rules[0].Priority, Equals, 1)
+	c.Assert(rules[1], Equals, first)
+	c.Assert(rules[1].Priority, Equals, 3)
+	c.Assert(rules[2].Priority, Equals, 4)
+	c.Assert(sheet.ConditionalFormatting[1].Rules[0].Priority, Equals, 2)
+}
+
+// Priorities are written unique across the sheet: in order of
+// Priority, then in sheet order, with unprioritised rules last.
+func (s *ConditionalFormatSuite) TestCFPriorities(c *C) {
+	style := &DifferentialStyle{Font: &Font{Bold: true}}
+	rule := func(priority int) *ConditionalFormatRule {
+		r := NewDuplicateValuesRule(style)
+		r.Priority = priority
+		return r
+	}
+	sheet := &Sheet{ConditionalFormatting: []ConditionalFormat{
+		{Sqref: "A1", Rules: []*ConditionalFormatRule{rule(5), rule(0), rule(2)}},
+		{Sqref: "B1", Rules: []*ConditionalFormatRule{rule(2), nil, rule(1)}},
+	}}
+	c.Assert(sheet.cfPriorities(), DeepEquals, [][]int{{4, 5, 2}, {3, 0, 1}})
+	// The model is left alone.
+	c.Assert(sheet.ConditionalFormatting[0].Rules[1].Priority, Equals, 0)
+
+	styles := newXlsxStyleSheet(nil)
+	worksheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), styles)
+	c.Assert(worksheet.ConditionalFormatting, HasLen, 2)
+	c.Assert(worksheet.ConditionalFormatting[0].CfRule, HasLen, 3)
+	c.Assert(worksheet.ConditionalFormatting[1].CfRule, HasLen, 2)
+	c.Assert(worksheet.ConditionalFormatting[1].CfRule[1].Priority, Equals, 1)
+}
+
+// Rules are written with their attributes, generated formulas and
+// differential styles.
+func (s *ConditionalFormatSuite) TestMakeXLSXCfRule(c *C) {
+	styles := newXlsxStyleSheet(nil)
+	style := &DifferentialStyle{
+		Font:   &Font{Color: "FF9C0006"},
+		Fill:   &Fill{BgColor: "FFFFC7CE"},
+		Border: &Border{Bottom: "thin", BottomColor: "FF000000"},
+		NumFmt: "0.0%",
+	}
+
+	rule := NewContainsTextRule(`say "hi"`, style)
+	rule.Priority = 3
+	body, err := xml.Marshal(rule.makeXLSXCfRule("B2:C4", 3, styles))
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<xlsxCfRule type="containsText" dxfId="0" priority="3" operator="containsText" text="say &#34;hi&#34;"><formula>NOT(ISERROR(SEARCH(&#34;say &#34;&#34;hi&#34;&#34;&#34;,B2)))</formula></xlsxCfRule>`)
+
+	// The same style is not added twice.
+	rule = NewAboveAverageRule(style)
+	rule.BelowAverage = true
+	rule.Priority = 4
+	body, err = xml.Marshal(rule.makeXLSXCfRule("A1", 4, styles))
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<xlsxCfRule type="aboveAverage" dxfId="0" priority="4" aboveAverage="false"></xlsxCfRule>`)
+	c.Assert(styles.Dxfs.Dxf, HasLen, 1)
+	dxfs, err := styles.Dxfs.Marshal()
+	c.Assert(err, IsNil)
+	c.Assert(dxfs, Equals, `<dxfs count="1"><dxf><font><color rgb="FF9C0006"/></font><numFmt numFmtId="164" formatCode="0.0%"/><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill><border><bottom style="thin"><color rgb="FF000000"/></bottom></border></dxf></dxfs>`)
+
+	rule = NewIconSetRule("4Arrows")
+	rule.Priority = 1
+	body, err = xml.Marshal(rule.makeXLSXCfRule("A1:A5", 1, styles))
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<xlsxCfRule type="iconSet" priority="1"><iconSet iconSet="4Arrows"><cfvo type="percent" val="0"></cfvo><cfvo type="percent" val="25"></cfvo><cfvo type="percent" val="50"></cfvo><cfvo type="percent" val="75"></cfvo></iconSet></xlsxCfRule>`)
+}
+
+// The parts of the font of a differential format that a Font doesn't
+// model are written back as they were read, unless the fields of the
+// Font closest to them are changed.
+func (s *ConditionalFormatSuite) TestDifferentialStyleFontRoundTrip(c *C) {
+	var xDxf xlsxDxf
+	c.Assert(xml.Unmarshal([]byte(`<dxf><font><strike/><sz val="10.5"/><color theme="1" tint="0.5"/></font></dxf>`), &xDxf), IsNil)
+	styles := newXlsxStyleSheet(nil)
+	style := styles.makeDifferentialStyle(xDxf)
+	c.Assert(style.Font.Size, Equals, 10)
+
+	marshal := func() string {
+		xDxf := style.makeXLSXDxf(styles)
+		body, err := xDxf.Marshal()
+		c.Assert(err, IsNil)
+		return body
+	}
+	c.Assert(marshal(), Equals, `<dxf><font><sz val="10.5"/><color theme="1" tint="0.5"/><strike/></font></dxf>`)
+	style.Font.Bold = true
+	c.Assert(marshal(), Equals, `<dxf><font><sz val="10.5"/><color theme="1" tint="0.5"/><b/><strike/></font></dxf>`)
+	style.Font.Size = 12
+	style.Font.Color = "FF9C0006"
+	c.Assert(marshal(), Equals, `<dxf><font><sz val="12"/><color rgb="FF9C0006"/><b/><strike/></font></dxf>`)
+}
+
+// Every type of rule survives a save and load cycle.
+func (s *ConditionalFormatSuite) TestSaveAndReadConditionalFormats(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	good := &DifferentialStyle{Font: &Font{Color: "FF006100"}, Fill: &Fill{BgColor: "FFC6EFCE"}}
+	bad := &DifferentialStyle{Font: &Font{Color: "FF9C0006", Bold: true}, NumFmt: "0.00"}
+	top := NewTop10Rule(10, good)
+	top.Percent = true
+	top.Bottom = true
+	rules := []*ConditionalFormatRule{
+		NewCellIsRule(CFOperatorBetween, good, "1", "10"),
+		NewExpressionRule("$A1>$B1", bad),
+		NewThreeColorScaleRule("FFF8696B", "FFFFEB84", "FF63BE7B"),
+		NewDataBarRule("FF638EC6"),
+		NewIconSetRule("3TrafficLights1"),
+		top,
+		NewAboveAverageRule(good),
+		NewDuplicateValuesRule(bad),
+		NewContainsTextRule("error", bad),
+		NewTimePeriodRule(CFLast7Days, good),
+	}
+	for i, rule := range rules {
+		sqref := "A1:B10"
+		if i%2 == 1 {
+			sqref = "C1:C10"
+		}
+		c.Assert(sheet.AddConditionalFormat(sqref, rule), IsNil)
+	}
+	rules[0].StopIfTrue = true
+	sheet.Cell(0, 0).SetInt(1)
+	xlsxPath := filepath.Join(c.MkDir(), "TestSaveAndReadConditionalFormats.xlsx")
+	c.Assert(f.Save(xlsxPath), IsNil)
+
+	xlsxFile, err := OpenFile(xlsxPath)
+	c.Assert(err, IsNil)
+	read := xlsxFile.Sheet["Sheet1"].ConditionalFormatting
+	c.Assert(read, HasLen, 2)
+	c.Assert(read[0].Sqref, Equals, "A1:B10")
+	c.Assert(read[1].Sqref, Equals, "C1:C10")
+	for i, rule := range rules {
+		cf := read[i%2]
+		c.Assert(cf.Rules, HasLen, len(rules)/2)
+		readRule := cf.Rules[i/2]
+		expected := *rule
+		if len(expected.Formula) == 0 {
+			// Generated formulas are read back.
+			expected.Formula = readRule.Formula
+		}
+		c.Assert(*readRule, DeepEquals, expected)
+	}
+	c.Assert(read[1].Rules[4].Formula, DeepEquals, []string{"AND(TODAY()-FLOOR(C1,1)<=6,FLOOR(C1,1)<=TODAY())"})
+}
+
+// The deprecated AddCF adds expression rules to the first sheet.
+func (s *ConditionalFormatSuite) TestAddCF(c *C) {
+	f := NewFile()
+	first, _ := f.AddSheet("First")
+	second, _ := f.AddSheet("Second")
+	err := f.AddCF(map[string][]map[string]string{"cf": {
+		{"sqref": "A1:A5", "formula": "A1>1", "BgColor": "FFFF0000"},
+		{"sqref": "B1:B5", "formula": "B1>1", "BgColor": "FF00FF00"},
+	}})
+	c.Assert(err, IsNil)
+	c.Assert(first.ConditionalFormatting, HasLen, 2)
+	c.Assert(second.ConditionalFormatting, HasLen, 0)
+	rule := first.ConditionalFormatting[1].Rules[0]
+	c.Assert(rule.Type, Equals, CFTypeExpression)
+	c.Assert(rule.Formula, DeepEquals, []string{"B1>1"})
+	c.Assert(rule.Style.Fill.BgColor, Equals, "FF00FF00")
+	c.Assert(rule.Priority, Equals, 2)
+}
@@ -0,0 +1,535 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/structer/xlsx/formula"
+)
+
+// PasteType says what CopyRange copies of the cells of a range.
+type PasteType int
+
+// Kinds of paste.
+const (
+	// PasteAll copies values, formulas, styles, number formats, merged
+	// cells and conditional formats.
+	PasteAll PasteType = iota
+	// PasteValues copies values alone: formulas are replaced by their
+	// results, and the destination keeps its formatting.
+	PasteValues
+	// PasteFormats copies styles, number formats, merged cells and
+	// conditional formats, and leaves the values of the destination.
+	PasteFormats
+)
+
+// PasteOptions are the options of CopyRange.  The zero value pastes
+// everything.
+type PasteOptions struct {
+	Type       PasteType
+	SkipBlanks bool // Empty cells of the source leave the destination as it is
+}
+
+// CopyRange copies the cells of src, a range of the sheet such as
+// "A1:C3", to the range that starts at the cell dst, as Excel's copy
+// and paste does.  dst may be on another sheet of the file, as in
+// "Sheet2!E1".  A whole row or column range is copied as far as the
+// sheet is used.
+//
+// The relative references of the copied formulas move with them, and
+// those pushed off the sheet become #REF!.  Merged cells that lie
+// inside src are copied, and those of the destination that overlap it
+// are unmerged.  The conditional formats of the cells of src are
+// copied, and they replace those of the destination.  A nil opts
+// pastes everything.
+func (s *Sheet) CopyRange(src, dst string, opts *PasteOptions) error {
+	if opts == nil {
+		opts = &PasteOptions{}
+	}
+	from, target, to, err := s.pasteAreas(src, dst)
+	if err != nil {
+		return err
+	}
+	rows, cols := to.top-from.top, to.left-from.left
+	cells := s.copyCells(from)
+	formats := opts.Type != PasteValues
+	var cfs []ConditionalFormat
+	if formats {
+		cfs = s.copyConditionalFormats(from, rows, cols)
+		target.unmerge(to)
+		target.clearConditionalFormats(to)
+	}
+
+	for i, line := range cells {
+		for j, c := range line {
+			if opts.SkipBlanks && (c == nil || c.Value == "" && c.formula == "") {
+				continue
+			}
+			cell := target.cellAt(to.top+i, to.left+j)
+			if c == nil && cell == nil {
+				continue
+			}
+			if cell == nil {
+				cell = target.Cell(to.top+i-1, to.left+j-1)
+			}
+			if c == nil {
+				c = &Cell{}
+			}
+			switch opts.Type {
+			case PasteValues:
+				pasteValue(cell, c)
+			case PasteFormats:
+				cell.style, cell.NumFmt = c.style, c.NumFmt
+				cell.HMerge, cell.VMerge = c.HMerge, c.VMerge
+			default:
+				row := cell.Row
+				*cell = *c
+				cell.Row = row
+				if cell.formula != "" {
+					cell.formula = shiftFormula(cell.formula, cols, rows)
+				}
+			}
+		}
+	}
+
+	for _, cf := range cfs {
+		if err := target.AddConditionalFormat(cf.Sqref, cf.Rules...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveRange moves the cells of src, a range of the sheet, to the range
+// that starts at the cell dst, as Excel's cut and paste does.  dst may
+// be on another sheet of the file.  The cells of src that the range
+// doesn't move onto are left empty.
+//
+// The formulas of the moved cells are kept as they are, but every
+// reference of the file to cells inside src follows them, as do the
+// merged cells inside src and the conditional formats that lie wholly
+// inside it.  References to the cells that are moved onto become
+// #REF!, as Excel makes them.
+func (s *Sheet) MoveRange(src, dst string) error {
+	from, target, to, err := s.pasteAreas(src, dst)
+	if err != nil {
+		return err
+	}
+	if s.File != nil {
+		for _, sheet := range s.File.Sheets {
+			if err := sheet.Load(); err != nil {
+				return err
+			}
+		}
+	}
+	rows, cols := to.top-from.top, to.left-from.left
+	cells := s.copyCells(from)
+
+	var moved, kept []ConditionalFormat
+	for _, cf := range s.ConditionalFormatting {
+		inside := true
+		for _, r := range strings.Fields(cf.Sqref) {
+			ref, ok := parseRange(r)
+			inside = inside && ok && from.contains(areaOf(*ref))
+		}
+		if inside {
+			moved = append(moved, cf)
+		} else {
+			kept = append(kept, cf)
+		}
+	}
+	s.ConditionalFormatting = kept
+
+	for y := from.top; y <= from.bottom; y++ {
+		for x := from.left; x <= from.right; x++ {
+			if cell := s.cellAt(y, x); cell != nil {
+				*cell = Cell{Row: cell.Row}
+			}
+		}
+	}
+	target.unmerge(to)
+	target.clearConditionalFormats(to)
+
+	// Only whole cell ranges move, as they do in Excel.
+	inSource := func(ref *formula.Ref, home *Sheet) bool {
+		return ref.From.Row > 0 && ref.From.Col > 0 && refersTo(ref, home, s) && from.contains(areaOf(*ref))
+	}
+	inTarget := func(ref *formula.Ref, home *Sheet) bool {
+		return ref.From.Row > 0 && ref.From.Col > 0 && refersTo(ref, home, target) && to.contains(areaOf(*ref))
+	}
+	fix := func(home *Sheet, n formula.Node) formula.Node {
+		ref, ok := n.(*formula.Ref)
+		switch {
+		case !ok || ref.Invalid:
+			return nil
+		case inSource(ref, home):
+			m := movedRef(*ref, rows, cols)
+			if target != s {
+				m.Sheet = target.Name
+			}
+			return &m
+		case inTarget(ref, home):
+			return &formula.Ref{Book: ref.Book, Sheet: ref.Sheet, Invalid: true}
+		}
+		return nil
+	}
+	if s.File == nil {
+		s.rewriteFormulas(func(n formula.Node) formula.Node {
+			return fix(s, n)
+		})
+	} else if err := s.File.rewriteFormulas(fix); err != nil {
+		return err
+	}
+
+	// The moved formulas were written on s, and end up on target.
+	fixMoved := func(n formula.Node) formula.Node {
+		ref, ok := n.(*formula.Ref)
+		switch {
+		case !ok || ref.Invalid:
+			return nil
+		case inSource(ref, s):
+			m := movedRef(*ref, rows, cols)
+			if ref.Sheet != "" && target != s {
+				m.Sheet = target.Name
+			}
+			return &m
+		case inTarget(ref, s):
+			return &formula.Ref{Book: ref.Book, Sheet: ref.Sheet, Invalid: true}
+		case ref.Sheet == "" && target != s:
+			q := *ref
+			q.Sheet = s.Name
+			return &q
+		}
+		return nil
+	}
+	for i, line := range cells {
+		for j, c := range line {
+			cell := target.cellAt(to.top+i, to.left+j)
+			if c == nil && cell == nil {
+				continue
+			}
+			if cell == nil {
+				cell = target.Cell(to.top+i-1, to.left+j-1)
+			}
+			if c == nil {
+				c = &Cell{}
+			}
+			row := cell.Row
+			*cell = *c
+			cell.Row = row
+			if cell.formula != "" {
+				cell.formula = rewriteFormula(cell.formula, fixMoved)
+			}
+		}
+	}
+	for _, cf := range moved {
+		cf.Sqref = adjustRanges(cf.Sqref, func(r formula.Ref) formula.Ref {
+			return movedRef(r, rows, cols)
+		})
+		for _, rule := range cf.Rules {
+			for i, text := range rule.Formula {
+				rule.Formula[i] = rewriteFormula(text, fixMoved)
+			}
+		}
+		target.ConditionalFormatting = append(target.ConditionalFormatting, cf)
+	}
+	return nil
+}
+
+// pasteAreas resolves the source range and destination cell of
+// CopyRange and MoveRange to the area of the sheet to copy, and the
+// sheet and area to paste it to.
+func (s *Sheet) pasteAreas(src, dst string) (from area, target *Sheet, to area, err error) {
+	if err = s.Load(); err != nil {
+		return
+	}
+	ref, ok := parseRange(src)
+	if !ok || ref.Book != "" || ref.LastSheet != "" || ref.Sheet != "" && !strings.EqualFold(ref.Sheet, s.Name) {
+		err = fmt.Errorf("xlsx: invalid source range '%s'", src)
+		return
+	}
+	from = areaOf(*ref)
+	if ref.From.Row == 0 && s.MaxRow > 0 {
+		from.bottom = s.MaxRow
+	}
+	if ref.From.Col == 0 && s.MaxCol > 0 {
+		from.right = s.MaxCol
+	}
+
+	ref, ok = parseRange(dst)
+	if !ok || ref.Book != "" || ref.LastSheet != "" {
+		err = fmt.Errorf("xlsx: invalid destination '%s'", dst)
+		return
+	}
+	target = s
+	if ref.Sheet != "" && !strings.EqualFold(ref.Sheet, s.Name) {
+		if s.File != nil {
+			target = s.File.sheetNamed(ref.Sheet)
+		}
+		if target == nil || s.File == nil {
+			err = fmt.Errorf("xlsx: no sheet named '%s'", ref.Sheet)
+			return
+		}
+		if err = target.Load(); err != nil {
+			return
+		}
+	}
+	top, left, _, _ := ref.Bounds()
+	to = from.shift(top-from.top, left-from.left)
+	if to.bottom > formula.MaxRow || to.right > formula.MaxCol {
+		err = fmt.Errorf("xlsx: range '%s' doesn't fit on the sheet at '%s'", src, dst)
+	}
+	return
+}
+
+// copyCells returns copies of the cells of an area of the sheet, row
+// by row, with nil for the cells that don't exist.  Merged cells that
+// don't lie wholly inside the area are unmerged in the copies.
+func (s *Sheet) copyCells(a area) [][]*Cell {
+	cells := make([][]*Cell, a.bottom-a.top+1)
+	for i := range cells {
+		cells[i] = make([]*Cell, a.right-a.left+1)
+		for j := range cells[i] {
+			cell := s.cellAt(a.top+i, a.left+j)
+			if cell == nil {
+				continue
+			}
+			c := *cell
+			c.richText = append([]RichTextRun(nil), cell.richText...)
+			if cell.style != nil {
+				style := *cell.style
+				c.style = &style
+			}
+			if a.top+i+c.VMerge > a.bottom || a.left+j+c.HMerge > a.right {
+				c.HMerge, c.VMerge = 0, 0
+			}
+			cells[i][j] = &c
+		}
+	}
+	return cells
+}
+
+// pasteValue sets the value of cell to that of c, with the result of
+// the formula of c in place of the formula.
+func pasteValue(cell, c *Cell) {
+	cell.Value, cell.richText, cell.formula, cell.cellType = c.Value, nil, "", c.cellType
+	switch c.cellType {
+	case CellTypeStringFormula:
+		cell.cellType = CellTypeString
+	case CellTypeFormula:
+		cell.cellType = CellTypeString
+		if _, err := strconv.ParseFloat(c.Value, 64); err == nil {
+			cell.cellType = CellTypeNumeric
+		}
+	}
+}
+
+// cellAt returns the cell at a row and column counted from 1, or nil
+// if the sheet doesn't have it.
+func (s *Sheet) cellAt(row, col int) *Cell {
+	if row > len(s.Rows) || s.Rows[row-1] == nil || col > len(s.Rows[row-1].Cells) {
+		return nil
+	}
+	return s.Rows[row-1].Cells[col-1]
+}
+
+// unmerge unmerges the merged cells that overlap an area.
+func (s *Sheet) unmerge(a area) {
+	for _, merge := range s.takeMerges() {
+		if _, ok := a.intersect(areaOf(merge)); !ok {
+			s.setMerge(merge)
+		}
+	}
+}
+
+// copyConditionalFormats returns copies of the conditional formats of
+// the cells of an area, moved by rows and cols.  The rules of the
+// copies don't have a priority yet.
+func (s *Sheet) copyConditionalFormats(a area, rows, cols int) []ConditionalFormat {
+	var cfs []ConditionalFormat
+	for _, cf := range s.ConditionalFormatting {
+		var ranges []area
+		var anchor area
+		for i, r := range strings.Fields(cf.Sqref) {
+			ref, ok := parseRange(r)
+			if !ok {
+				continue
+			}
+			if i == 0 {
+				anchor = areaOf(*ref)
+			}
+			if part, ok := a.intersect(areaOf(*ref)); ok {
+				ranges = append(ranges, part.shift(rows, cols))
+			}
+		}
+		if len(ranges) == 0 {
+			continue
+		}
+		copied := ConditionalFormat{Sqref: joinAreas(ranges)}
+		for _, rule := range cf.Rules {
+			if rule == nil {
+				continue
+			}
+			c := rule.clone()
+			c.Priority = 0
+			c.shiftFormulas(ranges[0].top-anchor.top, ranges[0].left-anchor.left)
+			copied.Rules = append(copied.Rules, c)
+		}
+		cfs = append(cfs, copied)
+	}
+	return cfs
+}
+
+// clearConditionalFormats takes the cells of an area out of the
+// ranges of the conditional formats of the sheet.
+func (s *Sheet) clearConditionalFormats(a area) {
+	var cfs []ConditionalFormat
+	for _, cf := range s.ConditionalFormatting {
+		var ranges []area
+		var anchor area
+		changed := false
+		for i, r := range strings.Fields(cf.Sqref) {
+			ref, ok := parseRange(r)
+			if !ok {
+				continue
+			}
+			ra := areaOf(*ref)
+			if i == 0 {
+				anchor = ra
+			}
+			if _, ok := a.intersect(ra); ok {
+				changed = true
+				ranges = append(ranges, ra.subtract(a)...)
+				continue
+			}
+			ranges = append(ranges, ra)
+		}
+		if !changed {
+			cfs = append(cfs, cf)
+			continue
+		}
+		if len(ranges) == 0 {
+			continue
+		}
+		cf.Sqref = joinAreas(ranges)
+		for _, rule := range cf.Rules {
+			if rule != nil {
+				rule.shiftFormulas(ranges[0].top-anchor.top, ranges[0].left-anchor.left)
+			}
+		}
+		cfs = append(cfs, cf)
+	}
+	s.ConditionalFormatting = cfs
+}
+
+// shiftFormulas moves the relative references of the formulas of a
+// rule by rows and cols, as when the top left cell of its range moves.
+func (rule *ConditionalFormatRule) shiftFormulas(rows, cols int) {
+	if rows == 0 && cols == 0 {
+		return
+	}
+	for i, text := range rule.Formula {
+		rule.Formula[i] = shiftFormula(text, cols, rows)
+	}
+}
+
+// movedRef returns ref moved by rows and cols, absolute rows and
+// columns included, as a reference to cut and pasted cells is.
+func movedRef(ref formula.Ref, rows, cols int) formula.Ref {
+	move := func(c formula.Cell) formula.Cell {
+		if c.Row > 0 {
+			c.Row += rows
+		}
+		if c.Col > 0 {
+			c.Col += cols
+		}
+		return c
+	}
+	ref.From = move(ref.From)
+	if ref.To != nil {
+		to := move(*ref.To)
+		ref.To = &to
+	}
+	return ref
+}
+
+// area is a rectangle of cells, whose rows and columns are counted
+// from 1 as they are in the formula package.
+type area struct {
+	top, left, bottom, right int
+}
+
+// areaOf returns the area of a reference.
+func areaOf(ref formula.Ref) area {
+	top, left, bottom, right := ref.Bounds()
+	return area{top, left, bottom, right}
+}
+
+// String returns the area in A1 notation, such as "B2" or "A1:C3".
+func (a area) String() string {
+	ref := formula.Ref{From: formula.Cell{Row: a.top, Col: a.left}}
+	if a.bottom != a.top || a.right != a.left {
+		ref.To = &formula.Cell{Row: a.bottom, Col: a.right}
+	}
+	return ref.String()
+}
+
+func (a area) contains(b area) bool {
+	return b.top >= a.top && b.bottom <= a.bottom && b.left >= a.left && b.right <= a.right
+}
+
+func (a area) shift(rows, cols int) area {
+	return area{a.top + rows, a.left + cols, a.bottom + rows, a.right + cols}
+}
+
+// intersect returns the cells that a and b have in common, if they
+// have any.
+func (a area) intersect(b area) (area, bool) {
+	if b.top > a.top {
+		a.top = b.top
+	}
+	if b.left > a.left {
+		a.left = b.left
+	}
+	if b.bottom < a.bottom {
+		a.bottom = b.bottom
+	}
+	if b.right < a.right {
+		a.right = b.right
+	}
+	return a, a.top <= a.bottom && a.left <= a.right
+}
+
+// subtract returns the cells of a that aren't in b, as up to four
+// areas: those above and below b, and those to its left and right.
+func (a area) subtract(b area) []area {
+	in, ok := a.intersect(b)
+	if !ok {
+		return []area{a}
+	}
+	var parts []area
+	if a.top < in.top {
+		parts = append(parts, area{a.top, a.left, in.top - 1, a.right})
+	}
+	if in.left > a.left {
+		parts = append(parts, area{in.top, a.left, in.bottom, in.left - 1})
+	}
+	if in.right < a.right {
+		parts = append(parts, area{in.top, in.right + 1, in.bottom, a.right})
+	}
+	if in.bottom < a.bottom {
+		parts = append(parts, area{in.bottom + 1, a.left, a.bottom, a.right})
+	}
+	return parts
+}
+
+// joinAreas returns a space separated list of areas, as the Sqref of a
+// ConditionalFormat.
+func joinAreas(areas []area) string {
+	refs := make([]string, len(areas))
+	for i, a := range areas {
+		refs[i] = a.String()
+	}
+	return strings.Join(refs, " ")
+}
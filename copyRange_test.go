@@ -0,0 +1,165 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type CopyRangeSuite struct{}
+
+var _ = Suite(&CopyRangeSuite{})
+
+func (s *CopyRangeSuite) TestCopyRange(c *C) {
+	f, sheets := newTestFile(c, []string{"Sheet1"}, map[string]interface{}{
+		"A1": 1, "A2": 2, "A3": "title",
+		"B1": "=A1*2", "B2": "=A2*$A$1",
+		"C1": "=SUM(A1:B1)",
+	})
+	sheet := sheets[0]
+	sheet.Cell(0, 0).GetStyle().Font.Bold = true
+	sheet.Cell(0, 0).NumFmt = "0.00"
+	sheet.Cell(2, 0).Merge(1, 0)
+	c.Assert(sheet.AddConditionalFormat("B1:B2", NewExpressionRule("B1>A1", &DifferentialStyle{NumFmt: "0.0"})), IsNil)
+	c.Assert(sheet.CopyRange("A1:C3", "E5", nil), IsNil)
+
+	c.Assert(sheet.Cell(4, 4).Value, Equals, "1")
+	c.Assert(sheet.Cell(4, 4).NumFmt, Equals, "0.00")
+	c.Assert(sheet.Cell(4, 4).GetStyle().Font.Bold, Equals, true)
+	c.Assert(sheet.Cell(4, 5).Formula(), Equals, "E5*2")
+	c.Assert(sheet.Cell(5, 5).Formula(), Equals, "E6*$A$1")
+	c.Assert(sheet.Cell(4, 6).Formula(), Equals, "SUM(E5:F5)")
+	c.Assert(sheet.Cell(6, 4).HMerge, Equals, 1)
+
+	// The copy has a style of its own.
+	sheet.Cell(4, 4).GetStyle().Font.Bold = false
+	c.Assert(sheet.Cell(0, 0).GetStyle().Font.Bold, Equals, true)
+
+	c.Assert(sheet.ConditionalFormatting, HasLen, 2)
+	c.Assert(sheet.ConditionalFormatting[1].Sqref, Equals, "F5:F6")
+	c.Assert(sheet.ConditionalFormatting[1].Rules[0].Formula, DeepEquals, []string{"F5>E5"})
+	c.Assert(sheet.ConditionalFormatting[1].Rules[0].Priority, Equals, 2)
+	c.Assert(sheet.ConditionalFormatting[0].Rules[0].Formula, DeepEquals, []string{"B1>A1"})
+
+	// Formulas pushed off the sheet refer to #REF!.
+	c.Assert(sheet.CopyRange("B1", "A1", nil), IsNil)
+	c.Assert(sheet.Cell(0, 0).Formula(), Equals, "#REF!*2")
+	c.Assert(sheet.ConditionalFormatting[2].Sqref, Equals, "A1")
+	c.Assert(sheet.ConditionalFormatting[2].Rules[0].Formula, DeepEquals, []string{"A1>#REF!"})
+
+	// Pasting over a conditional format takes the cells out of it.
+	c.Assert(sheet.CopyRange("A3", "B1", nil), IsNil)
+	c.Assert(sheet.Cell(0, 1).Value, Equals, "title")
+	c.Assert(sheet.Cell(0, 1).HMerge, Equals, 0)
+	c.Assert(sheet.ConditionalFormatting[0].Sqref, Equals, "B2")
+	c.Assert(sheet.ConditionalFormatting[0].Rules[0].Formula, DeepEquals, []string{"B2>A2"})
+
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheet.Cell(4, 6).Value, Equals, "3")
+}
+
+func (s *CopyRangeSuite) TestCopyRangeOptions(c *C) {
+	f, sheets := newTestFile(c, []string{"Sheet1", "Other"},
+		map[string]interface{}{"A1": 1, "A2": 2, "A3": "title", "B1": "=A1*2"},
+		map[string]interface{}{"A1": "keep", "A2": "old", "B3": "blank"})
+	sheet, other := sheets[0], sheets[1]
+	sheet.Cell(0, 0).GetStyle().Font.Bold = true
+	sheet.Cell(0, 0).NumFmt = "0.00"
+	sheet.Cell(2, 0).Merge(1, 0)
+	c.Assert(sheet.AddConditionalFormat("B1:B2", NewExpressionRule("B1>A1", nil)), IsNil)
+	c.Assert(f.Recalculate(), IsNil)
+
+	c.Assert(sheet.CopyRange("A1:B3", "Other!A1", &PasteOptions{Type: PasteValues, SkipBlanks: true}), IsNil)
+	c.Assert(other.Cell(0, 0).Value, Equals, "1")
+	c.Assert(other.Cell(0, 0).NumFmt, Equals, "")
+	c.Assert(other.Cell(0, 1).Value, Equals, "2")
+	c.Assert(other.Cell(0, 1).Formula(), Equals, "")
+	c.Assert(other.Cell(0, 1).Type(), Equals, CellTypeNumeric)
+	c.Assert(other.Cell(2, 1).Value, Equals, "blank")
+	c.Assert(other.Cell(2, 0).HMerge, Equals, 0)
+	c.Assert(other.ConditionalFormatting, HasLen, 0)
+
+	c.Assert(sheet.CopyRange("A:A", "Other!D1", &PasteOptions{Type: PasteFormats}), IsNil)
+	c.Assert(other.Cell(0, 3).Value, Equals, "")
+	c.Assert(other.Cell(0, 3).NumFmt, Equals, "0.00")
+	c.Assert(other.Cell(0, 3).GetStyle().Font.Bold, Equals, true)
+	c.Assert(other.MaxRow, Equals, 3)
+}
+
+func (s *CopyRangeSuite) TestCopyRangeErrors(c *C) {
+	_, sheets := newTestFile(c, []string{"Sheet1"}, map[string]interface{}{"A1": 1})
+	sheet := sheets[0]
+	c.Assert(sheet.CopyRange("A1:", "B1", nil), ErrorMatches, `xlsx: invalid source range 'A1:'`)
+	c.Assert(sheet.CopyRange("Other!A1", "B1", nil), ErrorMatches, `xlsx: invalid source range 'Other!A1'`)
+	c.Assert(sheet.CopyRange("A1", "Missing!B1", nil), ErrorMatches, `xlsx: no sheet named 'Missing'`)
+	c.Assert(sheet.CopyRange("A1:B2", "XFD1", nil), ErrorMatches, `xlsx: range 'A1:B2' doesn't fit on the sheet at 'XFD1'`)
+	c.Assert(sheet.MoveRange("A1", "1+1"), ErrorMatches, `xlsx: invalid destination '1\+1'`)
+}
+
+func (s *CopyRangeSuite) TestMoveRange(c *C) {
+	f, sheets := newTestFile(c, []string{"Sheet1", "Other"},
+		map[string]interface{}{
+			"A1": 1, "A2": 2, "A3": "title",
+			"B1": "=A1*2", "B2": "=A2*$A$1",
+			"C1": "=SUM(A1:B1)",
+			"D1": "=B2+A3", "D2": 5,
+		},
+		map[string]interface{}{"A1": "=Sheet1!B2", "A2": "=SUM(Sheet1!A1:B2)", "A3": "=Sheet1!D2"})
+	sheet, other := sheets[0], sheets[1]
+	sheet.Cell(0, 0).NumFmt = "0.00"
+	c.Assert(sheet.AddConditionalFormat("B1:B2", NewExpressionRule("B1>A1", nil)), IsNil)
+	f.DefinedNames = append(f.DefinedNames, &xlsxDefinedName{Name: "Table", Data: "Sheet1!$A$1:$B$2"})
+
+	c.Assert(sheet.MoveRange("A1:B2", "C2"), IsNil)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "")
+	c.Assert(sheet.Cell(0, 0).NumFmt, Equals, "")
+	c.Assert(sheet.Cell(1, 2).Value, Equals, "1")
+	c.Assert(sheet.Cell(1, 2).NumFmt, Equals, "0.00")
+	c.Assert(sheet.Cell(1, 3).Formula(), Equals, "C2*2")
+	c.Assert(sheet.Cell(2, 3).Formula(), Equals, "C3*$C$2")
+	// C1 wasn't moved, but its reference to A1 was.
+	c.Assert(sheet.Cell(0, 2).Formula(), Equals, "SUM(C2:D2)")
+	c.Assert(sheet.Cell(0, 3).Formula(), Equals, "D3+A3")
+	c.Assert(other.Cell(0, 0).Formula(), Equals, "Sheet1!D3")
+	c.Assert(other.Cell(1, 0).Formula(), Equals, "SUM(Sheet1!C2:D3)")
+	// D2 was moved onto.
+	c.Assert(other.Cell(2, 0).Formula(), Equals, "Sheet1!#REF!")
+	c.Assert(f.DefinedNames[0].Data, Equals, "Sheet1!$C$2:$D$3")
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+	c.Assert(sheet.ConditionalFormatting, HasLen, 1)
+	c.Assert(sheet.ConditionalFormatting[0].Sqref, Equals, "D2:D3")
+	c.Assert(sheet.ConditionalFormatting[0].Rules[0].Formula, DeepEquals, []string{"D2>C2"})
+
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheet.Cell(0, 2).Value, Equals, "3")
+	c.Assert(other.Cell(0, 0).Value, Equals, "2")
+}
+
+func (s *CopyRangeSuite) TestMoveRangeToOtherSheet(c *C) {
+	f, sheets := newTestFile(c, []string{"Sheet1", "Other"}, map[string]interface{}{
+		"A1": 1, "A2": 2, "A3": "title",
+		"B1": "=A1*2", "B2": "=A2*$A$1",
+		"C1": "=SUM(A1:B1)",
+	})
+	sheet, other := sheets[0], sheets[1]
+	sheet.Cell(2, 0).Merge(1, 0)
+	c.Assert(sheet.AddConditionalFormat("B1:B2", NewExpressionRule("B1>A1", nil)), IsNil)
+	c.Assert(sheet.MoveRange("A1:C3", "Other!B2"), IsNil)
+
+	c.Assert(sheet.Cell(0, 1).Formula(), Equals, "")
+	c.Assert(sheet.ConditionalFormatting, HasLen, 0)
+	c.Assert(other.Cell(1, 2).Formula(), Equals, "B2*2")
+	c.Assert(other.Cell(2, 2).Formula(), Equals, "B3*$B$2")
+	c.Assert(other.Cell(1, 3).Formula(), Equals, "SUM(B2:C2)")
+	c.Assert(other.Cell(3, 1).Value, Equals, "title")
+	c.Assert(other.Cell(3, 1).HMerge, Equals, 1)
+	c.Assert(other.ConditionalFormatting, HasLen, 1)
+	c.Assert(other.ConditionalFormatting[0].Sqref, Equals, "C2:C3")
+	c.Assert(other.ConditionalFormatting[0].Rules[0].Formula, DeepEquals, []string{"C2>B2"})
+
+	setCells(c, sheet, map[string]interface{}{"A5": 7, "A6": "=Other!C3"})
+	setCells(c, other, map[string]interface{}{"E1": "=A5"})
+	c.Assert(other.MoveRange("E1:E1", "Sheet1!B5"), IsNil)
+	c.Assert(sheet.Cell(4, 1).Formula(), Equals, "Other!A5")
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheet.Cell(5, 0).Value, Equals, "2")
+	c.Assert(sheet.Cell(4, 1).Value, Equals, "0")
+}
@@ -0,0 +1,265 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/structer/xlsx/formula"
+)
+
+// CloneSheet adds a copy of the sheet called name to the file, after
+// its last sheet, and calls it newName.  The rows, columns, views and
+// conditional formats of the sheet are copied, so that changing one
+// sheet leaves the other as it is.  References of the copied formulas
+// to the sheet by name are pointed at the copy, and the defined names
+// of the sheet are copied along with it.
+//
+// Hyperlinks are copied, but the drawings, comments, tables and other
+// parts of an opened sheet are not.
+func (f *File) CloneSheet(name, newName string) (*Sheet, error) {
+	sheet := f.sheetNamed(name)
+	if sheet == nil {
+		return nil, fmt.Errorf("xlsx: no sheet named '%s'", name)
+	}
+	return f.copySheet(sheet, newName)
+}
+
+// ImportSheet adds a copy of the sheet called name of other to the
+// file, after its last sheet, as CloneSheet does.  The copy has the
+// same name, which mustn't be taken in f.
+//
+// Cells, columns and conditional formats carry their styles by what
+// they hold rather than by their index in the style sheet of other,
+// and they get entries in the style sheet of f, fonts, number formats
+// and differential formats alike, when f is written.  The named cell
+// styles they are based on are added to the style sheet of f, if f was
+// opened from a file.  Dates are moved to the date system of f.
+// Formula references to other sheets of other are kept as they are,
+// and so refer to the sheets of f that have the same names.
+func (f *File) ImportSheet(other *File, name string) (*Sheet, error) {
+	sheet := other.sheetNamed(name)
+	if sheet == nil {
+		return nil, fmt.Errorf("xlsx: no sheet named '%s'", name)
+	}
+	return f.copySheet(sheet, sheet.Name)
+}
+
+// copySheet adds a copy of from, which may be a sheet of another file,
+// to the file as its last sheet, called name.
+func (f *File) copySheet(from *Sheet, name string) (*Sheet, error) {
+	if err := from.Load(); err != nil {
+		return nil, err
+	}
+	sheet, err := f.AddSheet(name)
+	if err != nil {
+		return nil, err
+	}
+	c := &sheetCopy{from: from.File, to: f, styles: make(map[*Style]*Style)}
+
+	sheet.Hidden = from.Hidden
+	sheet.MaxRow, sheet.MaxCol = from.MaxRow, from.MaxCol
+	sheet.SheetFormat = from.SheetFormat
+	for _, view := range from.SheetViews {
+		if view.Pane != nil {
+			pane := *view.Pane
+			view.Pane = &pane
+		}
+		sheet.SheetViews = append(sheet.SheetViews, view)
+	}
+	for _, col := range from.Cols {
+		copied := *col
+		copied.style = c.style(col.style)
+		sheet.Cols = append(sheet.Cols, &copied)
+	}
+	sheet.Rows = make([]*Row, len(from.Rows))
+	for y, row := range from.Rows {
+		if row == nil {
+			continue
+		}
+		copied := *row
+		copied.Sheet = sheet
+		copied.Cells = make([]*Cell, len(row.Cells))
+		for x, cell := range row.Cells {
+			if cell != nil {
+				copied.Cells[x] = c.cell(cell, &copied)
+			}
+		}
+		sheet.Rows[y] = &copied
+	}
+	for _, cf := range from.ConditionalFormatting {
+		copied := ConditionalFormat{Sqref: cf.Sqref}
+		for _, rule := range cf.Rules {
+			if rule != nil {
+				copied.Rules = append(copied.Rules, rule.clone())
+			}
+		}
+		sheet.ConditionalFormatting = append(sheet.ConditionalFormatting, copied)
+	}
+	sheet.parts = from.parts.copyHyperlinks()
+
+	var rename func(formula.Node) formula.Node
+	if from.File == f {
+		rename = renameSheet(from.Name, name)
+		sheet.rewriteFormulas(rename)
+	}
+	index := -1
+	for i, s := range from.File.Sheets {
+		if s == from {
+			index = i
+		}
+	}
+	for _, dn := range from.File.DefinedNames {
+		if !dn.isLocalTo(index) {
+			continue
+		}
+		copied := *dn
+		copied.setLocalSheet(len(f.Sheets) - 1)
+		if rename != nil {
+			copied.Data = rewriteFormula(dn.Data, rename)
+		}
+		f.DefinedNames = append(f.DefinedNames, &copied)
+	}
+	return sheet, nil
+}
+
+// sheetCopy is the copying of a sheet from one file to another, or to
+// the same file.
+type sheetCopy struct {
+	from, to *File
+	styles   map[*Style]*Style // the copies of the styles copied so far
+}
+
+// cell returns a copy of cell for row.
+func (c *sheetCopy) cell(cell *Cell, row *Row) *Cell {
+	copied := *cell
+	copied.Row = row
+	copied.richText = append([]RichTextRun(nil), cell.richText...)
+	copied.style = c.style(cell.style)
+	copied.date1904 = c.to.Date1904
+	if c.from.Date1904 != c.to.Date1904 && cell.cellType != CellTypeString && cell.cellType != CellTypeInline && cell.cellType != CellTypeStringFormula && showsDate(cell.NumFmt) {
+		if v, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+			v = timeToExcelTime(TimeFromExcelTime(v, c.from.Date1904), c.to.Date1904)
+			copied.Value = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return &copied
+}
+
+// style returns the copy of a style.  Cells that share a style share
+// its copy as well.
+func (c *sheetCopy) style(style *Style) *Style {
+	if style == nil {
+		return nil
+	}
+	if copied, ok := c.styles[style]; ok {
+		return copied
+	}
+	copied := *style
+	if c.from != c.to {
+		copied.read = nil
+		copied.NamedStyleIndex = c.namedStyle(style.NamedStyleIndex)
+	}
+	c.styles[style] = &copied
+	return &copied
+}
+
+// namedStyle adds the named cell style format at index in the style
+// sheet copied from to the style sheet copied to, and returns its index
+// there.  It returns nil if the style sheet copied to isn't one that
+// was read, as that of a new file is made afresh when it is written.
+func (c *sheetCopy) namedStyle(index *int) *int {
+	from, to := c.from.styles, c.to.styles
+	if index == nil || from == nil || to == nil || !to.fromFile || from.CellStyleXfs == nil || *index < 0 || *index >= len(from.CellStyleXfs.Xf) {
+		return nil
+	}
+	xf := from.CellStyleXfs.Xf[*index]
+	if xf.FontId >= 0 && xf.FontId < len(from.Fonts.Font) {
+		xf.FontId = to.addFont(from.Fonts.Font[xf.FontId])
+	}
+	if xf.FillId >= 0 && xf.FillId < len(from.Fills.Fill) {
+		xf.FillId = to.addFill(from.Fills.Fill[xf.FillId])
+	}
+	if xf.BorderId >= 0 && xf.BorderId < len(from.Borders.Border) {
+		xf.BorderId = to.addBorder(from.Borders.Border[xf.BorderId])
+	}
+	code := from.builtinNumberFormat(xf.NumFmtId)
+	if numFmt, ok := from.numFmtRefTable[xf.NumFmtId]; ok && code == "" {
+		code = numFmt.FormatCode
+	}
+	xf.NumFmtId = to.newNumFmt(code).NumFmtId
+	i := to.addCellStyleXf(xf)
+	return &i
+}
+
+// clone returns a copy of the rule that shares nothing with it.
+func (rule *ConditionalFormatRule) clone() *ConditionalFormatRule {
+	copied := *rule
+	copied.Formula = append([]string(nil), rule.Formula...)
+	if rule.Style != nil {
+		style := *rule.Style
+		if style.Font != nil {
+			font := *style.Font
+			style.Font = &font
+		}
+		if style.Fill != nil {
+			fill := *style.Fill
+			style.Fill = &fill
+		}
+		if style.Border != nil {
+			border := *style.Border
+			style.Border = &border
+		}
+		copied.Style = &style
+	}
+	if rule.ColorScale != nil {
+		copied.ColorScale = &ColorScale{
+			Values: append([]CFValue(nil), rule.ColorScale.Values...),
+			Colors: append([]string(nil), rule.ColorScale.Colors...),
+		}
+	}
+	if rule.DataBar != nil {
+		dataBar := *rule.DataBar
+		copied.DataBar = &dataBar
+	}
+	if rule.IconSet != nil {
+		iconSet := *rule.IconSet
+		iconSet.Values = append([]CFValue(nil), rule.IconSet.Values...)
+		copied.IconSet = &iconSet
+	}
+	return &copied
+}
+
+// copyHyperlinks returns the parts of a copy of the sheet: its
+// hyperlinks and the relationships of those that link to other files.
+// The other parts of a sheet belong to it alone.
+func (p *worksheetParts) copyHyperlinks() *worksheetParts {
+	if p == nil || p.hyperlinks == nil {
+		return nil
+	}
+	parts := &worksheetParts{hyperlinks: &xlsxHyperlinks{
+		Hyperlink: append([]xlsxHyperlink(nil), p.hyperlinks.Hyperlink...),
+	}}
+	for _, rel := range p.relationships {
+		if rel.Type == relTypeHyperlink {
+			parts.relationships = append(parts.relationships, rel)
+		}
+	}
+	return parts
+}
+
+// showsDate reports whether a number format shows dates, rather than
+// numbers or times alone.
+func showsDate(numFmt string) bool {
+	for _, section := range parseNumberFormat(numFmt).sections {
+		if !section.isDate {
+			continue
+		}
+		for _, token := range parseDateFormat(section.raw) {
+			switch token.kind {
+			case dateYear, dateMonth, dateDay, dateWeekday, dateEra, dateEraYear, dateBuddhistYear:
+				return true
+			}
+		}
+	}
+	return false
+}
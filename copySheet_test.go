@@ -0,0 +1,184 @@
+package xlsx
+
+import (
+	"bytes"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type CopySheetSuite struct{}
+
+var _ = Suite(&CopySheetSuite{})
+
+func (s *CopySheetSuite) TestCloneSheet(c *C) {
+	f, sheets := newTestFile(c, []string{"Summary", "My Data"}, nil, map[string]interface{}{
+		"A1": 10, "A2": 20,
+		"B1": "='My Data'!A1*2",
+		"B2": "=SUM(A1:A2)+Total",
+		"A4": "merged",
+	})
+	sheet := sheets[1]
+	sheet.Cell(0, 0).GetStyle().Font.Bold = true
+	sheet.Cell(0, 0).NumFmt = "0.00"
+	sheet.Cell(3, 0).Merge(1, 0)
+	sheet.Col(1).Width = 25
+	sheet.SheetViews = []SheetView{{Pane: &Pane{YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft", State: "frozen"}}}
+	c.Assert(sheet.AddConditionalFormat("A1:A2", NewCellIsRule(CFOperatorGreaterThan, &DifferentialStyle{Font: &Font{Bold: true}}, "15")), IsNil)
+	f.DefinedNames = append(f.DefinedNames,
+		&xlsxDefinedName{Name: "Total", Data: "'My Data'!$A$1:$A$2", LocalSheetID: localSheetID(1)},
+		&xlsxDefinedName{Name: "Global", Data: "Summary!$A$1"},
+	)
+	clone, err := f.CloneSheet("my data", "Copy")
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets, HasLen, 3)
+	c.Assert(f.Sheet["Copy"], Equals, clone)
+
+	c.Assert(clone.Cell(0, 0).Value, Equals, "10")
+	c.Assert(clone.Cell(0, 0).NumFmt, Equals, "0.00")
+	c.Assert(clone.Cell(0, 0).Row, Equals, clone.Rows[0])
+	c.Assert(clone.Cell(0, 1).Formula(), Equals, "Copy!A1*2")
+	c.Assert(clone.Cell(1, 1).Formula(), Equals, "SUM(A1:A2)+Total")
+	c.Assert(clone.Cell(3, 0).HMerge, Equals, 1)
+	c.Assert(clone.Cols[1].Width, Equals, 25.0)
+	c.Assert(clone.SheetViews[0].Pane.TopLeftCell, Equals, "A2")
+	c.Assert(clone.ConditionalFormatting, HasLen, 1)
+	c.Assert(clone.ConditionalFormatting[0].Rules[0].Style.Font.Bold, Equals, true)
+
+	// The copy shares nothing with the sheet.
+	clone.Cell(0, 0).GetStyle().Font.Bold = false
+	clone.Cell(0, 0).SetInt(1)
+	clone.Col(1).Width = 5
+	clone.SheetViews[0].Pane.TopLeftCell = "A5"
+	clone.ConditionalFormatting[0].Rules[0].Style.Font.Bold = false
+	clone.ConditionalFormatting[0].Rules[0].Formula[0] = "5"
+	c.Assert(sheet.Cell(0, 0).GetStyle().Font.Bold, Equals, true)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "10")
+	c.Assert(sheet.Cols[1].Width, Equals, 25.0)
+	c.Assert(sheet.SheetViews[0].Pane.TopLeftCell, Equals, "A2")
+	c.Assert(sheet.ConditionalFormatting[0].Rules[0].Style.Font.Bold, Equals, true)
+	c.Assert(sheet.ConditionalFormatting[0].Rules[0].Formula, DeepEquals, []string{"15"})
+
+	c.Assert(f.DefinedNames, HasLen, 3)
+	c.Assert(f.DefinedNames[2].Name, Equals, "Total")
+	c.Assert(f.DefinedNames[2].Data, Equals, "Copy!$A$1:$A$2")
+	c.Assert(*f.DefinedNames[2].LocalSheetID, Equals, 2)
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(clone.Cell(1, 1).Value, Equals, "41")
+	c.Assert(sheet.Cell(1, 1).Value, Equals, "50")
+}
+
+func (s *CopySheetSuite) TestCloneFirstSheet(c *C) {
+	f, _ := newTestFile(c, []string{"Summary", "My Data"})
+	f.DefinedNames = append(f.DefinedNames,
+		&xlsxDefinedName{Name: "Global", Data: "Summary!$A$1"},
+		&xlsxDefinedName{Name: "Top", Data: "Summary!$A$1", LocalSheetID: localSheetID(0)},
+	)
+	_, err := f.CloneSheet("Summary", "Copy")
+	c.Assert(err, IsNil)
+	c.Assert(f.DefinedNames, HasLen, 3)
+	c.Assert(f.DefinedNames[0].LocalSheetID, IsNil)
+	c.Assert(*f.DefinedNames[1].LocalSheetID, Equals, 0)
+	c.Assert(f.DefinedNames[2].Name, Equals, "Top")
+	c.Assert(f.DefinedNames[2].Data, Equals, "Copy!$A$1")
+	c.Assert(*f.DefinedNames[2].LocalSheetID, Equals, 2)
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+}
+
+func (s *CopySheetSuite) TestCloneSheetErrors(c *C) {
+	f, _ := newTestFile(c, []string{"Summary", "My Data"})
+	_, err := f.CloneSheet("Missing", "Copy")
+	c.Assert(err, ErrorMatches, `xlsx: no sheet named 'Missing'`)
+	_, err = f.CloneSheet("My Data", "Summary")
+	c.Assert(err, ErrorMatches, `duplicate sheet name 'Summary'.`)
+	c.Assert(f.Sheets, HasLen, 2)
+}
+
+func (s *CopySheetSuite) TestImportSheet(c *C) {
+	source, sheets := newTestFile(c, []string{"Summary", "My Data"}, nil, map[string]interface{}{
+		"A1": 10, "A2": 20,
+		"B1": "='My Data'!A1*2",
+		"A4": "merged",
+	})
+	source.Date1904 = true
+	sheet := sheets[1]
+	sheet.Cell(0, 0).GetStyle().Font.Bold = true
+	sheet.Cell(0, 0).NumFmt = "0.00"
+	sheet.Cell(2, 0).SetDate(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+	sheet.Cell(3, 0).Merge(1, 0)
+	sheet.Col(1).Width = 25
+	c.Assert(sheet.AddConditionalFormat("A1:A2", NewCellIsRule(CFOperatorGreaterThan, &DifferentialStyle{Font: &Font{Bold: true}}, "15")), IsNil)
+	source.DefinedNames = append(source.DefinedNames,
+		&xlsxDefinedName{Name: "Total", Data: "'My Data'!$A$1:$A$2", LocalSheetID: localSheetID(1)},
+		&xlsxDefinedName{Name: "Global", Data: "Summary!$A$1"},
+	)
+	var buf bytes.Buffer
+	c.Assert(source.Write(&buf), IsNil)
+	other, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(other.DefinedNames, DeepEquals, source.DefinedNames)
+
+	f := NewFile()
+	_, err = f.AddSheet("Summary")
+	c.Assert(err, IsNil)
+	imported, err := f.ImportSheet(other, "My Data")
+	c.Assert(err, IsNil)
+	c.Assert(imported.Name, Equals, "My Data")
+	c.Assert(imported.Cell(0, 1).Formula(), Equals, "'My Data'!A1*2")
+	c.Assert(f.DefinedNames, HasLen, 1)
+	c.Assert(*f.DefinedNames[0].LocalSheetID, Equals, 1)
+
+	// The date is moved to the 1900 date system.
+	date, err := imported.Cell(2, 0).GetTime(nil)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	_, err = f.ImportSheet(other, "My Data")
+	c.Assert(err, ErrorMatches, `duplicate sheet name 'My Data'.`)
+
+	buf.Reset()
+	c.Assert(f.Write(&buf), IsNil)
+	read, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.DefinedNames, DeepEquals, f.DefinedNames)
+	sheet = read.Sheet["My Data"]
+	c.Assert(sheet.Cell(0, 0).GetStyle().Font.Bold, Equals, true)
+	c.Assert(sheet.Cell(0, 0).NumFmt, Equals, "0.00")
+	c.Assert(sheet.Cell(3, 0).HMerge, Equals, 1)
+	c.Assert(sheet.Cols[1].Width, Equals, 25.0)
+	c.Assert(sheet.ConditionalFormatting, HasLen, 1)
+	c.Assert(sheet.ConditionalFormatting[0].Rules[0].Style.Font.Bold, Equals, true)
+	date, err = sheet.Cell(2, 0).GetTime(nil)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func (s *CopySheetSuite) TestImportSheetNamedStyle(c *C) {
+	other, err := OpenFile("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	f, err := OpenFile("./testdocs/macExcelTest.xlsx")
+	c.Assert(err, IsNil)
+	imported, err := f.ImportSheet(other, other.Sheets[0].Name)
+	c.Assert(err, IsNil)
+
+	original := other.Sheets[0].Cell(0, 0).GetStyle()
+	style := imported.Cell(0, 0).GetStyle()
+	c.Assert(style, Not(Equals), original)
+	c.Assert(style.Font, Equals, original.Font)
+	c.Assert(original.NamedStyleIndex, NotNil)
+	c.Assert(style.NamedStyleIndex, NotNil)
+	xf := f.styles.CellStyleXfs.Xf[*style.NamedStyleIndex]
+	originalXf := other.styles.CellStyleXfs.Xf[*original.NamedStyleIndex]
+	c.Assert(f.styles.Fonts.Font[xf.FontId].Equals(other.styles.Fonts.Font[originalXf.FontId]), Equals, true)
+	c.Assert(f.styles.Fills.Fill[xf.FillId].Equals(other.styles.Fills.Fill[originalXf.FillId]), Equals, true)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	read, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	sheet := read.Sheet[other.Sheets[0].Name]
+	c.Assert(sheet.Cell(0, 0).Value, Equals, other.Sheets[0].Cell(0, 0).Value)
+	c.Assert(sheet.Cell(0, 0).GetStyle().Font, Equals, original.Font)
+}
@@ -1,6 +1,7 @@
 package xlsx
 
 import (
+	"fmt"
 	"math"
 	"time"
 )
@@ -76,30 +77,86 @@ func doTheFliegelAndVanFlandernAlgorithm(jd int) (day, month, year int) {
 }
 
 // Convert an excelTime representation (stored as a floating point number) to a time.Time.
+//
+// In the 1900 date system, day 1 is 1 January 1900, and Excel counts
+// a 29 February 1900 that never was as day 60; that day is taken to
+// be 28 February.  Day 0 is 31 December 1899, which Excel shows as
+// 0 January 1900.  In the 1904 date system day 0 is 1 January 1904.
+// Negative values count back from day 0.
 func TimeFromExcelTime(excelTime float64, date1904 bool) time.Time {
-	var date time.Time
-	var intPart int64 = int64(excelTime)
-	// Excel uses Julian dates prior to March 1st 1900, and
-	// Gregorian thereafter.
-	if intPart <= 61 {
-		const OFFSET1900 = 15018.0
-		const OFFSET1904 = 16480.0
-		var date time.Time
-		if date1904 {
-			date = julianDateToGregorianTime(MJD_0, excelTime+OFFSET1904)
-		} else {
-			date = julianDateToGregorianTime(MJD_0, excelTime+OFFSET1900)
-		}
-		return date
+	var epoch time.Time
+	switch {
+	case date1904:
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	case excelTime >= 61:
+		epoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	case excelTime >= 60:
+		epoch = time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC)
+		excelTime--
+	default:
+		epoch = time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC)
+	}
+	days, fraction := math.Modf(excelTime)
+	// Round to the microsecond, as fractionOfADay does.
+	dayTime := time.Duration(math.Floor(fraction*24*60*60*1e6+0.5)) * time.Microsecond
+	return epoch.AddDate(0, 0, int(days)).Add(dayTime)
+}
+
+// timeToExcelTime converts the wall-clock time of t to an excelTime
+// in the 1900 or the 1904 date system.  It is the inverse of
+// TimeFromExcelTime.
+func timeToExcelTime(t time.Time, date1904 bool) float64 {
+	// Days since 30 December 1899, which is day 0 in the 1900
+	// system for every day from 1 March 1900.
+	const unixDays = 25569
+	t = timeToUTCTime(t)
+	excelTime := float64(t.Unix())/86400.0 + unixDays + float64(t.Nanosecond())/(86400.0*1e9)
+	switch {
+	case date1904:
+		excelTime -= 1462
+	case excelTime < 61:
+		// Before the day Excel thinks was 29 February 1900.
+		excelTime--
 	}
-	var floatPart float64 = excelTime - float64(intPart)
-	var dayNanoSeconds float64 = 24 * 60 * 60 * 1000 * 1000 * 1000
-	if date1904 {
-		date = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
-	} else {
-		date = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return excelTime
+}
+
+// iso8601Layouts are the forms of ISO 8601 date and time accepted in
+// cells of type "d", together with the built-in number format that
+// displays each of them.
+var iso8601Layouts = []struct {
+	layout   string
+	numFmtId int
+	timeOnly bool
+}{
+	{"2006-01-02T15:04:05.999999999Z07:00", 22, false},
+	{"2006-01-02T15:04:05.999999999", 22, false},
+	{"2006-01-02T15:04Z07:00", 22, false},
+	{"2006-01-02T15:04", 22, false},
+	{"2006-01-02", 14, false},
+	{"T15:04:05.999999999", 21, true},
+	{"15:04:05.999999999", 21, true},
+	{"T15:04", 20, true},
+	{"15:04", 20, true},
+}
+
+// excelTimeFromISO8601 converts the ISO 8601 representation of a date
+// or time, as stored in cells of type "d", to an excelTime.  The
+// wall-clock time is kept and any time zone offset is dropped, since
+// Excel has no notion of time zones.  The number format appropriate
+// to the value is returned along with it.
+func excelTimeFromISO8601(value string, date1904 bool) (float64, string, error) {
+	for _, l := range iso8601Layouts {
+		t, err := time.Parse(l.layout, value)
+		if err != nil {
+			continue
+		}
+		if l.timeOnly {
+			dayTime := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+				time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+			return float64(dayTime) / float64(24*time.Hour), builtInNumFmt[l.numFmtId], nil
+		}
+		return timeToExcelTime(t, date1904), builtInNumFmt[l.numFmtId], nil
 	}
-	durationDays := time.Duration(intPart) * time.Hour * 24
-	durationPart := time.Duration(dayNanoSeconds * floatPart)
-	return date.Add(durationDays).Add(durationPart)
+	return 0, "", fmt.Errorf("invalid ISO 8601 date '%s'", value)
 }
@@ -0,0 +1,418 @@
+package xlsx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type dateTokenKind int
+
+const (
+	dateLiteral        dateTokenKind = iota // text shown as it is
+	dateYear                                // yy or yyyy
+	dateMonth                               // m, mm, mmm, mmmm or mmmmm
+	dateDay                                 // d or dd
+	dateWeekday                             // ddd or dddd
+	dateHour                                // h or hh
+	dateMinute                              // m or mm, next to an hour or a second
+	dateSecond                              // s or ss
+	dateSubSecond                           // the 0s of ss.000
+	dateAMPM                                // AM/PM or A/P, in any case
+	dateEra                                 // g, gg or ggg
+	dateEraYear                             // e or ee
+	dateBuddhistYear                        // b, bb or bbbb
+	dateElapsedHours                        // [h] or [hh]
+	dateElapsedMinutes                      // [m] or [mm]
+	dateElapsedSeconds                      // [s] or [ss]
+)
+
+// dateToken is a token of a date or time format.  For codes, width
+// is the number of letters or digits in the code, and thai is set if
+// the code is shown in Thai digits.
+type dateToken struct {
+	kind  dateTokenKind
+	text  string
+	width int
+	thai  bool
+}
+
+// thaiDateCodes are the Thai letters that Thai formats use for the day,
+// month, year, hour, minute and second.  Their years are those of the
+// Buddhist era, and they are shown in Thai digits.
+var thaiDateCodes = map[rune]dateTokenKind{
+	'ว': dateDay,
+	'ด': dateMonth,
+	'ป': dateBuddhistYear,
+	'ช': dateHour,
+	'น': dateMinute,
+	'ท': dateSecond,
+}
+
+// elapsedCodes are the letters of the elapsed time codes, such as the
+// h of [h], along with the Thai letters for them.
+var elapsedCodes = map[rune]dateTokenKind{
+	'h': dateElapsedHours,
+	'm': dateElapsedMinutes,
+	's': dateElapsedSeconds,
+	'ช': dateElapsedHours,
+	'น': dateElapsedMinutes,
+	'ท': dateElapsedSeconds,
+}
+
+// isElapsed reports whether code, the lower case content of a [...]
+// in a format, is an elapsed time.
+func isElapsed(code string) bool {
+	_, ok := parseElapsed(code)
+	return ok
+}
+
+// parseElapsed returns the token for code, the lower case content of
+// a [...] in a date format, if it is an elapsed time such as [hh].
+func parseElapsed(code string) (dateToken, bool) {
+	r, size := utf8.DecodeRuneInString(code)
+	kind, ok := elapsedCodes[r]
+	if !ok || strings.Trim(code, string(r)) != "" {
+		return dateToken{}, false
+	}
+	return dateToken{kind: kind, width: len(code) / size, thai: r >= utf8.RuneSelf}, true
+}
+
+// parseDateFormat splits a date or time section of a number format
+// into tokens.  An m or mm code is taken to be minutes when it follows
+// an hour or comes before a second, with only literals in between, and
+// a month otherwise.  A t at the start of the format shows every code
+// in Thai digits.
+func parseDateFormat(format string) []dateToken {
+	var tokens []dateToken
+	literal := func(s string) {
+		tokens = append(tokens, dateToken{kind: dateLiteral, text: s})
+	}
+	thai := false
+	for i := 0; i < len(format); {
+		r, size := utf8.DecodeRuneInString(format[i:])
+		start := i
+		i += size
+		switch r {
+		case 't', 'T':
+			if start == 0 {
+				thai = true
+				continue
+			}
+		case '"':
+			end := strings.IndexByte(format[i:], '"')
+			if end < 0 {
+				end = len(format) - i
+			}
+			literal(format[i : i+end])
+			i += end
+			if i < len(format) {
+				i++
+			}
+			continue
+		case '\\', '_', '*':
+			if i < len(format) {
+				_, size = utf8.DecodeRuneInString(format[i:])
+				switch r {
+				case '\\':
+					literal(format[i : i+size])
+				case '_':
+					literal(" ")
+				}
+				i += size
+			}
+			continue
+		case '[':
+			end := strings.IndexByte(format[i:], ']')
+			if end < 0 {
+				end = len(format) - i
+			}
+			code := strings.ToLower(format[i : i+end])
+			i += end
+			if i < len(format) {
+				i++
+			}
+			if tok, ok := parseElapsed(code); ok {
+				tokens = append(tokens, tok)
+			}
+			continue
+		case 'A', 'a':
+			switch {
+			case hasPrefixFold(format[start:], "AM/PM"):
+				i = start + 5
+				tokens = append(tokens, dateToken{kind: dateAMPM, text: format[start:i]})
+				continue
+			case hasPrefixFold(format[start:], "A/P"):
+				i = start + 3
+				tokens = append(tokens, dateToken{kind: dateAMPM, text: format[start:i]})
+				continue
+			}
+		case '0':
+			n := len(tokens) - 1
+			if n >= 0 && tokens[n].kind == dateSubSecond {
+				tokens[n].width++
+				continue
+			}
+			if kind := lastDateCode(tokens); kind == dateSecond || kind == dateElapsedSeconds {
+				tokens = append(tokens, dateToken{kind: dateSubSecond, width: 1})
+				continue
+			}
+		}
+		if kind, ok := thaiDateCodes[r]; ok {
+			for strings.HasPrefix(format[i:], string(r)) {
+				i += size
+			}
+			width := (i - start) / size
+			if kind == dateDay && width > 2 {
+				kind = dateWeekday
+			}
+			tokens = append(tokens, dateToken{kind: kind, width: width, thai: true})
+			continue
+		}
+		lower := unicode.ToLower(r)
+		if !strings.ContainsRune("ymdhsgeb", lower) {
+			literal(format[start:i])
+			continue
+		}
+		for i < len(format) && unicode.ToLower(rune(format[i])) == lower {
+			i++
+		}
+		width := i - start
+		var kind dateTokenKind
+		switch lower {
+		case 'y':
+			kind = dateYear
+		case 'm':
+			kind = dateMonth
+		case 'd':
+			kind = dateDay
+			if width > 2 {
+				kind = dateWeekday
+			}
+		case 'h':
+			kind = dateHour
+		case 's':
+			kind = dateSecond
+		case 'g':
+			kind = dateEra
+		case 'e':
+			kind = dateEraYear
+		case 'b':
+			kind = dateBuddhistYear
+		}
+		tokens = append(tokens, dateToken{kind: kind, width: width})
+	}
+
+	// Now that every code is known, pick out the minutes.  The Thai
+	// letters for months and minutes differ.
+	for i, t := range tokens {
+		if t.kind != dateMonth || t.width > 2 || t.thai {
+			continue
+		}
+		before := lastDateCode(tokens[:i])
+		after := firstDateCode(tokens[i+1:])
+		if before == dateHour || before == dateElapsedHours || after == dateSecond || after == dateElapsedSeconds {
+			tokens[i].kind = dateMinute
+		}
+	}
+	if thai {
+		for i := range tokens {
+			tokens[i].thai = true
+		}
+	}
+	return tokens
+}
+
+// lastDateCode returns the kind of the last token that isn't a
+// literal, or dateLiteral if there isn't one.
+func lastDateCode(tokens []dateToken) dateTokenKind {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].kind != dateLiteral {
+			return tokens[i].kind
+		}
+	}
+	return dateLiteral
+}
+
+// firstDateCode returns the kind of the first token that isn't a
+// literal, or dateLiteral if there isn't one.
+func firstDateCode(tokens []dateToken) dateTokenKind {
+	for _, t := range tokens {
+		if t.kind != dateLiteral {
+			return t.kind
+		}
+	}
+	return dateLiteral
+}
+
+// formatDateTime renders the date serial number value with a date or
+// time section of a number format, using the names and designators of
+// locale.  Elapsed times, such as [h]:mm, are counted from zero and
+// aren't limited to a day.
+//
+// As in Excel, the time is rounded to the finest unit the format
+// shows, a second or the last decimal place of the seconds, before it
+// is split into fields, so 23:59:59.6 shown as hh:mm:ss is the next
+// day's 00:00:00.
+//
+// Eras come from the calendar of locale, and Buddhist years count
+// from 543 BC.
+func formatDateTime(value float64, format string, date1904 bool, locale *Locale) string {
+	tokens := parseDateFormat(format)
+	twelveHour := false
+	unit := 1.0 // the finest unit shown, in seconds
+	for _, t := range tokens {
+		switch t.kind {
+		case dateAMPM:
+			twelveHour = true
+		case dateSubSecond:
+			unit = math.Min(unit, math.Pow10(-t.width))
+		}
+	}
+	value = math.Round(value*86400/unit) * unit / 86400
+	t := TimeFromExcelTime(value, date1904).Round(time.Microsecond)
+	year, month, day, weekday := t.Year(), t.Month(), t.Day(), t.Weekday()
+	if !date1904 && value < 61 {
+		// Excel shows its 29 February 1900 and its 0 January 1900,
+		// and gives each day before the first of them the weekday
+		// of the day before.
+		switch {
+		case value >= 60:
+			month, day = time.February, 29
+		case value >= 0 && value < 1:
+			year, month, day = 1900, time.January, 0
+		}
+		if value < 60 {
+			weekday = (weekday + 6) % 7
+		}
+	}
+	era, eraYear := locale.era(year, month, day)
+	// The elapsed time in microseconds, rounded as the time is.
+	elapsed := math.Floor(math.Abs(value)*86400*1e6 + 0.5)
+	number := func(tok dateToken, n, width int) string {
+		if tok.thai {
+			return thaiDigits(pad(n, width))
+		}
+		return pad(n, width)
+	}
+
+	var b strings.Builder
+	if value < 0 && firstDateCode(tokens) >= dateElapsedHours {
+		b.WriteByte('-')
+	}
+	for i, tok := range tokens {
+		switch tok.kind {
+		case dateLiteral:
+			if tok.text == "." && i+1 < len(tokens) && tokens[i+1].kind == dateSubSecond {
+				b.WriteString(locale.DecimalSeparator)
+			} else {
+				b.WriteString(tok.text)
+			}
+		case dateYear:
+			if tok.width > 2 {
+				b.WriteString(number(tok, year, 4))
+			} else {
+				b.WriteString(number(tok, year%100, 2))
+			}
+		case dateBuddhistYear:
+			if tok.width > 2 {
+				b.WriteString(number(tok, year+543, 4))
+			} else {
+				b.WriteString(number(tok, (year+543)%100, 2))
+			}
+		case dateEra:
+			if era != nil {
+				switch tok.width {
+				case 1:
+					b.WriteString(era.Initial)
+				case 2:
+					b.WriteString(era.Abbreviation)
+				default:
+					b.WriteString(era.Name)
+				}
+			}
+		case dateEraYear:
+			b.WriteString(number(tok, eraYear, tok.width))
+		case dateMonth:
+			switch tok.width {
+			case 1, 2:
+				b.WriteString(number(tok, int(month), tok.width))
+			case 3:
+				b.WriteString(locale.MonthAbbreviations[month-1])
+			case 4:
+				b.WriteString(locale.MonthNames[month-1])
+			default:
+				b.WriteString(firstLetter(locale.MonthNames[month-1]))
+			}
+		case dateDay:
+			b.WriteString(number(tok, day, tok.width))
+		case dateWeekday:
+			if tok.width == 3 {
+				b.WriteString(locale.DayAbbreviations[weekday])
+			} else {
+				b.WriteString(locale.DayNames[weekday])
+			}
+		case dateHour:
+			hour := t.Hour()
+			if twelveHour {
+				hour %= 12
+				if hour == 0 {
+					hour = 12
+				}
+			}
+			b.WriteString(number(tok, hour, tok.width))
+		case dateMinute:
+			b.WriteString(number(tok, t.Minute(), tok.width))
+		case dateSecond:
+			b.WriteString(number(tok, t.Second(), tok.width))
+		case dateSubSecond:
+			digits := number(tok, t.Nanosecond(), 9)
+			for utf8.RuneCountInString(digits) < tok.width {
+				digits += number(tok, 0, 1)
+			}
+			b.WriteString(string([]rune(digits)[:tok.width]))
+		case dateAMPM:
+			b.WriteString(amPM(tok.text, t.Hour() >= 12, locale))
+		case dateElapsedHours:
+			b.WriteString(number(tok, int(elapsed/(3600*1e6)), tok.width))
+		case dateElapsedMinutes:
+			b.WriteString(number(tok, int(elapsed/(60*1e6)), tok.width))
+		case dateElapsedSeconds:
+			b.WriteString(number(tok, int(elapsed/1e6), tok.width))
+		}
+	}
+	return b.String()
+}
+
+// amPM returns the designator for the morning or the afternoon.  An
+// AM/PM code shows the designator of the locale, in lower case if the
+// code is; an A/P code shows the letter it is written with.
+func amPM(code string, pm bool, locale *Locale) string {
+	if len(code) == 5 {
+		designator := locale.AM
+		if pm {
+			designator = locale.PM
+		}
+		if code[0] == 'a' {
+			designator = strings.ToLower(designator)
+		}
+		return designator
+	}
+	if pm {
+		return code[2:]
+	}
+	return code[:1]
+}
+
+// pad returns n in decimal, padded with zeros to at least width
+// digits.
+func pad(n, width int) string {
+	s := strconv.Itoa(n)
+	for len(s) < width {
+		s = "0" + s
+	}
+	return s
+}
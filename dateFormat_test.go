@@ -0,0 +1,123 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type DateFormatSuite struct{}
+
+var _ = Suite(&DateFormatSuite{})
+
+// m is a month, unless it follows an hour or comes before a second.
+func (s *DateFormatSuite) TestMonthOrMinute(c *C) {
+	// Saturday 22 November 2003, 18:05:09
+	value := 37947.75 + (5*60+9)/86400.0
+	checkFormatCases(c, []formatCase{
+		{"m/d/yyyy", value, "11/22/2003"},
+		{"h:m", value, "18:5"},
+		{"hh:mm", value, "18:05"},
+		{"mm:ss", value, "05:09"},
+		{"h \"hours\" m \"minutes\"", value, "18 hours 5 minutes"},
+		{"yyyy-mm-dd hh:mm", value, "2003-11-22 18:05"},
+		{"mmm mmmm mmmmm", value, "Nov November N"},
+		{"ddd dddd", value, "Sat Saturday"},
+		{"yy yyyy", value, "03 2003"},
+	})
+}
+
+// Quoted and escaped text isn't read as codes.
+func (s *DateFormatSuite) TestLiterals(c *C) {
+	checkFormatCases(c, []formatCase{
+		{`"Day "d" of "mmmm`, 37947.75, "Day 22 of November"},
+		{`d\d\s`, 37947.75, "22ds"},
+		{`yyyy"年"m"月"d"日"`, 37947.75, "2003年11月22日"},
+	})
+}
+
+// AM/PM and A/P switch the hour to the 12 hour clock, and are shown
+// in the case they are written in.
+func (s *DateFormatSuite) TestAMPM(c *C) {
+	checkFormatCases(c, []formatCase{
+		{"h:mm AM/PM", 37947.75, "6:00 PM"},
+		{"h:mm am/pm", 37947.25, "6:00 am"},
+		{"hh:mm A/P", 37947.75, "06:00 P"},
+		{"h:mm a/p", 37947.0, "12:00 a"},
+		{"h:mm", 37947.75, "18:00"},
+	})
+}
+
+// Elapsed times count from zero, and aren't limited to a day.
+func (s *DateFormatSuite) TestElapsed(c *C) {
+	value := 1 + (3*3600+25*60+45)/86400.0 // 27:25:45
+	checkFormatCases(c, []formatCase{
+		{"[h]:mm:ss", value, "27:25:45"},
+		{"[hh]:mm", 0.125, "03:00"},
+		{"[mm]:ss", value, "1645:45"},
+		{"[ss]", value, "98745"},
+		{"[h]:mm:ss", -0.5, "-12:00:00"},
+	})
+}
+
+// Seconds can have up to three decimal places.
+func (s *DateFormatSuite) TestSubSeconds(c *C) {
+	value := 0.5 + 1.234/86400
+	checkFormatCases(c, []formatCase{
+		{"hh:mm:ss.000", value, "12:00:01.234"},
+		{"mm:ss.0", value, "00:01.2"},
+		{"[ss].00", value, "43201.23"},
+	})
+}
+
+// Times are rounded to the finest unit shown before they are split
+// into fields, carrying into the minute, hour and day.
+func (s *DateFormatSuite) TestRounding(c *C) {
+	checkFormatCases(c, []formatCase{
+		{"hh:mm:ss", 6.6 / 86400, "00:00:07"},
+		{"hh:mm:ss", 6.4 / 86400, "00:00:06"},
+		{"hh:mm:ss", 44197.0000810185, "00:00:07"},
+		{"hh:mm:ss", (86400 - 0.4) / 86400, "00:00:00"},
+		{"yyyy-mm-dd hh:mm:ss", 44197 + (86400-0.4)/86400, "2021-01-02 00:00:00"},
+		{"hh:mm:ss.0", 44197 + (86400-0.4)/86400, "23:59:59.6"},
+		{"hh:mm:ss.00", (59*60 + 59.996) / 86400, "01:00:00.00"},
+		{"hh:mm", (10*3600 + 29*60 + 59.6) / 86400, "10:30"},
+		{"[ss]", 6.6 / 86400, "07"},
+		{"[mm]:ss", 59.6 / 86400, "01:00"},
+	})
+}
+
+// Excel's 1900 date system has a 29 February 1900 and a 0 January
+// 1900, and its days before March 1900 are a weekday out.
+func (s *DateFormatSuite) TestLeapYearBug(c *C) {
+	checkFormatCases(c, []formatCase{
+		{"d mmm yyyy dddd", 59, "28 Feb 1900 Tuesday"},
+		{"d mmm yyyy dddd", 60, "29 Feb 1900 Wednesday"},
+		{"d mmm yyyy dddd", 61, "1 Mar 1900 Thursday"},
+		{"d mmm yyyy dddd", 1, "1 Jan 1900 Sunday"},
+		{"m/d/yyyy h:mm", 0.75, "1/0/1900 18:00"},
+		{"d mmm yyyy", -1, "30 Dec 1899"},
+	})
+
+	// None of that applies to the 1904 date system.
+	cell := Cell{}
+	cell.SetDateTimeWithFormat(0, "d mmm yyyy dddd")
+	cell.date1904 = true
+	val, err := cell.FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(val, Equals, "1 Jan 1904 Friday")
+}
+
+func (s *DateFormatSuite) TestParseDateFormat(c *C) {
+	c.Assert(parseDateFormat(`[h]:mm:ss.00 AM/PM "x"`), DeepEquals, []dateToken{
+		{kind: dateElapsedHours, width: 1},
+		{kind: dateLiteral, text: ":"},
+		{kind: dateMinute, width: 2},
+		{kind: dateLiteral, text: ":"},
+		{kind: dateSecond, width: 2},
+		{kind: dateLiteral, text: "."},
+		{kind: dateSubSecond, width: 2},
+		{kind: dateLiteral, text: " "},
+		{kind: dateAMPM, text: "AM/PM"},
+		{kind: dateLiteral, text: " "},
+		{kind: dateLiteral, text: "x"},
+	})
+}
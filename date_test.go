@@ -43,7 +43,11 @@ func (d *DateSuite) TestJulianDateToGregorianTime(c *C) {
 
 func (d *DateSuite) TestTimeFromExcelTime(c *C) {
 	date := TimeFromExcelTime(0, false)
-	c.Assert(date, Equals, time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC))
+	c.Assert(date, Equals, time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC))
+	date = TimeFromExcelTime(1, false)
+	c.Assert(date, Equals, time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC))
+	date = TimeFromExcelTime(59, false)
+	c.Assert(date, Equals, time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC))
 	date = TimeFromExcelTime(60, false)
 	c.Assert(date, Equals, time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC))
 	date = TimeFromExcelTime(61, false)
@@ -54,7 +58,7 @@ func (d *DateSuite) TestTimeFromExcelTime(c *C) {
 
 func (d *DateSuite) TestTimeFromExcelTimeWithFractionalPart(c *C) {
 	date := TimeFromExcelTime(0.114583333333333, false)
-	c.Assert(date.Round(time.Second), Equals, time.Date(1899, 12, 30, 2, 45, 0, 0, time.UTC))
+	c.Assert(date.Round(time.Second), Equals, time.Date(1899, 12, 31, 2, 45, 0, 0, time.UTC))
 
 	date = TimeFromExcelTime(60.1145833333333, false)
 	c.Assert(date.Round(time.Second), Equals, time.Date(1900, 2, 28, 2, 45, 0, 0, time.UTC))
@@ -72,5 +76,39 @@ func (d *DateSuite) TestTimeFromExcelTimeWithFractionalPart(c *C) {
 func (d *DateSuite) TestTimeFromExcelTimeWith1904Offest(c *C) {
 	date1904Offset := TimeFromExcelTime(39813.0, true)
 	c.Assert(date1904Offset, Equals, time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(TimeFromExcelTime(0, true), Equals, time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// Negative values count back from day 0, a fraction of a day back
+// being a time on the day before.
+func (d *DateSuite) TestTimeFromNegativeExcelTime(c *C) {
+	c.Assert(TimeFromExcelTime(-0.25, false), Equals, time.Date(1899, 12, 30, 18, 0, 0, 0, time.UTC))
+	c.Assert(TimeFromExcelTime(-366, false), Equals, time.Date(1898, 12, 30, 0, 0, 0, 0, time.UTC))
+	c.Assert(TimeFromExcelTime(-1.5, true), Equals, time.Date(1903, 12, 30, 12, 0, 0, 0, time.UTC))
+}
+
+func (d *DateSuite) TestTimeToExcelTime(c *C) {
+	cases := []struct {
+		t        time.Time
+		date1904 bool
+		expected float64
+	}{
+		{time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC), false, 1},
+		{time.Date(1900, 2, 28, 12, 0, 0, 0, time.UTC), false, 59.5},
+		{time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), false, 61},
+		{time.Date(2013, 1, 1, 6, 0, 0, 0, time.UTC), false, 41275.25},
+		{time.Date(2013, 1, 1, 6, 0, 0, 0, time.UTC), true, 39813.25},
+		{time.Date(1899, 12, 30, 18, 0, 0, 0, time.UTC), false, -0.25},
+		{time.Date(1903, 12, 31, 0, 0, 0, 0, time.UTC), true, -1},
+		{time.Date(2000, 1, 1, 0, 0, 0, 500000000, time.UTC), false, 36526 + 0.5/86400},
+	}
+	for _, tc := range cases {
+		excelTime := timeToExcelTime(tc.t, tc.date1904)
+		c.Assert(excelTime, Equals, tc.expected, Commentf("%v", tc.t))
+		c.Assert(TimeFromExcelTime(excelTime, tc.date1904), Equals, tc.t)
+	}
 
+	// The wall-clock time is kept, whatever the time zone.
+	tokyo := time.FixedZone("JST", 9*60*60)
+	c.Assert(timeToExcelTime(time.Date(2013, 1, 1, 6, 0, 0, 0, tokyo), false), Equals, 41275.25)
 }
@@ -4,11 +4,13 @@ import (
 	"archive/zip"
 	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 	//"os/exec"
 	//"errors"
 	//"path"
@@ -18,76 +20,41 @@ import (
 // to the user.
 type File struct {
 	worksheets     map[string]*zip.File
+	worksheetRels  map[string]*zip.File
+	workbookSheets []xlsxSheet
+	sheetXMLMap    map[string]string
 	referenceTable *RefTable
-	Date1904       bool
+	Date1904       bool           // Count dates from 1904, as Excel for the Mac once did
+	Location       *time.Location // Time zone of dates and times, or nil for wall-clock times; see Cell.SetDateTime
+	InlineStrings  bool           // Write string cells inline rather than as shared strings
+	trimSpace      bool
+	partial        bool // read with only some of its sheets, so it cannot be saved
 	styles         *xlsxStyleSheet
 	Sheets         []*Sheet
 	Sheet          map[string]*Sheet
 	theme          *theme
 	DefinedNames   []*xlsxDefinedName
+	closer         io.Closer
+	parts          *packageParts // parts of an opened file that are written back unchanged
 }
 
-func (f *File) AddCF(cf map[string][]map[string]string) (err error){
-	// loop over styles and get largest dxfid
-	// after setting new dxfs increment count of dxfs
-	newDxfId := "0"	
-	if f.styles != nil {
-		newDxfId = f.styles.Dxfs.Count
-		if newDxfId == ""{
-			newDxfId = "0"
-		}
+// AddCF adds conditional formats to the first sheet of the file.
+// Each map in cf["cf"] describes an expression rule by its "sqref",
+// "formula" and the "BgColor" given to matching cells.
+//
+// Deprecated: use Sheet.AddConditionalFormat, which supports every
+// type of rule and a full differential style.
+func (f *File) AddCF(cf map[string][]map[string]string) (err error) {
+	if len(f.Sheets) == 0 {
+		return nil
 	}
-	
-	for _, sheet := range f.Sheets {		
-		// loop over file CF to get dxf last priority
-		lastPriority := 0
-		for _, CFFile := range sheet.ConditionalFormatting {
-			priorityCFFile, err := strconv.Atoi(CFFile.CfRule.Priority)
-			if err != nil {
-				return err
-			}
-			if priorityCFFile > lastPriority{
-				lastPriority = priorityCFFile
-			}
-		}
-		
-		// loop over request cf's and add it to first sheet
-		for _, CFMap := range cf["cf"]{
-					
-			newCF := new(conditionalFormatting)
-			newCF.Sqref = CFMap["sqref"]
-			newCF.CfRule.Type = "expression"			
-			newCF.CfRule.DxfId = newDxfId
-			lastPriority++
-			strLastPriority := strconv.Itoa(lastPriority)
-			newCF.CfRule.Priority = strLastPriority
-			newCF.CfRule.Formula.Value = CFMap["formula"]
-
-			// add newCF to sheet
-			sheet.ConditionalFormatting = append(sheet.ConditionalFormatting, *newCF)
-			
-			// make new xlsxFill from request
-			dxfFill := new(xlsxFill)
-			newDxf := new(xlsxDxf)	
-
-			dxfFill.PatternFill.BgColor.RGB = CFMap["BgColor"]
-			
-			newDxf.Fill = *dxfFill
-			if f.styles == nil{
-				f.styles = new(xlsxStyleSheet)
-			}
-			f.styles.Dxfs.Dxf = append(f.styles.Dxfs.Dxf, *newDxf)
-			
-			intNewDxfId, err := strconv.Atoi(newDxfId)
-			if err != nil {
-				return err
-			}
-			
-			intNewDxfId++
-			newDxfId = strconv.Itoa(intNewDxfId)			
+	sheet := f.Sheets[0]
+	for _, CFMap := range cf["cf"] {
+		style := &DifferentialStyle{Fill: &Fill{BgColor: CFMap["BgColor"]}}
+		err = sheet.AddConditionalFormat(CFMap["sqref"], NewExpressionRule(CFMap["formula"], style))
+		if err != nil {
+			return err
 		}
-		f.styles.Dxfs.Count = newDxfId
-		break
 	}
 	return nil
 }
@@ -113,6 +80,97 @@ func OpenFile(filename string) (file *File, err error) {
 	return
 }
 
+// ReadOptions controls how much of an XLSX file is read by
+// OpenFileWithOptions and ReadZipReaderWithOptions.
+type ReadOptions struct {
+	// Sheets lists the names of the sheets to read.  Sheets that
+	// are not listed are skipped entirely.  If Sheets is empty,
+	// every sheet is read.  A file read with only some of its
+	// sheets cannot be written, as the skipped sheets would be lost.
+	Sheets []string
+	// Lazy defers decoding the worksheet of each sheet until its
+	// contents are first needed, or until Sheet.Load is called.
+	Lazy bool
+	// TrimSpace strips leading and trailing spaces, tabs and
+	// newlines from the values of string cells.  By default string
+	// values are read exactly as they are stored.
+	TrimSpace bool
+	// Locale is the locale of the Excel installation that saved
+	// the file.  It decides the codes of the built-in number
+	// formats that differ between locales, such as 31, which
+	// Excel in Japan shows as yyyy"年"m"月"d"日".  By default they
+	// are read as Excel in the United States reads them.
+	Locale *Locale
+}
+
+// selectSheets returns the workbook sheets chosen by the options, in
+// workbook order.
+func (o ReadOptions) selectSheets(sheets []xlsxSheet) ([]xlsxSheet, error) {
+	if len(o.Sheets) == 0 {
+		return sheets, nil
+	}
+	wanted := make(map[string]bool, len(o.Sheets))
+	for _, name := range o.Sheets {
+		wanted[name] = true
+	}
+	var selected []xlsxSheet
+	for _, sheet := range sheets {
+		if wanted[sheet.Name] {
+			selected = append(selected, sheet)
+			delete(wanted, sheet.Name)
+		}
+	}
+	for _, name := range o.Sheets {
+		if wanted[name] {
+			return nil, fmt.Errorf("Unable to find sheet '%s'", name)
+		}
+	}
+	return selected, nil
+}
+
+// OpenFileWithOptions() takes the name of an XLSX file and returns a
+// xlsx.File struct populated according to the provided options.  When
+// the options ask for lazy loading, the zip file is kept open until
+// File.Close is called.
+func OpenFileWithOptions(filename string, options ReadOptions) (*File, error) {
+	f, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !options.Lazy {
+		defer f.Close()
+		return ReadZipReaderWithOptions(&f.Reader, options)
+	}
+	file, err := ReadZipReaderWithOptions(&f.Reader, options)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	file.closer = f
+	return file, nil
+}
+
+// OpenFileForStreaming() takes the name of an XLSX file and returns
+// an xlsx.File struct whose sheets are not loaded.  The rows of each
+// sheet can then be read one at a time with File.OpenSheetRows,
+// without ever holding the whole worksheet in memory.  The zip file
+// is kept open until File.Close is called.
+func OpenFileForStreaming(filename string) (*File, error) {
+	return OpenFileWithOptions(filename, ReadOptions{Lazy: true})
+}
+
+// Close releases the underlying zip file of a File opened lazily
+// with OpenFileWithOptions or OpenFileForStreaming.  It is a no-op for
+// any other File.
+func (f *File) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	err := f.closer.Close()
+	f.closer = nil
+	return err
+}
+
 // OpenBinary() take bytes of an XLSX file and returns a populated
 // xlsx.File struct for it.
 func OpenBinary(bs []byte) (*File, error) {
@@ -137,10 +195,10 @@ func OpenReaderAt(r io.ReaderAt, size int64) (*File, error) {
 //
 // For example:
 //
-//    var mySlice [][][]string
-//    var value string
-//    mySlice = xlsx.FileToSlice("myXLSX.xlsx")
-//    value = mySlice[0][0][0]
+//	var mySlice [][][]string
+//	var value string
+//	mySlice = xlsx.FileToSlice("myXLSX.xlsx")
+//	value = mySlice[0][0][0]
 //
 // Here, value would be set to the raw value of the cell A1 in the
 // first sheet in the XLSX file.
@@ -202,9 +260,13 @@ func (f *File) AddSheet(sheetName string) (*Sheet, error) {
 }
 
 func (f *File) makeWorkbook() xlsxWorkbook {
+	definedNames := xlsxDefinedNames{DefinedName: make([]xlsxDefinedName, len(f.DefinedNames))}
+	for i, dn := range f.DefinedNames {
+		definedNames.DefinedName[i] = *dn
+	}
 	return xlsxWorkbook{
 		FileVersion: xlsxFileVersion{AppName: "Go XLSX"},
-		WorkbookPr:  xlsxWorkbookPr{ShowObjects: "all"},
+		WorkbookPr:  xlsxWorkbookPr{ShowObjects: "all", Date1904: f.Date1904},
 		BookViews: xlsxBookViews{
 			WorkBookView: []xlsxWorkBookView{
 				{
@@ -219,7 +281,8 @@ func (f *File) makeWorkbook() xlsxWorkbook {
 				},
 			},
 		},
-		Sheets: xlsxSheets{Sheet: make([]xlsxSheet, len(f.Sheets))},
+		Sheets:       xlsxSheets{Sheet: make([]xlsxSheet, len(f.Sheets))},
+		DefinedNames: definedNames,
 		CalcPr: xlsxCalcPr{
 			IterateCount: 100,
 			RefMode:      "A1",
@@ -237,20 +300,29 @@ func (f *File) makeWorkbook() xlsxWorkbook {
 // problem because the Go XML library doesn't multiple namespace
 // declarations in a single element of a document.  This function is a
 // horrible hack to fix that after the XML marshalling is completed.
+// It is applied to the workbook and to worksheets that refer to other
+// parts.
 func replaceRelationshipsNameSpace(workbookMarshal string) string {
 	newWorkbook := strings.Replace(workbookMarshal, `xmlns:relationships="http://schemas.openxmlformats.org/officeDocument/2006/relationships" relationships:id`, `r:id`, -1)
 	// Dirty hack to fix issues #63 and #91; encoding/xml currently
 	// "doesn't allow for additional namespaces to be defined in the
 	// root element of the document," as described by @tealeg in the
 	// comments for #63.
-	oldXmlns := `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`
-	newXmlns := `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`
-	return strings.Replace(newWorkbook, oldXmlns, newXmlns, 1)
+	for _, root := range []string{"workbook", "worksheet"} {
+		oldXmlns := `<` + root + ` xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`
+		newXmlns := `<` + root + ` xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`
+		newWorkbook = strings.Replace(newWorkbook, oldXmlns, newXmlns, 1)
+	}
+	return newWorkbook
 }
 
 // Construct a map of file name to XML content representing the file
-// in terms of the structure of an XLSX file.
+// in terms of the structure of an XLSX file.  A file read with only
+// some of its sheets gives an error.
 func (f *File) MarshallParts() (map[string]string, error) {
+	if f.partial {
+		return nil, errors.New("xlsx: cannot save a file read with only some of its sheets")
+	}
 	var parts map[string]string
 	var refTable *RefTable = NewSharedStringRefTable()
 	refTable.isWrite = true
@@ -259,14 +331,6 @@ func (f *File) MarshallParts() (map[string]string, error) {
 	var workbook xlsxWorkbook
 	var types xlsxTypes = MakeDefaultContentTypes()
 
-	marshal := func(thing interface{}) (string, error) {
-		body, err := xml.Marshal(thing)
-		if err != nil {
-			return "", err
-		}
-		return xml.Header + string(body), nil
-	}
-
 	parts = make(map[string]string)
 	workbook = f.makeWorkbook()
 
@@ -275,40 +339,89 @@ func (f *File) MarshallParts() (map[string]string, error) {
 	if f.styles == nil {
 		f.styles = newXlsxStyleSheet(f.theme)
 	}
+	for _, sheet := range f.Sheets {
+		if err := sheet.Load(); err != nil {
+			return parts, err
+		}
+	}
 	f.styles.reset()
+	sheetRefTable := refTable
+	if f.InlineStrings {
+		sheetRefTable = nil
+	}
 	for _, sheet := range f.Sheets {
-		xSheet := sheet.makeXLSXSheet(refTable, f.styles)
-		rId := fmt.Sprintf("rId%d", sheetIndex)
-		sheetId := strconv.Itoa(sheetIndex)
-		sheetPath := fmt.Sprintf("worksheets/sheet%d.xml", sheetIndex)
-		partName := "xl/" + sheetPath
-		types.Overrides = append(
-			types.Overrides,
-			xlsxOverride{
-				PartName:    "/" + partName,
-				ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"})
-		workbookRels[rId] = sheetPath
-		workbook.Sheets.Sheet[sheetIndex-1] = xlsxSheet{
-			Name:    sheet.Name,
-			SheetId: sheetId,
-			Id:      rId,
-			State:   "visible"}
-		parts[partName], err = marshal(xSheet)
+		xSheet := sheet.makeXLSXSheet(sheetRefTable, f.styles)
+		partName := addSheetToWorkbook(sheetIndex, sheet.Name, &workbook, workbookRels, &types)
+		parts[partName], err = marshalPart(xSheet)
 		if err != nil {
 			return parts, err
 		}
+		if sheet.parts != nil {
+			parts[partName] = replaceRelationshipsNameSpace(parts[partName])
+			if len(sheet.parts.relationships) > 0 {
+				parts[worksheetRelsName(partName)], err = marshalRels(sheet.parts.relationships)
+				if err != nil {
+					return parts, err
+				}
+			}
+		}
 		sheetIndex++
 	}
 
-	workbookMarshal, err := marshal(workbook)
+	err = f.marshallWorkbookParts(parts, workbook, workbookRels, types, refTable)
+	return parts, err
+}
+
+// marshalPart renders thing as a complete XML document.
+func marshalPart(thing interface{}) (string, error) {
+	body, err := xml.Marshal(thing)
 	if err != nil {
-		return parts, err
+		return "", err
 	}
-	workbookMarshal = replaceRelationshipsNameSpace(workbookMarshal)
-	parts["xl/workbook.xml"] = workbookMarshal
+	return xml.Header + string(body), nil
+}
+
+// addSheetToWorkbook registers the worksheet with the given (one
+// based) index and name in the workbook, its relationships and the
+// content types, and returns the name of the zip entry that should
+// hold the worksheet XML.
+func addSheetToWorkbook(sheetIndex int, sheetName string, workbook *xlsxWorkbook, workbookRels WorkBookRels, types *xlsxTypes) string {
+	rId := fmt.Sprintf("rId%d", sheetIndex)
+	sheetId := strconv.Itoa(sheetIndex)
+	sheetPath := fmt.Sprintf("worksheets/sheet%d.xml", sheetIndex)
+	partName := "xl/" + sheetPath
+	types.Overrides = append(
+		types.Overrides,
+		xlsxOverride{
+			PartName:    "/" + partName,
+			ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"})
+	workbookRels[rId] = sheetPath
+	workbook.Sheets.Sheet[sheetIndex-1] = xlsxSheet{
+		Name:    sheetName,
+		SheetId: sheetId,
+		Id:      rId,
+		State:   "visible"}
+	return partName
+}
+
+// marshallWorkbookParts adds every part other than the worksheets
+// themselves to parts: the workbook, its relationships, the shared
+// strings, the styles and the various templated parts.  The parts of
+// an opened file that the library does not model are added as they
+// were read.
+func (f *File) marshallWorkbookParts(parts map[string]string, workbook xlsxWorkbook, workbookRels WorkBookRels, types xlsxTypes, refTable *RefTable) error {
+	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	if f.parts != nil {
+		f.parts.addWorkbookRels(&xWRel, &workbook)
+		f.parts.addContentTypes(&types)
+	}
+
+	workbookMarshal, err := marshalPart(workbook)
 	if err != nil {
-		return parts, err
+		return err
 	}
+	workbookMarshal = replaceRelationshipsNameSpace(workbookMarshal)
+	parts["xl/workbook.xml"] = workbookMarshal
 
 	parts["_rels/.rels"] = TEMPLATE__RELS_DOT_RELS
 	parts["docProps/app.xml"] = TEMPLATE_DOCPROPS_APP
@@ -317,28 +430,31 @@ func (f *File) MarshallParts() (map[string]string, error) {
 	parts["xl/theme/theme1.xml"] = TEMPLATE_XL_THEME_THEME
 
 	xSST := refTable.makeXLSXSST()
-	parts["xl/sharedStrings.xml"], err = marshal(xSST)
+	parts["xl/sharedStrings.xml"], err = marshalPart(xSST)
 	if err != nil {
-		return parts, err
+		return err
 	}
 
-	xWRel := workbookRels.MakeXLSXWorkbookRels()
-
-	parts["xl/_rels/workbook.xml.rels"], err = marshal(xWRel)
+	parts["xl/_rels/workbook.xml.rels"], err = marshalPart(xWRel)
 	if err != nil {
-		return parts, err
+		return err
 	}
 
-	parts["[Content_Types].xml"], err = marshal(types)
+	parts["[Content_Types].xml"], err = marshalPart(types)
 	if err != nil {
-		return parts, err
+		return err
 	}
 	parts["xl/styles.xml"], err = f.styles.Marshal()
 	if err != nil {
-		return parts, err
+		return err
 	}
-
-	return parts, nil
+	if f.parts != nil {
+		for name, content := range f.parts.files {
+			parts[name] = string(content)
+		}
+		return f.parts.addRootRels(parts)
+	}
+	return nil
 }
 
 // Return the raw data contained in the File as three
@@ -347,16 +463,19 @@ func (f *File) MarshallParts() (map[string]string, error) {
 //
 // For example:
 //
-//    var mySlice [][][]string
-//    var value string
-//    mySlice = xlsx.FileToSlice("myXLSX.xlsx")
-//    value = mySlice[0][0][0]
+//	var mySlice [][][]string
+//	var value string
+//	mySlice = xlsx.FileToSlice("myXLSX.xlsx")
+//	value = mySlice[0][0][0]
 //
 // Here, value would be set to the raw value of the cell A1 in the
 // first sheet in the XLSX file.
 func (file *File) ToSlice() (output [][][]string, err error) {
 	output = [][][]string{}
 	for _, sheet := range file.Sheets {
+		if err := sheet.Load(); err != nil {
+			return output, err
+		}
 		s := [][]string{}
 		for _, row := range sheet.Rows {
 			if row == nil {
@@ -0,0 +1,185 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/structer/xlsx/formula"
+)
+
+// RenameSheet renames the sheet called name, which is matched without
+// regard to case, to newName.  The references to the sheet in the
+// formulas, conditional formats, defined names and hyperlinks of the
+// file are renamed along with it, and quoted as the new name needs,
+// as in 'New Name'!A1.  Charts and the other parts that the library
+// keeps as they are aren't changed.
+//
+// Excel doesn't allow names longer than 31 characters, names with any
+// of the characters : \ / ? * [ ], or names that start or end with an
+// apostrophe, and neither does RenameSheet.
+func (f *File) RenameSheet(name, newName string) error {
+	sheet := f.sheetNamed(name)
+	if sheet == nil {
+		return fmt.Errorf("xlsx: no sheet named '%s'", name)
+	}
+	if err := checkSheetName(newName); err != nil {
+		return err
+	}
+	if other := f.sheetNamed(newName); other != nil && other != sheet {
+		return fmt.Errorf("duplicate sheet name '%s'.", newName)
+	}
+	rename := renameSheet(sheet.Name, newName)
+	err := f.rewriteFormulas(func(home *Sheet, n formula.Node) formula.Node {
+		return rename(n)
+	})
+	if err != nil {
+		return err
+	}
+	for _, s := range f.Sheets {
+		if s.parts == nil || s.parts.hyperlinks == nil {
+			continue
+		}
+		links := s.parts.hyperlinks.Hyperlink
+		for i := range links {
+			if links[i].Location != "" {
+				links[i].Location = rewriteFormula(links[i].Location, rename)
+			}
+		}
+	}
+	delete(f.Sheet, sheet.Name)
+	sheet.Name = newName
+	f.Sheet[newName] = sheet
+	return nil
+}
+
+// MoveSheet moves the sheet called name so that it comes at index in
+// the order of the sheets, counting from 0.  The defined names of the
+// sheets keep to their sheets.
+func (f *File) MoveSheet(name string, index int) error {
+	sheet := f.sheetNamed(name)
+	if sheet == nil {
+		return fmt.Errorf("xlsx: no sheet named '%s'", name)
+	}
+	if index < 0 || index >= len(f.Sheets) {
+		return fmt.Errorf("xlsx: cannot move sheet '%s' to index %d", name, index)
+	}
+	old := make(map[*Sheet]int)
+	sheets := make([]*Sheet, 0, len(f.Sheets))
+	for i, s := range f.Sheets {
+		old[s] = i
+		if s != sheet {
+			sheets = append(sheets, s)
+		}
+	}
+	sheets = append(sheets[:index], append([]*Sheet{sheet}, sheets[index:]...)...)
+	moved := make([]int, len(sheets))
+	for i, s := range sheets {
+		moved[old[s]] = i
+	}
+	for _, dn := range f.DefinedNames {
+		if !dn.isGlobal() && *dn.LocalSheetID >= 0 && *dn.LocalSheetID < len(moved) {
+			dn.setLocalSheet(moved[*dn.LocalSheetID])
+		}
+	}
+	f.Sheets = sheets
+	return nil
+}
+
+// DeleteSheet deletes the sheet called name from the file.  The
+// references to it in the formulas and defined names of the other
+// sheets become #REF!, and a range of sheets such as Sheet1:Sheet3
+// that ends at it ends at the sheet next to it instead.  The defined
+// names of the sheet are deleted, and those of the sheets after it are
+// kept to their sheets as MoveSheet does.
+func (f *File) DeleteSheet(name string) error {
+	sheet := f.sheetNamed(name)
+	if sheet == nil {
+		return fmt.Errorf("xlsx: no sheet named '%s'", name)
+	}
+	index := f.sheetIndex(sheet.Name)
+	err := f.rewriteFormulas(func(home *Sheet, n formula.Node) formula.Node {
+		switch n := n.(type) {
+		case *formula.Ref:
+			if n.Book != "" {
+				return nil
+			}
+			if n.LastSheet == "" {
+				if strings.EqualFold(n.Sheet, sheet.Name) {
+					return &formula.Ref{Invalid: true}
+				}
+				return nil
+			}
+			first, last := f.sheetIndex(n.Sheet), f.sheetIndex(n.LastSheet)
+			if index != first && index != last {
+				return nil
+			}
+			if first < 0 || last < 0 || first >= last {
+				return &formula.Ref{Invalid: true}
+			}
+			ref := *n
+			if index == first {
+				ref.Sheet = f.Sheets[first+1].Name
+			} else {
+				ref.LastSheet = f.Sheets[last-1].Name
+			}
+			if strings.EqualFold(ref.Sheet, ref.LastSheet) {
+				ref.LastSheet = ""
+			}
+			return &ref
+		case *formula.Name:
+			if n.Book == "" && strings.EqualFold(n.Sheet, sheet.Name) {
+				return &formula.Ref{Invalid: true}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var names []*xlsxDefinedName
+	for _, dn := range f.DefinedNames {
+		switch {
+		case dn.isGlobal():
+		case *dn.LocalSheetID == index:
+			continue
+		case *dn.LocalSheetID > index:
+			dn.setLocalSheet(*dn.LocalSheetID - 1)
+		}
+		names = append(names, dn)
+	}
+	f.DefinedNames = names
+
+	f.Sheets = append(f.Sheets[:index], f.Sheets[index+1:]...)
+	delete(f.Sheet, sheet.Name)
+	if sheet.Selected && len(f.Sheets) > 0 {
+		f.Sheets[0].Selected = true
+	}
+	return nil
+}
+
+// sheetIndex returns the index of the sheet of the given name, which
+// is matched without regard to case, or -1.
+func (f *File) sheetIndex(name string) int {
+	for i, sheet := range f.Sheets {
+		if strings.EqualFold(sheet.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkSheetName returns an error if Excel doesn't allow name as the
+// name of a sheet.
+func checkSheetName(name string) error {
+	switch {
+	case name == "" || utf8.RuneCountInString(name) > 31:
+		return fmt.Errorf("xlsx: sheet name '%s' must be 1 to 31 characters long", name)
+	case strings.ContainsAny(name, `:\/?*[]`):
+		return fmt.Errorf(`xlsx: sheet name '%s' must not contain any of : \ / ? * [ ]`, name)
+	case strings.HasPrefix(name, "'") || strings.HasSuffix(name, "'"):
+		return fmt.Errorf("xlsx: sheet name '%s' must not start or end with an apostrophe", name)
+	}
+	return nil
+}
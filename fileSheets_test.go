@@ -0,0 +1,174 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type FileSheetsSuite struct{}
+
+var _ = Suite(&FileSheetsSuite{})
+
+// localSheetID returns a pointer to id, for the LocalSheetID of a
+// defined name.
+func localSheetID(id int) *int {
+	return &id
+}
+
+func (s *FileSheetsSuite) TestRenameSheet(c *C) {
+	f, sheets := newTestFile(c, []string{"First", "Data", "Last"},
+		map[string]interface{}{"A1": "=Data!A1*2", "A2": "=SUM(First:Last!B1)", "A3": "=Data!Rate+Rate"},
+		map[string]interface{}{"A1": 5, "A2": "=A1+Data!A1", "B1": 2},
+		map[string]interface{}{"A1": "=data!A1"})
+	c.Assert(sheets[2].AddConditionalFormat("A1", NewExpressionRule("Data!A1>1", nil)), IsNil)
+	sheets[2].parts = &worksheetParts{hyperlinks: &xlsxHyperlinks{Hyperlink: []xlsxHyperlink{{Ref: "B2", Location: "Data!A1"}}}}
+	f.DefinedNames = append(f.DefinedNames,
+		&xlsxDefinedName{Name: "Rate", Data: "0.5"},
+		&xlsxDefinedName{Name: "Rate", Data: "Data!$B$1", LocalSheetID: localSheetID(1)},
+	)
+
+	c.Assert(f.RenameSheet("data", "My 'Data"), IsNil)
+	c.Assert(sheets[1].Name, Equals, "My 'Data")
+	c.Assert(f.Sheet["My 'Data"], Equals, sheets[1])
+	_, ok := f.Sheet["Data"]
+	c.Assert(ok, Equals, false)
+
+	c.Assert(sheets[0].Cell(0, 0).Formula(), Equals, "'My ''Data'!A1*2")
+	c.Assert(sheets[0].Cell(2, 0).Formula(), Equals, "'My ''Data'!Rate+Rate")
+	c.Assert(sheets[1].Cell(1, 0).Formula(), Equals, "A1+'My ''Data'!A1")
+	c.Assert(sheets[2].Cell(0, 0).Formula(), Equals, "'My ''Data'!A1")
+	c.Assert(sheets[2].ConditionalFormatting[0].Rules[0].Formula, DeepEquals, []string{"'My ''Data'!A1>1"})
+	c.Assert(sheets[2].parts.hyperlinks.Hyperlink[0].Location, Equals, "'My ''Data'!A1")
+	c.Assert(f.DefinedNames[1].Data, Equals, "'My ''Data'!$B$1")
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+
+	c.Assert(f.RenameSheet("First", "Start"), IsNil)
+	c.Assert(sheets[0].Cell(1, 0).Formula(), Equals, "SUM(Start:Last!B1)")
+
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheets[0].Cell(0, 0).Value, Equals, "10")
+	c.Assert(sheets[0].Cell(2, 0).Value, Equals, "2.5")
+}
+
+func (s *FileSheetsSuite) TestRenameSheetErrors(c *C) {
+	f, _ := newTestFile(c, []string{"First", "Data", "Last"})
+	c.Assert(f.RenameSheet("Missing", "Other"), ErrorMatches, `xlsx: no sheet named 'Missing'`)
+	c.Assert(f.RenameSheet("Data", "last"), ErrorMatches, `duplicate sheet name 'last'.`)
+	c.Assert(f.RenameSheet("Data", ""), ErrorMatches, `xlsx: sheet name '' must be 1 to 31 characters long`)
+	c.Assert(f.RenameSheet("Data", "a/b"), ErrorMatches, `xlsx: sheet name 'a/b' must not contain any of .*`)
+	c.Assert(f.RenameSheet("Data", "'quoted'"), ErrorMatches, `xlsx: sheet name ''quoted'' must not start or end with an apostrophe`)
+	c.Assert(f.RenameSheet("Data", "DATA"), IsNil)
+	c.Assert(f.Sheets[1].Name, Equals, "DATA")
+}
+
+func (s *FileSheetsSuite) TestMoveSheet(c *C) {
+	f, sheets := newTestFile(c, []string{"First", "Data", "Last"},
+		map[string]interface{}{"A1": "=Data!Rate+Rate"},
+		map[string]interface{}{"B1": 2})
+	f.DefinedNames = append(f.DefinedNames,
+		&xlsxDefinedName{Name: "Rate", Data: "0.5"},
+		&xlsxDefinedName{Name: "Rate", Data: "Data!$B$1", LocalSheetID: localSheetID(1)},
+		&xlsxDefinedName{Name: "Print_Area", Data: "Last!$A$1:$B$1", LocalSheetID: localSheetID(2)},
+	)
+
+	c.Assert(f.MoveSheet("Last", 1), IsNil)
+	c.Assert(f.Sheets, DeepEquals, []*Sheet{sheets[0], sheets[2], sheets[1]})
+	c.Assert(*f.DefinedNames[1].LocalSheetID, Equals, 2)
+	c.Assert(*f.DefinedNames[2].LocalSheetID, Equals, 1)
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheets[0].Cell(0, 0).Value, Equals, "2.5")
+
+	c.Assert(f.MoveSheet("First", 2), IsNil)
+	c.Assert(f.Sheets, DeepEquals, []*Sheet{sheets[2], sheets[1], sheets[0]})
+	c.Assert(*f.DefinedNames[1].LocalSheetID, Equals, 1)
+	c.Assert(*f.DefinedNames[2].LocalSheetID, Equals, 0)
+
+	// Names of a sheet moved to the front stay its own.
+	c.Assert(f.MoveSheet("Data", 0), IsNil)
+	c.Assert(f.DefinedNames[0].LocalSheetID, IsNil)
+	c.Assert(*f.DefinedNames[1].LocalSheetID, Equals, 0)
+	c.Assert(*f.DefinedNames[2].LocalSheetID, Equals, 1)
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+	c.Assert(f.MoveSheet("Data", 2), IsNil)
+	c.Assert(f.DefinedNames[0].LocalSheetID, IsNil)
+	c.Assert(*f.DefinedNames[1].LocalSheetID, Equals, 2)
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheets[0].Cell(0, 0).Value, Equals, "2.5")
+
+	c.Assert(f.MoveSheet("First", 3), ErrorMatches, `xlsx: cannot move sheet 'First' to index 3`)
+	c.Assert(f.MoveSheet("Missing", 0), ErrorMatches, `xlsx: no sheet named 'Missing'`)
+}
+
+func (s *FileSheetsSuite) TestDeleteSheet(c *C) {
+	f, sheets := newTestFile(c, []string{"First", "Data", "Last"},
+		map[string]interface{}{"A1": "=Data!A1*2", "A2": "=SUM(First:Last!B1)", "A3": "=Data!Rate+Rate"},
+		nil,
+		map[string]interface{}{"A1": "=data!A1"})
+	f.DefinedNames = append(f.DefinedNames,
+		&xlsxDefinedName{Name: "Rate", Data: "0.5"},
+		&xlsxDefinedName{Name: "Rate", Data: "Data!$B$1", LocalSheetID: localSheetID(1)},
+		&xlsxDefinedName{Name: "Print_Area", Data: "Last!$A$1:$B$1", LocalSheetID: localSheetID(2)},
+	)
+
+	c.Assert(f.DeleteSheet("Data"), IsNil)
+	c.Assert(f.Sheets, DeepEquals, []*Sheet{sheets[0], sheets[2]})
+	_, ok := f.Sheet["Data"]
+	c.Assert(ok, Equals, false)
+
+	c.Assert(sheets[0].Cell(0, 0).Formula(), Equals, "#REF!*2")
+	c.Assert(sheets[0].Cell(1, 0).Formula(), Equals, "SUM(First:Last!B1)")
+	c.Assert(sheets[0].Cell(2, 0).Formula(), Equals, "#REF!+Rate")
+	c.Assert(sheets[2].Cell(0, 0).Formula(), Equals, "#REF!")
+	c.Assert(f.DefinedNames, HasLen, 2)
+	c.Assert(f.DefinedNames[1].Name, Equals, "Print_Area")
+	c.Assert(*f.DefinedNames[1].LocalSheetID, Equals, 1)
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+
+	c.Assert(f.DeleteSheet("first"), IsNil)
+	c.Assert(f.Sheets, DeepEquals, []*Sheet{sheets[2]})
+	c.Assert(sheets[2].Selected, Equals, true)
+	c.Assert(*f.DefinedNames[1].LocalSheetID, Equals, 0)
+	c.Assert(f.DeleteSheet("First"), ErrorMatches, `xlsx: no sheet named 'First'`)
+}
+
+func (s *FileSheetsSuite) TestDeleteFirstSheet(c *C) {
+	f, sheets := newTestFile(c, []string{"First", "Data", "Last"})
+	f.DefinedNames = append(f.DefinedNames,
+		&xlsxDefinedName{Name: "Rate", Data: "0.5"},
+		&xlsxDefinedName{Name: "Rate", Data: "First!$B$1", LocalSheetID: localSheetID(0)},
+		&xlsxDefinedName{Name: "Rate", Data: "Data!$B$1", LocalSheetID: localSheetID(1)},
+		&xlsxDefinedName{Name: "Print_Area", Data: "Last!$A$1:$B$1", LocalSheetID: localSheetID(2)},
+	)
+	c.Assert(f.DeleteSheet("First"), IsNil)
+	c.Assert(f.Sheets, DeepEquals, []*Sheet{sheets[1], sheets[2]})
+	c.Assert(f.DefinedNames, HasLen, 3)
+	c.Assert(f.DefinedNames[0].Data, Equals, "0.5")
+	c.Assert(f.DefinedNames[0].LocalSheetID, IsNil)
+	c.Assert(*f.DefinedNames[1].LocalSheetID, Equals, 0)
+	c.Assert(*f.DefinedNames[2].LocalSheetID, Equals, 1)
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+}
+
+func (s *FileSheetsSuite) TestDeleteSheetOfRange(c *C) {
+	f, sheets := newTestFile(c, []string{"First", "Data", "Last"},
+		map[string]interface{}{"A2": "=SUM(First:Last!B1)"},
+		map[string]interface{}{"A1": 5})
+	f.DefinedNames = append(f.DefinedNames,
+		&xlsxDefinedName{Name: "Rate", Data: "0.5"},
+		&xlsxDefinedName{Name: "Rate", Data: "Data!$B$1", LocalSheetID: localSheetID(1)},
+		&xlsxDefinedName{Name: "Print_Area", Data: "Last!$A$1:$B$1", LocalSheetID: localSheetID(2)},
+	)
+	c.Assert(f.DeleteSheet("Last"), IsNil)
+	c.Assert(sheets[0].Cell(1, 0).Formula(), Equals, "SUM(First:Data!B1)")
+	c.Assert(f.DeleteSheet("First"), IsNil)
+	c.Assert(sheets[1].Cell(0, 0).Value, Equals, "5")
+	c.Assert(f.DefinedNames, HasLen, 2)
+	c.Assert(*f.DefinedNames[1].LocalSheetID, Equals, 0)
+
+	f, sheets = newTestFile(c, []string{"First", "Data", "Last"},
+		map[string]interface{}{"A2": "=SUM(First:Last!B1)"})
+	c.Assert(f.MoveSheet("Last", 1), IsNil)
+	c.Assert(f.DeleteSheet("First"), IsNil)
+	c.Assert(sheets[0].Cell(1, 0).Formula(), Equals, "SUM(Last!B1)")
+}
@@ -1,8 +1,13 @@
 package xlsx
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/xml"
+	"io/ioutil"
 	"path/filepath"
+	"strings"
+	"time"
 
 	. "gopkg.in/check.v1"
 )
@@ -730,6 +735,110 @@ func (l *FileSuite) TestSaveFile(c *C) {
 	c.Assert(cell1.Value, Equals, "A cell!")
 }
 
+// Rich text cells survive a save and load cycle.
+func (l *FileSuite) TestSaveFileWithRichText(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	runs := []RichTextRun{
+		{Text: "Plain "},
+		{Font: &Font{Size: 12, Name: "Arial", Color: "FFFF0000", Bold: true}, Text: "red"},
+	}
+	sheet.AddRow().AddCell().SetRichText(runs)
+	sheet.AddRow().AddCell().SetString("Plain red")
+	xlsxPath := filepath.Join(c.MkDir(), "TestSaveFileWithRichText.xlsx")
+	c.Assert(f.Save(xlsxPath), IsNil)
+
+	xlsxFile, err := OpenFile(xlsxPath)
+	c.Assert(err, IsNil)
+	sheet = xlsxFile.Sheet["Sheet1"]
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "Plain red")
+	c.Assert(sheet.Cell(0, 0).RichText(), DeepEquals, runs)
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "Plain red")
+	c.Assert(sheet.Cell(1, 0).RichText(), IsNil)
+}
+
+// String values keep their whitespace through a save and load cycle,
+// unless trimming is asked for.
+func (l *FileSuite) TestSaveFilePreservesWhitespace(c *C) {
+	values := []string{"  leading", "trailing\n", "\tindented\nlines", "   "}
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	for _, value := range values {
+		sheet.AddRow().AddCell().SetString(value)
+	}
+	xlsxPath := filepath.Join(c.MkDir(), "TestSaveFilePreservesWhitespace.xlsx")
+	c.Assert(f.Save(xlsxPath), IsNil)
+
+	xlsxFile, err := OpenFile(xlsxPath)
+	c.Assert(err, IsNil)
+	for i, value := range values {
+		c.Assert(xlsxFile.Sheet["Sheet1"].Cell(i, 0).Value, Equals, value)
+	}
+
+	xlsxFile, err = OpenFileWithOptions(xlsxPath, ReadOptions{TrimSpace: true})
+	c.Assert(err, IsNil)
+	c.Assert(xlsxFile.Sheet["Sheet1"].Cell(0, 0).Value, Equals, "leading")
+	c.Assert(xlsxFile.Sheet["Sheet1"].Cell(2, 0).Value, Equals, "indented\nlines")
+	c.Assert(xlsxFile.Sheet["Sheet1"].Cell(3, 0).Value, Equals, "")
+}
+
+// A file in the 1904 date system is saved as one, with its dates
+// counted from 1904.
+func (l *FileSuite) TestSaveFileWithDate1904(c *C) {
+	f := NewFile()
+	f.Date1904 = true
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	when := time.Date(2013, 1, 1, 6, 0, 0, 0, time.UTC)
+	sheet.AddRow().AddCell().SetDateTime(when)
+	sheet.AddRow().AddCell().SetDate(when)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "39813.25")
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "39813")
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*<workbookPr showObjects="all" date1904="true">.*`)
+
+	xlsxPath := filepath.Join(c.MkDir(), "TestSaveFileWithDate1904.xlsx")
+	c.Assert(f.Save(xlsxPath), IsNil)
+	xlsxFile, err := OpenFile(xlsxPath)
+	c.Assert(err, IsNil)
+	c.Assert(xlsxFile.Date1904, Equals, true)
+	cell := xlsxFile.Sheet["Sheet1"].Cell(0, 0)
+	value, err := cell.Float()
+	c.Assert(err, IsNil)
+	c.Assert(TimeFromExcelTime(value, true), Equals, when)
+	formatted, err := cell.FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(formatted, Equals, "1/1/13 6:00")
+}
+
+// The defined names of a file, both of the workbook and of its sheets,
+// are saved with it.
+func (l *FileSuite) TestSaveFileWithDefinedNames(c *C) {
+	f, err := OpenFile("./testdocs/definedNames.xlsx")
+	c.Assert(err, IsNil)
+	c.Assert(f.DefinedNames, HasLen, 5)
+	f.Sheets[0].Cell(2, 0).SetFormula("Rate*2")
+	f.Sheets[1].Cell(0, 0).SetFormula("Rate*2")
+
+	xlsxPath := filepath.Join(c.MkDir(), "TestSaveFileWithDefinedNames.xlsx")
+	c.Assert(f.Save(xlsxPath), IsNil)
+	xlsxFile, err := OpenFile(xlsxPath)
+	c.Assert(err, IsNil)
+	c.Assert(xlsxFile.DefinedNames, DeepEquals, f.DefinedNames)
+	c.Assert(xlsxFile.DefinedNames[0].Name, Equals, "_xlnm.Print_Area")
+	c.Assert(*xlsxFile.DefinedNames[0].LocalSheetID, Equals, 0)
+	c.Assert(xlsxFile.DefinedNames[2].LocalSheetID, IsNil)
+	c.Assert(xlsxFile.DefinedNames[4].Comment, Equals, "The first heading")
+
+	c.Assert(xlsxFile.Recalculate(), IsNil)
+	c.Assert(xlsxFile.Sheets[0].Cell(2, 0).Value, Equals, "1")
+	c.Assert(xlsxFile.Sheets[1].Cell(0, 0).Value, Equals, "4")
+}
+
 type SliceReaderSuite struct{}
 
 var _ = Suite(&SliceReaderSuite{})
@@ -852,3 +961,98 @@ func (s *SliceReaderSuite) TestFileWithEmptyCols(c *C) {
 		c.Assert(val, Equals, "C1")
 	}
 }
+
+// Only the sheets listed in the ReadOptions are read.
+func (l *FileSuite) TestOpenFileWithOptionsSelectsSheets(c *C) {
+	f, err := OpenFileWithOptions("./testdocs/testfile.xlsx", ReadOptions{Sheets: []string{"Tabelle1"}})
+	c.Assert(err, IsNil)
+	c.Assert(len(f.Sheets), Equals, 1)
+	c.Assert(len(f.Sheet), Equals, 1)
+	sheet, ok := f.Sheet["Tabelle1"]
+	c.Assert(ok, Equals, true)
+	c.Assert(len(sheet.Rows), Equals, 2)
+
+	// Saving it would lose the other sheets.
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), ErrorMatches, `xlsx: cannot save a file read with only some of its sheets`)
+
+	f, err = OpenFileWithOptions("./testdocs/testfile.xlsx", ReadOptions{Sheets: []string{"Tabelle3", "Tabelle1", "Tabelle2"}})
+	c.Assert(err, IsNil)
+	c.Assert(f.Write(&buf), IsNil)
+
+	_, err = OpenFileWithOptions("./testdocs/testfile.xlsx", ReadOptions{Sheets: []string{"NoSuchSheet"}})
+	c.Assert(err, NotNil)
+}
+
+// A lazily read sheet is only decoded when its contents are needed.
+func (l *FileSuite) TestOpenFileWithOptionsLazy(c *C) {
+	f, err := OpenFileWithOptions("./testdocs/testfile.xlsx", ReadOptions{Lazy: true})
+	c.Assert(err, IsNil)
+	defer f.Close()
+	c.Assert(len(f.Sheets), Equals, 3)
+	sheet := f.Sheet["Tabelle1"]
+	c.Assert(sheet.Rows, IsNil)
+	c.Assert(sheet.Cell(1, 1).Value, Equals, "Quuk")
+	c.Assert(len(sheet.Rows), Equals, 2)
+	c.Assert(sheet.Rows[0].Sheet, Equals, sheet)
+
+	other := f.Sheets[1]
+	c.Assert(other.Rows, IsNil)
+	c.Assert(other.Load(), IsNil)
+	c.Assert(other.Load(), IsNil)
+
+	output, err := f.ToSlice()
+	c.Assert(err, IsNil)
+	fileToSliceCheckOutput(c, output)
+}
+
+// rewriteTestFile returns the XLSX file at path with old replaced by
+// new in the part called name.
+func rewriteTestFile(c *C, path, name, old, new string) []byte {
+	r, err := zip.OpenReader(path)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		c.Assert(err, IsNil)
+		if f.Name == name {
+			c.Assert(strings.Contains(string(data), old), Equals, true)
+			data = []byte(strings.Replace(string(data), old, new, 1))
+		}
+		part, err := w.Create(f.Name)
+		c.Assert(err, IsNil)
+		_, err = part.Write(data)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	return buf.Bytes()
+}
+
+// A lazily read sheet that can't be decoded keeps its error, for Load,
+// Write and the sheet methods that return an error.
+func (l *FileSuite) TestOpenFileWithOptionsLazyLoadError(c *C) {
+	data := rewriteTestFile(c, "./testdocs/testfile.xlsx", "xl/worksheets/sheet1.xml", "<v>0</v>", "<v>zz</v>")
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	f, err := ReadZipReaderWithOptions(r, ReadOptions{Lazy: true})
+	c.Assert(err, IsNil)
+	sheet := f.Sheet["Tabelle1"]
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "")
+
+	err = sheet.Load()
+	c.Assert(err, NotNil)
+	cellErr, ok := err.(*CellError)
+	c.Assert(ok, Equals, true)
+	c.Assert(cellErr.Cell, Equals, "A1")
+	c.Assert(sheet.Load(), Equals, err)
+	c.Assert(sheet.SetColWidth(0, 1, 20), Equals, err)
+	c.Assert(sheet.AddConditionalFormat("A1", NewExpressionRule("TRUE", nil)), Equals, err)
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), Equals, err)
+	c.Assert(f.Sheets[1].Load(), IsNil)
+}
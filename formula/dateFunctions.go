@@ -0,0 +1,289 @@
+package formula
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+func init() {
+	register(map[string]function{
+		"DATE":      {date, 3, 3},
+		"DATEDIF":   {dateDif, 3, 3},
+		"DATEVALUE": {dateValue, 1, 1},
+		"DAY":       {datePart(func(y, m, d int) int { return d }), 1, 1},
+		"DAYS":      {days, 2, 2},
+		"EDATE":     {edate(false), 2, 2},
+		"EOMONTH":   {edate(true), 2, 2},
+		"HOUR":      {timePart(func(s int) int { return s / 3600 }), 1, 1},
+		"MINUTE":    {timePart(func(s int) int { return s / 60 % 60 }), 1, 1},
+		"MONTH":     {datePart(func(y, m, d int) int { return m }), 1, 1},
+		"NOW":       {now, 0, 0},
+		"SECOND":    {timePart(func(s int) int { return s % 60 }), 1, 1},
+		"TIME":      {timeFunc, 3, 3},
+		"TIMEVALUE": {timeValue, 1, 1},
+		"TODAY":     {today, 0, 0},
+		"WEEKDAY":   {weekday, 1, 2},
+		"YEAR":      {datePart(func(y, m, d int) int { return y }), 1, 1},
+	})
+}
+
+var (
+	epoch1900 = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+	epoch1904 = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// serialFromTime returns the date serial number of the wall clock
+// time of t.  In the 1900 date system the days before 1 March 1900 are
+// a day less, for the 29 February 1900 that Excel counts.
+func serialFromTime(t time.Time, date1904 bool) float64 {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	if date1904 {
+		return t.Sub(epoch1904).Hours() / 24
+	}
+	serial := t.Sub(epoch1900).Hours() / 24
+	if serial < 61 {
+		serial--
+	}
+	return serial
+}
+
+// dateFromSerial returns the year, month and day of a date serial
+// number, which may be Excel's 29 February or 0 January 1900.
+func dateFromSerial(serial float64, date1904 bool) (year, month, day int) {
+	days := math.Floor(serial)
+	if !date1904 {
+		switch {
+		case days == 0:
+			return 1900, 1, 0
+		case days == 60:
+			return 1900, 2, 29
+		case days < 60:
+			days++
+		}
+	}
+	epoch := epoch1900
+	if date1904 {
+		epoch = epoch1904
+	}
+	t := epoch.AddDate(0, 0, int(days))
+	return t.Year(), int(t.Month()), t.Day()
+}
+
+// serial returns argument i as a date serial number, which must not be
+// negative.
+func (c *Call) serial(i int) float64 {
+	v := c.scalar(i)
+	if v.Type == ValueString {
+		if f, ok := parseDate(v.Text, c.Date1904); ok {
+			return f
+		}
+	}
+	f := c.number(i)
+	if f < 0 || f >= 2958466 {
+		fail(ErrNum)
+	}
+	return f
+}
+
+// secondsOf returns the time of day of a date serial number, in whole
+// seconds.
+func secondsOf(serial float64) int {
+	return int(math.Round((serial-math.Floor(serial))*86400)) % 86400
+}
+
+// dateSerial returns the serial number of a date, where the month and
+// day may run over into the next year or month.
+func dateSerial(year, month, day int, date1904 bool) Value {
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	serial := serialFromTime(t, date1904)
+	if serial < 0 || t.Year() > 9999 {
+		return ErrorValue(ErrNum)
+	}
+	return NumberValue(serial)
+}
+
+// date returns the serial number of a date.  Years from 0 to 1899 are
+// counted from 1900.
+func date(c *Call) Value {
+	year, month, day := c.int(0), c.int(1), c.int(2)
+	if year < 0 || year > 9999 {
+		return ErrorValue(ErrNum)
+	}
+	if year < 1900 {
+		year += 1900
+	}
+	return dateSerial(year, month, day, c.Date1904)
+}
+
+// timeFunc returns the fraction of a day of a time.
+func timeFunc(c *Call) Value {
+	seconds := c.int(0)*3600 + c.int(1)*60 + c.int(2)
+	if seconds < 0 {
+		return ErrorValue(ErrNum)
+	}
+	return NumberValue(float64(seconds%86400) / 86400)
+}
+
+func datePart(part func(year, month, day int) int) Func {
+	return func(c *Call) Value {
+		return NumberValue(float64(part(dateFromSerial(c.serial(0), c.Date1904))))
+	}
+}
+
+func timePart(part func(seconds int) int) Func {
+	return func(c *Call) Value {
+		return NumberValue(float64(part(secondsOf(c.serial(0)))))
+	}
+}
+
+// weekday returns the day of the week of a date, numbered as the type
+// says: from 1 for Sunday by default, from 1 or 0 for Monday for types
+// 2 and 3, and from 1 for Monday to Sunday for types 11 to 17.
+func weekday(c *Call) Value {
+	days := int(math.Floor(c.serial(0)))
+	// In the 1900 date system day 1 is taken to be a Sunday, as it
+	// is by Excel, and in the 1904 system day 0 is a Friday.
+	wd := (days + 6) % 7
+	if c.Date1904 {
+		wd = (days + 5) % 7
+	}
+	switch typ := c.intOr(1, 1); {
+	case typ == 1:
+		return NumberValue(float64(wd + 1))
+	case typ == 2:
+		return NumberValue(float64((wd+6)%7 + 1))
+	case typ == 3:
+		return NumberValue(float64((wd + 6) % 7))
+	case typ >= 11 && typ <= 17:
+		first := (typ - 10) % 7
+		return NumberValue(float64((wd-first+7)%7 + 1))
+	}
+	return ErrorValue(ErrNum)
+}
+
+// edate returns the date some months after a date, or the last day of
+// that month for EOMONTH.  A day past the end of the month is moved to
+// its last day.
+func edate(endOfMonth bool) Func {
+	return func(c *Call) Value {
+		year, month, day := dateFromSerial(c.serial(0), c.Date1904)
+		month += c.int(1)
+		last := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		if endOfMonth || day > last {
+			day = last
+		}
+		return dateSerial(year, month, day, c.Date1904)
+	}
+}
+
+func days(c *Call) Value {
+	return NumberValue(math.Floor(c.serial(0)) - math.Floor(c.serial(1)))
+}
+
+// dateDif returns the whole years, months or days between two dates,
+// as the unit says: Y, M or D, or MD, YM or YD for the days or months
+// left over after whole months or years.
+func dateDif(c *Call) Value {
+	start, end := c.serial(0), c.serial(1)
+	if start > end {
+		return ErrorValue(ErrNum)
+	}
+	y1, m1, d1 := dateFromSerial(start, c.Date1904)
+	y2, m2, d2 := dateFromSerial(end, c.Date1904)
+	months := (y2-y1)*12 + m2 - m1
+	if d2 < d1 {
+		months--
+	}
+	switch strings.ToUpper(c.text(2)) {
+	case "Y":
+		return NumberValue(float64(months / 12))
+	case "M":
+		return NumberValue(float64(months))
+	case "D":
+		return NumberValue(math.Floor(end) - math.Floor(start))
+	case "YM":
+		return NumberValue(float64(months % 12))
+	case "MD":
+		if d2 >= d1 {
+			return NumberValue(float64(d2 - d1))
+		}
+		// The days from the same day of the month before.
+		before := dateSerial(y2, m2-1, d1, c.Date1904)
+		return NumberValue(math.Floor(end) - before.Number)
+	case "YD":
+		year := y2
+		if m2 < m1 || m2 == m1 && d2 < d1 {
+			year--
+		}
+		anniversary := dateSerial(year, m1, d1, c.Date1904)
+		return NumberValue(math.Floor(end) - anniversary.Number)
+	}
+	return ErrorValue(ErrNum)
+}
+
+// dateLayouts are the layouts of the dates that DATEVALUE and VALUE
+// read, as Excel in the United States does.
+var dateLayouts = []string{
+	"2006-01-02", "2006/01/02", "1/2/2006", "1/2/06", "1-2-2006",
+	"2 Jan 2006", "2-Jan-2006", "2-Jan-06", "Jan 2, 2006", "January 2, 2006", "2 January 2006",
+}
+
+// timeLayouts are the layouts of the times that may follow a date, or
+// stand on their own.
+var timeLayouts = []string{"15:04", "15:04:05", "15:04:05.999999999", "3:04 PM", "3:04:05 PM", "3:04PM"}
+
+// parseDate reads the text of a date, a time or both as a date serial
+// number.
+func parseDate(s string, date1904 bool) (float64, bool) {
+	s = strings.TrimSpace(s)
+	for _, d := range append([]string{""}, dateLayouts...) {
+		for _, t := range append([]string{""}, timeLayouts...) {
+			layout := strings.TrimSpace(d + " " + t)
+			if layout == "" {
+				continue
+			}
+			parsed, err := time.Parse(layout, s)
+			if err != nil {
+				continue
+			}
+			if d == "" {
+				return float64(parsed.Hour()*3600+parsed.Minute()*60+parsed.Second()) / 86400, true
+			}
+			return serialFromTime(parsed, date1904), true
+		}
+	}
+	return 0, false
+}
+
+func dateValue(c *Call) Value {
+	f, ok := parseDate(c.text(0), c.Date1904)
+	if !ok {
+		return ErrorValue(ErrValue)
+	}
+	return NumberValue(math.Floor(f))
+}
+
+func timeValue(c *Call) Value {
+	f, ok := parseDate(c.text(0), c.Date1904)
+	if !ok {
+		return ErrorValue(ErrValue)
+	}
+	return NumberValue(f - math.Floor(f))
+}
+
+// now returns the time of the evaluator's clock.
+func (e *Evaluator) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+func now(c *Call) Value {
+	return NumberValue(serialFromTime(c.now(), c.Date1904))
+}
+
+func today(c *Call) Value {
+	return NumberValue(math.Floor(serialFromTime(c.now(), c.Date1904)))
+}
@@ -0,0 +1,772 @@
+package formula
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The error values of formulas.
+const (
+	ErrNull  = "#NULL!"
+	ErrDiv0  = "#DIV/0!"
+	ErrValue = "#VALUE!"
+	ErrRef   = "#REF!"
+	ErrName  = "#NAME?"
+	ErrNum   = "#NUM!"
+	ErrNA    = "#N/A"
+)
+
+// ValueType is the type of a Value.
+type ValueType int
+
+const (
+	ValueBlank  ValueType = iota // an empty cell or an argument left out
+	ValueNumber                  // a number, which may be a date
+	ValueString                  // text
+	ValueBool                    // TRUE or FALSE
+	ValueError                   // an error value, such as #DIV/0!
+	ValueArray                   // an array, or the cells of a range
+)
+
+// Value is the value of a formula, or of a part of one.
+type Value struct {
+	Type   ValueType
+	Number float64
+	Text   string // the text, or the error value
+	Bool   bool
+	Rows   [][]Value // the rows of an array
+	// Ref is set for the value of a reference: it is the reference,
+	// with its Sheet filled in.
+	Ref *Ref
+}
+
+// NumberValue returns a number, or #NUM! if n is not finite.
+func NumberValue(n float64) Value {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return ErrorValue(ErrNum)
+	}
+	return Value{Type: ValueNumber, Number: n}
+}
+
+// StringValue returns text.
+func StringValue(s string) Value {
+	return Value{Type: ValueString, Text: s}
+}
+
+// BoolValue returns TRUE or FALSE.
+func BoolValue(b bool) Value {
+	return Value{Type: ValueBool, Bool: b}
+}
+
+// ErrorValue returns an error value, such as ErrDiv0.
+func ErrorValue(err string) Value {
+	return Value{Type: ValueError, Text: err}
+}
+
+// IsError reports whether v is an error value.
+func (v Value) IsError() bool {
+	return v.Type == ValueError
+}
+
+// String returns the value as a cell shows it without a number
+// format.
+func (v Value) String() string {
+	switch v.Type {
+	case ValueNumber:
+		return formatNumber(v.Number)
+	case ValueString, ValueError:
+		return v.Text
+	case ValueBool:
+		if v.Bool {
+			return "TRUE"
+		}
+		return "FALSE"
+	case ValueArray:
+		var b strings.Builder
+		b.WriteByte('{')
+		for i, row := range v.Rows {
+			if i > 0 {
+				b.WriteByte(';')
+			}
+			for j, x := range row {
+				if j > 0 {
+					b.WriteByte(',')
+				}
+				if x.Type == ValueString {
+					b.WriteString(strconv.Quote(x.Text))
+				} else {
+					b.WriteString(x.String())
+				}
+			}
+		}
+		b.WriteByte('}')
+		return b.String()
+	}
+	return ""
+}
+
+// formatNumber returns n with up to 15 significant digits, as the
+// General number format shows it.
+func formatNumber(n float64) string {
+	if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	s := strconv.FormatFloat(n, 'G', 15, 64)
+	if mantissa, exp, ok := cut(s, "E"); ok {
+		if strings.Contains(mantissa, ".") {
+			mantissa = strings.TrimRight(strings.TrimRight(mantissa, "0"), ".")
+		}
+		return mantissa + "E" + exp
+	}
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// Context gives an Evaluator the cells and names of a workbook.
+type Context interface {
+	// Cell returns the value of the cell at row and col of sheet,
+	// counting from 1.  A cell holding a formula has the value of
+	// the formula.
+	Cell(sheet string, row, col int) Value
+	// Size returns the number of rows and columns in use on sheet,
+	// which bounds references to whole rows and columns, and
+	// whether there is such a sheet.
+	Size(sheet string) (rows, cols int, ok bool)
+	// Name returns the formula of a defined name as it is seen from
+	// sheet, or nil if there's no such name.
+	Name(sheet, name string) Node
+}
+
+// Evaluator computes the values of formulas.
+type Evaluator struct {
+	Context Context
+	// Date1904 is set for workbooks that count dates from 1904.
+	Date1904 bool
+	// Now returns the time for NOW and TODAY.  If it is nil,
+	// time.Now is used.
+	Now func() time.Time
+	// Format formats a number for TEXT.  If it is nil, TEXT only
+	// knows the General format.
+	Format func(value float64, format string) string
+}
+
+// maxNameDepth bounds the nesting of defined names, so that names
+// defined in terms of themselves come to an end.
+const maxNameDepth = 32
+
+// evaluation is the evaluation of a formula in a cell.
+type evaluation struct {
+	*Evaluator
+	sheet    string
+	row, col int
+	depth    int
+	// calls is the number of function calls that the part of the
+	// formula being evaluated is an argument of.
+	calls int
+}
+
+// Eval returns the value of a formula in the cell at row and col of
+// sheet, counting from 1, as the cell shows it: a reference to a range
+// gives the cell of the range in the formula's row or column, an array
+// its first item, and an empty cell 0.
+func (e *Evaluator) Eval(n Node, sheet string, row, col int) Value {
+	ev := &evaluation{Evaluator: e, sheet: sheet, row: row, col: col}
+	v := ev.intersect(ev.eval(n))
+	if v.Type == ValueBlank {
+		return NumberValue(0)
+	}
+	v.Ref = nil
+	return v
+}
+
+// EvalArray returns the value of a formula in the cell at row and col
+// of sheet, leaving arrays and the values of ranges whole.
+func (e *Evaluator) EvalArray(n Node, sheet string, row, col int) Value {
+	ev := &evaluation{Evaluator: e, sheet: sheet, row: row, col: col}
+	return ev.eval(n)
+}
+
+func (e *evaluation) eval(n Node) Value {
+	switch n := n.(type) {
+	case *Number:
+		return NumberValue(n.Value)
+	case *String:
+		return StringValue(n.Value)
+	case *Bool:
+		return BoolValue(n.Value)
+	case *Error:
+		return ErrorValue(n.Value)
+	case *Array:
+		a := Value{Type: ValueArray, Rows: make([][]Value, len(n.Rows))}
+		for i, row := range n.Rows {
+			a.Rows[i] = make([]Value, len(row))
+			for j, x := range row {
+				a.Rows[i][j] = e.eval(x)
+			}
+		}
+		return a
+	case *Ref:
+		return e.ref(n)
+	case *Name:
+		if n.Book != "" {
+			return ErrorValue(ErrRef)
+		}
+		sheet := n.Sheet
+		if sheet == "" {
+			sheet = e.sheet
+		}
+		def := e.Context.Name(sheet, n.Name)
+		if def == nil || e.depth >= maxNameDepth {
+			return ErrorValue(ErrName)
+		}
+		e.depth++
+		defer func() { e.depth-- }()
+		return e.eval(def)
+	case *TableRef:
+		// Tables aren't known to the evaluator.
+		return ErrorValue(ErrRef)
+	case *Function:
+		return e.call(n)
+	case *Missing:
+		return Value{}
+	case *Unary:
+		x := e.operand(e.eval(n.X))
+		switch n.Op {
+		case "-":
+			return lift1(x, func(v Value) Value {
+				f, err := toNumber(v)
+				if err != nil {
+					return *err
+				}
+				return NumberValue(-f)
+			})
+		case "@":
+			return e.intersect(x)
+		}
+		return x
+	case *Postfix:
+		x := e.operand(e.eval(n.X))
+		if n.Op == "%" {
+			return lift1(x, func(v Value) Value {
+				f, err := toNumber(v)
+				if err != nil {
+					return *err
+				}
+				return NumberValue(f / 100)
+			})
+		}
+		return x
+	case *Binary:
+		return e.binary(n)
+	case *Paren:
+		return e.eval(n.X)
+	}
+	return ErrorValue(ErrValue)
+}
+
+// area returns a reference to the cells from top to bottom and left to
+// right of sheet.
+func area(sheet string, top, left, bottom, right int) *Ref {
+	r := &Ref{Sheet: sheet, From: Cell{Row: top, Col: left}}
+	if top != bottom || left != right {
+		r.To = &Cell{Row: bottom, Col: right}
+	}
+	return r
+}
+
+// ref returns the value of a reference.
+func (e *evaluation) ref(r *Ref) Value {
+	if r.Invalid || r.Book != "" || r.LastSheet != "" {
+		return ErrorValue(ErrRef)
+	}
+	ref := *r
+	if ref.Sheet == "" {
+		ref.Sheet = e.sheet
+	}
+	return e.area(&ref)
+}
+
+// area returns the values of the cells of ref, whose Sheet is set.
+// Only the rows and columns in use are read of whole rows and columns.
+func (e *evaluation) area(ref *Ref) Value {
+	rows, cols, ok := e.Context.Size(ref.Sheet)
+	if !ok {
+		return ErrorValue(ErrRef)
+	}
+	top, left, bottom, right := ref.Bounds()
+	if ref.To == nil {
+		v := e.Context.Cell(ref.Sheet, top, left)
+		v.Ref = ref
+		return v
+	}
+	if ref.From.Row == 0 && bottom > rows {
+		bottom = rows
+	}
+	if ref.From.Col == 0 && right > cols {
+		right = cols
+	}
+	v := Value{Type: ValueArray, Ref: ref}
+	for row := top; row <= bottom; row++ {
+		values := make([]Value, 0, right-left+1)
+		for col := left; col <= right; col++ {
+			values = append(values, e.Context.Cell(ref.Sheet, row, col))
+		}
+		v.Rows = append(v.Rows, values)
+	}
+	return v
+}
+
+// intersect returns the one value of an array or a range that a cell
+// shows: the cell of a range in the formula's row or column, or the
+// first item of an array.
+func (e *evaluation) intersect(v Value) Value {
+	if v.Type != ValueArray {
+		return v
+	}
+	if v.Ref != nil && v.Ref.Sheet != "" {
+		top, left, bottom, right := v.Ref.Bounds()
+		row, col := top, left
+		switch {
+		case top == bottom:
+		case left == right:
+		default:
+			return ErrorValue(ErrValue)
+		}
+		if top != bottom {
+			row = e.row
+			if row < top || row > bottom {
+				return ErrorValue(ErrValue)
+			}
+		}
+		if left != right {
+			col = e.col
+			if col < left || col > right {
+				return ErrorValue(ErrValue)
+			}
+		}
+		c := e.Context.Cell(v.Ref.Sheet, row, col)
+		c.Ref = area(v.Ref.Sheet, row, col, row, col)
+		return c
+	}
+	if len(v.Rows) == 0 || len(v.Rows[0]) == 0 {
+		return ErrorValue(ErrValue)
+	}
+	return v.Rows[0][0]
+}
+
+// operand returns the value of an operand of an operator.  Outside
+// the arguments of functions, a range gives the one cell of it that
+// the formula's cell meets, as it does in formulas that aren't array
+// formulas.
+func (e *evaluation) operand(v Value) Value {
+	if e.calls == 0 && v.Type == ValueArray && v.Ref != nil {
+		return e.intersect(v)
+	}
+	return v
+}
+
+func (e *evaluation) binary(n *Binary) Value {
+	x, y := e.eval(n.X), e.eval(n.Y)
+	switch n.Op {
+	case ":", " ":
+		if x.IsError() {
+			return x
+		}
+		if y.IsError() {
+			return y
+		}
+		if x.Ref == nil || y.Ref == nil || !strings.EqualFold(x.Ref.Sheet, y.Ref.Sheet) {
+			return ErrorValue(ErrValue)
+		}
+		t1, l1, b1, r1 := x.Ref.Bounds()
+		t2, l2, b2, r2 := y.Ref.Bounds()
+		if n.Op == ":" {
+			return e.area(area(x.Ref.Sheet, min(t1, t2), min(l1, l2), max(b1, b2), max(r1, r2)))
+		}
+		top, left, bottom, right := max(t1, t2), max(l1, l2), min(b1, b2), min(r1, r2)
+		if top > bottom || left > right {
+			return ErrorValue(ErrNull)
+		}
+		return e.area(area(x.Ref.Sheet, top, left, bottom, right))
+	case ",":
+		// A union is only of use to the functions that take lists
+		// of values, so it is made into one row of them.
+		var values []Value
+		for _, v := range []Value{x, y} {
+			if v.IsError() {
+				return v
+			}
+			if v.Type != ValueArray {
+				values = append(values, v)
+				continue
+			}
+			for _, row := range v.Rows {
+				values = append(values, row...)
+			}
+		}
+		return Value{Type: ValueArray, Rows: [][]Value{values}}
+	}
+	x, y = e.operand(x), e.operand(y)
+	switch n.Op {
+	case "&":
+		return lift2(x, y, func(a, b Value) Value {
+			if a.IsError() {
+				return a
+			}
+			if b.IsError() {
+				return b
+			}
+			return StringValue(toText(a) + toText(b))
+		})
+	case "=", "<>", "<", ">", "<=", ">=":
+		return lift2(x, y, func(a, b Value) Value {
+			if a.IsError() {
+				return a
+			}
+			if b.IsError() {
+				return b
+			}
+			c := compare(a, b)
+			switch n.Op {
+			case "=":
+				return BoolValue(c == 0)
+			case "<>":
+				return BoolValue(c != 0)
+			case "<":
+				return BoolValue(c < 0)
+			case ">":
+				return BoolValue(c > 0)
+			case "<=":
+				return BoolValue(c <= 0)
+			}
+			return BoolValue(c >= 0)
+		})
+	}
+	return lift2(x, y, func(a, b Value) Value {
+		f, err := toNumber(a)
+		if err != nil {
+			return *err
+		}
+		g, err := toNumber(b)
+		if err != nil {
+			return *err
+		}
+		switch n.Op {
+		case "+":
+			return NumberValue(f + g)
+		case "-":
+			return NumberValue(f - g)
+		case "*":
+			return NumberValue(f * g)
+		case "/":
+			if g == 0 {
+				return ErrorValue(ErrDiv0)
+			}
+			return NumberValue(f / g)
+		case "^":
+			if f == 0 && g <= 0 {
+				if g == 0 {
+					return ErrorValue(ErrNum)
+				}
+				return ErrorValue(ErrDiv0)
+			}
+			return NumberValue(math.Pow(f, g))
+		}
+		return ErrorValue(ErrValue)
+	})
+}
+
+// lift1 applies f to v, or to each item of v if it is an array.
+func lift1(v Value, f func(Value) Value) Value {
+	if v.Type != ValueArray {
+		return f(v)
+	}
+	a := Value{Type: ValueArray, Rows: make([][]Value, len(v.Rows))}
+	for i, row := range v.Rows {
+		a.Rows[i] = make([]Value, len(row))
+		for j, x := range row {
+			a.Rows[i][j] = f(x)
+		}
+	}
+	return a
+}
+
+// lift2 applies f to x and y or, if either is an array, to their
+// items pair by pair.  An array of one row or column is repeated to
+// match the other, and the items that one of them lacks are #N/A.
+func lift2(x, y Value, f func(a, b Value) Value) Value {
+	if x.Type != ValueArray && y.Type != ValueArray {
+		return f(x, y)
+	}
+	xr, xc := dims(x)
+	yr, yc := dims(y)
+	rows, cols := max(xr, yr), max(xc, yc)
+	a := Value{Type: ValueArray, Rows: make([][]Value, rows)}
+	for i := 0; i < rows; i++ {
+		a.Rows[i] = make([]Value, cols)
+		for j := 0; j < cols; j++ {
+			p, okp := item(x, i, j)
+			q, okq := item(y, i, j)
+			if !okp || !okq {
+				a.Rows[i][j] = ErrorValue(ErrNA)
+				continue
+			}
+			a.Rows[i][j] = f(p, q)
+		}
+	}
+	return a
+}
+
+// dims returns the numbers of rows and columns of v, which are 1 for
+// a value that isn't an array.
+func dims(v Value) (rows, cols int) {
+	if v.Type != ValueArray {
+		return 1, 1
+	}
+	if len(v.Rows) == 0 {
+		return 0, 0
+	}
+	return len(v.Rows), len(v.Rows[0])
+}
+
+// item returns the item of v at row i and column j, repeating a single
+// row or column, and whether there is one.
+func item(v Value, i, j int) (Value, bool) {
+	if v.Type != ValueArray {
+		return v, true
+	}
+	rows, cols := dims(v)
+	if rows == 1 {
+		i = 0
+	}
+	if cols == 1 {
+		j = 0
+	}
+	if i >= rows || j >= cols {
+		return Value{}, false
+	}
+	return v.Rows[i][j], true
+}
+
+// toNumber returns v as a number.  Text is read as a number if it is
+// one, TRUE is 1 and an empty value 0.
+func toNumber(v Value) (float64, *Value) {
+	switch v.Type {
+	case ValueNumber:
+		return v.Number, nil
+	case ValueBool:
+		if v.Bool {
+			return 1, nil
+		}
+		return 0, nil
+	case ValueBlank:
+		return 0, nil
+	case ValueString:
+		if f, ok := parseNumber(v.Text); ok {
+			return f, nil
+		}
+	case ValueError:
+		return 0, &v
+	case ValueArray:
+		if len(v.Rows) > 0 && len(v.Rows[0]) > 0 {
+			return toNumber(v.Rows[0][0])
+		}
+	}
+	err := ErrorValue(ErrValue)
+	return 0, &err
+}
+
+// parseNumber reads text as a number, which may be a percentage.
+func parseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	percent := strings.HasSuffix(s, "%")
+	if percent {
+		s = strings.TrimSpace(s[:len(s)-1])
+	}
+	f, err := strconv.ParseFloat(strings.Replace(s, ",", "", -1), 64)
+	if err != nil || s == "" || strings.ContainsAny(s, "xXpPnN_") {
+		return 0, false
+	}
+	if percent {
+		f /= 100
+	}
+	return f, true
+}
+
+// toText returns v as text.
+func toText(v Value) string {
+	if v.Type == ValueArray {
+		if len(v.Rows) > 0 && len(v.Rows[0]) > 0 {
+			return toText(v.Rows[0][0])
+		}
+		return ""
+	}
+	return v.String()
+}
+
+// toBool returns v as a logical value.  Numbers other than 0 are TRUE,
+// and so is the text TRUE.
+func toBool(v Value) (bool, *Value) {
+	switch v.Type {
+	case ValueBool:
+		return v.Bool, nil
+	case ValueNumber:
+		return v.Number != 0, nil
+	case ValueBlank:
+		return false, nil
+	case ValueString:
+		switch strings.ToUpper(v.Text) {
+		case "TRUE":
+			return true, nil
+		case "FALSE":
+			return false, nil
+		}
+	case ValueError:
+		return false, &v
+	case ValueArray:
+		if len(v.Rows) > 0 && len(v.Rows[0]) > 0 {
+			return toBool(v.Rows[0][0])
+		}
+	}
+	err := ErrorValue(ErrValue)
+	return false, &err
+}
+
+// compare returns -1, 0 or 1 as a is less than, equal to or greater
+// than b.  Numbers come before text, and text before logical values;
+// text is compared without regard to case, and an empty value is
+// taken to be 0, "" or FALSE to match the other value.
+func compare(a, b Value) int {
+	if a.Type == ValueBlank {
+		a = blankLike(b)
+	}
+	if b.Type == ValueBlank {
+		b = blankLike(a)
+	}
+	if a.Type != b.Type {
+		return sign(float64(typeOrder(a) - typeOrder(b)))
+	}
+	switch a.Type {
+	case ValueNumber:
+		return sign(a.Number - b.Number)
+	case ValueString:
+		return strings.Compare(strings.ToLower(a.Text), strings.ToLower(b.Text))
+	case ValueBool:
+		if a.Bool == b.Bool {
+			return 0
+		}
+		if b.Bool {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// blankLike returns the empty value of the type of v.
+func blankLike(v Value) Value {
+	switch v.Type {
+	case ValueString:
+		return StringValue("")
+	case ValueBool:
+		return BoolValue(false)
+	}
+	return NumberValue(0)
+}
+
+func typeOrder(v Value) int {
+	switch v.Type {
+	case ValueNumber:
+		return 0
+	case ValueString:
+		return 1
+	case ValueBool:
+		return 2
+	}
+	return 3
+}
+
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// call calls a function.  The functions of the library signal errors
+// in their arguments by panicking with an argError, which ends the
+// call with its error value.
+func (e *evaluation) call(n *Function) (v Value) {
+	name := functionName(n.Name)
+	fn, ok := lookupFunction(name)
+	if !ok {
+		return ErrorValue(ErrName)
+	}
+	if len(n.Args) < fn.min || fn.max >= 0 && len(n.Args) > fn.max {
+		return ErrorValue(ErrValue)
+	}
+	c := &Call{Evaluator: e.Evaluator, Name: name, Sheet: e.sheet, Row: e.row, Col: e.col}
+	e.calls++
+	for _, arg := range n.Args {
+		c.Args = append(c.Args, e.eval(arg))
+	}
+	e.calls--
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(argError)
+			if !ok {
+				panic(r)
+			}
+			v = ErrorValue(string(err))
+		}
+	}()
+	return fn.fn(c)
+}
+
+// functionName returns the name of a function as the library knows
+// it: in upper case, without the prefixes that mark newer functions in
+// files.
+func functionName(name string) string {
+	name = strings.ToUpper(name)
+	for _, prefix := range []string{"_XLFN.", "_XLWS."} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return name
+}
+
+// argError is the error value of an argument that a function can't
+// use.
+type argError string
+
+func (e argError) Error() string {
+	return fmt.Sprintf("formula: %s", string(e))
+}
@@ -0,0 +1,234 @@
+package formula
+
+import (
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// testContext is a workbook of sheets of values, keyed by A1 cell
+// names, and of names defined by formulas.
+type testContext struct {
+	sheets map[string]map[string]Value
+	names  map[string]string
+}
+
+func (t *testContext) Cell(sheet string, row, col int) Value {
+	for name, cells := range t.sheets {
+		if strings.EqualFold(name, sheet) {
+			return cells[CellName(row, col)]
+		}
+	}
+	return Value{}
+}
+
+func (t *testContext) Size(sheet string) (rows, cols int, ok bool) {
+	for name, cells := range t.sheets {
+		if !strings.EqualFold(name, sheet) {
+			continue
+		}
+		for ref := range cells {
+			n, err := Parse(ref)
+			if err != nil {
+				panic(err)
+			}
+			cell := n.(*Ref).From
+			rows, cols = max(rows, cell.Row), max(cols, cell.Col)
+		}
+		return rows, cols, true
+	}
+	return 0, 0, false
+}
+
+func (t *testContext) Name(sheet, name string) Node {
+	for _, key := range []string{sheet + "!" + name, name} {
+		if f, ok := t.names[strings.ToUpper(key)]; ok {
+			n, err := Parse(f)
+			if err != nil {
+				panic(err)
+			}
+			return n
+		}
+	}
+	return nil
+}
+
+func newTestEvaluator() *Evaluator {
+	return &Evaluator{
+		Context: &testContext{
+			sheets: map[string]map[string]Value{
+				"Sheet1": {
+					"A1": NumberValue(1), "A2": NumberValue(2), "A3": NumberValue(3), "A4": NumberValue(4),
+					"B1": StringValue("apple"), "B2": StringValue("banana"), "B3": StringValue("cherry"),
+					"B4": StringValue("date"),
+					"C1": BoolValue(true), "C2": ErrorValue(ErrDiv0), "C3": StringValue("12"),
+					"D1": NumberValue(10), "D2": NumberValue(20), "D3": NumberValue(30), "D4": NumberValue(40),
+				},
+				"My Sheet": {
+					"A1": NumberValue(100), "B2": StringValue("x"),
+				},
+			},
+			names: map[string]string{
+				"RATE":          "0.5",
+				"DATA":          "Sheet1!$A$1:$A$4",
+				"MY SHEET!RATE": "2",
+				"LOOP":          "LOOP+1",
+			},
+		},
+		Now: func() time.Time { return time.Date(2020, time.March, 15, 18, 0, 0, 0, time.UTC) },
+	}
+}
+
+// evalString evaluates a formula in cell E5 of Sheet1, or of the sheet
+// named before a "|".
+func evalString(c *C, e *Evaluator, formula string) string {
+	sheet := "Sheet1"
+	if i := strings.Index(formula, "|"); i >= 0 {
+		sheet, formula = formula[:i], formula[i+1:]
+	}
+	n, err := Parse(formula)
+	c.Assert(err, IsNil, Commentf(formula))
+	return e.Eval(n, sheet, 5, 5).String()
+}
+
+type evalCase struct {
+	formula, expected string
+}
+
+func checkEval(c *C, cases []evalCase) {
+	e := newTestEvaluator()
+	for _, test := range cases {
+		c.Check(evalString(c, e, test.formula), Equals, test.expected, Commentf(test.formula))
+	}
+}
+
+func (s *FormulaSuite) TestEvalOperators(c *C) {
+	checkEval(c, []evalCase{
+		{"1+2*3", "7"},
+		{"(1+2)*3", "9"},
+		{"2^3^2", "64"},
+		{"-2^2", "4"},
+		{"50%", "0.5"},
+		{"1/0", "#DIV/0!"},
+		{"0.1+0.2", "0.3"},
+		{"1/3", "0.333333333333333"},
+		{"\"a\"&1&TRUE", "a1TRUE"},
+		{"\"12\"+1", "13"},
+		{"\"x\"+1", "#VALUE!"},
+		{"TRUE+1", "2"},
+		{"1=1", "TRUE"},
+		{"\"a\"=\"A\"", "TRUE"},
+		{"\"a\"<\"b\"", "TRUE"},
+		{"1<\"a\"", "TRUE"},
+		{"\"z\"<TRUE", "TRUE"},
+		{"1<>2", "TRUE"},
+		{"#N/A+1", "#N/A"},
+		{"1E+300*1E+300", "#NUM!"},
+	})
+}
+
+func (s *FormulaSuite) TestEvalReferences(c *C) {
+	checkEval(c, []evalCase{
+		{"A1+A2", "3"},
+		{"A1+Z99", "1"},
+		{"Z99", "0"},
+		{"B1&Z99", "apple"},
+		{"C2", "#DIV/0!"},
+		{"C3+1", "13"},
+		{"Sheet1!A4", "4"},
+		{"'My Sheet'!A1/2", "50"},
+		{"My Sheet|A1+B2", "#VALUE!"},
+		{"Nowhere!A1", "#REF!"},
+		{"[1]Sheet1!A1", "#REF!"},
+		{"Sheet1:Sheet2!A1", "#REF!"},
+		{"#REF!", "#REF!"},
+		{"SUM(A1:A4)", "10"},
+		{"SUM(A:A)", "10"},
+		{"SUM(4:4)", "44"},
+		{"SUM(A1:A2:A4)", "10"},
+		{"SUM(A1:D4 A3:D3)", "33"},
+		{"SUM(A1:A4 D1:D4)", "#NULL!"},
+		{"SUM(A1:B4 A3:D3)", "3"},
+		{"SUM((A1,D1))", "11"},
+		{"A:A", "0"},
+		{"A1:B1", "#VALUE!"},
+	})
+}
+
+func (s *FormulaSuite) TestEvalIntersection(c *C) {
+	e := newTestEvaluator()
+	n, err := Parse("D1:D4*2")
+	c.Assert(err, IsNil)
+	c.Assert(e.Eval(n, "Sheet1", 3, 5).String(), Equals, "60")
+	c.Assert(e.Eval(n, "Sheet1", 5, 5).String(), Equals, "#VALUE!")
+
+	n, err = Parse("A1:D1")
+	c.Assert(err, IsNil)
+	c.Assert(e.Eval(n, "Sheet1", 9, 4).String(), Equals, "10")
+
+	v := e.EvalArray(n, "Sheet1", 9, 4)
+	c.Assert(v.Type, Equals, ValueArray)
+	c.Assert(v.Rows, HasLen, 1)
+	c.Assert(v.Rows[0], HasLen, 4)
+	c.Assert(v.Ref.String(), Equals, "Sheet1!A1:D1")
+
+	n, err = Parse("{1,2;3,4}*10")
+	c.Assert(err, IsNil)
+	v = e.EvalArray(n, "Sheet1", 1, 1)
+	c.Assert(v.Rows, DeepEquals, [][]Value{
+		{NumberValue(10), NumberValue(20)},
+		{NumberValue(30), NumberValue(40)},
+	})
+	c.Assert(e.Eval(n, "Sheet1", 1, 1).String(), Equals, "10")
+}
+
+func (s *FormulaSuite) TestEvalNames(c *C) {
+	checkEval(c, []evalCase{
+		{"Rate*10", "5"},
+		{"My Sheet|Rate*10", "20"},
+		{"SUM(Data)", "10"},
+		{"Missing", "#NAME?"},
+		{"Loop", "#NAME?"},
+		{"NOSUCHFUNCTION(1)", "#NAME?"},
+		{"_xlfn.CONCAT(\"a\",\"b\")", "ab"},
+		{"SUM()", "#VALUE!"},
+		{"ABS(1,2)", "#VALUE!"},
+		{"Table1[Column]", "#REF!"},
+	})
+}
+
+func (s *FormulaSuite) TestRegisterFunction(c *C) {
+	RegisterFunction("double", 1, 1, func(call *Call) Value {
+		v := call.Args[0]
+		if v.Type != ValueNumber {
+			return ErrorValue(ErrValue)
+		}
+		return NumberValue(v.Number * 2)
+	})
+	checkEval(c, []evalCase{
+		{"DOUBLE(21)", "42"},
+		{"double(A4)", "8"},
+		{"DOUBLE()", "#VALUE!"},
+	})
+}
+
+func (s *FormulaSuite) TestValueString(c *C) {
+	cases := []struct {
+		value    Value
+		expected string
+	}{
+		{Value{}, ""},
+		{NumberValue(0), "0"},
+		{NumberValue(-1.5), "-1.5"},
+		{NumberValue(1e21), "1E+21"},
+		{NumberValue(1.5e-10), "1.5E-10"},
+		{NumberValue(123456789012345678), "1.23456789012346E+17"},
+		{StringValue("text"), "text"},
+		{BoolValue(false), "FALSE"},
+		{ErrorValue(ErrNA), "#N/A"},
+	}
+	for _, test := range cases {
+		c.Check(test.value.String(), Equals, test.expected, Commentf("%#v", test.value))
+	}
+}
@@ -0,0 +1,335 @@
+// Package formula reads and writes the formulas of Excel cells.
+//
+// Tokenize splits a formula into tokens that can be put back together
+// exactly as written, and Parse builds a syntax tree of the nodes
+// declared here, which Format turns back into text.  Formulas are
+// read in the A1 notation used in XLSX files, or with ParseR1C1 in the
+// R1C1 notation.  Rewrite changes the references of a formula while
+// keeping the rest of its text as it is.
+//
+// An Evaluator computes the value of a formula, reading cells and
+// defined names through a Context, with a library of Excel's math,
+// statistical, logical, text, lookup and date functions that
+// RegisterFunction extends.
+package formula
+
+// The largest row and column numbers of a worksheet.
+const (
+	MaxRow = 1048576
+	MaxCol = 16384
+)
+
+// Node is a node of the syntax tree of a formula: one of *Number,
+// *String, *Bool, *Error, *Array, *Ref, *Name, *TableRef, *Function,
+// *Missing, *Unary, *Postfix, *Binary and *Paren.
+type Node interface {
+	String() string
+	node()
+}
+
+// Number is a number, such as 1.5 or 1E-3.
+type Number struct {
+	Value float64
+	Text  string // the number as written, or "" to print Value
+}
+
+// String is a string, such as "text", without its quotes.
+type String struct {
+	Value string
+}
+
+// Bool is TRUE or FALSE.
+type Bool struct {
+	Value bool
+}
+
+// Error is an error value, such as #DIV/0! or #N/A.
+type Error struct {
+	Value string
+}
+
+// Array is an array constant, such as {1,2;3,4}, held row by row.
+type Array struct {
+	Rows [][]Node
+}
+
+// Cell is a corner of a reference.  Rows and columns are numbered from
+// 1; a zero column makes a reference to whole rows, and a zero row a
+// reference to whole columns.  An absolute row or column is written
+// with a $ and stays put when the formula is copied.
+type Cell struct {
+	Row, Col       int
+	RowAbs, ColAbs bool
+}
+
+// Ref is a reference to a cell, a range of cells, whole rows or whole
+// columns, possibly on other sheets.
+type Ref struct {
+	// Book is the external workbook, as written before the sheet
+	// name: [1] or C:\dir\[Book.xlsx].  It is "" for the formula's
+	// own workbook.
+	Book string
+	// Sheet is the sheet of the reference, or "" for the formula's
+	// own sheet.  LastSheet ends a range of sheets, as in
+	// Sheet1:Sheet3!A1.
+	Sheet     string
+	LastSheet string
+	// From is the cell, or the first corner of the range, and To
+	// the other corner of a range, or nil for a single cell.
+	From Cell
+	To   *Cell
+	// Invalid is set for #REF!, a reference to cells that have
+	// been deleted.
+	Invalid bool
+}
+
+// Name is a defined name, possibly of a sheet or of an external
+// workbook.
+type Name struct {
+	Book, Sheet string
+	Name        string
+}
+
+// TableRef is a structured reference to a table, such as
+// Table1[[#Headers],[Sales]:[Costs]] or [@Qty].
+type TableRef struct {
+	// Table is the name of the table, or "" inside the table
+	// itself.
+	Table string
+	// Items are the special items #All, #Data, #Headers, #Totals
+	// and #This Row.
+	Items []string
+	// Columns holds one column, or the first and last columns of
+	// a range, without escapes.
+	Columns []string
+	// At is set for the @ shorthand of #This Row.
+	At bool
+}
+
+// Function is a call to a function, such as SUM(A1:A3).
+type Function struct {
+	Name string
+	Args []Node
+}
+
+// Missing is an argument left out, as in IF(A1,,1).
+type Missing struct{}
+
+// Unary is a prefix operator: -, + or @.
+type Unary struct {
+	Op string
+	X  Node
+}
+
+// Postfix is a postfix operator: % or the spill operator #.
+type Postfix struct {
+	Op string
+	X  Node
+}
+
+// Binary is a binary operator: the range operator :, the
+// intersection operator " ", the union operator ",", or one of
+// ^ * / + - & = <> < > <= >=.
+type Binary struct {
+	Op   string
+	X, Y Node
+}
+
+// Paren is an expression in parentheses.
+type Paren struct {
+	X Node
+}
+
+func (*Number) node()   {}
+func (*String) node()   {}
+func (*Bool) node()     {}
+func (*Error) node()    {}
+func (*Array) node()    {}
+func (*Ref) node()      {}
+func (*Name) node()     {}
+func (*TableRef) node() {}
+func (*Function) node() {}
+func (*Missing) node()  {}
+func (*Unary) node()    {}
+func (*Postfix) node()  {}
+func (*Binary) node()   {}
+func (*Paren) node()    {}
+
+// Inspect calls f for node and, while f returns true, for each of the
+// nodes below it, depth first.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *Array:
+		for _, row := range n.Rows {
+			for _, x := range row {
+				Inspect(x, f)
+			}
+		}
+	case *Function:
+		for _, x := range n.Args {
+			Inspect(x, f)
+		}
+	case *Unary:
+		Inspect(n.X, f)
+	case *Postfix:
+		Inspect(n.X, f)
+	case *Binary:
+		Inspect(n.X, f)
+		Inspect(n.Y, f)
+	case *Paren:
+		Inspect(n.X, f)
+	}
+}
+
+// Bounds returns the first and last rows and columns of a reference,
+// counting from 1, with whole rows and columns running to the edges of
+// the worksheet.
+func (r Ref) Bounds() (top, left, bottom, right int) {
+	from, to := r.From, r.From
+	if r.To != nil {
+		to = *r.To
+	}
+	top, left, bottom, right = from.Row, from.Col, to.Row, to.Col
+	if top > bottom {
+		top, bottom = bottom, top
+	}
+	if left > right {
+		left, right = right, left
+	}
+	if top == 0 {
+		top, bottom = 1, MaxRow
+	}
+	if left == 0 {
+		left, right = 1, MaxCol
+	}
+	return top, left, bottom, right
+}
+
+// Shift returns the reference moved by rows and cols, as it is when
+// its formula is copied that far: absolute rows and columns stay put.
+// A reference moved off the worksheet becomes invalid.
+func (r Ref) Shift(rows, cols int) Ref {
+	if r.Invalid {
+		return r
+	}
+	shift := func(c Cell) (Cell, bool) {
+		if c.Row > 0 && !c.RowAbs {
+			c.Row += rows
+			if c.Row < 1 || c.Row > MaxRow {
+				return c, false
+			}
+		}
+		if c.Col > 0 && !c.ColAbs {
+			c.Col += cols
+			if c.Col < 1 || c.Col > MaxCol {
+				return c, false
+			}
+		}
+		return c, true
+	}
+	var ok bool
+	if r.From, ok = shift(r.From); !ok {
+		return r.invalid()
+	}
+	if r.To != nil {
+		to, ok := shift(*r.To)
+		if !ok {
+			return r.invalid()
+		}
+		r.To = &to
+	}
+	return r
+}
+
+// InsertRows returns the reference as it is after n rows are inserted
+// before row at: the rows from at on move down, and a range that spans
+// row at grows.  Whole columns stay as they are.  A reference pushed
+// off the worksheet becomes invalid, and a range pushed past its edge
+// is cut short.
+func (r Ref) InsertRows(at, n int) Ref {
+	return r.adjust(true, at, n)
+}
+
+// DeleteRows returns the reference as it is after the n rows from row
+// at on are deleted: the rows below them move up, and a range that
+// spans them shrinks.  A reference to deleted cells only becomes
+// invalid.
+func (r Ref) DeleteRows(at, n int) Ref {
+	return r.adjust(true, at, -n)
+}
+
+// InsertCols is InsertRows for columns.
+func (r Ref) InsertCols(at, n int) Ref {
+	return r.adjust(false, at, n)
+}
+
+// DeleteCols is DeleteRows for columns.
+func (r Ref) DeleteCols(at, n int) Ref {
+	return r.adjust(false, at, -n)
+}
+
+// adjust inserts n rows or columns at at, or deletes -n of them.
+func (r Ref) adjust(rows bool, at, n int) Ref {
+	if r.Invalid || n == 0 {
+		return r
+	}
+	from, to := r.From, r.From
+	if r.To != nil {
+		to = *r.To
+	}
+	lo, hi, limit := &from.Col, &to.Col, MaxCol
+	if rows {
+		lo, hi, limit = &from.Row, &to.Row, MaxRow
+	}
+	if *lo == 0 {
+		return r
+	}
+	if *lo > *hi {
+		lo, hi = hi, lo
+	}
+	first, last := *lo, *hi
+	if n > 0 {
+		if first >= at {
+			first += n
+		}
+		if last >= at {
+			last += n
+		}
+		if first > limit {
+			return r.invalid()
+		}
+		last = min(last, limit)
+	} else {
+		end := at - n
+		switch {
+		case first >= at && last < end:
+			return r.invalid()
+		case last < at:
+		case first >= end:
+			first, last = first+n, last+n
+		default:
+			if first > at {
+				first = at
+			}
+			if last < end {
+				last = at - 1
+			} else {
+				last += n
+			}
+		}
+	}
+	*lo, *hi = first, last
+	r.From = from
+	if r.To != nil {
+		r.To = &to
+	}
+	return r
+}
+
+// invalid returns the reference as #REF!, on its sheet.
+func (r Ref) invalid() Ref {
+	return Ref{Book: r.Book, Sheet: r.Sheet, LastSheet: r.LastSheet, Invalid: true}
+}
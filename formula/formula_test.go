@@ -0,0 +1,90 @@
+package formula
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type FormulaSuite struct{}
+
+var _ = Suite(&FormulaSuite{})
+
+func (s *FormulaSuite) TestInspect(c *C) {
+	n, err := Parse("SUM(A1,Sheet2!B2:C3)*Rate+{1,2}")
+	c.Assert(err, IsNil)
+	var refs []string
+	Inspect(n, func(n Node) bool {
+		switch n.(type) {
+		case *Ref, *Name:
+			refs = append(refs, n.String())
+		case *Array:
+			return false
+		}
+		return true
+	})
+	c.Assert(refs, DeepEquals, []string{"A1", "Sheet2!B2:C3", "Rate"})
+}
+
+func (s *FormulaSuite) TestShift(c *C) {
+	cases := []struct {
+		ref        string
+		rows, cols int
+		expected   string
+	}{
+		{"A1", 1, 2, "C2"},
+		{"$A1", 1, 2, "$A2"},
+		{"A$1", 1, 2, "C$1"},
+		{"Sheet1!$A$1:B2", 3, 0, "Sheet1!$A$1:B5"},
+		{"A:B", 5, 1, "B:C"},
+		{"$1:2", 1, 5, "$1:3"},
+		{"B2", -2, 0, "#REF!"},
+		{"Sheet1!B2", 0, -2, "Sheet1!#REF!"},
+		{"XFD1", 0, 1, "#REF!"},
+	}
+	for _, test := range cases {
+		n, err := Parse(test.ref)
+		c.Assert(err, IsNil)
+		shifted := n.(*Ref).Shift(test.rows, test.cols)
+		c.Assert(shifted.String(), Equals, test.expected, Commentf(test.ref))
+	}
+}
+
+func (s *FormulaSuite) TestInsertDelete(c *C) {
+	cases := []struct {
+		ref      string
+		adjust   func(Ref) Ref
+		expected string
+	}{
+		{"A1", func(r Ref) Ref { return r.InsertRows(2, 3) }, "A1"},
+		{"$A$2", func(r Ref) Ref { return r.InsertRows(2, 3) }, "$A$5"},
+		{"A1:B4", func(r Ref) Ref { return r.InsertRows(2, 3) }, "A1:B7"},
+		{"B4:A1", func(r Ref) Ref { return r.InsertRows(2, 3) }, "B7:A1"},
+		{"A:B", func(r Ref) Ref { return r.InsertRows(2, 3) }, "A:B"},
+		{"2:4", func(r Ref) Ref { return r.InsertRows(3, 1) }, "2:5"},
+		{"A1048575", func(r Ref) Ref { return r.InsertRows(1, 2) }, "#REF!"},
+		{"A2:A1048575", func(r Ref) Ref { return r.InsertRows(1, 2) }, "A4:A1048576"},
+		{"C1", func(r Ref) Ref { return r.InsertCols(2, 2) }, "E1"},
+		{"A1:C1", func(r Ref) Ref { return r.InsertCols(2, 2) }, "A1:E1"},
+		{"1:1", func(r Ref) Ref { return r.InsertCols(2, 2) }, "1:1"},
+		{"A1", func(r Ref) Ref { return r.DeleteRows(2, 3) }, "A1"},
+		{"A3", func(r Ref) Ref { return r.DeleteRows(2, 3) }, "#REF!"},
+		{"Sheet1!A3", func(r Ref) Ref { return r.DeleteRows(2, 3) }, "Sheet1!#REF!"},
+		{"A6", func(r Ref) Ref { return r.DeleteRows(2, 3) }, "A3"},
+		{"A2:A4", func(r Ref) Ref { return r.DeleteRows(2, 3) }, "#REF!"},
+		{"A1:A3", func(r Ref) Ref { return r.DeleteRows(2, 3) }, "A1:A1"},
+		{"A1:A10", func(r Ref) Ref { return r.DeleteRows(2, 3) }, "A1:A7"},
+		{"A3:A10", func(r Ref) Ref { return r.DeleteRows(2, 3) }, "A2:A7"},
+		{"$3:$10", func(r Ref) Ref { return r.DeleteRows(2, 3) }, "$2:$7"},
+		{"D1:F1", func(r Ref) Ref { return r.DeleteCols(5, 1) }, "D1:E1"},
+		{"A:A", func(r Ref) Ref { return r.DeleteCols(1, 1) }, "#REF!"},
+	}
+	for _, test := range cases {
+		n, err := Parse(test.ref)
+		c.Assert(err, IsNil)
+		adjusted := test.adjust(*n.(*Ref))
+		c.Check(adjusted.String(), Equals, test.expected, Commentf(test.ref))
+	}
+}
@@ -0,0 +1,320 @@
+package formula
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Func is a worksheet function.  It returns the value of a call, which
+// may be an error value.
+type Func func(c *Call) Value
+
+// Call is a call to a worksheet function.
+type Call struct {
+	*Evaluator
+	Name string // the name of the function, in upper case
+	// Args are the values of the arguments.  An argument that is a
+	// reference holds the values of its cells and has its Ref set;
+	// one that is left out is blank, with no Ref.
+	Args []Value
+	// Sheet, Row and Col are the cell of the formula.
+	Sheet    string
+	Row, Col int
+}
+
+type function struct {
+	fn       Func
+	min, max int
+}
+
+var (
+	functionsLock sync.RWMutex
+	functions     = map[string]function{}
+)
+
+// RegisterFunction adds a function to the library of the evaluator, or
+// replaces the function of that name.  The function takes at least min
+// arguments and at most max, or any number if max is -1.
+func RegisterFunction(name string, min, max int, fn Func) {
+	functionsLock.Lock()
+	defer functionsLock.Unlock()
+	functions[functionName(name)] = function{fn, min, max}
+}
+
+func lookupFunction(name string) (function, bool) {
+	functionsLock.RLock()
+	defer functionsLock.RUnlock()
+	fn, ok := functions[name]
+	return fn, ok
+}
+
+// register adds a table of functions to the library.
+func register(table map[string]function) {
+	for name, fn := range table {
+		functions[name] = fn
+	}
+}
+
+// fail ends a call to a function of the library with an error value.
+func fail(err string) {
+	panic(argError(err))
+}
+
+// check ends a call with v if it is an error value.
+func check(v Value) Value {
+	if v.IsError() {
+		fail(v.Text)
+	}
+	return v
+}
+
+// given reports whether argument i was given, rather than left out.
+func (c *Call) given(i int) bool {
+	return i < len(c.Args) && (c.Args[i].Type != ValueBlank || c.Args[i].Ref != nil)
+}
+
+// arg returns argument i, or a blank value if there isn't one.
+func (c *Call) arg(i int) Value {
+	if i < len(c.Args) {
+		return c.Args[i]
+	}
+	return Value{}
+}
+
+// scalar returns the one value of argument i that the function takes,
+// from the cell of a range in the formula's row or column, or the first
+// item of an array.
+func (c *Call) scalar(i int) Value {
+	v := c.arg(i)
+	if v.Type == ValueArray {
+		e := &evaluation{Evaluator: c.Evaluator, sheet: c.Sheet, row: c.Row, col: c.Col}
+		v = e.intersect(v)
+	}
+	return check(v)
+}
+
+// number returns argument i as a number.
+func (c *Call) number(i int) float64 {
+	f, err := toNumber(c.scalar(i))
+	if err != nil {
+		fail(err.Text)
+	}
+	return f
+}
+
+// numberOr returns argument i as a number, or def if it was left out.
+func (c *Call) numberOr(i int, def float64) float64 {
+	if !c.given(i) {
+		return def
+	}
+	return c.number(i)
+}
+
+// int returns argument i as a number, with its fraction dropped.
+func (c *Call) int(i int) int {
+	return int(math.Trunc(c.number(i)))
+}
+
+// intOr returns argument i as a whole number, or def if it was left
+// out.
+func (c *Call) intOr(i int, def int) int {
+	if !c.given(i) {
+		return def
+	}
+	return c.int(i)
+}
+
+// text returns argument i as text.
+func (c *Call) text(i int) string {
+	return toText(c.scalar(i))
+}
+
+// textOr returns argument i as text, or def if it was left out.
+func (c *Call) textOr(i int, def string) string {
+	if !c.given(i) {
+		return def
+	}
+	return c.text(i)
+}
+
+// bool returns argument i as a logical value.
+func (c *Call) bool(i int) bool {
+	b, err := toBool(c.scalar(i))
+	if err != nil {
+		fail(err.Text)
+	}
+	return b
+}
+
+// boolOr returns argument i as a logical value, or def if it was left
+// out.
+func (c *Call) boolOr(i int, def bool) bool {
+	if !c.given(i) {
+		return def
+	}
+	return c.bool(i)
+}
+
+// array returns argument i as an array, making a single value into an
+// array of one.
+func (c *Call) array(i int) Value {
+	v := check(c.arg(i))
+	if v.Type != ValueArray {
+		return Value{Type: ValueArray, Rows: [][]Value{{v}}, Ref: v.Ref}
+	}
+	return v
+}
+
+// flatten returns the items of v row by row, or v itself if it isn't
+// an array.
+func flatten(v Value) []Value {
+	if v.Type != ValueArray {
+		return []Value{v}
+	}
+	var values []Value
+	for _, row := range v.Rows {
+		values = append(values, row...)
+	}
+	return values
+}
+
+// fromCells reports whether v holds the values of cells or an array,
+// whose text and logical values functions such as SUM skip, rather than
+// a value given as such.
+func fromCells(v Value) bool {
+	return v.Type == ValueArray || v.Ref != nil
+}
+
+// numbers returns the numbers among the arguments from argument i on,
+// as SUM and AVERAGE count them: the numbers of ranges and arrays, and
+// the arguments that are numbers or can be read as numbers.  Any error
+// value ends the call.
+func (c *Call) numbers(i int) []float64 {
+	var numbers []float64
+	for _, arg := range c.Args[min(i, len(c.Args)):] {
+		if !fromCells(arg) {
+			f, err := toNumber(arg)
+			if err != nil {
+				fail(err.Text)
+			}
+			numbers = append(numbers, f)
+			continue
+		}
+		for _, v := range flatten(arg) {
+			switch v.Type {
+			case ValueNumber:
+				numbers = append(numbers, v.Number)
+			case ValueError:
+				fail(v.Text)
+			}
+		}
+	}
+	return numbers
+}
+
+// values returns the values of the arguments from argument i on, with
+// ranges and arrays taken apart.
+func (c *Call) values(i int) []Value {
+	var values []Value
+	for _, arg := range c.Args[min(i, len(c.Args)):] {
+		values = append(values, flatten(arg)...)
+	}
+	return values
+}
+
+// criteria returns the test of a criteria argument of functions such
+// as COUNTIF: a value to match, which may start with one of the
+// operators = <> < > <= >=.  Text matched with = or <> may hold the
+// wildcards * and ?, and ~ before one of them matches it.
+func criteria(v Value) func(Value) bool {
+	if v.Type != ValueString {
+		if v.Type == ValueBlank {
+			v = NumberValue(0)
+		}
+		return func(x Value) bool {
+			return x.Type == v.Type && compare(x, v) == 0
+		}
+	}
+	op, operand := "=", v.Text
+	for _, o := range []string{"<>", "<=", ">=", "=", "<", ">"} {
+		if strings.HasPrefix(operand, o) {
+			op, operand = o, operand[len(o):]
+			break
+		}
+	}
+	var want Value
+	switch f, ok := parseNumber(operand); {
+	case ok:
+		want = NumberValue(f)
+	case strings.EqualFold(operand, "TRUE") || strings.EqualFold(operand, "FALSE"):
+		want = BoolValue(strings.EqualFold(operand, "TRUE"))
+	case strings.HasPrefix(operand, "#"):
+		want = ErrorValue(errorValue(operand))
+	default:
+		want = StringValue(operand)
+	}
+	if want.Type == ValueString && (op == "=" || op == "<>") {
+		match := func(x Value) bool { return x.Type == ValueBlank || x.Type == ValueString && x.Text == "" }
+		if operand != "" {
+			re := wildcard(operand)
+			match = func(x Value) bool { return x.Type == ValueString && re.MatchString(x.Text) }
+		}
+		if op == "<>" {
+			return func(x Value) bool { return !match(x) }
+		}
+		return match
+	}
+	return func(x Value) bool {
+		if op == "<>" {
+			return x.Type != want.Type || compare(x, want) != 0
+		}
+		if x.Type != want.Type {
+			return false
+		}
+		c := compare(x, want)
+		switch op {
+		case "=":
+			return c == 0
+		case "<":
+			return c < 0
+		case ">":
+			return c > 0
+		case "<=":
+			return c <= 0
+		}
+		return c >= 0
+	}
+}
+
+// wildcard returns a regular expression matching the whole of a text
+// with the wildcards * and ?, without regard to case.
+func wildcard(pattern string) *regexp.Regexp {
+	return regexp.MustCompile("(?is)^" + wildcardExpr(pattern) + "$")
+}
+
+// wildcardExpr returns the regular expression for a text with the
+// wildcards * and ?, where ~ before a wildcard or ~ stands for it.
+func wildcardExpr(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; {
+		case ch == '~' && i+1 < len(pattern) && strings.IndexByte("*?~", pattern[i+1]) >= 0:
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case ch == '*':
+			b.WriteString(".*")
+		case ch == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// hasWildcards reports whether text holds the wildcards of criteria.
+func hasWildcards(s string) bool {
+	return strings.ContainsAny(s, "*?~")
+}
@@ -0,0 +1,253 @@
+package formula
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *FormulaSuite) TestMathFunctions(c *C) {
+	checkEval(c, []evalCase{
+		{"ABS(-2.5)", "2.5"},
+		{"SUM(A1:A4,5,TRUE)", "16"},
+		{"SUM(A1:C4)", "#DIV/0!"},
+		{"SUM(B1:B4,C3)", "0"},
+		{"SUM(\"3\",1)", "4"},
+		{"SUM(\"x\",1)", "#VALUE!"},
+		{"PRODUCT(A1:A4)", "24"},
+		{"ROUND(2.345,2)", "2.35"},
+		{"ROUND(-2.5,0)", "-3"},
+		{"ROUND(1234,-2)", "1200"},
+		{"ROUNDUP(1.201,2)", "1.21"},
+		{"ROUNDDOWN(-1.29,1)", "-1.2"},
+		{"INT(-1.5)", "-2"},
+		{"TRUNC(-1.5)", "-1"},
+		{"MOD(-3,2)", "1"},
+		{"MOD(3,0)", "#DIV/0!"},
+		{"QUOTIENT(7,2)", "3"},
+		{"POWER(2,10)", "1024"},
+		{"SQRT(-1)", "#NUM!"},
+		{"SQRT(16)", "4"},
+		{"LOG(100)", "2"},
+		{"LOG(8,2)", "3"},
+		{"LN(EXP(1))", "1"},
+		{"FACT(5)", "120"},
+		{"CEILING(2.1,0.5)", "2.5"},
+		{"FLOOR(2.9,0.5)", "2.5"},
+		{"EVEN(1.5)", "2"},
+		{"ODD(2)", "3"},
+		{"SIGN(-4)", "-1"},
+		{"SUMPRODUCT(A1:A4,D1:D4)", "300"},
+		{"SUMPRODUCT((A1:A4>2)*D1:D4)", "70"},
+		{"SUMSQ(1,2,3)", "14"},
+		{"SUMIF(A1:A4,\">2\")", "7"},
+		{"SUMIF(B1:B4,\"b*\",D1:D4)", "20"},
+		{"SUMIFS(D1:D4,A1:A4,\">=2\",B1:B4,\"<>cherry\")", "60"},
+	})
+}
+
+func (s *FormulaSuite) TestStatisticalFunctions(c *C) {
+	checkEval(c, []evalCase{
+		{"AVERAGE(A1:A4)", "2.5"},
+		{"AVERAGE(B1:B4)", "#DIV/0!"},
+		{"AVERAGEA(A1:B2)", "0.75"},
+		{"AVERAGEIF(A1:A4,\"<3\",D1:D4)", "15"},
+		{"COUNT(A1:D4,\"1\",\"x\")", "9"},
+		{"COUNTA(A1:D4)", "15"},
+		{"COUNTBLANK(A1:E4)", "5"},
+		{"COUNTIF(B1:B4,\"?a*\")", "2"},
+		{"COUNTIF(A1:A4,2)", "1"},
+		{"COUNTIFS(A1:A4,\">1\",D1:D4,\"<40\")", "2"},
+		{"MAX(A1:A4,D1:D2)", "20"},
+		{"MIN(B1:B4)", "0"},
+		{"MAXIFS(D1:D4,A1:A4,\"<4\")", "30"},
+		{"MINIFS(D1:D4,A1:A4,\">1\")", "20"},
+		{"MEDIAN(1,3,2,4)", "2.5"},
+		{"MODE(1,2,2,3)", "2"},
+		{"MODE(1,2,3)", "#N/A"},
+		{"LARGE(D1:D4,2)", "30"},
+		{"SMALL(D1:D4,5)", "#NUM!"},
+		{"RANK(30,D1:D4)", "2"},
+		{"RANK(30,D1:D4,1)", "3"},
+		{"STDEV(2,4,4,4,5,5,7,9)", "2.1380899352994"},
+		{"STDEV.P(2,4,4,4,5,5,7,9)", "2"},
+		{"VAR(A1:A4)", "1.66666666666667"},
+		{"VAR.P(A1:A4)", "1.25"},
+	})
+}
+
+func (s *FormulaSuite) TestLogicalFunctions(c *C) {
+	checkEval(c, []evalCase{
+		{"IF(A1=1,\"yes\",\"no\")", "yes"},
+		{"IF(FALSE,1)", "FALSE"},
+		{"IF(\"x\",1,2)", "#VALUE!"},
+		{"IF(C2,1,2)", "#DIV/0!"},
+		{"IF(TRUE,1,1/0)", "1"},
+		{"AND(TRUE,A1:A4)", "TRUE"},
+		{"AND(C1,0)", "FALSE"},
+		{"AND(B1:B2)", "#VALUE!"},
+		{"OR(FALSE,0,1)", "TRUE"},
+		{"XOR(TRUE,TRUE,TRUE)", "TRUE"},
+		{"NOT(0)", "TRUE"},
+		{"IFERROR(1/0,\"none\")", "none"},
+		{"IFERROR(1,\"none\")", "1"},
+		{"IFNA(#N/A,2)", "2"},
+		{"IFNA(#VALUE!,2)", "#VALUE!"},
+		{"IFS(A1>1,\"a\",A1>0,\"b\")", "b"},
+		{"IFS(FALSE,1)", "#N/A"},
+		{"SWITCH(A2,1,\"one\",2,\"two\",\"many\")", "two"},
+		{"SWITCH(9,1,\"one\",\"many\")", "many"},
+		{"SWITCH(9,1,\"one\")", "#N/A"},
+		{"TRUE()", "TRUE"},
+		{"ISBLANK(Z1)", "TRUE"},
+		{"ISBLANK(\"\")", "FALSE"},
+		{"ISERROR(C2)", "TRUE"},
+		{"ISERR(NA())", "FALSE"},
+		{"ISNA(NA())", "TRUE"},
+		{"ISNUMBER(C3)", "FALSE"},
+		{"ISTEXT(B1)", "TRUE"},
+		{"ISNONTEXT(A1)", "TRUE"},
+		{"ISLOGICAL(C1)", "TRUE"},
+		{"ISEVEN(-2.5)", "TRUE"},
+		{"ISODD(3)", "TRUE"},
+		{"ISREF(A1:B2)", "TRUE"},
+		{"ISREF(1)", "FALSE"},
+		{"N(C1)", "1"},
+		{"N(B1)", "0"},
+	})
+}
+
+func (s *FormulaSuite) TestTextFunctions(c *C) {
+	checkEval(c, []evalCase{
+		{"LEN(\"héllo\")", "5"},
+		{"LEFT(\"hello\",2)", "he"},
+		{"LEFT(\"hello\")", "h"},
+		{"RIGHT(\"hello\",10)", "hello"},
+		{"LEFT(\"hello\",-1)", "#VALUE!"},
+		{"MID(\"hello\",2,3)", "ell"},
+		{"MID(\"hello\",9,3)", ""},
+		{"MID(\"hello\",0,3)", "#VALUE!"},
+		{"UPPER(B1)", "APPLE"},
+		{"LOWER(\"ABC\")", "abc"},
+		{"PROPER(\"hello wORLD o'neil\")", "Hello World O'Neil"},
+		{"TRIM(\"  a   b  \")", "a b"},
+		{"CLEAN(\"a\"&CHAR(9)&\"b\")", "ab"},
+		{"CONCATENATE(\"a\",1,TRUE)", "a1TRUE"},
+		{"CONCAT(B1:B2,\"!\")", "applebanana!"},
+		{"TEXTJOIN(\", \",TRUE,B1:B2,\"\",\"c\")", "apple, banana, c"},
+		{"TEXTJOIN(\"-\",FALSE,\"a\",\"\",\"b\")", "a--b"},
+		{"EXACT(\"a\",\"A\")", "FALSE"},
+		{"FIND(\"l\",\"hello\")", "3"},
+		{"FIND(\"l\",\"hello\",4)", "4"},
+		{"FIND(\"L\",\"hello\")", "#VALUE!"},
+		{"SEARCH(\"L\",\"hello\")", "3"},
+		{"SEARCH(\"l?o\",\"hello\")", "3"},
+		{"SEARCH(\"~?\",\"what?\")", "5"},
+		{"REPLACE(\"abcdef\",2,3,\"X\")", "aXef"},
+		{"SUBSTITUTE(\"a-b-c\",\"-\",\"+\")", "a+b+c"},
+		{"SUBSTITUTE(\"a-b-c\",\"-\",\"+\",2)", "a-b+c"},
+		{"REPT(\"ab\",3)", "ababab"},
+		{"T(B1)", "apple"},
+		{"T(A1)", ""},
+		{"TEXT(0.5,\"General\")", "0.5"},
+		{"VALUE(\"1,234.5\")", "1234.5"},
+		{"VALUE(\"50%\")", "0.5"},
+		{"VALUE(\"x\")", "#VALUE!"},
+		{"CHAR(65)", "A"},
+		{"CODE(\"A\")", "65"},
+	})
+}
+
+func (s *FormulaSuite) TestLookupFunctions(c *C) {
+	checkEval(c, []evalCase{
+		{"VLOOKUP(3,A1:D4,4,FALSE)", "30"},
+		{"VLOOKUP(2.5,A1:D4,2)", "banana"},
+		{"VLOOKUP(0,A1:D4,2)", "#N/A"},
+		{"VLOOKUP(3,A1:D4,5,FALSE)", "#REF!"},
+		{"HLOOKUP(\"a*\",B1:D4,2,FALSE)", "banana"},
+		{"MATCH(\"cherry\",B1:B4,0)", "3"},
+		{"MATCH(25,D1:D4)", "2"},
+		{"MATCH(25,{40,30,20,10},-1)", "2"},
+		{"MATCH(\"x\",B1:B4,0)", "#N/A"},
+		{"INDEX(B1:B4,2)", "banana"},
+		{"INDEX(A1:D4,3,4)", "30"},
+		{"INDEX({1,2;3,4},2,1)", "3"},
+		{"SUM(INDEX(A1:D4,0,4))", "100"},
+		{"SUM(A1:INDEX(A1:A4,3))", "6"},
+		{"INDEX(A1:D4,5,1)", "#REF!"},
+		{"LOOKUP(2.5,A1:A4,B1:B4)", "banana"},
+		{"XLOOKUP(\"cherry\",B1:B4,D1:D4)", "30"},
+		{"XLOOKUP(\"x\",B1:B4,D1:D4,\"none\")", "none"},
+		{"XLOOKUP(25,D1:D4,B1:B4,,1)", "cherry"},
+		{"XLOOKUP(25,D1:D4,B1:B4,,-1)", "banana"},
+		{"SUM(XLOOKUP(3,A1:A4,A1:D4))", "33"},
+		{"CHOOSE(2,\"a\",\"b\")", "b"},
+		{"CHOOSE(3,\"a\",\"b\")", "#VALUE!"},
+		{"ROW()", "5"},
+		{"ROW(C3)", "3"},
+		{"COLUMN(C3:D4)", "3"},
+		{"ROWS(A1:D4)", "4"},
+		{"COLUMNS({1,2,3})", "3"},
+		{"SUM(OFFSET(A1,1,3,2,1))", "50"},
+		{"OFFSET(A1,-1,0)", "#REF!"},
+		{"INDIRECT(\"D\"&2)", "20"},
+		{"INDIRECT(\"'My Sheet'!A1\")", "100"},
+		{"SUM(INDIRECT(\"R1C1:R4C1\",FALSE))", "10"},
+		{"INDIRECT(\"1+\")", "#REF!"},
+	})
+}
+
+func (s *FormulaSuite) TestDateFunctions(c *C) {
+	checkEval(c, []evalCase{
+		{"DATE(2020,1,31)", "43861"},
+		{"DATE(2020,14,1)", "44228"},
+		{"DATE(120,1,1)", "43831"},
+		{"DATE(1900,3,1)", "61"},
+		{"DATE(1900,2,28)", "59"},
+		{"DATE(-1,1,1)", "#NUM!"},
+		{"YEAR(43861)", "2020"},
+		{"MONTH(43861)", "1"},
+		{"DAY(43861)", "31"},
+		{"DAY(60)", "29"},
+		{"MONTH(60)", "2"},
+		{"DAY(61)", "1"},
+		{"YEAR(-1)", "#NUM!"},
+		{"TIME(12,30,0)", "0.520833333333333"},
+		{"HOUR(0.75)", "18"},
+		{"MINUTE(TIME(1,2,3))", "2"},
+		{"SECOND(TIME(1,2,3))", "3"},
+		{"WEEKDAY(43861)", "6"},
+		{"WEEKDAY(43861,2)", "5"},
+		{"WEEKDAY(43861,3)", "4"},
+		{"WEEKDAY(43861,16)", "7"},
+		{"WEEKDAY(1)", "1"},
+		{"EDATE(43861,1)", "43890"},
+		{"EOMONTH(43861,1)", "43890"},
+		{"EOMONTH(43861,-1)", "43830"},
+		{"DAYS(43890,43861)", "29"},
+		{"DATEDIF(43861,44228,\"M\")", "12"},
+		{"DATEDIF(43861,44228,\"Y\")", "1"},
+		{"DATEDIF(43861,44228,\"MD\")", "1"},
+		{"DATEDIF(44228,43861,\"D\")", "#NUM!"},
+		{"DATEVALUE(\"2020-01-31\")", "43861"},
+		{"DATEVALUE(\"1/31/2020\")", "43861"},
+		{"DATEVALUE(\"31 January 2020\")", "43861"},
+		{"DATEVALUE(\"x\")", "#VALUE!"},
+		{"TIMEVALUE(\"18:00\")", "0.75"},
+		{"VALUE(\"2020-01-31 12:00\")", "43861.5"},
+		{"YEAR(\"2020-01-31\")", "2020"},
+		{"TODAY()", "43905"},
+		{"NOW()", "43905.75"},
+	})
+}
+
+func (s *FormulaSuite) TestDate1904(c *C) {
+	e := newTestEvaluator()
+	e.Date1904 = true
+	for formula, expected := range map[string]string{
+		"DATE(1904,1,1)":  "0",
+		"DATE(2020,1,31)": "42399",
+		"YEAR(42399)":     "2020",
+		"WEEKDAY(0)":      "6",
+	} {
+		c.Check(evalString(c, e, formula), Equals, expected, Commentf(formula))
+	}
+}
@@ -0,0 +1,192 @@
+package formula
+
+import (
+	"math"
+)
+
+func init() {
+	register(map[string]function{
+		"AND":       {and, 1, -1},
+		"FALSE":     {constant(BoolValue(false)), 0, 0},
+		"IF":        {ifFunc, 1, 3},
+		"IFERROR":   {ifError, 2, 2},
+		"IFNA":      {ifNA, 2, 2},
+		"IFS":       {ifs, 2, -1},
+		"NOT":       {not, 1, 1},
+		"OR":        {or, 1, -1},
+		"SWITCH":    {switchFunc, 3, -1},
+		"TRUE":      {constant(BoolValue(true)), 0, 0},
+		"XOR":       {xor, 1, -1},
+		"ISBLANK":   {is(func(v Value) bool { return v.Type == ValueBlank }), 1, 1},
+		"ISERR":     {is(func(v Value) bool { return v.IsError() && v.Text != ErrNA }), 1, 1},
+		"ISERROR":   {is(Value.IsError), 1, 1},
+		"ISEVEN":    {isParity(0), 1, 1},
+		"ISLOGICAL": {is(func(v Value) bool { return v.Type == ValueBool }), 1, 1},
+		"ISNA":      {is(func(v Value) bool { return v.IsError() && v.Text == ErrNA }), 1, 1},
+		"ISNONTEXT": {is(func(v Value) bool { return v.Type != ValueString }), 1, 1},
+		"ISNUMBER":  {is(func(v Value) bool { return v.Type == ValueNumber }), 1, 1},
+		"ISODD":     {isParity(1), 1, 1},
+		"ISREF":     {isRef, 1, 1},
+		"ISTEXT":    {is(func(v Value) bool { return v.Type == ValueString }), 1, 1},
+		"N":         {nFunc, 1, 1},
+		"NA":        {constant(ErrorValue(ErrNA)), 0, 0},
+	})
+}
+
+func constant(v Value) Func {
+	return func(c *Call) Value {
+		return v
+	}
+}
+
+func ifFunc(c *Call) Value {
+	if c.bool(0) {
+		if len(c.Args) < 2 {
+			return BoolValue(true)
+		}
+		return c.Args[1]
+	}
+	if len(c.Args) < 3 {
+		return BoolValue(false)
+	}
+	return c.Args[2]
+}
+
+func ifs(c *Call) Value {
+	if len(c.Args)%2 != 0 {
+		return ErrorValue(ErrValue)
+	}
+	for i := 0; i < len(c.Args); i += 2 {
+		if c.bool(i) {
+			return c.Args[i+1]
+		}
+	}
+	return ErrorValue(ErrNA)
+}
+
+func ifError(c *Call) Value {
+	if c.Args[0].IsError() {
+		return c.Args[1]
+	}
+	return c.Args[0]
+}
+
+func ifNA(c *Call) Value {
+	if c.Args[0].IsError() && c.Args[0].Text == ErrNA {
+		return c.Args[1]
+	}
+	return c.Args[0]
+}
+
+// switchFunc compares its first argument with each value that follows
+// and returns the result paired with the first equal one, or the
+// default that may end the arguments.
+func switchFunc(c *Call) Value {
+	v := c.scalar(0)
+	i := 1
+	for ; i+1 < len(c.Args); i += 2 {
+		w := c.scalar(i)
+		if v.Type == w.Type && compare(v, w) == 0 {
+			return c.Args[i+1]
+		}
+	}
+	if i < len(c.Args) {
+		return c.Args[i]
+	}
+	return ErrorValue(ErrNA)
+}
+
+// logicals returns the logical values of the arguments, as AND and OR
+// take them: the logical values and numbers of ranges, and the
+// arguments that are or can be read as logical values.
+func (c *Call) logicals() []bool {
+	var values []bool
+	for _, arg := range c.Args {
+		if !fromCells(arg) {
+			b, err := toBool(arg)
+			if err != nil {
+				fail(err.Text)
+			}
+			values = append(values, b)
+			continue
+		}
+		for _, v := range flatten(arg) {
+			switch v.Type {
+			case ValueBool, ValueNumber:
+				b, _ := toBool(v)
+				values = append(values, b)
+			case ValueError:
+				fail(v.Text)
+			}
+		}
+	}
+	if len(values) == 0 {
+		fail(ErrValue)
+	}
+	return values
+}
+
+func and(c *Call) Value {
+	for _, b := range c.logicals() {
+		if !b {
+			return BoolValue(false)
+		}
+	}
+	return BoolValue(true)
+}
+
+func or(c *Call) Value {
+	for _, b := range c.logicals() {
+		if b {
+			return BoolValue(true)
+		}
+	}
+	return BoolValue(false)
+}
+
+func xor(c *Call) Value {
+	odd := false
+	for _, b := range c.logicals() {
+		odd = odd != b
+	}
+	return BoolValue(odd)
+}
+
+func not(c *Call) Value {
+	return BoolValue(!c.bool(0))
+}
+
+// is makes a function that tests the value of its argument.
+func is(test func(Value) bool) Func {
+	return func(c *Call) Value {
+		v := c.Args[0]
+		if v.Type == ValueArray {
+			v = (&evaluation{Evaluator: c.Evaluator, sheet: c.Sheet, row: c.Row, col: c.Col}).intersect(v)
+		}
+		return BoolValue(test(v))
+	}
+}
+
+func isParity(parity float64) Func {
+	return func(c *Call) Value {
+		n := math.Trunc(c.number(0))
+		return BoolValue(math.Abs(math.Mod(n, 2)) == parity)
+	}
+}
+
+func isRef(c *Call) Value {
+	return BoolValue(c.Args[0].Ref != nil)
+}
+
+// nFunc returns a number as it is, TRUE as 1 and anything else as 0.
+func nFunc(c *Call) Value {
+	v := c.scalar(0)
+	switch v.Type {
+	case ValueNumber:
+		return v
+	case ValueBool:
+		f, _ := toNumber(v)
+		return NumberValue(f)
+	}
+	return NumberValue(0)
+}
@@ -0,0 +1,372 @@
+package formula
+
+import (
+	"regexp"
+)
+
+func init() {
+	register(map[string]function{
+		"CHOOSE":   {choose, 2, -1},
+		"COLUMN":   {column, 0, 1},
+		"COLUMNS":  {columns, 1, 1},
+		"HLOOKUP":  {hlookup, 3, 4},
+		"INDEX":    {index, 2, 3},
+		"INDIRECT": {indirect, 1, 2},
+		"LOOKUP":   {lookup, 2, 3},
+		"MATCH":    {match, 2, 3},
+		"OFFSET":   {offset, 3, 5},
+		"ROW":      {row, 0, 1},
+		"ROWS":     {rows, 1, 1},
+		"VLOOKUP":  {vlookup, 3, 4},
+		"XLOOKUP":  {xlookup, 3, 6},
+	})
+}
+
+// position returns the position of v among values, or -1 if it isn't
+// there.  For mode 0 the value must be equal, and text may hold
+// wildcards.  For mode 1 it is the last value that isn't greater than
+// v, and for mode -1 the last value that isn't less, in values sorted
+// in ascending or descending order.
+func position(values []Value, v Value, mode int) int {
+	if mode == 0 {
+		var test func(Value) bool
+		if v.Type == ValueString && hasWildcards(v.Text) {
+			re := wildcard(v.Text)
+			test = func(x Value) bool { return x.Type == ValueString && re.MatchString(x.Text) }
+		} else {
+			test = func(x Value) bool { return x.Type == v.Type && compare(x, v) == 0 }
+		}
+		for i, x := range values {
+			if test(x) {
+				return i
+			}
+		}
+		return -1
+	}
+	found := -1
+	for i, x := range values {
+		if x.Type != v.Type {
+			continue
+		}
+		c := compare(x, v)
+		if c == 0 {
+			return i
+		}
+		if c*mode > 0 {
+			break
+		}
+		found = i
+	}
+	return found
+}
+
+// lookupValue returns the value to look for, from argument 0.
+func (c *Call) lookupValue() Value {
+	v := c.scalar(0)
+	if v.Type == ValueBlank {
+		return NumberValue(0)
+	}
+	return v
+}
+
+func match(c *Call) Value {
+	v := c.lookupValue()
+	a := c.array(1)
+	rows, cols := dims(a)
+	if rows > 1 && cols > 1 {
+		return ErrorValue(ErrNA)
+	}
+	mode := sign(c.numberOr(2, 1))
+	i := position(flatten(a), v, mode)
+	if i < 0 {
+		return ErrorValue(ErrNA)
+	}
+	return NumberValue(float64(i + 1))
+}
+
+// columnOf returns column j of an array, as a list.
+func columnOf(a Value, j int) []Value {
+	values := make([]Value, len(a.Rows))
+	for i, row := range a.Rows {
+		values[i] = row[j]
+	}
+	return values
+}
+
+func vlookup(c *Call) Value {
+	v, table, col := c.lookupValue(), c.array(1), c.int(2)
+	approx := c.boolOr(3, true)
+	rows, cols := dims(table)
+	if col < 1 {
+		return ErrorValue(ErrValue)
+	}
+	if col > cols || rows == 0 {
+		return ErrorValue(ErrRef)
+	}
+	mode := 0
+	if approx {
+		mode = 1
+	}
+	i := position(columnOf(table, 0), v, mode)
+	if i < 0 {
+		return ErrorValue(ErrNA)
+	}
+	return table.Rows[i][col-1]
+}
+
+func hlookup(c *Call) Value {
+	v, table, row := c.lookupValue(), c.array(1), c.int(2)
+	approx := c.boolOr(3, true)
+	rows, _ := dims(table)
+	if row < 1 {
+		return ErrorValue(ErrValue)
+	}
+	if row > rows {
+		return ErrorValue(ErrRef)
+	}
+	mode := 0
+	if approx {
+		mode = 1
+	}
+	i := position(table.Rows[0], v, mode)
+	if i < 0 {
+		return ErrorValue(ErrNA)
+	}
+	return table.Rows[row-1][i]
+}
+
+// lookup is the vector form of LOOKUP, which looks for the last value
+// that isn't greater in a sorted row or column and returns the value in
+// the same place of the result vector.
+func lookup(c *Call) Value {
+	v := c.lookupValue()
+	values := flatten(c.array(1))
+	results := values
+	if c.given(2) {
+		results = flatten(c.array(2))
+	}
+	i := position(values, v, 1)
+	if i < 0 || i >= len(results) {
+		return ErrorValue(ErrNA)
+	}
+	return results[i]
+}
+
+// xlookup looks for a value in a row or column and returns the value,
+// row or column in the same place of the return array.
+func xlookup(c *Call) Value {
+	v := c.lookupValue()
+	a, results := c.array(1), c.array(2)
+	rows, cols := dims(a)
+	if rows > 1 && cols > 1 {
+		return ErrorValue(ErrValue)
+	}
+	values := flatten(a)
+	matchMode, searchMode := c.intOr(4, 0), c.intOr(5, 1)
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+		if searchMode < 0 {
+			order[i] = len(values) - 1 - i
+		}
+	}
+	var re *regexp.Regexp
+	if matchMode == 2 && v.Type == ValueString {
+		re = wildcard(v.Text)
+	}
+	found, nearest := -1, -1
+search:
+	for _, i := range order {
+		x := values[i]
+		switch matchMode {
+		case 0, 2:
+			if re != nil && x.Type == ValueString && re.MatchString(x.Text) ||
+				re == nil && x.Type == v.Type && compare(x, v) == 0 {
+				found = i
+				break search
+			}
+		case -1, 1:
+			// The exact value, or else the nearest one below it
+			// for -1 or above it for 1.
+			if x.Type != v.Type {
+				continue
+			}
+			switch cmp := compare(x, v); {
+			case cmp == 0:
+				found = i
+				break search
+			case cmp == matchMode && (nearest < 0 || compare(x, values[nearest]) == -matchMode):
+				nearest = i
+			}
+		default:
+			return ErrorValue(ErrValue)
+		}
+	}
+	if found < 0 {
+		found = nearest
+	}
+	if found < 0 {
+		if c.given(3) {
+			return c.Args[3]
+		}
+		return ErrorValue(ErrNA)
+	}
+	rr, rc := dims(results)
+	switch {
+	case cols == 1 && rr == rows:
+		if rc == 1 {
+			return results.Rows[found][0]
+		}
+		return Value{Type: ValueArray, Rows: [][]Value{results.Rows[found]}}
+	case rows == 1 && rc == cols:
+		if rr == 1 {
+			return results.Rows[0][found]
+		}
+		return Value{Type: ValueArray, Rows: rowsOf(columnOf(results, found))}
+	}
+	return ErrorValue(ErrValue)
+}
+
+// rowsOf makes a column of values into the rows of an array.
+func rowsOf(values []Value) [][]Value {
+	rows := make([][]Value, len(values))
+	for i, v := range values {
+		rows[i] = []Value{v}
+	}
+	return rows
+}
+
+// index returns the item of an array at a row and a column, counting
+// from 1, or a whole row or column for a 0.  For a range, the value is
+// that of a reference to the cells, so INDEX can end a range.
+func index(c *Call) Value {
+	a := c.array(0)
+	rows, cols := dims(a)
+	row, col := c.int(1), c.intOr(2, 0)
+	if !c.given(2) && rows == 1 {
+		row, col = 1, row
+	} else if !c.given(2) && cols == 1 {
+		col = 1
+	}
+	if row < 0 || col < 0 || row > rows || col > cols {
+		return ErrorValue(ErrRef)
+	}
+	top, left, bottom, right := 1, 1, rows, cols
+	if row > 0 {
+		top, bottom = row, row
+	}
+	if col > 0 {
+		left, right = col, col
+	}
+	if a.Ref != nil {
+		t, l, _, _ := a.Ref.Bounds()
+		e := &evaluation{Evaluator: c.Evaluator, sheet: c.Sheet, row: c.Row, col: c.Col}
+		return e.area(area(a.Ref.Sheet, t+top-1, l+left-1, t+bottom-1, l+right-1))
+	}
+	if top == bottom && left == right {
+		return a.Rows[top-1][left-1]
+	}
+	v := Value{Type: ValueArray}
+	for i := top; i <= bottom; i++ {
+		v.Rows = append(v.Rows, a.Rows[i-1][left-1:right])
+	}
+	return v
+}
+
+func choose(c *Call) Value {
+	i := c.int(0)
+	if i < 1 || i >= len(c.Args) {
+		return ErrorValue(ErrValue)
+	}
+	return c.Args[i]
+}
+
+// refArg returns the reference of argument i, which must be one.
+func (c *Call) refArg(i int) *Ref {
+	v := check(c.arg(i))
+	if v.Ref == nil {
+		fail(ErrValue)
+	}
+	return v.Ref
+}
+
+func row(c *Call) Value {
+	if len(c.Args) == 0 {
+		return NumberValue(float64(c.Row))
+	}
+	top, _, _, _ := c.refArg(0).Bounds()
+	return NumberValue(float64(top))
+}
+
+func column(c *Call) Value {
+	if len(c.Args) == 0 {
+		return NumberValue(float64(c.Col))
+	}
+	_, left, _, _ := c.refArg(0).Bounds()
+	return NumberValue(float64(left))
+}
+
+func rows(c *Call) Value {
+	v := check(c.arg(0))
+	if v.Ref != nil {
+		top, _, bottom, _ := v.Ref.Bounds()
+		return NumberValue(float64(bottom - top + 1))
+	}
+	r, _ := dims(v)
+	return NumberValue(float64(r))
+}
+
+func columns(c *Call) Value {
+	v := check(c.arg(0))
+	if v.Ref != nil {
+		_, left, _, right := v.Ref.Bounds()
+		return NumberValue(float64(right - left + 1))
+	}
+	_, k := dims(v)
+	return NumberValue(float64(k))
+}
+
+// offset returns the range of the given height and width, by default
+// those of the reference, that is some rows and columns away from it.
+func offset(c *Call) Value {
+	ref := c.refArg(0)
+	top, left, bottom, right := ref.Bounds()
+	height, width := c.intOr(3, bottom-top+1), c.intOr(4, right-left+1)
+	top += c.int(1)
+	left += c.int(2)
+	if height < 1 || width < 1 {
+		return ErrorValue(ErrRef)
+	}
+	bottom, right = top+height-1, left+width-1
+	if top < 1 || left < 1 || bottom > MaxRow || right > MaxCol {
+		return ErrorValue(ErrRef)
+	}
+	e := &evaluation{Evaluator: c.Evaluator, sheet: c.Sheet, row: c.Row, col: c.Col}
+	return e.area(area(ref.Sheet, top, left, bottom, right))
+}
+
+// indirect returns the value of a reference given as text, in A1
+// notation or, if the second argument is FALSE, in R1C1 notation.
+func indirect(c *Call) Value {
+	text := c.text(0)
+	var n Node
+	var err error
+	if c.boolOr(1, true) {
+		n, err = Parse(text)
+	} else {
+		n, err = ParseR1C1(text, c.Row, c.Col)
+	}
+	if err != nil {
+		return ErrorValue(ErrRef)
+	}
+	switch n.(type) {
+	case *Ref, *Name:
+		e := &evaluation{Evaluator: c.Evaluator, sheet: c.Sheet, row: c.Row, col: c.Col}
+		v := e.eval(n)
+		if v.Ref == nil && !v.IsError() {
+			return ErrorValue(ErrRef)
+		}
+		return v
+	}
+	return ErrorValue(ErrRef)
+}
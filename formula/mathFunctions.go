@@ -0,0 +1,656 @@
+package formula
+
+import (
+	"math"
+	"sort"
+)
+
+func init() {
+	register(map[string]function{
+		"ABS":        {math1(math.Abs), 1, 1},
+		"CEILING":    {ceiling, 1, 2},
+		"EVEN":       {even, 1, 1},
+		"EXP":        {math1(math.Exp), 1, 1},
+		"FACT":       {fact, 1, 1},
+		"FLOOR":      {floor, 1, 2},
+		"INT":        {math1(math.Floor), 1, 1},
+		"LN":         {logarithm(math.Log), 1, 1},
+		"LOG":        {log, 1, 2},
+		"LOG10":      {logarithm(math.Log10), 1, 1},
+		"MOD":        {mod, 2, 2},
+		"ODD":        {odd, 1, 1},
+		"PI":         {pi, 0, 0},
+		"POWER":      {power, 2, 2},
+		"PRODUCT":    {product, 1, -1},
+		"QUOTIENT":   {quotient, 2, 2},
+		"ROUND":      {round(math.Round), 1, 2},
+		"ROUNDDOWN":  {round(math.Trunc), 1, 2},
+		"ROUNDUP":    {round(roundUp), 1, 2},
+		"SIGN":       {math1(signum), 1, 1},
+		"SQRT":       {sqrt, 1, 1},
+		"SUM":        {sum, 1, -1},
+		"SUMIF":      {sumIf, 2, 3},
+		"SUMIFS":     {sumIfs, 3, -1},
+		"SUMPRODUCT": {sumProduct, 1, -1},
+		"TRUNC":      {trunc, 1, 2},
+		"AVERAGE":    {average, 1, -1},
+		"AVERAGEA":   {averageA, 1, -1},
+		"AVERAGEIF":  {averageIf, 2, 3},
+		"AVERAGEIFS": {averageIfs, 3, -1},
+		"COUNT":      {count, 1, -1},
+		"COUNTA":     {countA, 1, -1},
+		"COUNTBLANK": {countBlank, 1, 1},
+		"COUNTIF":    {countIf, 2, 2},
+		"COUNTIFS":   {countIfs, 2, -1},
+		"LARGE":      {large, 2, 2},
+		"MAX":        {maximum, 1, -1},
+		"MAXIFS":     {maxIfs, 3, -1},
+		"MEDIAN":     {median, 1, -1},
+		"MIN":        {minimum, 1, -1},
+		"MINIFS":     {minIfs, 3, -1},
+		"MODE":       {mode, 1, -1},
+		"MODE.SNGL":  {mode, 1, -1},
+		"RANK":       {rank, 2, 3},
+		"RANK.EQ":    {rank, 2, 3},
+		"SMALL":      {small, 2, 2},
+		"STDEV":      {stdev(1), 1, -1},
+		"STDEV.S":    {stdev(1), 1, -1},
+		"STDEV.P":    {stdev(0), 1, -1},
+		"STDEVP":     {stdev(0), 1, -1},
+		"VAR":        {variance(1), 1, -1},
+		"VAR.S":      {variance(1), 1, -1},
+		"VAR.P":      {variance(0), 1, -1},
+		"VARP":       {variance(0), 1, -1},
+		"SUMSQ":      {sumSquares, 1, -1},
+	})
+}
+
+// math1 makes a function of one number out of f.
+func math1(f func(float64) float64) Func {
+	return func(c *Call) Value {
+		return NumberValue(f(c.number(0)))
+	}
+}
+
+func signum(f float64) float64 {
+	return float64(sign(f))
+}
+
+func logarithm(f func(float64) float64) Func {
+	return func(c *Call) Value {
+		n := c.number(0)
+		if n <= 0 {
+			return ErrorValue(ErrNum)
+		}
+		return NumberValue(f(n))
+	}
+}
+
+func log(c *Call) Value {
+	n, base := c.number(0), c.numberOr(1, 10)
+	if n <= 0 || base <= 0 {
+		return ErrorValue(ErrNum)
+	}
+	if base == 1 {
+		return ErrorValue(ErrDiv0)
+	}
+	return NumberValue(math.Log(n) / math.Log(base))
+}
+
+func sqrt(c *Call) Value {
+	n := c.number(0)
+	if n < 0 {
+		return ErrorValue(ErrNum)
+	}
+	return NumberValue(math.Sqrt(n))
+}
+
+func pi(c *Call) Value {
+	return NumberValue(math.Pi)
+}
+
+func power(c *Call) Value {
+	n, p := c.number(0), c.number(1)
+	if n == 0 && p == 0 {
+		return ErrorValue(ErrNum)
+	}
+	if n == 0 && p < 0 {
+		return ErrorValue(ErrDiv0)
+	}
+	return NumberValue(math.Pow(n, p))
+}
+
+func mod(c *Call) Value {
+	n, d := c.number(0), c.number(1)
+	if d == 0 {
+		return ErrorValue(ErrDiv0)
+	}
+	return NumberValue(n - d*math.Floor(n/d))
+}
+
+func quotient(c *Call) Value {
+	n, d := c.number(0), c.number(1)
+	if d == 0 {
+		return ErrorValue(ErrDiv0)
+	}
+	return NumberValue(math.Trunc(n / d))
+}
+
+func fact(c *Call) Value {
+	n := math.Trunc(c.number(0))
+	if n < 0 || n > 170 {
+		return ErrorValue(ErrNum)
+	}
+	f := 1.0
+	for i := 2.0; i <= n; i++ {
+		f *= i
+	}
+	return NumberValue(f)
+}
+
+// roundUp rounds away from zero.
+func roundUp(f float64) float64 {
+	if f < 0 {
+		return -math.Ceil(-f)
+	}
+	return math.Ceil(f)
+}
+
+// round makes a function that rounds a number to a number of digits
+// with f, which rounds to a whole number.  The number is first rounded
+// to 15 significant digits, so that 2.675 is taken as it is written
+// rather than as the binary number just below it.
+func round(f func(float64) float64) Func {
+	return func(c *Call) Value {
+		n, digits := c.number(0), c.intOr(1, 0)
+		scale := math.Pow(10, float64(digits))
+		x := significant(n*scale, 15)
+		return NumberValue(f(x) / scale)
+	}
+}
+
+// significant rounds f to n significant digits.
+func significant(f float64, n int) float64 {
+	if f == 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return f
+	}
+	exp := math.Ceil(math.Log10(math.Abs(f)))
+	scale := math.Pow(10, float64(n)-exp)
+	return math.Round(f*scale) / scale
+}
+
+func trunc(c *Call) Value {
+	n, digits := c.number(0), c.intOr(1, 0)
+	scale := math.Pow(10, float64(digits))
+	return NumberValue(math.Trunc(significant(n*scale, 15)) / scale)
+}
+
+// ceiling rounds away from zero to a multiple of the significance,
+// which is 1 by default.
+func ceiling(c *Call) Value {
+	n, s := c.number(0), c.numberOr(1, 1)
+	if s == 0 || n == 0 {
+		return NumberValue(0)
+	}
+	if n > 0 && s < 0 {
+		return ErrorValue(ErrNum)
+	}
+	return NumberValue(roundUp(significant(n/s, 15)) * s)
+}
+
+// floor rounds towards zero to a multiple of the significance.
+func floor(c *Call) Value {
+	n, s := c.number(0), c.numberOr(1, 1)
+	if n == 0 {
+		return NumberValue(0)
+	}
+	if s == 0 {
+		return ErrorValue(ErrDiv0)
+	}
+	if n > 0 && s < 0 {
+		return ErrorValue(ErrNum)
+	}
+	return NumberValue(math.Trunc(significant(n/s, 15)) * s)
+}
+
+func even(c *Call) Value {
+	n := roundUp(c.number(0))
+	if math.Mod(n, 2) != 0 {
+		n += signum(n)
+	}
+	return NumberValue(n)
+}
+
+func odd(c *Call) Value {
+	n := roundUp(c.number(0))
+	if math.Mod(n, 2) == 0 {
+		if n == 0 {
+			return NumberValue(1)
+		}
+		n += signum(n)
+	}
+	return NumberValue(n)
+}
+
+func sum(c *Call) Value {
+	total := 0.0
+	for _, n := range c.numbers(0) {
+		total += n
+	}
+	return NumberValue(total)
+}
+
+func sumSquares(c *Call) Value {
+	total := 0.0
+	for _, n := range c.numbers(0) {
+		total += n * n
+	}
+	return NumberValue(total)
+}
+
+func product(c *Call) Value {
+	numbers := c.numbers(0)
+	if len(numbers) == 0 {
+		return NumberValue(0)
+	}
+	p := 1.0
+	for _, n := range numbers {
+		p *= n
+	}
+	return NumberValue(p)
+}
+
+// sumProduct multiplies the items of arrays of the same size and adds
+// up the products.  Items that aren't numbers count as 0.
+func sumProduct(c *Call) Value {
+	var arrays [][]Value
+	rows, cols := dims(c.array(0))
+	for i := range c.Args {
+		a := c.array(i)
+		if r, k := dims(a); r != rows || k != cols {
+			return ErrorValue(ErrValue)
+		}
+		arrays = append(arrays, flatten(a))
+	}
+	total := 0.0
+	for j := range arrays[0] {
+		p := 1.0
+		for _, a := range arrays {
+			v := check(a[j])
+			if v.Type != ValueNumber {
+				p = 0
+				continue
+			}
+			p *= v.Number
+		}
+		total += p
+	}
+	return NumberValue(total)
+}
+
+// matching returns the items of the range argument at i whose items in
+// the criteria ranges pass the criteria that follow each of them, from
+// argument from on.  Each criteria range must have the shape of the
+// first range.
+func (c *Call) matching(i, from int) []Value {
+	values := flatten(c.array(i))
+	rows, cols := dims(c.array(i))
+	pass := make([]bool, len(values))
+	for k := range pass {
+		pass[k] = true
+	}
+	for j := from; j < len(c.Args); j += 2 {
+		if j+1 >= len(c.Args) {
+			fail(ErrValue)
+		}
+		r := c.array(j)
+		if rr, rc := dims(r); rr != rows || rc != cols {
+			fail(ErrValue)
+		}
+		test := criteria(c.scalar(j + 1))
+		for k, v := range flatten(r) {
+			if pass[k] && !test(v) {
+				pass[k] = false
+			}
+		}
+	}
+	var matched []Value
+	for k, v := range values {
+		if pass[k] {
+			matched = append(matched, v)
+		}
+	}
+	return matched
+}
+
+// matchingIf returns the items that SUMIF and AVERAGEIF take: those of
+// the range at argument 2, or of the range at argument 0 if it is left
+// out, whose items in the range at argument 0 pass the criteria.  The
+// range at argument 2 is resized to the shape of the first.
+func (c *Call) matchingIf() []Value {
+	test := criteria(c.scalar(1))
+	tested := c.array(0)
+	values := tested
+	if c.given(2) {
+		values = c.resize(c.array(2), tested)
+	}
+	var matched []Value
+	for i, row := range tested.Rows {
+		for j, v := range row {
+			if test(v) {
+				x, _ := item(values, i, j)
+				matched = append(matched, x)
+			}
+		}
+	}
+	return matched
+}
+
+// resize returns the range v made the size of the range like, starting
+// at the same cell, as SUMIF reads its sum range.
+func (c *Call) resize(v, like Value) Value {
+	rows, cols := dims(like)
+	if r, k := dims(v); v.Ref == nil || r == rows && k == cols {
+		return v
+	}
+	top, left, _, _ := v.Ref.Bounds()
+	e := &evaluation{Evaluator: c.Evaluator, sheet: c.Sheet, row: c.Row, col: c.Col}
+	return e.area(area(v.Ref.Sheet, top, left, top+rows-1, left+cols-1))
+}
+
+// numbersOf returns the numbers among values.
+func numbersOf(values []Value) []float64 {
+	var numbers []float64
+	for _, v := range values {
+		switch v.Type {
+		case ValueNumber:
+			numbers = append(numbers, v.Number)
+		case ValueError:
+			fail(v.Text)
+		}
+	}
+	return numbers
+}
+
+func sumOf(numbers []float64) float64 {
+	total := 0.0
+	for _, n := range numbers {
+		total += n
+	}
+	return total
+}
+
+func sumIf(c *Call) Value {
+	return NumberValue(sumOf(numbersOf(c.matchingIf())))
+}
+
+func sumIfs(c *Call) Value {
+	return NumberValue(sumOf(numbersOf(c.matching(0, 1))))
+}
+
+func mean(numbers []float64) Value {
+	if len(numbers) == 0 {
+		return ErrorValue(ErrDiv0)
+	}
+	return NumberValue(sumOf(numbers) / float64(len(numbers)))
+}
+
+func average(c *Call) Value {
+	return mean(c.numbers(0))
+}
+
+// averageA counts text in ranges as 0 and TRUE as 1.
+func averageA(c *Call) Value {
+	var numbers []float64
+	for _, arg := range c.Args {
+		if !fromCells(arg) {
+			f, err := toNumber(arg)
+			if err != nil {
+				fail(err.Text)
+			}
+			numbers = append(numbers, f)
+			continue
+		}
+		for _, v := range flatten(arg) {
+			switch v.Type {
+			case ValueNumber:
+				numbers = append(numbers, v.Number)
+			case ValueString:
+				numbers = append(numbers, 0)
+			case ValueBool:
+				f, _ := toNumber(v)
+				numbers = append(numbers, f)
+			case ValueError:
+				fail(v.Text)
+			}
+		}
+	}
+	return mean(numbers)
+}
+
+func averageIf(c *Call) Value {
+	return mean(numbersOf(c.matchingIf()))
+}
+
+func averageIfs(c *Call) Value {
+	return mean(numbersOf(c.matching(0, 1)))
+}
+
+// count counts the numbers of ranges and the arguments that are
+// numbers or can be read as numbers.
+func count(c *Call) Value {
+	n := 0
+	for _, arg := range c.Args {
+		if !fromCells(arg) {
+			if arg.Type != ValueError {
+				if _, err := toNumber(arg); err == nil {
+					n++
+				}
+			}
+			continue
+		}
+		for _, v := range flatten(arg) {
+			if v.Type == ValueNumber {
+				n++
+			}
+		}
+	}
+	return NumberValue(float64(n))
+}
+
+func countA(c *Call) Value {
+	n := 0
+	for _, arg := range c.Args {
+		if !fromCells(arg) {
+			n++
+			continue
+		}
+		for _, v := range flatten(arg) {
+			if v.Type != ValueBlank {
+				n++
+			}
+		}
+	}
+	return NumberValue(float64(n))
+}
+
+// countBlank counts empty cells and cells holding "".
+func countBlank(c *Call) Value {
+	v := c.arg(0)
+	if v.Ref == nil {
+		return ErrorValue(ErrValue)
+	}
+	n := 0
+	for _, x := range flatten(v) {
+		if x.Type == ValueBlank || x.Type == ValueString && x.Text == "" {
+			n++
+		}
+	}
+	return NumberValue(float64(n))
+}
+
+func countIf(c *Call) Value {
+	test := criteria(c.scalar(1))
+	n := 0
+	for _, v := range flatten(c.array(0)) {
+		if test(v) {
+			n++
+		}
+	}
+	return NumberValue(float64(n))
+}
+
+func countIfs(c *Call) Value {
+	if len(c.Args)%2 != 0 {
+		return ErrorValue(ErrValue)
+	}
+	return NumberValue(float64(len(c.matching(0, 0))))
+}
+
+func maximum(c *Call) Value {
+	return NumberValue(maxOf(c.numbers(0)))
+}
+
+func minimum(c *Call) Value {
+	return NumberValue(minOf(c.numbers(0)))
+}
+
+func maxIfs(c *Call) Value {
+	return NumberValue(maxOf(numbersOf(c.matching(0, 1))))
+}
+
+func minIfs(c *Call) Value {
+	return NumberValue(minOf(numbersOf(c.matching(0, 1))))
+}
+
+// maxOf returns the largest number, or 0 if there are none.
+func maxOf(numbers []float64) float64 {
+	if len(numbers) == 0 {
+		return 0
+	}
+	m := numbers[0]
+	for _, n := range numbers[1:] {
+		m = math.Max(m, n)
+	}
+	return m
+}
+
+// minOf returns the smallest number, or 0 if there are none.
+func minOf(numbers []float64) float64 {
+	if len(numbers) == 0 {
+		return 0
+	}
+	m := numbers[0]
+	for _, n := range numbers[1:] {
+		m = math.Min(m, n)
+	}
+	return m
+}
+
+func sorted(numbers []float64) []float64 {
+	s := append([]float64(nil), numbers...)
+	sort.Float64s(s)
+	return s
+}
+
+func median(c *Call) Value {
+	s := sorted(c.numbers(0))
+	switch {
+	case len(s) == 0:
+		return ErrorValue(ErrNum)
+	case len(s)%2 == 1:
+		return NumberValue(s[len(s)/2])
+	}
+	return NumberValue((s[len(s)/2-1] + s[len(s)/2]) / 2)
+}
+
+// mode returns the number that comes up most often, the first of them
+// if there are several, or #N/A if no number comes up twice.
+func mode(c *Call) Value {
+	numbers := c.numbers(0)
+	counts := map[float64]int{}
+	best, bestCount := 0.0, 1
+	for _, n := range numbers {
+		counts[n]++
+		if counts[n] > bestCount {
+			best, bestCount = n, counts[n]
+		}
+	}
+	if bestCount < 2 {
+		return ErrorValue(ErrNA)
+	}
+	return NumberValue(best)
+}
+
+func large(c *Call) Value {
+	s := sorted(numbersOf(flatten(c.array(0))))
+	k := int(math.Ceil(c.number(1)))
+	if k < 1 || k > len(s) {
+		return ErrorValue(ErrNum)
+	}
+	return NumberValue(s[len(s)-k])
+}
+
+func small(c *Call) Value {
+	s := sorted(numbersOf(flatten(c.array(0))))
+	k := int(math.Ceil(c.number(1)))
+	if k < 1 || k > len(s) {
+		return ErrorValue(ErrNum)
+	}
+	return NumberValue(s[k-1])
+}
+
+// rank returns the rank of a number among the numbers of a range, from
+// the largest, or from the smallest if the order isn't 0.
+func rank(c *Call) Value {
+	n := c.number(0)
+	numbers := numbersOf(flatten(c.array(1)))
+	ascending := c.numberOr(2, 0) != 0
+	r, found := 1, false
+	for _, x := range numbers {
+		switch {
+		case x == n:
+			found = true
+		case ascending && x < n, !ascending && x > n:
+			r++
+		}
+	}
+	if !found {
+		return ErrorValue(ErrNA)
+	}
+	return NumberValue(float64(r))
+}
+
+// sumOfSquares returns the sum of the squares of the differences of
+// numbers from their mean.
+func sumOfSquares(numbers []float64) float64 {
+	m := sumOf(numbers) / float64(len(numbers))
+	total := 0.0
+	for _, n := range numbers {
+		total += (n - m) * (n - m)
+	}
+	return total
+}
+
+// variance makes a function of the variance of a sample, with ddof 1,
+// or of a whole population, with ddof 0.
+func variance(ddof int) Func {
+	return func(c *Call) Value {
+		numbers := c.numbers(0)
+		if len(numbers) <= ddof {
+			return ErrorValue(ErrDiv0)
+		}
+		return NumberValue(sumOfSquares(numbers) / float64(len(numbers)-ddof))
+	}
+}
+
+func stdev(ddof int) Func {
+	v := variance(ddof)
+	return func(c *Call) Value {
+		x := v(c)
+		if x.IsError() {
+			return x
+		}
+		return NumberValue(math.Sqrt(x.Number))
+	}
+}
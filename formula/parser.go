@@ -0,0 +1,605 @@
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a formula in A1 notation, as it is stored in XLSX
+// files.  A leading = is skipped.
+func Parse(formula string) (Node, error) {
+	tokens, err := Tokenize(formula)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{formula: formula, tokens: tokens}
+	return p.parse()
+}
+
+// ParseR1C1 parses a formula in R1C1 notation, written in the cell at
+// row and col, counting from 1.  Relative references, such as R[-1]C,
+// are made into references to the cells they point at from there.
+func ParseR1C1(formula string, row, col int) (Node, error) {
+	tokens, err := TokenizeR1C1(formula)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{formula: formula, tokens: tokens, r1c1: true, row: row, col: col}
+	return p.parse()
+}
+
+type parser struct {
+	formula  string
+	tokens   []Token
+	pos      int
+	r1c1     bool
+	row, col int
+}
+
+// Operator precedences, from the loosest to the tightest.
+const (
+	precComparison = iota
+	precConcat
+	precAdd
+	precMul
+	precPower
+	precPercent
+	precPrefix
+	precUnion
+	precIntersect
+	precRange
+	precPrimary
+)
+
+var binaryPrecedence = map[string]int{
+	"=": precComparison, "<>": precComparison, "<": precComparison, ">": precComparison,
+	"<=": precComparison, ">=": precComparison,
+	"&": precConcat,
+	"+": precAdd, "-": precAdd,
+	"*": precMul, "/": precMul,
+	"^": precPower,
+	",": precUnion,
+	" ": precIntersect,
+	":": precRange,
+}
+
+func (p *parser) parse() (Node, error) {
+	if p.peek() == nil {
+		return nil, p.errorf(len(p.formula), "empty formula")
+	}
+	n, err := p.expr(precComparison, false)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t != nil {
+		return nil, p.unexpected(t)
+	}
+	return n, nil
+}
+
+func (p *parser) errorf(pos int, format string, args ...interface{}) error {
+	return &SyntaxError{Formula: p.formula, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) unexpected(t *Token) error {
+	return p.errorf(t.Pos, "unexpected %q", t.Text)
+}
+
+// peek returns the next token that isn't white space, or nil at the end
+// of the formula.
+func (p *parser) peek() *Token {
+	for i := p.pos; i < len(p.tokens); i++ {
+		if p.tokens[i].Type != TokenSpace {
+			return &p.tokens[i]
+		}
+	}
+	return nil
+}
+
+// next returns and consumes the next token that isn't white space.
+func (p *parser) next() *Token {
+	for p.pos < len(p.tokens) {
+		t := &p.tokens[p.pos]
+		p.pos++
+		if t.Type != TokenSpace {
+			return t
+		}
+	}
+	return nil
+}
+
+// expect consumes the next token, which must be of type typ.
+func (p *parser) expect(typ TokenType, what string) error {
+	t := p.next()
+	if t == nil {
+		return p.errorf(len(p.formula), "missing %s", what)
+	}
+	if t.Type != typ {
+		return p.unexpected(t)
+	}
+	return nil
+}
+
+// startsOperand reports whether t can start an operand.
+func startsOperand(t *Token) bool {
+	switch t.Type {
+	case TokenNumber, TokenString, TokenBool, TokenError, TokenRef, TokenName, TokenTable,
+		TokenFunction, TokenOpenParen, TokenOpenBrace:
+		return true
+	}
+	return false
+}
+
+// operator returns the binary or postfix operator that comes next, with
+// the number of tokens it takes up, or "" if there isn't one.  White
+// space between two operands is the intersection operator, and a comma
+// is the union operator where union is set, inside parentheses.
+func (p *parser) operator(union bool) (string, int) {
+	i := p.pos
+	for i < len(p.tokens) && p.tokens[i].Type == TokenSpace {
+		i++
+	}
+	if i == len(p.tokens) {
+		return "", 0
+	}
+	t := &p.tokens[i]
+	switch {
+	case t.Type == TokenOperator && t.Text != "@":
+		return t.Text, i - p.pos + 1
+	case t.Type == TokenComma && union:
+		return ",", i - p.pos + 1
+	case i > p.pos && startsOperand(t):
+		return " ", i - p.pos
+	}
+	return "", 0
+}
+
+// expr parses an expression whose operators bind at least as tightly as
+// prec.
+func (p *parser) expr(prec int, union bool) (Node, error) {
+	x, err := p.unary(union)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, n := p.operator(union)
+		if op == "" {
+			return x, nil
+		}
+		if op == "%" {
+			if precPercent < prec {
+				return x, nil
+			}
+			p.pos += n
+			x = &Postfix{Op: op, X: x}
+			continue
+		}
+		opPrec, ok := binaryPrecedence[op]
+		if !ok {
+			return nil, p.unexpected(&p.tokens[p.pos+n-1])
+		}
+		if opPrec < prec {
+			return x, nil
+		}
+		p.pos += n
+		y, err := p.expr(opPrec+1, union)
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Op: op, X: x, Y: y}
+	}
+}
+
+// unary parses an operand with its prefix operators and spill operator.
+func (p *parser) unary(union bool) (Node, error) {
+	t := p.peek()
+	if t != nil && t.Type == TokenOperator && (t.Text == "-" || t.Text == "+" || t.Text == "@") {
+		p.next()
+		x, err := p.expr(precPrefix, union)
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Op: t.Text, X: x}, nil
+	}
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.tokens) && p.tokens[p.pos].Type == TokenOperator && p.tokens[p.pos].Text == "#" {
+		p.pos++
+		x = &Postfix{Op: "#", X: x}
+	}
+	return x, nil
+}
+
+// primary parses an operand.
+func (p *parser) primary() (Node, error) {
+	t := p.next()
+	if t == nil {
+		return nil, p.errorf(len(p.formula), "missing operand")
+	}
+	switch t.Type {
+	case TokenNumber:
+		v, err := strconv.ParseFloat(t.Text, 64)
+		if err != nil {
+			return nil, p.errorf(t.Pos, "invalid number %q", t.Text)
+		}
+		return &Number{Value: v, Text: t.Text}, nil
+	case TokenString:
+		return &String{Value: unquote(t.Text, '"')}, nil
+	case TokenBool:
+		return &Bool{Value: strings.EqualFold(t.Text, "TRUE")}, nil
+	case TokenError:
+		return &Error{Value: errorValue(t.Text)}, nil
+	case TokenRef, TokenName, TokenTable:
+		return p.operand(t)
+	case TokenFunction:
+		return p.function(t)
+	case TokenOpenParen:
+		x, err := p.expr(precComparison, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(TokenCloseParen, ")"); err != nil {
+			return nil, err
+		}
+		return &Paren{X: x}, nil
+	case TokenOpenBrace:
+		return p.array()
+	}
+	return nil, p.unexpected(t)
+}
+
+// operand parses a reference, name or structured reference token.
+func (p *parser) operand(t *Token) (Node, error) {
+	switch t.Type {
+	case TokenRef:
+		ref, ok := p.ref(t.Text)
+		if !ok {
+			return nil, p.errorf(t.Pos, "invalid reference %q", t.Text)
+		}
+		return ref, nil
+	case TokenName:
+		book, sheet, _, name := splitPrefix(t.Text)
+		return &Name{Book: book, Sheet: sheet, Name: name}, nil
+	case TokenTable:
+		table, ok := parseTableRef(t.Text)
+		if !ok {
+			return nil, p.errorf(t.Pos, "invalid structured reference %q", t.Text)
+		}
+		return table, nil
+	}
+	return nil, p.unexpected(t)
+}
+
+func (p *parser) function(t *Token) (Node, error) {
+	f := &Function{Name: t.Text[:len(t.Text)-1]}
+	if next := p.peek(); next != nil && next.Type == TokenCloseParen {
+		p.next()
+		return f, nil
+	}
+	for {
+		var arg Node = &Missing{}
+		if next := p.peek(); next == nil || next.Type != TokenComma && next.Type != TokenCloseParen {
+			var err error
+			if arg, err = p.expr(precComparison, false); err != nil {
+				return nil, err
+			}
+		}
+		f.Args = append(f.Args, arg)
+		next := p.next()
+		if next == nil {
+			return nil, p.errorf(len(p.formula), "missing )")
+		}
+		switch next.Type {
+		case TokenComma:
+			continue
+		case TokenCloseParen:
+			return f, nil
+		}
+		return nil, p.unexpected(next)
+	}
+}
+
+func (p *parser) array() (Node, error) {
+	a := &Array{Rows: [][]Node{nil}}
+	for {
+		x, err := p.constant()
+		if err != nil {
+			return nil, err
+		}
+		row := len(a.Rows) - 1
+		a.Rows[row] = append(a.Rows[row], x)
+		t := p.next()
+		if t == nil {
+			return nil, p.errorf(len(p.formula), "missing }")
+		}
+		switch t.Type {
+		case TokenComma:
+			continue
+		case TokenSemicolon:
+			a.Rows = append(a.Rows, nil)
+			continue
+		case TokenCloseBrace:
+			for _, r := range a.Rows {
+				if len(r) != len(a.Rows[0]) {
+					return nil, p.errorf(t.Pos, "array rows differ in length")
+				}
+			}
+			return a, nil
+		}
+		return nil, p.unexpected(t)
+	}
+}
+
+// constant parses an item of an array: a number, which may have a
+// sign, a string, TRUE or FALSE, or an error value.
+func (p *parser) constant() (Node, error) {
+	t := p.next()
+	if t == nil {
+		return nil, p.errorf(len(p.formula), "missing }")
+	}
+	switch t.Type {
+	case TokenNumber, TokenString, TokenBool, TokenError:
+		p.pos--
+		return p.primary()
+	case TokenOperator:
+		if t.Text != "-" && t.Text != "+" {
+			break
+		}
+		n := p.next()
+		if n == nil || n.Type != TokenNumber {
+			break
+		}
+		p.pos--
+		x, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Op: t.Text, X: x}, nil
+	}
+	return nil, p.errorf(t.Pos, "array items must be numbers, strings, logical values or errors")
+}
+
+// ref parses the text of a reference token.
+func (p *parser) ref(text string) (*Ref, bool) {
+	book, sheet, lastSheet, body := splitPrefix(text)
+	r := &Ref{Book: book, Sheet: sheet, LastSheet: lastSheet}
+	if strings.EqualFold(body, "#REF!") {
+		r.Invalid = true
+		return r, true
+	}
+	from, to := body, ""
+	if i := strings.IndexByte(body, ':'); i >= 0 {
+		from, to = body[:i], body[i+1:]
+	}
+	cell := p.a1Cell
+	if p.r1c1 {
+		cell = p.r1c1Cell
+	}
+	var ok bool
+	if r.From, ok = cell(from); !ok {
+		return nil, false
+	}
+	if to != "" {
+		c, ok := cell(to)
+		if !ok {
+			return nil, false
+		}
+		r.To = &c
+	}
+	if r.To == nil && (r.From.Row == 0 || r.From.Col == 0) {
+		// A lone row or column, as R1C1 notation allows, is a
+		// range of one.
+		to := r.From
+		r.To = &to
+	}
+	return r, true
+}
+
+// a1Cell parses a cell, a column or a row in A1 notation, such as $A1,
+// $A or 1.
+func (p *parser) a1Cell(s string) (Cell, bool) {
+	var c Cell
+	if strings.HasPrefix(s, "$") && len(s) > 1 && isLetter(s[1]) {
+		c.ColAbs = true
+		s = s[1:]
+	}
+	n := 0
+	for n < len(s) && isLetter(s[n]) {
+		n++
+	}
+	c.Col = colNumber(s[:n])
+	if n == 0 {
+		c.ColAbs = false
+	}
+	s = s[n:]
+	if strings.HasPrefix(s, "$") {
+		c.RowAbs = true
+		s = s[1:]
+	}
+	if s != "" {
+		row, err := strconv.Atoi(s)
+		if err != nil {
+			return c, false
+		}
+		c.Row = row
+	}
+	if c.Row > MaxRow || c.Col > MaxCol || c.Row == 0 && c.Col == 0 {
+		return c, false
+	}
+	return c, true
+}
+
+// r1c1Cell parses a cell, a column or a row in R1C1 notation, such as
+// R1C[-1], C2 or R, making relative rows and columns into the ones they
+// point at from the parser's cell.
+func (p *parser) r1c1Cell(s string) (Cell, bool) {
+	var c Cell
+	var ok bool
+	if s != "" && (s[0] == 'R' || s[0] == 'r') {
+		n := r1c1RowLength(s)
+		if c.Row, c.RowAbs, ok = r1c1Part(s[1:n], p.row); !ok || c.Row > MaxRow {
+			return c, false
+		}
+		s = s[n:]
+	}
+	if s != "" {
+		if c.Col, c.ColAbs, ok = r1c1Part(s[1:], p.col); !ok || c.Col > MaxCol {
+			return c, false
+		}
+	}
+	return c, true
+}
+
+// r1c1Part returns the row or column written s after the R or C, where
+// base is the row or column of the formula's cell.
+func r1c1Part(s string, base int) (int, bool, bool) {
+	switch {
+	case s == "":
+		return base, false, base > 0
+	case s[0] == '[':
+		offset, err := strconv.Atoi(s[1 : len(s)-1])
+		if err != nil || base+offset < 1 {
+			return 0, false, false
+		}
+		return base + offset, false, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, false, false
+	}
+	return n, true, true
+}
+
+// splitPrefix splits the book and sheet prefix off the text of a
+// reference or a name.
+func splitPrefix(text string) (book, sheet, lastSheet, rest string) {
+	end := sheetPrefixLength(text)
+	if end == 0 {
+		return "", "", "", text
+	}
+	prefix := text[:end-1]
+	rest = text[end:]
+	if strings.HasPrefix(prefix, "'") {
+		prefix = unquote(prefix, '\'')
+	}
+	if i := strings.LastIndexByte(prefix, ']'); i >= 0 {
+		book, prefix = prefix[:i+1], prefix[i+1:]
+	}
+	sheet = prefix
+	if i := strings.IndexByte(prefix, ':'); i >= 0 {
+		sheet, lastSheet = prefix[:i], prefix[i+1:]
+	}
+	return book, sheet, lastSheet, rest
+}
+
+// unquote removes the quotes around s and undoes doubled quotes.
+func unquote(s string, quote byte) string {
+	if len(s) >= 2 && s[0] == quote && s[len(s)-1] == quote {
+		s = s[1 : len(s)-1]
+	}
+	q := string(quote)
+	return strings.Replace(s, q+q, q, -1)
+}
+
+// errorValue returns the error value s in its usual case.
+func errorValue(s string) string {
+	for _, e := range errorValues {
+		if strings.EqualFold(s, e) {
+			return e
+		}
+	}
+	return s
+}
+
+// tableItems are the special items of structured references.
+var tableItems = []string{"#All", "#Data", "#Headers", "#Totals", "#This Row"}
+
+// parseTableRef parses a structured reference, such as Table1[Qty],
+// Table1[[#Headers],[Qty]:[Price]] or [@Qty].
+func parseTableRef(text string) (*TableRef, bool) {
+	open := strings.IndexByte(text, '[')
+	if open < 0 || !strings.HasSuffix(text, "]") {
+		return nil, false
+	}
+	t := &TableRef{Table: text[:open]}
+	spec := strings.TrimSpace(text[open+1 : len(text)-1])
+	if strings.HasPrefix(spec, "@") {
+		t.At = true
+		spec = strings.TrimSpace(spec[1:])
+		if spec == "" {
+			return t, true
+		}
+	}
+	if !strings.HasPrefix(spec, "[") {
+		// The simple form, with one item or column.
+		if spec != "" && !t.addSpecifier(spec) {
+			return nil, false
+		}
+		return t, true
+	}
+	rangeNext := false
+	for spec != "" {
+		n, ok := bracketLength(spec)
+		if !ok {
+			return nil, false
+		}
+		item := spec[1 : n-1]
+		if rangeNext {
+			if len(t.Columns) != 1 || strings.HasPrefix(item, "#") {
+				return nil, false
+			}
+			t.Columns = append(t.Columns, unescapeColumn(item))
+			rangeNext = false
+		} else if !t.addSpecifier(item) {
+			return nil, false
+		}
+		spec = strings.TrimSpace(spec[n:])
+		switch {
+		case spec == "":
+		case spec[0] == ',':
+			spec = strings.TrimSpace(spec[1:])
+		case spec[0] == ':':
+			spec = strings.TrimSpace(spec[1:])
+			rangeNext = true
+		default:
+			return nil, false
+		}
+	}
+	return t, !rangeNext
+}
+
+// addSpecifier adds a special item or a column to the reference.
+func (t *TableRef) addSpecifier(s string) bool {
+	if strings.HasPrefix(s, "#") {
+		for _, item := range tableItems {
+			if strings.EqualFold(s, item) {
+				t.Items = append(t.Items, item)
+				return true
+			}
+		}
+		return false
+	}
+	if len(t.Columns) > 0 {
+		return false
+	}
+	t.Columns = append(t.Columns, unescapeColumn(s))
+	return true
+}
+
+// unescapeColumn undoes the ' escapes of a column name.
+func unescapeColumn(s string) string {
+	if !strings.Contains(s, "'") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
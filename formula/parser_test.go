@@ -0,0 +1,129 @@
+package formula
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type ParserSuite struct{}
+
+var _ = Suite(&ParserSuite{})
+
+func (s *ParserSuite) TestParseRef(c *C) {
+	n, err := Parse("'[Book 1.xlsx]My ''Sheet'''!$B3:C$4")
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Ref{
+		Book:  "[Book 1.xlsx]",
+		Sheet: "My 'Sheet'",
+		From:  Cell{Row: 3, Col: 2, ColAbs: true},
+		To:    &Cell{Row: 4, Col: 3, RowAbs: true},
+	})
+
+	n, err = Parse("Sheet1:Sheet3!A:$C")
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Ref{Sheet: "Sheet1", LastSheet: "Sheet3", From: Cell{Col: 1}, To: &Cell{Col: 3, ColAbs: true}})
+
+	n, err = Parse("$2:3")
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Ref{From: Cell{Row: 2, RowAbs: true}, To: &Cell{Row: 3}})
+
+	n, err = Parse("Sheet1!#REF!")
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Ref{Sheet: "Sheet1", Invalid: true})
+
+	n, err = Parse("[1]!Rate")
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Name{Book: "[1]", Name: "Rate"})
+}
+
+func (s *ParserSuite) TestParseR1C1(c *C) {
+	n, err := ParseR1C1("R[-1]C:R5C[2]", 3, 4)
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Ref{From: Cell{Row: 2, Col: 4}, To: &Cell{Row: 5, Col: 6, RowAbs: true}})
+
+	n, err = ParseR1C1("C2", 3, 4)
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Ref{From: Cell{Col: 2, ColAbs: true}, To: &Cell{Col: 2, ColAbs: true}})
+
+	_, err = ParseR1C1("R[-3]C", 3, 4)
+	c.Assert(err, ErrorMatches, `formula: invalid reference "R\[-3\]C" .*`)
+}
+
+func (s *ParserSuite) TestParseTableRef(c *C) {
+	cases := []struct {
+		formula  string
+		expected *TableRef
+	}{
+		{"Table1[]", &TableRef{Table: "Table1"}},
+		{"Table1[Qty]", &TableRef{Table: "Table1", Columns: []string{"Qty"}}},
+		{"Table1[#totals]", &TableRef{Table: "Table1", Items: []string{"#Totals"}}},
+		{"Table1[[#Headers],[#Data],[Unit']s]:[Price]]", &TableRef{Table: "Table1",
+			Items: []string{"#Headers", "#Data"}, Columns: []string{"Unit]s", "Price"}}},
+		{"[@Qty]", &TableRef{At: true, Columns: []string{"Qty"}}},
+		{"Table1[@[Unit Price]]", &TableRef{Table: "Table1", At: true, Columns: []string{"Unit Price"}}},
+		{"Table1[[#This Row],[Qty]]", &TableRef{Table: "Table1", Items: []string{"#This Row"}, Columns: []string{"Qty"}}},
+	}
+	for _, test := range cases {
+		n, err := Parse(test.formula)
+		c.Assert(err, IsNil, Commentf(test.formula))
+		c.Assert(n, DeepEquals, test.expected, Commentf(test.formula))
+	}
+	_, err := Parse("Table1[[Qty]:[#Totals]]")
+	c.Assert(err, ErrorMatches, "formula: invalid structured reference .*")
+}
+
+func (s *ParserSuite) TestParseExpressions(c *C) {
+	n, err := Parse("=-2^2+3*4%&\"x\"")
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Binary{Op: "&",
+		X: &Binary{Op: "+",
+			X: &Binary{Op: "^", X: &Unary{Op: "-", X: &Number{Value: 2, Text: "2"}}, Y: &Number{Value: 2, Text: "2"}},
+			Y: &Binary{Op: "*", X: &Number{Value: 3, Text: "3"}, Y: &Postfix{Op: "%", X: &Number{Value: 4, Text: "4"}}},
+		},
+		Y: &String{Value: "x"},
+	})
+
+	n, err = Parse("IF(A1, ,{1,-2;\"a\",#N/A})")
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Function{Name: "IF", Args: []Node{
+		&Ref{From: Cell{Row: 1, Col: 1}},
+		&Missing{},
+		&Array{Rows: [][]Node{
+			{&Number{Value: 1, Text: "1"}, &Unary{Op: "-", X: &Number{Value: 2, Text: "2"}}},
+			{&String{Value: "a"}, &Error{Value: "#N/A"}},
+		}},
+	}})
+
+	n, err = Parse("SUM((A1:B2 B1, C3))")
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Function{Name: "SUM", Args: []Node{&Paren{X: &Binary{Op: ",",
+		X: &Binary{Op: " ",
+			X: &Ref{From: Cell{Row: 1, Col: 1}, To: &Cell{Row: 2, Col: 2}},
+			Y: &Ref{From: Cell{Row: 1, Col: 2}},
+		},
+		Y: &Ref{From: Cell{Row: 3, Col: 3}},
+	}}}})
+
+	n, err = Parse("@A1:A3 = TRUE")
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, &Binary{Op: "=",
+		X: &Unary{Op: "@", X: &Ref{From: Cell{Row: 1, Col: 1}, To: &Cell{Row: 3, Col: 1}}},
+		Y: &Bool{Value: true},
+	})
+}
+
+func (s *ParserSuite) TestParseErrors(c *C) {
+	cases := []struct{ formula, message string }{
+		{"", "empty formula"},
+		{"1+", "missing operand"},
+		{"SUM(1", "missing \\)"},
+		{"(1", "missing \\)"},
+		{"1 2)", `unexpected "\)"`},
+		{"{1,A1}", "array items must be numbers, strings, logical values or errors"},
+		{"{1,2;3}", "array rows differ in length"},
+		{"1,2", `unexpected ","`},
+	}
+	for _, test := range cases {
+		_, err := Parse(test.formula)
+		c.Assert(err, ErrorMatches, "formula: "+test.message+" at offset .*", Commentf(test.formula))
+	}
+}
@@ -0,0 +1,377 @@
+package formula
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Format returns the text of a formula in A1 notation, without a
+// leading =.  Parentheses are added where the tree needs them.
+func Format(n Node) string {
+	var p printer
+	p.node(n)
+	return p.String()
+}
+
+// FormatR1C1 returns the text of a formula in R1C1 notation, for the
+// cell at row and col, counting from 1.
+func FormatR1C1(n Node, row, col int) string {
+	p := printer{r1c1: true, row: row, col: col}
+	p.node(n)
+	return p.String()
+}
+
+// Rewrite calls fn for each reference, name and structured reference of
+// a formula in A1 notation and puts the text of the node it returns in
+// place of the old one.  The rest of the formula is kept as it is
+// written.  If fn returns nil, the reference is left alone.
+func Rewrite(formula string, fn func(Node) Node) (string, error) {
+	tokens, err := Tokenize(formula)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if strings.HasPrefix(formula, "=") {
+		b.WriteByte('=')
+	}
+	p := &parser{formula: formula, tokens: tokens}
+	for i := range tokens {
+		t := &tokens[i]
+		switch t.Type {
+		case TokenRef, TokenName, TokenTable:
+			n, err := p.operand(t)
+			if err != nil {
+				return "", err
+			}
+			if n = fn(n); n != nil {
+				b.WriteString(Format(n))
+				continue
+			}
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String(), nil
+}
+
+func (n *Number) String() string   { return Format(n) }
+func (n *String) String() string   { return Format(n) }
+func (n *Bool) String() string     { return Format(n) }
+func (n *Error) String() string    { return Format(n) }
+func (n *Array) String() string    { return Format(n) }
+func (n *Ref) String() string      { return Format(n) }
+func (n *Name) String() string     { return Format(n) }
+func (n *TableRef) String() string { return Format(n) }
+func (n *Function) String() string { return Format(n) }
+func (n *Missing) String() string  { return Format(n) }
+func (n *Unary) String() string    { return Format(n) }
+func (n *Postfix) String() string  { return Format(n) }
+func (n *Binary) String() string   { return Format(n) }
+func (n *Paren) String() string    { return Format(n) }
+
+type printer struct {
+	strings.Builder
+	r1c1     bool
+	row, col int
+}
+
+// precedence returns how tightly the operator at the top of n binds.
+func precedence(n Node) int {
+	switch n := n.(type) {
+	case *Binary:
+		return binaryPrecedence[n.Op]
+	case *Unary:
+		return precPrefix
+	case *Postfix:
+		if n.Op == "%" {
+			return precPercent
+		}
+	}
+	return precPrimary
+}
+
+// operand prints the operand x of an operator binding as tightly as
+// prec, in parentheses if x binds less tightly.
+func (p *printer) operand(x Node, prec int) {
+	if precedence(x) < prec {
+		p.WriteByte('(')
+		p.node(x)
+		p.WriteByte(')')
+		return
+	}
+	p.node(x)
+}
+
+func (p *printer) node(n Node) {
+	switch n := n.(type) {
+	case *Number:
+		if n.Text != "" {
+			p.WriteString(n.Text)
+		} else {
+			p.WriteString(strconv.FormatFloat(n.Value, 'G', -1, 64))
+		}
+	case *String:
+		p.WriteString(`"` + strings.Replace(n.Value, `"`, `""`, -1) + `"`)
+	case *Bool:
+		if n.Value {
+			p.WriteString("TRUE")
+		} else {
+			p.WriteString("FALSE")
+		}
+	case *Error:
+		p.WriteString(n.Value)
+	case *Array:
+		p.WriteByte('{')
+		for i, row := range n.Rows {
+			if i > 0 {
+				p.WriteByte(';')
+			}
+			for j, x := range row {
+				if j > 0 {
+					p.WriteByte(',')
+				}
+				p.node(x)
+			}
+		}
+		p.WriteByte('}')
+	case *Ref:
+		p.ref(n)
+	case *Name:
+		p.prefix(n.Book, n.Sheet, "")
+		p.WriteString(n.Name)
+	case *TableRef:
+		p.tableRef(n)
+	case *Function:
+		p.WriteString(n.Name)
+		p.WriteByte('(')
+		for i, x := range n.Args {
+			if i > 0 {
+				p.WriteByte(',')
+			}
+			p.node(x)
+		}
+		p.WriteByte(')')
+	case *Missing:
+	case *Unary:
+		p.WriteString(n.Op)
+		p.operand(n.X, precPrefix)
+	case *Postfix:
+		p.operand(n.X, precedence(n))
+		p.WriteString(n.Op)
+	case *Binary:
+		if n.Op == "," {
+			// A union is only read as one inside parentheses.
+			p.WriteByte('(')
+			p.binary(n)
+			p.WriteByte(')')
+			return
+		}
+		p.binary(n)
+	case *Paren:
+		p.WriteByte('(')
+		if x, ok := n.X.(*Binary); ok {
+			p.binary(x)
+		} else {
+			p.node(n.X)
+		}
+		p.WriteByte(')')
+	}
+}
+
+// binary prints a binary operator without the parentheses a union
+// needs, which a union that is the left operand of another shares.
+func (p *printer) binary(n *Binary) {
+	prec := binaryPrecedence[n.Op]
+	if x, ok := n.X.(*Binary); ok && n.Op == "," && x.Op == "," {
+		p.binary(x)
+	} else {
+		p.operand(n.X, prec)
+	}
+	p.WriteString(n.Op)
+	p.operand(n.Y, prec+1)
+}
+
+// prefix prints the book and sheet before a reference or a name.
+func (p *printer) prefix(book, sheet, lastSheet string) {
+	if book == "" && sheet == "" {
+		return
+	}
+	s := book + sheet
+	if lastSheet != "" {
+		s += ":" + lastSheet
+	}
+	if needsQuotes(sheet) || needsQuotes(lastSheet) || strings.ContainsAny(book, " \\/:'") {
+		s = "'" + strings.Replace(s, "'", "''", -1) + "'"
+	}
+	p.WriteString(s)
+	p.WriteByte('!')
+}
+
+// needsQuotes reports whether a sheet name must be quoted in a
+// formula: if it holds anything but letters, digits, _ and ., starts
+// with a digit, or could be taken for a reference.
+func needsQuotes(sheet string) bool {
+	if sheet == "" {
+		return false
+	}
+	for i, r := range sheet {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+			return true
+		}
+		if i == 0 && unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return referenceLength(sheet, false) == len(sheet) || referenceLength(sheet, true) == len(sheet) ||
+		strings.EqualFold(sheet, "TRUE") || strings.EqualFold(sheet, "FALSE")
+}
+
+func (p *printer) ref(r *Ref) {
+	p.prefix(r.Book, r.Sheet, r.LastSheet)
+	if r.Invalid {
+		p.WriteString("#REF!")
+		return
+	}
+	from, to := r.From, r.To
+	if !p.r1c1 && to != nil && (from.Row == 0 || from.Col == 0) {
+		// Whole rows and columns are written without the other part.
+		p.a1Cell(from)
+		p.WriteByte(':')
+		p.a1Cell(*to)
+		return
+	}
+	if p.r1c1 && to != nil && *to == from && (from.Row == 0 || from.Col == 0) {
+		p.r1c1Cell(from)
+		return
+	}
+	cell := p.a1Cell
+	if p.r1c1 {
+		cell = p.r1c1Cell
+	}
+	cell(from)
+	if to != nil {
+		p.WriteByte(':')
+		cell(*to)
+	}
+}
+
+func (p *printer) a1Cell(c Cell) {
+	if c.Col > 0 {
+		if c.ColAbs {
+			p.WriteByte('$')
+		}
+		p.WriteString(ColumnName(c.Col))
+	}
+	if c.Row > 0 {
+		if c.RowAbs {
+			p.WriteByte('$')
+		}
+		p.WriteString(strconv.Itoa(c.Row))
+	}
+}
+
+func (p *printer) r1c1Cell(c Cell) {
+	part := func(letter byte, n, base int, abs bool) {
+		if n == 0 {
+			return
+		}
+		p.WriteByte(letter)
+		switch {
+		case abs:
+			p.WriteString(strconv.Itoa(n))
+		case n != base:
+			p.WriteString("[" + strconv.Itoa(n-base) + "]")
+		}
+	}
+	part('R', c.Row, p.row, c.RowAbs)
+	part('C', c.Col, p.col, c.ColAbs)
+}
+
+func (p *printer) tableRef(t *TableRef) {
+	p.WriteString(t.Table)
+	p.WriteByte('[')
+	defer p.WriteByte(']')
+	if t.At {
+		p.WriteByte('@')
+		switch {
+		case len(t.Columns) == 1 && isSimpleColumn(t.Columns[0]):
+			p.WriteString(t.Columns[0])
+		case len(t.Columns) > 0:
+			p.tableColumns(t.Columns)
+		}
+		return
+	}
+	switch {
+	case len(t.Items)+len(t.Columns) == 0:
+	case len(t.Items) == 1 && len(t.Columns) == 0:
+		p.WriteString(t.Items[0])
+	case len(t.Items) == 0 && len(t.Columns) == 1:
+		p.WriteString(escapeColumn(t.Columns[0]))
+	default:
+		for i, item := range t.Items {
+			if i > 0 {
+				p.WriteByte(',')
+			}
+			p.WriteString("[" + item + "]")
+		}
+		if len(t.Columns) > 0 {
+			if len(t.Items) > 0 {
+				p.WriteByte(',')
+			}
+			p.tableColumns(t.Columns)
+		}
+	}
+}
+
+// tableColumns prints a column, or a range of columns, in brackets.
+func (p *printer) tableColumns(columns []string) {
+	for i, c := range columns {
+		if i > 0 {
+			p.WriteByte(':')
+		}
+		p.WriteString("[" + escapeColumn(c) + "]")
+	}
+}
+
+// isSimpleColumn reports whether a column name can be written after @
+// without brackets.
+func isSimpleColumn(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// escapeColumn escapes the characters of a column name that have a
+// meaning in structured references.
+func escapeColumn(s string) string {
+	if !strings.ContainsAny(s, "[]#'") {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("[]#'", r) {
+			b.WriteByte('\'')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ColumnName returns the letters of the column col, counting from 1
+// for A.
+func ColumnName(col int) string {
+	var b []byte
+	for ; col > 0; col = (col - 1) / 26 {
+		b = append([]byte{byte('A' + (col-1)%26)}, b...)
+	}
+	return string(b)
+}
+
+// CellName returns the name of the cell at row and col, counting from
+// 1, such as B3.
+func CellName(row, col int) string {
+	return ColumnName(col) + strconv.Itoa(row)
+}
@@ -0,0 +1,93 @@
+package formula
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type PrinterSuite struct{}
+
+var _ = Suite(&PrinterSuite{})
+
+// Formulas print back as they are written, less white space.
+func (s *PrinterSuite) TestFormat(c *C) {
+	for _, formula := range []string{
+		"SUM(A1:B2,Sheet1!$C$3)*-2^2%",
+		"'My Sheet'!A1+'[Book 1.xlsx]Sheet1'!B:B+[1]Sheet2!$1:$3",
+		`IF(A1,,"say ""hi""")`,
+		"Table1[[#Headers],[Qty]:[Price]]+[@Qty]+T[@[Unit Price]]+T[#All]+T[Unit'#]",
+		"{1,-2;\"a\",TRUE}",
+		"SUM((A1,B2,C3),A1:A3 A2:C2)",
+		"'Sheet 1:Sheet 3'!A1",
+		"A1#+@B1:B3",
+		"Sheet1!#REF!+#REF!",
+		"_xlfn.CONCAT(\"a\",[1]!Rate,Sheet1!Rate)",
+		"(1+2)*3",
+	} {
+		n, err := Parse(formula)
+		c.Assert(err, IsNil, Commentf(formula))
+		c.Assert(Format(n), Equals, formula)
+	}
+	n, err := Parse("= SUM( A1 , 2 ) ")
+	c.Assert(err, IsNil)
+	c.Assert(n.String(), Equals, "SUM(A1,2)")
+}
+
+// Trees that are built rather than parsed get the parentheses they
+// need.
+func (s *PrinterSuite) TestFormatParentheses(c *C) {
+	one, two := &Number{Value: 1}, &Number{Value: 2.5}
+	c.Assert(Format(&Binary{Op: "*", X: &Binary{Op: "+", X: one, Y: two}, Y: one}), Equals, "(1+2.5)*1")
+	c.Assert(Format(&Binary{Op: "-", X: one, Y: &Binary{Op: "-", X: two, Y: one}}), Equals, "1-(2.5-1)")
+	c.Assert(Format(&Unary{Op: "-", X: &Binary{Op: "^", X: one, Y: two}}), Equals, "-(1^2.5)")
+	c.Assert(Format(&Function{Name: "SUM", Args: []Node{&Binary{Op: ",", X: one, Y: two}}}), Equals, "SUM((1,2.5))")
+}
+
+func (s *PrinterSuite) TestQuoteSheetNames(c *C) {
+	cases := map[string]string{
+		"Sheet1":  "Sheet1!A1",
+		"A1":      "'A1'!A1",
+		"RC":      "'RC'!A1",
+		"1Sheet":  "'1Sheet'!A1",
+		"Bob's":   "'Bob''s'!A1",
+		"Été_2.0": "Été_2.0!A1",
+	}
+	for sheet, expected := range cases {
+		c.Assert(Format(&Ref{Sheet: sheet, From: Cell{Row: 1, Col: 1}}), Equals, expected)
+	}
+}
+
+func (s *PrinterSuite) TestFormatR1C1(c *C) {
+	n, err := Parse("A1+$B2:C$3+Sheet1!D:D+2:2")
+	c.Assert(err, IsNil)
+	c.Assert(FormatR1C1(n, 2, 2), Equals, "R[-1]C[-1]+RC2:R3C[1]+Sheet1!C[2]+R")
+
+	n, err = ParseR1C1("R[-1]C[-1]+RC2:R3C[1]+Sheet1!C[2]+R", 2, 2)
+	c.Assert(err, IsNil)
+	c.Assert(Format(n), Equals, "A1+$B2:C$3+Sheet1!D:D+2:2")
+}
+
+func (s *PrinterSuite) TestRewrite(c *C) {
+	res, err := Rewrite(`= SUM( A1:B2 , Sheet1!C3 ) & "A1" & Rate`, func(n Node) Node {
+		switch n := n.(type) {
+		case *Ref:
+			shifted := n.Shift(1, 1)
+			return &shifted
+		case *Name:
+			return &Name{Sheet: "Sheet2", Name: n.Name}
+		}
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, `= SUM( B2:C3 , Sheet1!D4 ) & "A1" & Sheet2!Rate`)
+
+	_, err = Rewrite(`"abc`, func(n Node) Node { return n })
+	c.Assert(err, NotNil)
+}
+
+func (s *PrinterSuite) TestColumnName(c *C) {
+	c.Assert(ColumnName(1), Equals, "A")
+	c.Assert(ColumnName(26), Equals, "Z")
+	c.Assert(ColumnName(27), Equals, "AA")
+	c.Assert(ColumnName(MaxCol), Equals, "XFD")
+	c.Assert(CellName(3, 28), Equals, "AB3")
+}
@@ -0,0 +1,277 @@
+package formula
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	register(map[string]function{
+		"CHAR":        {char, 1, 1},
+		"CLEAN":       {clean, 1, 1},
+		"CODE":        {code, 1, 1},
+		"CONCAT":      {concat, 1, -1},
+		"CONCATENATE": {concatenate, 1, -1},
+		"EXACT":       {exact, 2, 2},
+		"FIND":        {find(false), 2, 3},
+		"LEFT":        {left, 1, 2},
+		"LEN":         {length, 1, 1},
+		"LOWER":       {text1(strings.ToLower), 1, 1},
+		"MID":         {mid, 3, 3},
+		"PROPER":      {text1(proper), 1, 1},
+		"REPLACE":     {replace, 4, 4},
+		"REPT":        {rept, 2, 2},
+		"RIGHT":       {right, 1, 2},
+		"SEARCH":      {find(true), 2, 3},
+		"SUBSTITUTE":  {substitute, 3, 4},
+		"T":           {tFunc, 1, 1},
+		"TEXT":        {textFunc, 2, 2},
+		"TEXTJOIN":    {textJoin, 3, -1},
+		"TRIM":        {text1(trim), 1, 1},
+		"UPPER":       {text1(strings.ToUpper), 1, 1},
+		"VALUE":       {valueFunc, 1, 1},
+	})
+}
+
+// text1 makes a function of one text out of f.
+func text1(f func(string) string) Func {
+	return func(c *Call) Value {
+		return StringValue(f(c.text(0)))
+	}
+}
+
+// proper capitalizes the first letter of each word, and makes the
+// others lower case.
+func proper(s string) string {
+	var b strings.Builder
+	inWord := false
+	for _, r := range s {
+		if inWord {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		inWord = unicode.IsLetter(r)
+	}
+	return b.String()
+}
+
+// trim removes the spaces from the ends of text, and all but one of
+// each run of spaces within it.
+func trim(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return r == ' ' }), " ")
+}
+
+func clean(c *Call) Value {
+	return StringValue(strings.Map(func(r rune) rune {
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, c.text(0)))
+}
+
+func concat(c *Call) Value {
+	var b strings.Builder
+	for _, v := range c.values(0) {
+		b.WriteString(toText(check(v)))
+	}
+	return StringValue(b.String())
+}
+
+func concatenate(c *Call) Value {
+	var b strings.Builder
+	for i := range c.Args {
+		b.WriteString(c.text(i))
+	}
+	return StringValue(b.String())
+}
+
+// textJoin joins the texts of its arguments from the third on with a
+// delimiter, skipping empty ones if its second argument is TRUE.
+func textJoin(c *Call) Value {
+	delimiter, skipEmpty := c.text(0), c.bool(1)
+	var texts []string
+	for _, v := range c.values(2) {
+		s := toText(check(v))
+		if s == "" && skipEmpty {
+			continue
+		}
+		texts = append(texts, s)
+	}
+	return StringValue(strings.Join(texts, delimiter))
+}
+
+func exact(c *Call) Value {
+	return BoolValue(c.text(0) == c.text(1))
+}
+
+func length(c *Call) Value {
+	return NumberValue(float64(utf8.RuneCountInString(c.text(0))))
+}
+
+// chars returns argument i as a number of characters, which must not
+// be negative, or def if it is left out.
+func (c *Call) chars(i, def int) int {
+	n := c.intOr(i, def)
+	if n < 0 {
+		fail(ErrValue)
+	}
+	return n
+}
+
+func left(c *Call) Value {
+	s, n := []rune(c.text(0)), c.chars(1, 1)
+	return StringValue(string(s[:min(n, len(s))]))
+}
+
+func right(c *Call) Value {
+	s, n := []rune(c.text(0)), c.chars(1, 1)
+	return StringValue(string(s[len(s)-min(n, len(s)):]))
+}
+
+func mid(c *Call) Value {
+	s, start, n := []rune(c.text(0)), c.int(1), c.chars(2, 0)
+	if start < 1 {
+		return ErrorValue(ErrValue)
+	}
+	if start > len(s) {
+		return StringValue("")
+	}
+	return StringValue(string(s[start-1 : min(start-1+n, len(s))]))
+}
+
+func replace(c *Call) Value {
+	s, start, n, with := []rune(c.text(0)), c.int(1), c.chars(2, 0), c.text(3)
+	if start < 1 {
+		return ErrorValue(ErrValue)
+	}
+	from := min(start-1, len(s))
+	to := min(from+n, len(s))
+	return StringValue(string(s[:from]) + with + string(s[to:]))
+}
+
+func rept(c *Call) Value {
+	s, n := c.text(0), c.chars(1, 0)
+	if len(s)*n > 32767 {
+		return ErrorValue(ErrValue)
+	}
+	return StringValue(strings.Repeat(s, n))
+}
+
+// substitute replaces old text with new, or only the given occurrence
+// of it.
+func substitute(c *Call) Value {
+	s, old, with := c.text(0), c.text(1), c.text(2)
+	if old == "" {
+		return StringValue(s)
+	}
+	if !c.given(3) {
+		return StringValue(strings.Replace(s, old, with, -1))
+	}
+	nth := c.int(3)
+	if nth < 1 {
+		return ErrorValue(ErrValue)
+	}
+	at := 0
+	for i := 1; ; i++ {
+		j := strings.Index(s[at:], old)
+		if j < 0 {
+			return StringValue(s)
+		}
+		if i == nth {
+			return StringValue(s[:at+j] + with + s[at+j+len(old):])
+		}
+		at += j + len(old)
+	}
+}
+
+// find makes FIND, which looks for text as it is, or SEARCH, which
+// ignores case and knows wildcards.  Both return the position of the
+// text in characters, counting from 1.
+func find(search bool) Func {
+	return func(c *Call) Value {
+		needle, haystack := c.text(0), []rune(c.text(1))
+		start := c.intOr(2, 1)
+		if start < 1 || start > len(haystack)+1 {
+			return ErrorValue(ErrValue)
+		}
+		rest := string(haystack[start-1:])
+		var at int
+		if search {
+			loc := regexp.MustCompile("(?is)" + wildcardExpr(needle)).FindStringIndex(rest)
+			if loc == nil {
+				return ErrorValue(ErrValue)
+			}
+			at = loc[0]
+		} else {
+			at = strings.Index(rest, needle)
+			if at < 0 {
+				return ErrorValue(ErrValue)
+			}
+		}
+		return NumberValue(float64(start + utf8.RuneCountInString(rest[:at])))
+	}
+}
+
+func tFunc(c *Call) Value {
+	v := c.scalar(0)
+	if v.Type == ValueString {
+		return v
+	}
+	return StringValue("")
+}
+
+// textFunc formats a number with a number format.
+func textFunc(c *Call) Value {
+	v := c.scalar(0)
+	format := c.text(1)
+	f, err := toNumber(v)
+	if err != nil {
+		if v.Type == ValueString {
+			return v
+		}
+		return *err
+	}
+	if c.Format == nil {
+		return StringValue(formatNumber(f))
+	}
+	return StringValue(c.Format(f, format))
+}
+
+func valueFunc(c *Call) Value {
+	v := c.scalar(0)
+	switch v.Type {
+	case ValueNumber:
+		return v
+	case ValueBlank:
+		return NumberValue(0)
+	case ValueString:
+		if f, ok := parseNumber(v.Text); ok {
+			return NumberValue(f)
+		}
+		if f, ok := parseDate(v.Text, c.Date1904); ok {
+			return NumberValue(f)
+		}
+	}
+	return ErrorValue(ErrValue)
+}
+
+func char(c *Call) Value {
+	n := c.int(0)
+	if n < 1 || n > 255 {
+		return ErrorValue(ErrValue)
+	}
+	return StringValue(string(rune(n)))
+}
+
+func code(c *Call) Value {
+	s := c.text(0)
+	if s == "" {
+		return ErrorValue(ErrValue)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return NumberValue(float64(r))
+}
@@ -0,0 +1,534 @@
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenType is the kind of a Token.
+type TokenType int
+
+const (
+	TokenNumber     TokenType = iota // 1.5
+	TokenString                      // "text", with its quotes
+	TokenBool                        // TRUE or FALSE
+	TokenError                       // #DIV/0!
+	TokenRef                         // A1, $A$1:B2, A:C, 1:3, Sheet1!A1 or Sheet1!#REF!
+	TokenName                        // a defined name, such as Sheet1!Rate
+	TokenTable                       // a structured reference, such as Table1[Qty]
+	TokenFunction                    // a function name with its opening parenthesis, SUM(
+	TokenOpenParen                   // (
+	TokenCloseParen                  // )
+	TokenOpenBrace                   // {, which opens an array
+	TokenCloseBrace                  // }
+	TokenComma                       // , between arguments, array items or union operands
+	TokenSemicolon                   // ; between the rows of an array
+	TokenOperator                    // + - * / ^ & = <> < > <= >= % : @ #
+	TokenSpace                       // white space, which may be the intersection operator
+)
+
+// Token is a token of a formula.  The texts of the tokens of a formula
+// make up the formula exactly as it is written.
+type Token struct {
+	Type TokenType
+	Text string
+	Pos  int // the byte offset of the token in the formula
+}
+
+// errorValues are the error values, longest first where one is a
+// prefix of another.
+var errorValues = []string{"#NULL!", "#DIV/0!", "#VALUE!", "#REF!", "#NAME?", "#NUM!", "#N/A",
+	"#GETTING_DATA", "#SPILL!", "#CALC!", "#FIELD!", "#BLOCKED!", "#CONNECT!", "#UNKNOWN!", "#BUSY!"}
+
+// SyntaxError is an error in the text of a formula.
+type SyntaxError struct {
+	Formula string
+	Pos     int // the byte offset of the error
+	Msg     string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("formula: %s at offset %d of %q", e.Msg, e.Pos, e.Formula)
+}
+
+// Tokenize splits a formula in A1 notation into tokens.  A leading =
+// is skipped.
+func Tokenize(formula string) ([]Token, error) {
+	return tokenize(formula, false)
+}
+
+// TokenizeR1C1 splits a formula in R1C1 notation into tokens.
+func TokenizeR1C1(formula string) ([]Token, error) {
+	return tokenize(formula, true)
+}
+
+type tokenizer struct {
+	formula string
+	r1c1    bool
+	pos     int
+	tokens  []Token
+}
+
+func tokenize(formula string, r1c1 bool) ([]Token, error) {
+	t := &tokenizer{formula: formula, r1c1: r1c1}
+	if strings.HasPrefix(formula, "=") {
+		t.pos = 1
+	}
+	for t.pos < len(t.formula) {
+		if err := t.next(); err != nil {
+			return nil, err
+		}
+	}
+	return t.tokens, nil
+}
+
+func (t *tokenizer) emit(typ TokenType, n int) {
+	t.tokens = append(t.tokens, Token{Type: typ, Text: t.formula[t.pos : t.pos+n], Pos: t.pos})
+	t.pos += n
+}
+
+func (t *tokenizer) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Formula: t.formula, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// afterOperand reports whether the last token ends an operand, so that
+// what follows is an operator rather than the start of another operand.
+func (t *tokenizer) afterOperand() bool {
+	if len(t.tokens) == 0 {
+		return false
+	}
+	switch t.tokens[len(t.tokens)-1].Type {
+	case TokenNumber, TokenString, TokenBool, TokenError, TokenRef, TokenName, TokenTable,
+		TokenCloseParen, TokenCloseBrace:
+		return true
+	case TokenOperator:
+		op := t.tokens[len(t.tokens)-1].Text
+		return op == "%" || op == "#"
+	}
+	return false
+}
+
+func (t *tokenizer) next() error {
+	s := t.formula[t.pos:]
+	r, _ := utf8.DecodeRuneInString(s)
+	switch {
+	case unicode.IsSpace(r):
+		n := len(s) - len(strings.TrimLeftFunc(s, unicode.IsSpace))
+		t.emit(TokenSpace, n)
+		return nil
+	case r == '"':
+		n, ok := quotedLength(s, '"')
+		if !ok {
+			return t.errorf("unterminated string")
+		}
+		t.emit(TokenString, n)
+		return nil
+	case r == '(':
+		t.emit(TokenOpenParen, 1)
+		return nil
+	case r == ')':
+		t.emit(TokenCloseParen, 1)
+		return nil
+	case r == '{':
+		t.emit(TokenOpenBrace, 1)
+		return nil
+	case r == '}':
+		t.emit(TokenCloseBrace, 1)
+		return nil
+	case r == ',':
+		t.emit(TokenComma, 1)
+		return nil
+	case r == ';':
+		t.emit(TokenSemicolon, 1)
+		return nil
+	case r == '#':
+		for _, e := range errorValues {
+			if hasPrefixFold(s, e) {
+				t.emit(TokenError, len(e))
+				return nil
+			}
+		}
+		if t.afterOperand() {
+			t.emit(TokenOperator, 1)
+			return nil
+		}
+		return t.errorf("unknown error value")
+	case strings.ContainsRune("+-*/^&=%@:", r):
+		t.emit(TokenOperator, 1)
+		return nil
+	case r == '<':
+		if strings.HasPrefix(s, "<>") || strings.HasPrefix(s, "<=") {
+			t.emit(TokenOperator, 2)
+		} else {
+			t.emit(TokenOperator, 1)
+		}
+		return nil
+	case r == '>':
+		if strings.HasPrefix(s, ">=") {
+			t.emit(TokenOperator, 2)
+		} else {
+			t.emit(TokenOperator, 1)
+		}
+		return nil
+	}
+
+	// References, names, functions, tables and numbers.
+	if n, typ := t.scanReference(s); n > 0 {
+		t.emit(typ, n)
+		return nil
+	}
+	if n := numberLength(s); n > 0 {
+		t.emit(TokenNumber, n)
+		return nil
+	}
+	if r == '[' {
+		n, ok := bracketLength(s)
+		if !ok {
+			return t.errorf("unterminated structured reference")
+		}
+		t.emit(TokenTable, n)
+		return nil
+	}
+	if isNameStart(r) {
+		n := nameLength(s)
+		name := s[:n]
+		switch {
+		case n < len(s) && s[n] == '(':
+			t.emit(TokenFunction, n+1)
+		case n < len(s) && s[n] == '[':
+			m, ok := bracketLength(s[n:])
+			if !ok {
+				return t.errorf("unterminated structured reference")
+			}
+			t.emit(TokenTable, n+m)
+		case strings.EqualFold(name, "TRUE") || strings.EqualFold(name, "FALSE"):
+			t.emit(TokenBool, n)
+		default:
+			t.emit(TokenName, n)
+		}
+		return nil
+	}
+	return t.errorf("unexpected %q", r)
+}
+
+// scanReference returns the length of the reference or sheet
+// qualified name at the start of s, and its token type, or 0 if there
+// isn't one.
+func (t *tokenizer) scanReference(s string) (int, TokenType) {
+	prefix := sheetPrefixLength(s)
+	body := s[prefix:]
+	if prefix > 0 && hasPrefixFold(body, "#REF!") {
+		return prefix + len("#REF!"), TokenRef
+	}
+	if n := referenceLength(body, t.r1c1); n > 0 {
+		return prefix + n, TokenRef
+	}
+	if prefix == 0 {
+		return 0, 0
+	}
+	r, _ := utf8.DecodeRuneInString(body)
+	if !isNameStart(r) {
+		return 0, 0
+	}
+	return prefix + nameLength(body), TokenName
+}
+
+// sheetPrefixLength returns the length of the sheet and book prefix,
+// up to and including its !, at the start of s, or 0 if there isn't
+// one.
+func sheetPrefixLength(s string) int {
+	if strings.HasPrefix(s, "'") {
+		n, ok := quotedLength(s, '\'')
+		if ok && n < len(s) && s[n] == '!' {
+			return n + 1
+		}
+		return 0
+	}
+	n := 0
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return 0
+		}
+		n = end + 1
+		if n < len(s) && s[n] == '!' {
+			return n + 1 // a name in an external book, [1]!Rate
+		}
+	}
+	m := sheetNameLength(s[n:])
+	if m == 0 {
+		return 0
+	}
+	n += m
+	if n < len(s) && s[n] == ':' {
+		m = sheetNameLength(s[n+1:])
+		if m == 0 {
+			return 0
+		}
+		n += 1 + m
+	}
+	if n < len(s) && s[n] == '!' {
+		return n + 1
+	}
+	return 0
+}
+
+// sheetNameLength returns the length of the unquoted sheet name at the
+// start of s.
+func sheetNameLength(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// referenceLength returns the length of the cell, range, row range or
+// column range reference at the start of s, or 0 if there isn't one.
+func referenceLength(s string, r1c1 bool) int {
+	cell, col, row := a1CellLength, a1ColLength, a1RowLength
+	if r1c1 {
+		cell, col, row = r1c1CellLength, r1c1ColLength, r1c1RowLength
+	}
+	for i, scan := range []func(string) int{cell, col, row} {
+		n := scan(s)
+		if n == 0 {
+			continue
+		}
+		if n < len(s) && s[n] == ':' {
+			if m := scan(s[n+1:]); m > 0 && endsReference(s[n+1+m:]) {
+				return n + 1 + m
+			}
+		}
+		// A lone column or row, such as A or 1, is a name or a
+		// number, except in R1C1 notation.
+		if endsReference(s[n:]) && (i == 0 || r1c1) {
+			return n
+		}
+	}
+	return 0
+}
+
+// endsReference reports whether a reference may end before s: a
+// reference can't run on into a name, a function call or a table.
+func endsReference(s string) bool {
+	if s == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return !isNameChar(r) && r != '(' && r != '[' && r != '!'
+}
+
+func a1CellLength(s string) int {
+	n := a1ColLength(s)
+	if n == 0 {
+		return 0
+	}
+	m := a1RowLength(s[n:])
+	if m == 0 {
+		return 0
+	}
+	return n + m
+}
+
+// a1ColLength returns the length of the column, such as $AB, at the
+// start of s.
+func a1ColLength(s string) int {
+	n := 0
+	if strings.HasPrefix(s, "$") {
+		n++
+	}
+	start := n
+	for n < len(s) && n-start < 3 && isLetter(s[n]) {
+		n++
+	}
+	if n == start || colNumber(s[start:n]) > MaxCol {
+		return 0
+	}
+	if n < len(s) && isLetter(s[n]) {
+		return 0
+	}
+	return n
+}
+
+// a1RowLength returns the length of the row, such as $12, at the start
+// of s.
+func a1RowLength(s string) int {
+	n := 0
+	if strings.HasPrefix(s, "$") {
+		n++
+	}
+	start := n
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	if n == start {
+		return 0
+	}
+	if row, err := strconv.Atoi(s[start:n]); err != nil || row < 1 || row > MaxRow {
+		return 0
+	}
+	return n
+}
+
+func r1c1CellLength(s string) int {
+	n := r1c1RowLength(s)
+	if n == 0 {
+		return 0
+	}
+	m := r1c1ColLength(s[n:])
+	if m == 0 {
+		return 0
+	}
+	return n + m
+}
+
+func r1c1RowLength(s string) int {
+	return r1c1PartLength(s, 'R')
+}
+
+func r1c1ColLength(s string) int {
+	return r1c1PartLength(s, 'C')
+}
+
+// r1c1PartLength returns the length of the row or column, such as R,
+// R2 or R[-1], at the start of s.
+func r1c1PartLength(s string, letter byte) int {
+	if s == "" || s[0]|0x20 != letter|0x20 {
+		return 0
+	}
+	n := 1
+	switch {
+	case n < len(s) && s[n] == '[':
+		end := strings.IndexByte(s[n:], ']')
+		if end < 0 {
+			return 0
+		}
+		if _, err := strconv.Atoi(s[n+1 : n+end]); err != nil {
+			return 0
+		}
+		n += end + 1
+	default:
+		for n < len(s) && isDigit(s[n]) {
+			n++
+		}
+	}
+	return n
+}
+
+// numberLength returns the length of the number at the start of s, or
+// 0 if there isn't one.
+func numberLength(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	if n < len(s) && s[n] == '.' {
+		n++
+		for n < len(s) && isDigit(s[n]) {
+			n++
+		}
+	}
+	if n == 0 || s[:n] == "." {
+		return 0
+	}
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		m := n + 1
+		if m < len(s) && (s[m] == '+' || s[m] == '-') {
+			m++
+		}
+		if m < len(s) && isDigit(s[m]) {
+			for m < len(s) && isDigit(s[m]) {
+				m++
+			}
+			n = m
+		}
+	}
+	return n
+}
+
+// quotedLength returns the length of the text in quotes at the start
+// of s, where doubled quotes stand for one, and whether the text ends.
+func quotedLength(s string, quote byte) (int, bool) {
+	for n := 1; n < len(s); n++ {
+		if s[n] == quote {
+			if n+1 < len(s) && s[n+1] == quote {
+				n++
+				continue
+			}
+			return n + 1, true
+		}
+	}
+	return len(s), false
+}
+
+// bracketLength returns the length of the brackets at the start of s,
+// with those nested in them, and whether they are closed.  A ' escapes
+// the character after it.
+func bracketLength(s string) (int, bool) {
+	depth := 0
+	for n := 0; n < len(s); n++ {
+		switch s[n] {
+		case '\'':
+			n++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return n + 1, true
+			}
+		}
+	}
+	return len(s), false
+}
+
+// nameLength returns the length of the name at the start of s.
+func nameLength(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !isNameChar(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func isNameStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '\\'
+}
+
+func isNameChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '\\' || r == '.' || r == '?'
+}
+
+func isLetter(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// colNumber returns the number of the column with the letters s,
+// counting from 1 for A.
+func colNumber(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		n = n*26 + int(s[i]|0x20-'a') + 1
+	}
+	return n
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
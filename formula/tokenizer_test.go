@@ -0,0 +1,90 @@
+package formula
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type TokenizerSuite struct{}
+
+var _ = Suite(&TokenizerSuite{})
+
+// tokenTypes returns the texts of the tokens with their types, or the
+// error.
+func tokenTypes(c *C, formula string, r1c1 bool) []string {
+	tokenize := Tokenize
+	if r1c1 {
+		tokenize = TokenizeR1C1
+	}
+	tokens, err := tokenize(formula)
+	c.Assert(err, IsNil, Commentf(formula))
+	var texts []string
+	for _, t := range tokens {
+		texts = append(texts, tokenNames[t.Type]+" "+t.Text)
+	}
+	return texts
+}
+
+var tokenNames = map[TokenType]string{
+	TokenNumber: "number", TokenString: "string", TokenBool: "bool", TokenError: "error",
+	TokenRef: "ref", TokenName: "name", TokenTable: "table", TokenFunction: "function",
+	TokenOpenParen: "(", TokenCloseParen: ")", TokenOpenBrace: "{", TokenCloseBrace: "}",
+	TokenComma: ",", TokenSemicolon: ";", TokenOperator: "op", TokenSpace: "space",
+}
+
+func (s *TokenizerSuite) TestTokenize(c *C) {
+	cases := []struct {
+		formula string
+		tokens  []string
+	}{
+		{"=1.5E+3%", []string{"number 1.5E+3", "op %"}},
+		{`"a""b"&TRUE`, []string{`string "a""b"`, "op &", "bool TRUE"}},
+		{"SUM($A$1:B2, 3)", []string{"function SUM(", "ref $A$1:B2", ", ,", "space  ", "number 3", ") )"}},
+		{"LOG10(A1)", []string{"function LOG10(", "ref A1", ") )"}},
+		{"A:C+1:3", []string{"ref A:C", "op +", "ref 1:3"}},
+		{"Sheet1!A1*'My Sheet'!B:B", []string{"ref Sheet1!A1", "op *", "ref 'My Sheet'!B:B"}},
+		{"[1]Sheet1!A1+[1]!Rate", []string{"ref [1]Sheet1!A1", "op +", "name [1]!Rate"}},
+		{"Sheet1:Sheet3!A1", []string{"ref Sheet1:Sheet3!A1"}},
+		{"Sheet1!Rate*AB12C", []string{"name Sheet1!Rate", "op *", "name AB12C"}},
+		{"XFE1+A1048577", []string{"name XFE1", "op +", "name A1048577"}},
+		{"Table1[[#Headers],[Qty]]+[@Qty]", []string{"table Table1[[#Headers],[Qty]]", "op +", "table [@Qty]"}},
+		{"T[a']b]", []string{"table T[a']b]"}},
+		{"{1,2;3,4}", []string{"{ {", "number 1", ", ,", "number 2", "; ;", "number 3", ", ,", "number 4", "} }"}},
+		{"#DIV/0!+Sheet1!#REF!+#REF!", []string{"error #DIV/0!", "op +", "ref Sheet1!#REF!", "op +", "error #REF!"}},
+		{"A1#<>-@B1", []string{"ref A1", "op #", "op <>", "op -", "op @", "ref B1"}},
+		{"A1:A3 B2", []string{"ref A1:A3", "space  ", "ref B2"}},
+	}
+	for _, test := range cases {
+		c.Assert(tokenTypes(c, test.formula, false), DeepEquals, test.tokens, Commentf(test.formula))
+	}
+}
+
+func (s *TokenizerSuite) TestTokenizeR1C1(c *C) {
+	c.Assert(tokenTypes(c, "R[-1]C+R1C1:R2C2*SUM(R,C[2])", true), DeepEquals,
+		[]string{"ref R[-1]C", "op +", "ref R1C1:R2C2", "op *", "function SUM(", "ref R", ", ,", "ref C[2]", ") )"})
+	c.Assert(tokenTypes(c, "Rate+RC", true), DeepEquals, []string{"name Rate", "op +", "ref RC"})
+}
+
+// The texts of the tokens make up the formula.
+func (s *TokenizerSuite) TestTokenTexts(c *C) {
+	formula := "=IF( Sheet1!A1 >= 2 , {1;2} , \"x\" ) & [@Qty]"
+	tokens, err := Tokenize(formula)
+	c.Assert(err, IsNil)
+	var b strings.Builder
+	b.WriteString("=")
+	for _, t := range tokens {
+		c.Assert(formula[t.Pos:t.Pos+len(t.Text)], Equals, t.Text)
+		b.WriteString(t.Text)
+	}
+	c.Assert(b.String(), Equals, formula)
+}
+
+func (s *TokenizerSuite) TestTokenizeErrors(c *C) {
+	for _, formula := range []string{`"abc`, "T[[a]", "A1!", "#FOO"} {
+		_, err := Tokenize(formula)
+		c.Assert(err, FitsTypeOf, &SyntaxError{}, Commentf(formula))
+	}
+	_, err := Tokenize(`1+"abc`)
+	c.Assert(err, ErrorMatches, `formula: unterminated string at offset 2 of "1\+\\"abc"`)
+}
@@ -0,0 +1,281 @@
+package xlsx
+
+import (
+	"fmt"
+
+	"github.com/structer/xlsx/formula"
+)
+
+// InsertRows inserts n empty rows before the row at index at, counting
+// from 0, moving the rows from there on down.  The references to the
+// moved cells follow them: those in the formulas, conditional formats,
+// defined names and hyperlink locations of the whole file, and the
+// merged cells and hyperlinks of the sheet.  A range that spans the new rows grows to
+// take them in.  Charts, tables and the other parts that the library
+// keeps as they are aren't changed.
+func (s *Sheet) InsertRows(at, n int) error {
+	return s.insertDelete(true, true, at, n)
+}
+
+// DeleteRows deletes the n rows from the row at index at on, counting
+// from 0, moving the rows below them up.  References follow the moved
+// cells as they do for InsertRows, and a range that spans the deleted
+// rows shrinks.  A reference to deleted cells alone becomes #REF!, and
+// merged cells, conditional formats and hyperlinks of deleted cells
+// alone are removed.
+func (s *Sheet) DeleteRows(at, n int) error {
+	return s.insertDelete(true, false, at, n)
+}
+
+// InsertCols inserts n empty columns before the column at index at,
+// counting from 0, as InsertRows does for rows.  The new columns take
+// the width and style of a column definition that spans them.
+func (s *Sheet) InsertCols(at, n int) error {
+	return s.insertDelete(false, true, at, n)
+}
+
+// DeleteCols deletes the n columns from the column at index at on,
+// counting from 0, as DeleteRows does for rows.
+func (s *Sheet) DeleteCols(at, n int) error {
+	return s.insertDelete(false, false, at, n)
+}
+
+// insertDelete inserts or deletes n rows or columns at index at, and
+// fixes up the references to the cells that move.
+func (s *Sheet) insertDelete(rows, insert bool, at, n int) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+	what, limit, used := "columns", formula.MaxCol, s.MaxCol
+	if rows {
+		what, limit, used = "rows", formula.MaxRow, s.MaxRow
+	}
+	verb := "delete"
+	if insert {
+		verb = "insert"
+	}
+	if at < 0 || n < 0 || at+n > limit || insert && at < used && used+n > limit {
+		return fmt.Errorf("xlsx: cannot %s %d %s at index %d", verb, n, what, at)
+	}
+	if n == 0 {
+		return nil
+	}
+	if !insert {
+		n = -n
+	}
+	if s.File != nil {
+		for _, sheet := range s.File.Sheets {
+			if err := sheet.Load(); err != nil {
+				return err
+			}
+		}
+	}
+
+	adjust := func(r formula.Ref) formula.Ref {
+		switch {
+		case rows && n > 0:
+			return r.InsertRows(at+1, n)
+		case rows:
+			return r.DeleteRows(at+1, -n)
+		case n > 0:
+			return r.InsertCols(at+1, n)
+		}
+		return r.DeleteCols(at+1, -n)
+	}
+
+	merges := s.takeMerges()
+	if rows {
+		s.moveRows(at, n)
+	} else {
+		s.moveCols(at, n, adjust)
+	}
+	for _, merge := range merges {
+		if merge = adjust(merge); !merge.Invalid {
+			s.setMerge(merge)
+		}
+	}
+
+	var cfs []ConditionalFormat
+	for _, cf := range s.ConditionalFormatting {
+		if cf.Sqref = adjustRanges(cf.Sqref, adjust); cf.Sqref != "" {
+			cfs = append(cfs, cf)
+		}
+	}
+	s.ConditionalFormatting = cfs
+
+	if s.parts != nil && s.parts.hyperlinks != nil {
+		var links []xlsxHyperlink
+		for _, link := range s.parts.hyperlinks.Hyperlink {
+			if link.Ref = adjustRanges(link.Ref, adjust); link.Ref != "" {
+				links = append(links, link)
+			}
+		}
+		s.parts.hyperlinks.Hyperlink = links
+	}
+
+	fix := func(home *Sheet, node formula.Node) formula.Node {
+		ref, ok := node.(*formula.Ref)
+		if !ok || !refersTo(ref, home, s) {
+			return nil
+		}
+		adjusted := adjust(*ref)
+		if adjusted.String() == ref.String() {
+			return nil
+		}
+		return &adjusted
+	}
+	if s.File == nil {
+		fn := func(node formula.Node) formula.Node {
+			return fix(s, node)
+		}
+		s.rewriteFormulas(fn)
+		s.rewriteLocations(fn)
+		return nil
+	}
+	if err := s.File.rewriteFormulas(fix); err != nil {
+		return err
+	}
+	for _, sheet := range s.File.Sheets {
+		home := sheet
+		sheet.rewriteLocations(func(node formula.Node) formula.Node {
+			return fix(home, node)
+		})
+	}
+	return nil
+}
+
+// rewriteLocations rewrites with fn the references in the locations
+// within the workbook that the hyperlinks of the sheet point to, such
+// as Sheet1!B5.
+func (s *Sheet) rewriteLocations(fn func(formula.Node) formula.Node) {
+	if s.parts == nil || s.parts.hyperlinks == nil {
+		return
+	}
+	links := s.parts.hyperlinks.Hyperlink
+	for i := range links {
+		if links[i].Location != "" {
+			links[i].Location = rewriteFormula(links[i].Location, fn)
+		}
+	}
+}
+
+// takeMerges returns the ranges of the merged cells of the sheet,
+// which are unmerged.
+func (s *Sheet) takeMerges() []formula.Ref {
+	var merges []formula.Ref
+	for y, row := range s.Rows {
+		if row == nil {
+			continue
+		}
+		for x, cell := range row.Cells {
+			if cell == nil || cell.HMerge == 0 && cell.VMerge == 0 {
+				continue
+			}
+			merges = append(merges, formula.Ref{
+				From: formula.Cell{Row: y + 1, Col: x + 1},
+				To:   &formula.Cell{Row: y + 1 + cell.VMerge, Col: x + 1 + cell.HMerge},
+			})
+			cell.HMerge, cell.VMerge = 0, 0
+		}
+	}
+	return merges
+}
+
+// setMerge merges the cells of a range, unless it is a single cell.
+func (s *Sheet) setMerge(merge formula.Ref) {
+	top, left, bottom, right := merge.Bounds()
+	if top == bottom && left == right {
+		return
+	}
+	s.Cell(top-1, left-1).Merge(right-left, bottom-top)
+}
+
+// resized returns the number of rows or columns in use, count, after n
+// of them are inserted at index at or -n deleted from there.
+func resized(count, at, n int) int {
+	switch {
+	case at >= count:
+		return count
+	case at-n > count:
+		return at
+	}
+	return count + n
+}
+
+// moveRows inserts n empty rows at index at, or deletes -n rows.
+func (s *Sheet) moveRows(at, n int) {
+	if at < len(s.Rows) {
+		if n > 0 {
+			rows := make([]*Row, n)
+			for i := range rows {
+				rows[i] = &Row{Sheet: s}
+			}
+			s.Rows = append(s.Rows[:at], append(rows, s.Rows[at:]...)...)
+		} else {
+			end := at - n
+			if end > len(s.Rows) {
+				end = len(s.Rows)
+			}
+			s.Rows = append(s.Rows[:at], s.Rows[end:]...)
+		}
+	}
+	s.MaxRow = resized(s.MaxRow, at, n)
+}
+
+// moveCols inserts n empty columns at index at, or deletes -n columns,
+// adjusting the ranges of the column definitions that stay with
+// adjust.
+func (s *Sheet) moveCols(at, n int, adjust func(formula.Ref) formula.Ref) {
+	for _, row := range s.Rows {
+		if row == nil || at >= len(row.Cells) {
+			continue
+		}
+		if n > 0 {
+			cells := make([]*Cell, n)
+			for i := range cells {
+				cells[i] = NewCell(row)
+			}
+			row.Cells = append(row.Cells[:at], append(cells, row.Cells[at:]...)...)
+		} else {
+			end := at - n
+			if end > len(row.Cells) {
+				end = len(row.Cells)
+			}
+			row.Cells = append(row.Cells[:at], row.Cells[end:]...)
+		}
+	}
+
+	if at < len(s.Cols) {
+		if n < 0 {
+			end := at - n
+			if end > len(s.Cols) {
+				end = len(s.Cols)
+			}
+			s.Cols = append(s.Cols[:at], s.Cols[end:]...)
+		}
+		for _, col := range s.Cols {
+			if col.Min == 0 {
+				continue
+			}
+			r := adjust(formula.Ref{From: formula.Cell{Col: col.Min}, To: &formula.Cell{Col: col.Max}})
+			if !r.Invalid {
+				_, col.Min, _, col.Max = r.Bounds()
+			}
+		}
+		if n > 0 {
+			cols := make([]*Col, n)
+			for i := range cols {
+				if at > 0 && s.Cols[at-1].Max > at {
+					// The column to the left is defined by a
+					// range that now spans the new ones.
+					col := *s.Cols[at-1]
+					cols[i] = &col
+					continue
+				}
+				cols[i] = &Col{Min: at + i + 1, Max: at + i + 1, style: NewStyle()}
+			}
+			s.Cols = append(s.Cols[:at], append(cols, s.Cols[at:]...)...)
+		}
+	}
+	s.MaxCol = resized(s.MaxCol, at, n)
+}
@@ -0,0 +1,153 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type InsertDeleteSuite struct{}
+
+var _ = Suite(&InsertDeleteSuite{})
+
+func (s *InsertDeleteSuite) TestInsertRows(c *C) {
+	f, sheets := newTestFile(c, []string{"Sheet1", "Other"},
+		map[string]interface{}{"A1": 1, "A2": 2, "A3": 3, "B1": "=SUM(A1:A3)", "B3": "=A3*2", "C2": "merged"},
+		map[string]interface{}{"A1": "=Sheet1!A2+'Sheet1'!A$1", "A2": "=SUM(Sheet1!B:B)+A1"})
+	sheet, other := sheets[0], sheets[1]
+	sheet.Cell(1, 2).Merge(1, 1)
+	c.Assert(sheet.AddConditionalFormat("A2:A3", NewExpressionRule("A2>1", &DifferentialStyle{NumFmt: "0.0"})), IsNil)
+	f.DefinedNames = append(f.DefinedNames, &xlsxDefinedName{Name: "Data", Data: "Sheet1!$A$2:$A$3"})
+	c.Assert(sheet.InsertRows(1, 2), IsNil)
+
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "1")
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "")
+	c.Assert(sheet.Cell(2, 0).Value, Equals, "")
+	c.Assert(sheet.Cell(3, 0).Value, Equals, "2")
+	c.Assert(sheet.Cell(4, 0).Value, Equals, "3")
+	c.Assert(sheet.MaxRow, Equals, 5)
+	c.Assert(sheet.Cell(0, 1).Formula(), Equals, "SUM(A1:A5)")
+	c.Assert(sheet.Cell(4, 1).Formula(), Equals, "A5*2")
+	c.Assert(other.Cell(0, 0).Formula(), Equals, "Sheet1!A4+'Sheet1'!A$1")
+	c.Assert(other.Cell(1, 0).Formula(), Equals, "SUM(Sheet1!B:B)+A1")
+	c.Assert(f.DefinedNames[0].Data, Equals, "Sheet1!$A$4:$A$5")
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+
+	merged := sheet.Cell(3, 2)
+	c.Assert(merged.Value, Equals, "merged")
+	c.Assert(merged.HMerge, Equals, 1)
+	c.Assert(merged.VMerge, Equals, 1)
+	c.Assert(sheet.Cell(1, 2).VMerge, Equals, 0)
+
+	c.Assert(sheet.ConditionalFormatting, HasLen, 1)
+	c.Assert(sheet.ConditionalFormatting[0].Sqref, Equals, "A4:A5")
+	c.Assert(sheet.ConditionalFormatting[0].Rules[0].Formula, DeepEquals, []string{"A4>1"})
+
+	c.Assert(f.Recalculate(), IsNil)
+	c.Assert(sheet.Cell(0, 1).Value, Equals, "6")
+	c.Assert(other.Cell(0, 0).Value, Equals, "3")
+}
+
+func (s *InsertDeleteSuite) TestDeleteRows(c *C) {
+	f, sheets := newTestFile(c, []string{"Sheet1", "Other"},
+		map[string]interface{}{"A1": 1, "A2": 2, "A3": 3, "B1": "=SUM(A1:A3)", "B3": "=A3*2", "C2": "merged"},
+		map[string]interface{}{"A1": "=Sheet1!A2+'Sheet1'!A$1"})
+	sheet, other := sheets[0], sheets[1]
+	sheet.Cell(1, 2).Merge(1, 1)
+	c.Assert(sheet.AddConditionalFormat("A2:A3", NewExpressionRule("A2>1", nil)), IsNil)
+	f.DefinedNames = append(f.DefinedNames, &xlsxDefinedName{Name: "Data", Data: "Sheet1!$A$2:$A$3"})
+	c.Assert(sheet.DeleteRows(1, 1), IsNil)
+
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "1")
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "3")
+	c.Assert(sheet.MaxRow, Equals, 2)
+	c.Assert(sheet.Cell(0, 1).Formula(), Equals, "SUM(A1:A2)")
+	c.Assert(sheet.Cell(1, 1).Formula(), Equals, "A2*2")
+	c.Assert(other.Cell(0, 0).Formula(), Equals, "Sheet1!#REF!+'Sheet1'!A$1")
+	c.Assert(f.DefinedNames[0].Data, Equals, "Sheet1!$A$2:$A$2")
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+
+	// The merged cell lost its first row, and the one below takes
+	// its place.
+	c.Assert(sheet.Cell(1, 2).HMerge, Equals, 1)
+	c.Assert(sheet.Cell(1, 2).VMerge, Equals, 0)
+
+	c.Assert(sheet.ConditionalFormatting[0].Sqref, Equals, "A2:A2")
+
+	c.Assert(sheet.DeleteRows(1, 5), IsNil)
+	c.Assert(sheet.Rows, HasLen, 1)
+	c.Assert(sheet.MaxRow, Equals, 1)
+	c.Assert(sheet.ConditionalFormatting, HasLen, 0)
+	c.Assert(f.DefinedNames[0].Data, Equals, "Sheet1!#REF!")
+}
+
+func (s *InsertDeleteSuite) TestInsertDeleteCols(c *C) {
+	f, sheets := newTestFile(c, []string{"Sheet1", "Other"},
+		map[string]interface{}{"A1": 1, "A2": 2, "A3": 3, "B1": "=SUM(A1:A3)", "C2": "merged"},
+		map[string]interface{}{"A1": "=Sheet1!A2+'Sheet1'!A$1", "A2": "=SUM(Sheet1!B:B)+A1"})
+	sheet, other := sheets[0], sheets[1]
+	sheet.Cell(1, 2).Merge(1, 1)
+	c.Assert(sheet.AddConditionalFormat("A2:A3", NewExpressionRule("A2>1", nil)), IsNil)
+	f.DefinedNames = append(f.DefinedNames, &xlsxDefinedName{Name: "Data", Data: "Sheet1!$A$2:$A$3"})
+	sheet.Col(0).Width = 10
+	sheet.Col(1).Width = 20
+
+	c.Assert(sheet.InsertCols(1, 1), IsNil)
+	c.Assert(sheet.MaxCol, Equals, 4)
+	c.Assert(sheet.Cols, HasLen, 4)
+	c.Assert(sheet.Cols[1].Width, Equals, 0.0)
+	c.Assert(sheet.Cols[2].Width, Equals, 20.0)
+	c.Assert(sheet.Cols[2].Min, Equals, 3)
+	c.Assert(sheet.Cell(0, 2).Formula(), Equals, "SUM(A1:A3)")
+	c.Assert(sheet.Cell(1, 3).Value, Equals, "merged")
+	c.Assert(sheet.Cell(1, 3).HMerge, Equals, 1)
+	c.Assert(other.Cell(1, 0).Formula(), Equals, "SUM(Sheet1!C:C)+A1")
+
+	c.Assert(sheet.DeleteCols(0, 1), IsNil)
+	c.Assert(sheet.Cols, HasLen, 3)
+	c.Assert(sheet.Cols[1].Width, Equals, 20.0)
+	c.Assert(sheet.Cols[1].Min, Equals, 2)
+	c.Assert(sheet.Cell(0, 1).Formula(), Equals, "SUM(#REF!)")
+	c.Assert(other.Cell(0, 0).Formula(), Equals, "Sheet1!#REF!+Sheet1!#REF!")
+	c.Assert(other.Cell(1, 0).Formula(), Equals, "SUM(Sheet1!B:B)+A1")
+	c.Assert(f.DefinedNames[0].Data, Equals, "Sheet1!#REF!")
+	c.Assert(reopenTestFile(c, f).DefinedNames, DeepEquals, f.DefinedNames)
+	c.Assert(sheet.ConditionalFormatting, HasLen, 0)
+}
+
+func (s *InsertDeleteSuite) TestInsertDeleteHyperlinks(c *C) {
+	_, sheets := newTestFile(c, []string{"Sheet1", "Other"})
+	sheet, other := sheets[0], sheets[1]
+	sheet.parts = &worksheetParts{hyperlinks: &xlsxHyperlinks{Hyperlink: []xlsxHyperlink{
+		{Ref: "A2", Location: "Sheet1!A3"},
+		{Ref: "B1", Location: "A2"},
+	}}}
+	other.parts = &worksheetParts{hyperlinks: &xlsxHyperlinks{Hyperlink: []xlsxHyperlink{
+		{Ref: "A1", Location: "'Sheet1'!A2:B3"},
+		{Ref: "A2", Location: "A2"},
+	}}}
+
+	c.Assert(sheet.InsertRows(1, 2), IsNil)
+	c.Assert(sheet.parts.hyperlinks.Hyperlink, DeepEquals, []xlsxHyperlink{
+		{Ref: "A4", Location: "Sheet1!A5"},
+		{Ref: "B1", Location: "A4"},
+	})
+	c.Assert(other.parts.hyperlinks.Hyperlink, DeepEquals, []xlsxHyperlink{
+		{Ref: "A1", Location: "Sheet1!A4:B5"},
+		{Ref: "A2", Location: "A2"},
+	})
+
+	c.Assert(sheet.DeleteRows(3, 2), IsNil)
+	c.Assert(sheet.parts.hyperlinks.Hyperlink, DeepEquals, []xlsxHyperlink{
+		{Ref: "B1", Location: "#REF!"},
+	})
+	c.Assert(other.parts.hyperlinks.Hyperlink[0].Location, Equals, "Sheet1!#REF!")
+}
+
+func (s *InsertDeleteSuite) TestInsertDeleteErrors(c *C) {
+	_, sheets := newTestFile(c, []string{"Sheet1"}, map[string]interface{}{"A1": 1, "A2": 2, "A3": 3})
+	sheet := sheets[0]
+	c.Assert(sheet.InsertRows(-1, 1), ErrorMatches, `xlsx: cannot insert 1 rows at index -1`)
+	c.Assert(sheet.DeleteCols(0, -1), ErrorMatches, `xlsx: cannot delete -1 columns at index 0`)
+	c.Assert(sheet.InsertRows(0, 1048575), ErrorMatches, `xlsx: cannot insert 1048575 rows at index 0`)
+	c.Assert(sheet.InsertRows(3, 0), IsNil)
+	c.Assert(sheet.Cell(2, 0).Value, Equals, "3")
+}
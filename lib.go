@@ -2,13 +2,17 @@ package xlsx
 
 import (
 	"archive/zip"
+	"bytes"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"path"
 	"strconv"
 	"strings"
+
+	"github.com/structer/xlsx/formula"
 )
 
 // XLSXReaderError is the standard error type for otherwise undefined
@@ -23,25 +27,68 @@ func (e *XLSXReaderError) Error() string {
 	return e.Err
 }
 
+// CellError is returned when the data of a particular cell, or range
+// of cells, in a worksheet cannot be read.  It identifies the sheet
+// and the cell, and carries the underlying cause.
+type CellError struct {
+	Sheet string // Name of the sheet containing the cell
+	Cell  string // Reference of the cell or range, e.g. "B7" or "A1:C4"
+	Err   error  // The underlying cause
+}
+
+// Error returns a description of the CellError that names the sheet
+// and the cell, in order that it might comply with the builtin.error
+// interface.
+func (e *CellError) Error() string {
+	return fmt.Sprintf("sheet '%s', cell %s: %s", e.Sheet, e.Cell, e.Err)
+}
+
+// Unwrap returns the underlying cause of the CellError.
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+// CellErrors is returned when several cells can't be read, such as
+// by Sheet.ReadStructs, which reads on past the cells it can't.  It
+// holds a CellError for each of them, in order.
+type CellErrors []*CellError
+
+// Error returns the descriptions of the CellErrors, separated by
+// semicolons.
+func (e CellErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// newCellError wraps err in a CellError for the cell or range ref of
+// sheet.
+func newCellError(sheet *Sheet, ref string, err error) *CellError {
+	cellErr := &CellError{Cell: ref, Err: err}
+	if sheet != nil {
+		cellErr.Sheet = sheet.Name
+	}
+	return cellErr
+}
+
 // getRangeFromString is an internal helper function that converts
 // XLSX internal range syntax to a pair of integers.  For example,
 // the range string "1:3" yield the upper and lower intergers 1 and 3.
 func getRangeFromString(rangeString string) (lower int, upper int, error error) {
 	var parts []string
 	parts = strings.SplitN(rangeString, ":", 2)
-	if parts[0] == "" {
-		error = errors.New(fmt.Sprintf("Invalid range '%s'\n", rangeString))
-	}
-	if parts[1] == "" {
-		error = errors.New(fmt.Sprintf("Invalid range '%s'\n", rangeString))
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return lower, upper, errors.New(fmt.Sprintf("Invalid range '%s'\n", rangeString))
 	}
 	lower, error = strconv.Atoi(parts[0])
 	if error != nil {
-		error = errors.New(fmt.Sprintf("Invalid range (not integer in lower bound) %s\n", rangeString))
+		return lower, upper, errors.New(fmt.Sprintf("Invalid range (not integer in lower bound) %s\n", rangeString))
 	}
 	upper, error = strconv.Atoi(parts[1])
 	if error != nil {
-		error = errors.New(fmt.Sprintf("Invalid range (not integer in upper bound) %s\n", rangeString))
+		return lower, upper, errors.New(fmt.Sprintf("Invalid range (not integer in upper bound) %s\n", rangeString))
 	}
 	return lower, upper, error
 }
@@ -89,12 +136,12 @@ func formatColumnName(colId []int) string {
 			// range 0-25, all other numbers are 1-26,
 			// hence we use a differente offset for the
 			// last part.
-			result += string(part + 65)
+			result += string(rune(part + 65))
 		} else {
 			// Don't output leading 0s, as there is no
 			// representation of 0 in this format.
 			if part > 0 {
-				result += string(part + 64)
+				result += string(rune(part + 64))
 			}
 		}
 	}
@@ -222,7 +269,7 @@ func calculateMaxMinFromWorksheet(worksheet *xlsxWorksheet) (minx, miny, maxx, m
 		for _, cell := range row.C {
 			x, y, err = getCoordsFromCellIDString(cell.R)
 			if err != nil {
-				return -1, -1, -1, -1, err
+				return -1, -1, -1, -1, &CellError{Cell: cell.R, Err: fmt.Errorf("Invalid Cell Coord, %s", cell.R)}
 			}
 			if x < minx {
 				minx = x
@@ -251,7 +298,7 @@ func calculateMaxMinFromWorksheet(worksheet *xlsxWorksheet) (minx, miny, maxx, m
 // return an empty Row large enough to encompass that span and
 // populate it with empty cells.  All rows start from cell 1 -
 // regardless of the lower bound of the span.
-func makeRowFromSpan(spans string, sheet *Sheet) *Row {
+func makeRowFromSpan(spans string, sheet *Sheet) (*Row, error) {
 	var error error
 	var upper int
 	var row *Row
@@ -261,20 +308,22 @@ func makeRowFromSpan(spans string, sheet *Sheet) *Row {
 	row.Sheet = sheet
 	_, upper, error = getRangeFromString(spans)
 	if error != nil {
-		panic(error)
+		return nil, error
+	}
+	if upper < 0 {
+		return nil, fmt.Errorf("Invalid range '%s'", spans)
 	}
-	error = nil
 	row.Cells = make([]*Cell, upper)
 	for i := 0; i < upper; i++ {
 		cell = new(Cell)
 		cell.Value = ""
 		row.Cells[i] = cell
 	}
-	return row
+	return row, nil
 }
 
 // makeRowFromRaw returns the Row representation of the xlsxRow.
-func makeRowFromRaw(rawrow xlsxRow, sheet *Sheet) *Row {
+func makeRowFromRaw(rawrow xlsxRow, sheet *Sheet) (*Row, error) {
 	var upper int
 	var row *Row
 	var cell *Cell
@@ -287,7 +336,7 @@ func makeRowFromRaw(rawrow xlsxRow, sheet *Sheet) *Row {
 		if rawcell.R != "" {
 			x, _, error := getCoordsFromCellIDString(rawcell.R)
 			if error != nil {
-				panic(fmt.Sprintf("Invalid Cell Coord, %s\n", rawcell.R))
+				return nil, newCellError(sheet, rawcell.R, fmt.Errorf("Invalid Cell Coord, %s", rawcell.R))
 			}
 			if x > upper {
 				upper = x
@@ -306,7 +355,7 @@ func makeRowFromRaw(rawrow xlsxRow, sheet *Sheet) *Row {
 		cell.Value = ""
 		row.Cells[i] = cell
 	}
-	return row
+	return row, nil
 }
 
 func makeEmptyRow(sheet *Sheet) *Row {
@@ -338,49 +387,7 @@ func formulaForCell(rawcell xlsxC, sharedFormulas map[int]sharedFormula) string
 				sharedFormulas[f.Si] = sharedFormula{x, y, res}
 			} else {
 				sharedFormula := sharedFormulas[f.Si]
-				dx := x - sharedFormula.x
-				dy := y - sharedFormula.y
-				orig := []byte(sharedFormula.formula)
-				var start, end int
-				var stringLiteral bool
-				for end = 0; end < len(orig); end++ {
-					c := orig[end]
-
-					if c == '"' {
-						stringLiteral = !stringLiteral
-					}
-
-					if stringLiteral {
-						continue // Skip characters in quotes
-					}
-
-					if c >= 'A' && c <= 'Z' || c == '$' {
-						res += string(orig[start:end])
-						start = end
-						end++
-						foundNum := false
-						for ; end < len(orig); end++ {
-							idc := orig[end]
-							if idc >= '0' && idc <= '9' || idc == '$' {
-								foundNum = true
-							} else if idc >= 'A' && idc <= 'Z' {
-								if foundNum {
-									break
-								}
-							} else {
-								break
-							}
-						}
-						if foundNum {
-							cellID := string(orig[start:end])
-							res += shiftCell(cellID, dx, dy)
-							start = end
-						}
-					}
-				}
-				if start < len(orig) {
-					res += string(orig[start:])
-				}
+				res = shiftFormula(sharedFormula.formula, x-sharedFormula.x, y-sharedFormula.y)
 			}
 		}
 	} else {
@@ -389,59 +396,36 @@ func formulaForCell(rawcell xlsxC, sharedFormulas map[int]sharedFormula) string
 	return strings.Trim(res, " \t\n\r")
 }
 
-// shiftCell returns the cell shifted according to dx and dy taking into consideration of absolute
-// references with dollar sign ($)
-func shiftCell(cellID string, dx, dy int) string {
-	fx, fy, _ := getCoordsFromCellIDString(cellID)
-
-	// Is fixed column?
-	fixedCol := strings.Index(cellID, "$") == 0
-
-	// Is fixed row?
-	fixedRow := strings.LastIndex(cellID, "$") > 0
-
-	if !fixedCol {
-		// Shift column
-		fx += dx
-	}
-
-	if !fixedRow {
-		// Shift row
-		fy += dy
-	}
-
-	// New shifted cell
-	shiftedCellID := getCellIDStringFromCoords(fx, fy)
-
-	if !fixedCol && !fixedRow {
-		return shiftedCellID
-	}
-
-	// There are absolute references, need to put the $ back into the formula.
-	letterPart := strings.Map(letterOnlyMapF, shiftedCellID)
-	numberPart := strings.Map(intOnlyMapF, shiftedCellID)
-
-	result := ""
-
-	if fixedCol {
-		result += "$"
-	}
-
-	result += letterPart
-
-	if fixedRow {
-		result += "$"
-	}
-
-	result += numberPart
-
-	return result
+// shiftFormula returns the formula as it is when copied dx columns
+// across and dy rows down: its relative references move with it.  A
+// formula that can't be parsed is returned as it is.
+func shiftFormula(f string, dx, dy int) string {
+	return rewriteFormula(f, func(n formula.Node) formula.Node {
+		if ref, ok := n.(*formula.Ref); ok {
+			shifted := ref.Shift(dy, dx)
+			return &shifted
+		}
+		return nil
+	})
 }
 
 // fillCellData attempts to extract a valid value, usable in
-// CSV form from the raw cell value.  Note - this is not actually
-// general enough - we should support retaining tabs and newlines.
-func fillCellData(rawcell xlsxC, reftable *RefTable, sharedFormulas map[int]sharedFormula, cell *Cell) {
+// CSV form from the raw cell value.  String values are kept exactly
+// as stored, including any surrounding whitespace.  An error is
+// returned if the raw value can't be interpreted.
+func fillCellData(rawcell xlsxC, reftable *RefTable, sharedFormulas map[int]sharedFormula, cell *Cell) error {
+	if rawcell.T == "inlineStr" { // Inline String
+		if rawcell.Is != nil {
+			if len(rawcell.Is.R) > 0 {
+				cell.richText = makeRichTextRuns(rawcell.Is.R)
+				cell.Value = richTextString(cell.richText)
+			} else {
+				cell.Value = rawcell.Is.T
+			}
+		}
+		cell.cellType = CellTypeInline
+		return nil
+	}
 	var data string = rawcell.V
 	if len(data) > 0 {
 		vval := strings.Trim(data, " \t\n\r")
@@ -449,12 +433,35 @@ func fillCellData(rawcell xlsxC, reftable *RefTable, sharedFormulas map[int]shar
 		case "s": // Shared String
 			ref, error := strconv.Atoi(vval)
 			if error != nil {
-				panic(error)
+				return fmt.Errorf("invalid shared string index '%s'", vval)
+			}
+			if reftable == nil || ref < 0 || ref >= reftable.Length() {
+				return fmt.Errorf("shared string index %d out of range", ref)
 			}
 			cell.Value = reftable.ResolveSharedString(ref)
+			cell.richText = reftable.ResolveRichText(ref)
 			cell.cellType = CellTypeString
+		case "str": // String, normally the result of a formula
+			cell.Value = data
+			if rawcell.F == nil {
+				cell.cellType = CellTypeString
+			} else {
+				cell.formula = formulaForCell(rawcell, sharedFormulas)
+				cell.cellType = CellTypeStringFormula
+			}
+		case "d": // ISO 8601 Date
+			excelTime, format, err := excelTimeFromISO8601(vval, cell.date1904)
+			if err != nil {
+				return err
+			}
+			cell.Value = strconv.FormatFloat(excelTime, 'f', -1, 64)
+			if cell.NumFmt == "" || cell.NumFmt == builtInNumFmt[builtInNumFmtIndex_GENERAL] {
+				cell.NumFmt = format
+			}
+			cell.cellType = CellTypeDate
 		case "b": // Boolean
 			cell.Value = vval
+			cell.formula = formulaForCell(rawcell, sharedFormulas)
 			cell.cellType = CellTypeBool
 		case "e": // Error
 			cell.Value = vval
@@ -472,14 +479,32 @@ func fillCellData(rawcell xlsxC, reftable *RefTable, sharedFormulas map[int]shar
 				cell.cellType = CellTypeFormula
 			}
 		}
+	} else if rawcell.F != nil {
+		// A formula whose result is an empty string, or that has
+		// not been calculated yet.
+		cell.formula = formulaForCell(rawcell, sharedFormulas)
+		cell.cellType = CellTypeFormula
+		if rawcell.T == "str" {
+			cell.cellType = CellTypeStringFormula
+		}
+	}
+	return nil
+}
+
+// trimCellValue strips the surrounding whitespace from the value of
+// a string cell, for files read with ReadOptions.TrimSpace.
+func trimCellValue(cell *Cell) {
+	switch cell.cellType {
+	case CellTypeString, CellTypeInline, CellTypeFormula, CellTypeStringFormula:
+		cell.Value = strings.Trim(cell.Value, " \t\n\r")
 	}
 }
 
 // readRowsFromSheet is an internal helper function that extracts the
 // rows from a XSLXWorksheet, populates them with Cells and resolves
 // the value references from the reference table and stores them in
-// the rows and columns.
-func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*Row, []*Col, int, int) {
+// the rows and columns.  Malformed cell data results in a *CellError.
+func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*Row, []*Col, int, int, error) {
 	var rows []*Row
 	var cols []*Col
 	var row *Row
@@ -490,16 +515,25 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 	sharedFormulas := map[int]sharedFormula{}
 
 	if len(Worksheet.SheetData.Row) == 0 {
-		return nil, nil, 0, 0
+		return nil, nil, 0, 0, nil
 	}
 	reftable = file.referenceTable
 	if len(Worksheet.Dimension.Ref) > 0 {
 		minCol, minRow, maxCol, maxRow, err = getMaxMinFromDimensionRef(Worksheet.Dimension.Ref)
+		if err != nil {
+			return nil, nil, 0, 0, newCellError(sheet, Worksheet.Dimension.Ref, fmt.Errorf("invalid dimension: %s", err))
+		}
 	} else {
 		minCol, minRow, maxCol, maxRow, err = calculateMaxMinFromWorksheet(Worksheet)
+		if cellErr, ok := err.(*CellError); ok && sheet != nil {
+			cellErr.Sheet = sheet.Name
+		}
+		if err != nil {
+			return nil, nil, 0, 0, err
+		}
 	}
-	if err != nil {
-		panic(err.Error())
+	if minCol < 0 || minRow < 0 || maxCol < minCol || maxRow < minRow {
+		return nil, nil, 0, 0, newCellError(sheet, Worksheet.Dimension.Ref, errors.New("invalid dimension"))
 	}
 
 	rowCount = maxRow + 1
@@ -521,7 +555,7 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 			// exist outside the defined dimensions of the
 			// spreadsheet - we deliberately exclude these
 			// columns.
-			for i := rawcol.Min; i <= rawcol.Max && i <= colCount; i++ {
+			for i := rawcol.Min; i > 0 && i <= rawcol.Max && i <= colCount; i++ {
 				col := &Col{
 					Min:          rawcol.Min,
 					Max:          rawcol.Max,
@@ -556,9 +590,15 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 		}
 		// range is not empty and only one range exist
 		if len(rawrow.Spans) != 0 && strings.Count(rawrow.Spans, ":") == 1 {
-			row = makeRowFromSpan(rawrow.Spans, sheet)
+			row, err = makeRowFromSpan(rawrow.Spans, sheet)
+			if err != nil {
+				return nil, nil, 0, 0, newCellError(sheet, fmt.Sprintf("%d:%d", rawrow.R, rawrow.R), err)
+			}
 		} else {
-			row = makeRowFromRaw(rawrow, sheet)
+			row, err = makeRowFromRaw(rawrow, sheet)
+			if err != nil {
+				return nil, nil, 0, 0, err
+			}
 		}
 
 		row.Hidden = rawrow.Hidden
@@ -573,9 +613,18 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 		for _, rawcell := range rawrow.C {
 			h, v, err := Worksheet.MergeCells.getExtent(rawcell.R)
 			if err != nil {
-				panic(err.Error())
+				return nil, nil, 0, 0, newCellError(sheet, rawcell.R, fmt.Errorf("invalid merged range: %s", err))
+			}
+			x, _, err := getCoordsFromCellIDString(rawcell.R)
+			if err != nil && rawcell.R != "" {
+				return nil, nil, 0, 0, newCellError(sheet, rawcell.R, fmt.Errorf("Invalid Cell Coord, %s", rawcell.R))
+			}
+
+			// Cells may lie outside the span declared for
+			// the row.
+			for x >= len(row.Cells) || insertColIndex >= len(row.Cells) {
+				row.Cells = append(row.Cells, new(Cell))
 			}
-			x, _, _ := getCoordsFromCellIDString(rawcell.R)
 
 			// Some spreadsheets will omit blank cells
 			// from the data.
@@ -588,12 +637,18 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 			cell := row.Cells[cellX]
 			cell.HMerge = h
 			cell.VMerge = v
-			fillCellData(rawcell, reftable, sharedFormulas, cell)
 			if file.styles != nil {
 				cell.style = file.styles.getStyle(rawcell.S)
 				cell.NumFmt = file.styles.getNumberFormat(rawcell.S)
 			}
 			cell.date1904 = file.Date1904
+			err = fillCellData(rawcell, reftable, sharedFormulas, cell)
+			if err != nil {
+				return nil, nil, 0, 0, newCellError(sheet, rawcell.R, err)
+			}
+			if file.trimSpace {
+				trimCellValue(cell)
+			}
 			// Cell is considered hidden if the row or the column of this cell is hidden
 			cell.Hidden = rawrow.Hidden || (len(cols) > cellX && cols[cellX].Hidden)
 			insertColIndex++
@@ -603,7 +658,7 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 		}
 		insertRowIndex++
 	}
-	return rows, cols, colCount, rowCount
+	return rows, cols, colCount, rowCount, nil
 }
 
 type indexedSheet struct {
@@ -638,20 +693,8 @@ func readSheetViews(xSheetViews xlsxSheetViews) []SheetView {
 // into a Sheet struct.  This work can be done in parallel and so
 // readSheetsFromZipFile will spawn an instance of this function per
 // sheet and get the results back on the provided channel.
-func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *File, sheetXMLMap map[string]string) {
+func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *File, sheetXMLMap map[string]string, sheet *Sheet) {
 	result := &indexedSheet{Index: index, Sheet: nil, Error: nil}
-	defer func() {
-		if e := recover(); e != nil {
-			switch e.(type) {
-			case error:
-				result.Error = e.(error)
-			default:
-				result.Error = errors.New("unexpected error")
-			}
-			// The only thing here, is if one close the channel. but its not the case
-			sc <- result
-		}
-	}()
 
 	worksheet, error := getWorksheetFromSheet(rsheet, fi.worksheets, sheetXMLMap)
 	if error != nil {
@@ -659,33 +702,32 @@ func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *F
 		sc <- result
 		return
 	}
-	sheet := new(Sheet)
+	sheet.Name = rsheet.Name
 	sheet.File = fi
-	sheet.Rows, sheet.Cols, sheet.MaxCol, sheet.MaxRow = readRowsFromSheet(worksheet, fi, sheet)
+	zf := worksheetFileForSheet(rsheet, fi.worksheets, sheetXMLMap)
+	sheet.parts, error = readWorksheetParts(worksheet, fi.worksheetRels[worksheetRelsName(zf.Name)])
+	if error != nil {
+		result.Error = error
+		sc <- result
+		return
+	}
+	sheet.Rows, sheet.Cols, sheet.MaxCol, sheet.MaxRow, error = readRowsFromSheet(worksheet, fi, sheet)
+	if error != nil {
+		result.Error = error
+		sc <- result
+		return
+	}
 	sheet.Hidden = rsheet.State == sheetStateHidden || rsheet.State == sheetStateVeryHidden
 	sheet.SheetViews = readSheetViews(worksheet.SheetViews)
-	
-
-	SheetConditionalFormattings := new([]conditionalFormatting)
-	
-	for _, condFormat := range worksheet.ConditionalFormatting{
-		FormulaCF := new(formulaCF)
-		CfRule := new(cfRule)
-		SheetConditionalFormatting := new(conditionalFormatting)		
-		
-		FormulaCF.Value = condFormat.CfRule.Formula.Value
-		CfRule.Formula = *FormulaCF
-		CfRule.Type = condFormat.CfRule.Type
-		CfRule.DxfId = condFormat.CfRule.DxfId
-		CfRule.Priority = condFormat.CfRule.Priority
-		CfRule.Operator = condFormat.CfRule.Operator
-		CfRule.Text = condFormat.CfRule.Text
-		
-		SheetConditionalFormatting.CfRule = *CfRule
-		SheetConditionalFormatting.Sqref = condFormat.Sqref
-		*SheetConditionalFormattings = append(*SheetConditionalFormattings, *SheetConditionalFormatting)		
-	}
-	sheet.ConditionalFormatting = *SheetConditionalFormattings	
+
+	sheet.ConditionalFormatting = nil
+	for _, condFormat := range worksheet.ConditionalFormatting {
+		cf := ConditionalFormat{Sqref: condFormat.Sqref}
+		for _, xRule := range condFormat.CfRule {
+			cf.Rules = append(cf.Rules, makeConditionalFormatRule(xRule, fi.styles))
+		}
+		sheet.ConditionalFormatting = append(sheet.ConditionalFormatting, cf)
+	}
 
 	sheet.SheetFormat.DefaultColWidth = worksheet.SheetFormatPr.DefaultColWidth
 	sheet.SheetFormat.DefaultRowHeight = worksheet.SheetFormatPr.DefaultRowHeight
@@ -699,7 +741,9 @@ func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *F
 // readSheetsFromZipFile is an internal helper function that loops
 // over the Worksheets defined in the XSLXWorkbook and loads them into
 // Sheet objects stored in the Sheets slice of a xlsx.File struct.
-func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]string) (map[string]*Sheet, []*Sheet, error) {
+// The options decide which sheets are read and whether their
+// worksheets are decoded now or on first use.
+func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]string, options ReadOptions) (map[string]*Sheet, []*Sheet, error) {
 	var workbook *xlsxWorkbook
 	var err error
 	var rc io.ReadCloser
@@ -722,23 +766,59 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 	}
 
 	// Only try and read sheets that have corresponding files.
-	// Notably this excludes chartsheets don't right now
+	// Notably this excludes chartsheets, which are kept as they are
+	// along with the other parts the library does not model.
 	var workbookSheets []xlsxSheet
-	for _, sheet := range workbook.Sheets.Sheet {
+	for i, sheet := range workbook.Sheets.Sheet {
 		if f := worksheetFileForSheet(sheet, file.worksheets, sheetXMLMap); f != nil {
 			workbookSheets = append(workbookSheets, sheet)
+		} else if file.parts != nil && file.parts.hasWorkbookRel(sheet.Id) {
+			file.parts.sheets = append(file.parts.sheets, preservedSheet{sheet, i})
 		}
 	}
+	if file.parts != nil {
+		file.parts.externalReferences = workbook.ExternalReferences
+		file.parts.pivotCaches = workbook.PivotCaches
+	}
+	file.sheetXMLMap = sheetXMLMap
+	file.workbookSheets = workbookSheets
+	workbookSheets, err = options.selectSheets(workbookSheets)
+	if err != nil {
+		return nil, nil, err
+	}
+	file.partial = len(workbookSheets) < len(file.workbookSheets)
 	sheetCount = len(workbookSheets)
 	sheetsByName := make(map[string]*Sheet, sheetCount)
 	sheets := make([]*Sheet, sheetCount)
+	if options.Lazy {
+		for i := range workbookSheets {
+			rawsheet := workbookSheets[i]
+			sheet := &Sheet{
+				Name:     rawsheet.Name,
+				File:     file,
+				Hidden:   rawsheet.State == sheetStateHidden || rawsheet.State == sheetStateVeryHidden,
+				rawSheet: &rawsheet,
+			}
+			sheetsByName[rawsheet.Name] = sheet
+			sheets[i] = sheet
+		}
+		return sheetsByName, sheets, nil
+	}
+	// The channel is closed by the goroutine that sends on it, which
+	// stops reading sheets once one of them has failed.
 	sheetChan := make(chan *indexedSheet, sheetCount)
-	defer close(sheetChan)
+	failed := make(chan struct{})
+	defer close(failed)
 
 	go func() {
-		err = nil
+		defer close(sheetChan)
 		for i, rawsheet := range workbookSheets {
-			readSheetFromFile(sheetChan, i, rawsheet, file, sheetXMLMap)
+			select {
+			case <-failed:
+				return
+			default:
+			}
+			readSheetFromFile(sheetChan, i, rawsheet, file, sheetXMLMap, new(Sheet))
 		}
 	}()
 
@@ -797,21 +877,33 @@ func readStylesFromZipFile(f *zip.File, theme *theme) (*xlsxStyleSheet, error) {
 	if error != nil {
 		return nil, error
 	}
+	defer rc.Close()
+	data, error := ioutil.ReadAll(rc)
+	if error != nil {
+		return nil, error
+	}
 	style = newXlsxStyleSheet(theme)
-	decoder = xml.NewDecoder(rc)
+	decoder = xml.NewDecoder(bytes.NewReader(data))
 	error = decoder.Decode(style)
 	if error != nil {
 		return nil, error
 	}
+	error = style.readRaw(data)
+	if error != nil {
+		return nil, error
+	}
 	buildNumFmtRefTable(style)
 	return style, nil
 }
 
+// buildNumFmtRefTable indexes the custom number formats of a style
+// sheet by their ids.
 func buildNumFmtRefTable(style *xlsxStyleSheet) {
+	style.numFmtRefTable = make(map[int]xlsxNumFmt, len(style.NumFmts.NumFmt))
 	for _, numFmt := range style.NumFmts.NumFmt {
-		// We do this for the side effect of populating the NumFmtRefTable.
-		style.addNumFmt(numFmt)
+		style.numFmtRefTable[numFmt.NumFmtId] = numFmt
 	}
+	style.NumFmts.Count = len(style.NumFmts.NumFmt)
 }
 
 func readThemeFromZipFile(f *zip.File) (*theme, error) {
@@ -912,6 +1004,14 @@ func ReadZip(f *zip.ReadCloser) (*File, error) {
 // ReadZipReader() can be used to read an XLSX in memory without
 // touching the filesystem.
 func ReadZipReader(r *zip.Reader) (*File, error) {
+	return ReadZipReaderWithOptions(r, ReadOptions{})
+}
+
+// ReadZipReaderWithOptions() reads an XLSX in memory, like
+// ReadZipReader, but only reads the sheets selected by the
+// options.  If the options ask for lazy loading, r must remain
+// readable until every sheet that is needed has been loaded.
+func ReadZipReaderWithOptions(r *zip.Reader, options ReadOptions) (*File, error) {
 	var err error
 	var file *File
 	var reftable *RefTable
@@ -928,8 +1028,10 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 	var worksheets map[string]*zip.File
 
 	file = NewFile()
+	file.trimSpace = options.TrimSpace
 	// file.numFmtRefTable = make(map[int]xlsxNumFmt, 1)
 	worksheets = make(map[string]*zip.File, len(r.File))
+	worksheetRels := make(map[string]*zip.File)
 	for _, v = range r.File {
 		switch v.Name {
 		case "xl/sharedStrings.xml":
@@ -943,7 +1045,9 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 		case "xl/theme/theme1.xml":
 			themeFile = v
 		default:
-			if len(v.Name) > 14 {
+			if strings.HasPrefix(v.Name, "xl/worksheets/_rels/") {
+				worksheetRels[v.Name] = v
+			} else if len(v.Name) > 14 {
 				if v.Name[0:13] == "xl/worksheets" {
 					worksheets[v.Name[14:len(v.Name)-4]] = v
 				}
@@ -961,6 +1065,11 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 		return nil, fmt.Errorf("Input xlsx contains no worksheets.")
 	}
 	file.worksheets = worksheets
+	file.worksheetRels = worksheetRels
+	file.parts, err = readPackageParts(r)
+	if err != nil {
+		return nil, err
+	}
 	reftable, err = readSharedStringsFromZipFile(sharedStrings)
 	if err != nil {
 		return nil, err
@@ -979,10 +1088,11 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 		if err != nil {
 			return nil, err
 		}
+		style.locale = options.Locale
 
 		file.styles = style
 	}
-	sheetsByName, sheets, err = readSheetsFromZipFile(workbook, file, sheetXMLMap)
+	sheetsByName, sheets, err = readSheetsFromZipFile(workbook, file, sheetXMLMap, options)
 	if err != nil {
 		return nil, err
 	}
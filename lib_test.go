@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/xml"
 	"os"
+	"path/filepath"
 
 	"strings"
 
@@ -245,22 +246,29 @@ func (l *LibSuite) TestMakeRowFromSpan(c *C) {
 	var row *Row
 	var length int
 	var sheet *Sheet
+	var err error
 	sheet = new(Sheet)
 	rangeString = "1:3"
-	row = makeRowFromSpan(rangeString, sheet)
+	row, err = makeRowFromSpan(rangeString, sheet)
+	c.Assert(err, IsNil)
 	length = len(row.Cells)
 	c.Assert(length, Equals, 3)
 	c.Assert(row.Sheet, Equals, sheet)
 	rangeString = "5:7" // Note - we ignore lower bound!
-	row = makeRowFromSpan(rangeString, sheet)
+	row, err = makeRowFromSpan(rangeString, sheet)
+	c.Assert(err, IsNil)
 	length = len(row.Cells)
 	c.Assert(length, Equals, 7)
 	c.Assert(row.Sheet, Equals, sheet)
 	rangeString = "1:1"
-	row = makeRowFromSpan(rangeString, sheet)
+	row, err = makeRowFromSpan(rangeString, sheet)
+	c.Assert(err, IsNil)
 	length = len(row.Cells)
 	c.Assert(length, Equals, 1)
 	c.Assert(row.Sheet, Equals, sheet)
+	rangeString = "1"
+	_, err = makeRowFromSpan(rangeString, sheet)
+	c.Assert(err, NotNil)
 }
 
 func (l *LibSuite) TestReadRowsFromSheet(c *C) {
@@ -325,7 +333,8 @@ func (l *LibSuite) TestReadRowsFromSheet(c *C) {
 	file := new(File)
 	file.referenceTable = MakeSharedStringRefTable(sst)
 	sheet := new(Sheet)
-	rows, cols, maxCols, maxRows := readRowsFromSheet(worksheet, file, sheet)
+	rows, cols, maxCols, maxRows, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(maxRows, Equals, 2)
 	c.Assert(maxCols, Equals, 2)
 	row := rows[0]
@@ -349,6 +358,122 @@ func (l *LibSuite) TestReadRowsFromSheet(c *C) {
 	c.Assert(pane.YSplit, Equals, 1.0)
 }
 
+// Cells of the inline string, formula string and ISO 8601 date types
+// are mapped to the matching CellTypes.
+func (l *LibSuite) TestReadRowsFromSheetWithOtherCellTypes(c *C) {
+	var sheetxml = bytes.NewBufferString(`
+<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <dimension ref="A1:F1"/>
+  <sheetData>
+    <row r="1">
+      <c r="A1" t="inlineStr"><is><t>Inline</t></is></c>
+      <c r="B1" t="inlineStr"><is><r><t>Rich </t></r><r><rPr><i/></rPr><t>inline</t></r></is></c>
+      <c r="C1" t="str"><f>A1&amp;"!"</f><v>Inline!</v></c>
+      <c r="D1" t="str"><f>""</f></c>
+      <c r="E1" t="d"><v>2020-01-02T12:00:00</v></c>
+      <c r="F1" t="d"><v>06:00:00</v></c>
+    </row>
+  </sheetData>
+</worksheet>`)
+	worksheet := new(xlsxWorksheet)
+	err := xml.NewDecoder(sheetxml).Decode(worksheet)
+	c.Assert(err, IsNil)
+	file := new(File)
+	file.referenceTable = NewSharedStringRefTable()
+	sheet := new(Sheet)
+	rows, _, _, _, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
+	cells := rows[0].Cells
+
+	c.Assert(cells[0].Type(), Equals, CellTypeInline)
+	c.Assert(cells[0].Value, Equals, "Inline")
+	c.Assert(cells[1].Type(), Equals, CellTypeInline)
+	c.Assert(cells[1].Value, Equals, "Rich inline")
+	c.Assert(cells[1].RichText(), HasLen, 2)
+	c.Assert(cells[1].RichText()[1].Font.Italic, Equals, true)
+
+	c.Assert(cells[2].Type(), Equals, CellTypeStringFormula)
+	c.Assert(cells[2].Formula(), Equals, `A1&"!"`)
+	c.Assert(cells[2].Value, Equals, "Inline!")
+	c.Assert(cells[3].Type(), Equals, CellTypeStringFormula)
+	c.Assert(cells[3].Formula(), Equals, `""`)
+	c.Assert(cells[3].Value, Equals, "")
+
+	c.Assert(cells[4].Type(), Equals, CellTypeDate)
+	c.Assert(cells[4].Value, Equals, "43832.5")
+	c.Assert(cells[4].NumFmt, Equals, builtInNumFmt[22])
+	c.Assert(cells[5].Type(), Equals, CellTypeDate)
+	c.Assert(cells[5].Value, Equals, "0.25")
+	c.Assert(cells[5].NumFmt, Equals, builtInNumFmt[21])
+}
+
+// Malformed cell data is reported as a CellError identifying the
+// sheet and the cell, rather than causing a panic.
+func (l *LibSuite) TestReadRowsFromSheetWithBadSharedString(c *C) {
+	var sharedstringsXML = bytes.NewBufferString(`
+<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="1" uniqueCount="1">
+  <si>
+    <t>Foo</t>
+  </si>
+</sst>`)
+	var sheetxml = bytes.NewBufferString(`
+<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <dimension ref="A1:B2"/>
+  <sheetData>
+    <row r="1">
+      <c r="A1" t="s"><v>0</v></c>
+    </row>
+    <row r="2">
+      <c r="B2" t="s"><v>99</v></c>
+    </row>
+  </sheetData>
+</worksheet>`)
+	worksheet := new(xlsxWorksheet)
+	err := xml.NewDecoder(sheetxml).Decode(worksheet)
+	c.Assert(err, IsNil)
+	sst := new(xlsxSST)
+	err = xml.NewDecoder(sharedstringsXML).Decode(sst)
+	c.Assert(err, IsNil)
+	file := new(File)
+	file.referenceTable = MakeSharedStringRefTable(sst)
+	sheet := &Sheet{Name: "Upload"}
+	_, _, _, _, err = readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, NotNil)
+	cellErr, ok := err.(*CellError)
+	c.Assert(ok, Equals, true)
+	c.Assert(cellErr.Sheet, Equals, "Upload")
+	c.Assert(cellErr.Cell, Equals, "B2")
+	c.Assert(cellErr.Err, NotNil)
+}
+
+// A bad cell in one sheet of a workbook of several sheets makes
+// reading the workbook fail with its CellError.
+func (l *LibSuite) TestReadWorkbookWithBadCell(c *C) {
+	f := NewFile()
+	for _, name := range []string{"Bad", "Big", "Bigger"} {
+		sheet, err := f.AddSheet(name)
+		c.Assert(err, IsNil)
+		sheet.Cell(0, 0).SetString(name)
+		for y := 1; y < 2000; y++ {
+			sheet.Cell(y, 0).SetInt(y)
+			sheet.Cell(y, 1).SetString("text")
+		}
+	}
+	path := filepath.Join(c.MkDir(), "bad.xlsx")
+	c.Assert(f.Save(path), IsNil)
+
+	data := rewriteTestFile(c, path, "xl/worksheets/sheet1.xml", "<v>0</v>", "<v>zz</v>")
+	_, err := OpenBinary(data)
+	c.Assert(err, NotNil)
+	cellErr, ok := err.(*CellError)
+	c.Assert(ok, Equals, true)
+	c.Assert(cellErr.Sheet, Equals, "Bad")
+	c.Assert(cellErr.Cell, Equals, "A1")
+}
+
 func (l *LibSuite) TestReadRowsFromSheetWithMergeCells(c *C) {
 	var sharedstringsXML = bytes.NewBufferString(`
 <?xml version="1.0" encoding="UTF-8" standalone="yes"?>
@@ -403,7 +528,8 @@ func (l *LibSuite) TestReadRowsFromSheetWithMergeCells(c *C) {
 	file := new(File)
 	file.referenceTable = MakeSharedStringRefTable(sst)
 	sheet := new(Sheet)
-	rows, _, _, _ := readRowsFromSheet(worksheet, file, sheet)
+	rows, _, _, _, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	row := rows[0] //
 	cell1 := row.Cells[0]
 	c.Assert(cell1.HMerge, Equals, 1)
@@ -480,7 +606,8 @@ func (l *LibSuite) TestReadRowsFromSheetBadR(c *C) {
 	sheet := new(Sheet)
 	// Discarding all return values; this test is a regression for
 	// a panic due to an "index out of range."
-	readRowsFromSheet(worksheet, file, sheet)
+	_, _, _, _, err = readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 }
 
 func (l *LibSuite) TestReadRowsFromSheetWithLeadingEmptyRows(c *C) {
@@ -526,7 +653,8 @@ func (l *LibSuite) TestReadRowsFromSheetWithLeadingEmptyRows(c *C) {
 	file := new(File)
 	file.referenceTable = MakeSharedStringRefTable(sst)
 	sheet := new(Sheet)
-	rows, _, maxCols, maxRows := readRowsFromSheet(worksheet, file, sheet)
+	rows, _, maxCols, maxRows, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(maxRows, Equals, 5)
 	c.Assert(maxCols, Equals, 1)
 
@@ -592,7 +720,8 @@ func (l *LibSuite) TestReadRowsFromSheetWithLeadingEmptyCols(c *C) {
 	file := new(File)
 	file.referenceTable = MakeSharedStringRefTable(sst)
 	sheet := new(Sheet)
-	rows, cols, maxCols, maxRows := readRowsFromSheet(worksheet, file, sheet)
+	rows, cols, maxCols, maxRows, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(maxRows, Equals, 2)
 	c.Assert(maxCols, Equals, 4)
 
@@ -731,7 +860,8 @@ func (l *LibSuite) TestReadRowsFromSheetWithEmptyCells(c *C) {
 	file := new(File)
 	file.referenceTable = MakeSharedStringRefTable(sst)
 	sheet := new(Sheet)
-	rows, cols, maxCols, maxRows := readRowsFromSheet(worksheet, file, sheet)
+	rows, cols, maxCols, maxRows, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(maxRows, Equals, 3)
 	c.Assert(maxCols, Equals, 3)
 
@@ -775,7 +905,8 @@ func (l *LibSuite) TestReadRowsFromSheetWithTrailingEmptyCells(c *C) {
 	file := new(File)
 	file.referenceTable = MakeSharedStringRefTable(sst)
 	sheet := new(Sheet)
-	rows, _, maxCol, maxRow := readRowsFromSheet(worksheet, file, sheet)
+	rows, _, maxCol, maxRow, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(maxCol, Equals, 4)
 	c.Assert(maxRow, Equals, 8)
 
@@ -885,7 +1016,8 @@ func (l *LibSuite) TestReadRowsFromSheetWithMultipleSpans(c *C) {
 	file := new(File)
 	file.referenceTable = MakeSharedStringRefTable(sst)
 	sheet := new(Sheet)
-	rows, _, maxCols, maxRows := readRowsFromSheet(worksheet, file, sheet)
+	rows, _, maxCols, maxRows, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(maxRows, Equals, 2)
 	c.Assert(maxCols, Equals, 4)
 	row := rows[0]
@@ -960,7 +1092,8 @@ func (l *LibSuite) TestReadRowsFromSheetWithMultipleTypes(c *C) {
 	file := new(File)
 	file.referenceTable = MakeSharedStringRefTable(sst)
 	sheet := new(Sheet)
-	rows, _, maxCols, maxRows := readRowsFromSheet(worksheet, file, sheet)
+	rows, _, maxCols, maxRows, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(maxRows, Equals, 1)
 	c.Assert(maxCols, Equals, 6)
 	row := rows[0]
@@ -1033,7 +1166,8 @@ func (l *LibSuite) TestReadRowsFromSheetWithHiddenColumn(c *C) {
 	file := new(File)
 	file.referenceTable = MakeSharedStringRefTable(sst)
 	sheet := new(Sheet)
-	rows, _, maxCols, maxRows := readRowsFromSheet(worksheet, file, sheet)
+	rows, _, maxCols, maxRows, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(maxRows, Equals, 1)
 	c.Assert(maxCols, Equals, 2)
 	row := rows[0]
@@ -1070,7 +1204,8 @@ func (l *LibSuite) TestReadRowFromRaw(c *C) {
 	cell = xlsxC{R: "A2"}
 	rawRow.C = append(rawRow.C, cell)
 	sheet := new(Sheet)
-	row = makeRowFromRaw(rawRow, sheet)
+	row, err := makeRowFromRaw(rawRow, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
 	c.Assert(row.Cells, HasLen, 1)
 	c.Assert(row.Sheet, Equals, sheet)
@@ -1089,7 +1224,8 @@ func (l *LibSuite) TestReadRowFromRawWithMissingCells(c *C) {
 	cell = xlsxC{R: "E1"}
 	rawRow.C = append(rawRow.C, cell)
 	sheet := new(Sheet)
-	row = makeRowFromRaw(rawRow, sheet)
+	row, err := makeRowFromRaw(rawRow, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
 	c.Assert(row.Cells, HasLen, 5)
 	c.Assert(row.Sheet, Equals, sheet)
@@ -1111,7 +1247,8 @@ func (l *LibSuite) TestReadRowFromRawWithPartialCoordinates(c *C) {
 	cell = xlsxC{}
 	rawRow.C = append(rawRow.C, cell)
 	sheet := new(Sheet)
-	row = makeRowFromRaw(rawRow, sheet)
+	row, err := makeRowFromRaw(rawRow, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
 	c.Assert(row.Cells, HasLen, 27)
 	c.Assert(row.Sheet, Equals, sheet)
@@ -1169,7 +1306,8 @@ func (l *LibSuite) TestSharedFormulas(c *C) {
 
 	file := new(File)
 	sheet := new(Sheet)
-	rows, _, maxCols, maxRows := readRowsFromSheet(worksheet, file, sheet)
+	rows, _, maxCols, maxRows, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	c.Assert(maxCols, Equals, 3)
 	c.Assert(maxRows, Equals, 2)
 
@@ -1247,6 +1385,24 @@ func (l *LibSuite) TestSharedFormulasWithAbsoluteReferences(c *C) {
 	}
 }
 
+// Sheet names, defined names, function names and strings that look
+// like cell references are left alone when a shared formula is copied.
+func (l *LibSuite) TestSharedFormulasWithNamesLikeReferences(c *C) {
+	cases := []struct{ formula, expected string }{
+		{"Sheet1!A1+LOG10(B1)", "Sheet1!B2+LOG10(C2)"},
+		{"'My Sheet'!$A1*'AB1'!A$1", "'My Sheet'!$A2*'AB1'!B$1"},
+		{`"A1"&A1&ATAN2(1,1)`, `"A1"&B2&ATAN2(1,1)`},
+		{"SUM(A:A)+SUM(1:1)+Table1[Qty]", "SUM(B:B)+SUM(2:2)+Table1[Qty]"},
+		{"XFD1+A1048576", "#REF!+#REF!"},
+	}
+	sharedFormulas := map[int]sharedFormula{}
+	for i, test := range cases {
+		sharedFormulas[i] = sharedFormula{0, 0, test.formula}
+		cell := xlsxC{R: "B2", F: &xlsxF{T: "shared", Si: i}}
+		c.Assert(formulaForCell(cell, sharedFormulas), Equals, test.expected, Commentf(test.formula))
+	}
+}
+
 // Avoid panic when cell.F.T is "e" (for error)
 func (l *LibSuite) TestFormulaForCellPanic(c *C) {
 	cell := xlsxC{R: "A1"}
@@ -1308,7 +1464,8 @@ func (l *LibSuite) TestRowNotOverwrittenWhenFollowedByEmptyRow(c *C) {
 	file.referenceTable = MakeSharedStringRefTable(sst)
 
 	sheet := new(Sheet)
-	rows, _, _, _ := readRowsFromSheet(worksheet, file, sheet)
+	rows, _, _, _, err := readRowsFromSheet(worksheet, file, sheet)
+	c.Assert(err, IsNil)
 	cells := rows[3].Cells
 
 	c.Assert(cells, HasLen, 1)
@@ -0,0 +1,460 @@
+package xlsx
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Locale describes how formatted cell values are written for a
+// language and region: the separators used in numbers, and the names
+// used in dates and times.  Pass one to Cell.FormattedValueWithLocale.
+type Locale struct {
+	// Name is the language tag of the locale, such as "de-DE".
+	Name string
+	// LCID is the Windows locale identifier, such as 0x407, that
+	// number formats use in prefixes like [$-407].
+	LCID int
+
+	DecimalSeparator string
+	GroupSeparator   string
+
+	// Month names start with January, and day names with Sunday.
+	MonthNames         [12]string
+	MonthAbbreviations [12]string
+	DayNames           [7]string
+	DayAbbreviations   [7]string
+
+	// AM and PM are the designators shown for an AM/PM code.
+	AM string
+	PM string
+
+	// Eras are the eras of the locale's calendar, in the order they
+	// began, if it counts its years from the start of each era.
+	// Number formats show the era with g, gg or ggg, and the year of
+	// the era with e or ee.  Without eras, e shows the year.
+	Eras []Era
+
+	// NumFmts holds the codes of the built-in number formats that
+	// Excel defines differently for each locale: the currencies 5
+	// to 8, and the dates, times and Thai formats 23 to 36 and 50
+	// to 81.  See ReadOptions.Locale.
+	NumFmts map[int]string
+}
+
+// Era is an era of a calendar that counts its years from the start of
+// each era, such as the Japanese calendar.
+type Era struct {
+	// Start is the first day of the era, which is its year 1.
+	Start time.Time
+	// Name is shown for ggg, Abbreviation for gg and Initial for g.
+	Name         string
+	Abbreviation string
+	Initial      string
+}
+
+// LocaleEnUS is English as written in the United States.  It is the
+// locale used by Cell.FormattedValue.
+var LocaleEnUS = &Locale{
+	Name:             "en-US",
+	LCID:             0x409,
+	DecimalSeparator: ".",
+	GroupSeparator:   ",",
+	MonthNames: [12]string{"January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"},
+	MonthAbbreviations: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun",
+		"Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	DayNames:         [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	DayAbbreviations: [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	AM:               "AM",
+	PM:               "PM",
+	NumFmts: map[int]string{
+		5: `$#,##0_);($#,##0)`,
+		6: `$#,##0_);[Red]($#,##0)`,
+		7: `$#,##0.00_);($#,##0.00)`,
+		8: `$#,##0.00_);[Red]($#,##0.00)`,
+	},
+}
+
+// LocaleEnGB is English as written in the United Kingdom.
+var LocaleEnGB = &Locale{
+	Name:               "en-GB",
+	LCID:               0x809,
+	DecimalSeparator:   ".",
+	GroupSeparator:     ",",
+	MonthNames:         LocaleEnUS.MonthNames,
+	MonthAbbreviations: LocaleEnUS.MonthAbbreviations,
+	DayNames:           LocaleEnUS.DayNames,
+	DayAbbreviations:   LocaleEnUS.DayAbbreviations,
+	AM:                 "AM",
+	PM:                 "PM",
+	NumFmts: map[int]string{
+		5: `"£"#,##0;\-"£"#,##0`,
+		6: `"£"#,##0;[Red]\-"£"#,##0`,
+		7: `"£"#,##0.00;\-"£"#,##0.00`,
+		8: `"£"#,##0.00;[Red]\-"£"#,##0.00`,
+	},
+}
+
+// LocaleDeDE is German as written in Germany.
+var LocaleDeDE = &Locale{
+	Name:             "de-DE",
+	LCID:             0x407,
+	DecimalSeparator: ",",
+	GroupSeparator:   ".",
+	MonthNames: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni",
+		"Juli", "August", "September", "Oktober", "November", "Dezember"},
+	MonthAbbreviations: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun",
+		"Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+	DayNames:         [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	DayAbbreviations: [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+	AM:               "AM",
+	PM:               "PM",
+	NumFmts: map[int]string{
+		5: `#,##0 "€";\-#,##0 "€"`,
+		6: `#,##0 "€";[Red]\-#,##0 "€"`,
+		7: `#,##0.00 "€";\-#,##0.00 "€"`,
+		8: `#,##0.00 "€";[Red]\-#,##0.00 "€"`,
+	},
+}
+
+// LocaleFrFR is French as written in France.  Thousands are separated
+// with a no-break space.
+var LocaleFrFR = &Locale{
+	Name:             "fr-FR",
+	LCID:             0x40C,
+	DecimalSeparator: ",",
+	GroupSeparator:   "\u00a0",
+	MonthNames: [12]string{"janvier", "février", "mars", "avril", "mai", "juin",
+		"juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+	MonthAbbreviations: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin",
+		"juil.", "août", "sept.", "oct.", "nov.", "déc."},
+	DayNames:         [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+	DayAbbreviations: [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	AM:               "AM",
+	PM:               "PM",
+	NumFmts: map[int]string{
+		5: `#,##0 "€";\-#,##0 "€"`,
+		6: `#,##0 "€";[Red]\-#,##0 "€"`,
+		7: `#,##0.00 "€";\-#,##0.00 "€"`,
+		8: `#,##0.00 "€";[Red]\-#,##0.00 "€"`,
+	},
+}
+
+// LocaleJaJP is Japanese as written in Japan.
+var LocaleJaJP = &Locale{
+	Name:             "ja-JP",
+	LCID:             0x411,
+	DecimalSeparator: ".",
+	GroupSeparator:   ",",
+	MonthNames: [12]string{"1月", "2月", "3月", "4月", "5月", "6月",
+		"7月", "8月", "9月", "10月", "11月", "12月"},
+	MonthAbbreviations: [12]string{"1月", "2月", "3月", "4月", "5月", "6月",
+		"7月", "8月", "9月", "10月", "11月", "12月"},
+	DayNames:         [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+	DayAbbreviations: [7]string{"日", "月", "火", "水", "木", "金", "土"},
+	AM:               "午前",
+	PM:               "午後",
+	Eras: []Era{
+		{time.Date(1868, time.October, 23, 0, 0, 0, 0, time.UTC), "明治", "明", "M"},
+		{time.Date(1912, time.July, 30, 0, 0, 0, 0, time.UTC), "大正", "大", "T"},
+		{time.Date(1926, time.December, 25, 0, 0, 0, 0, time.UTC), "昭和", "昭", "S"},
+		{time.Date(1989, time.January, 8, 0, 0, 0, 0, time.UTC), "平成", "平", "H"},
+		{time.Date(2019, time.May, 1, 0, 0, 0, 0, time.UTC), "令和", "令", "R"},
+	},
+	NumFmts: map[int]string{
+		5:  `"¥"#,##0;"¥"\-#,##0`,
+		6:  `"¥"#,##0;[Red]"¥"\-#,##0`,
+		7:  `"¥"#,##0.00;"¥"\-#,##0.00`,
+		8:  `"¥"#,##0.00;[Red]"¥"\-#,##0.00`,
+		27: `[$-411]ge.m.d`,
+		28: `[$-411]ggge"年"m"月"d"日"`,
+		29: `[$-411]ggge"年"m"月"d"日"`,
+		30: `m/d/yy`,
+		31: `yyyy"年"m"月"d"日"`,
+		32: `h"時"mm"分"`,
+		33: `h"時"mm"分"ss"秒"`,
+		34: `yyyy"年"m"月"`,
+		35: `m"月"d"日"`,
+		36: `[$-411]ge.m.d`,
+		50: `[$-411]ge.m.d`,
+		51: `[$-411]ggge"年"m"月"d"日"`,
+		52: `yyyy"年"m"月"`,
+		53: `m"月"d"日"`,
+		54: `[$-411]ggge"年"m"月"d"日"`,
+		55: `yyyy"年"m"月"`,
+		56: `m"月"d"日"`,
+		57: `[$-411]ge.m.d`,
+		58: `[$-411]ggge"年"m"月"d"日"`,
+	},
+}
+
+// LocaleZhCN is Chinese as written in mainland China.
+var LocaleZhCN = &Locale{
+	Name:             "zh-CN",
+	LCID:             0x804,
+	DecimalSeparator: ".",
+	GroupSeparator:   ",",
+	MonthNames: [12]string{"一月", "二月", "三月", "四月", "五月", "六月",
+		"七月", "八月", "九月", "十月", "十一月", "十二月"},
+	MonthAbbreviations: [12]string{"1月", "2月", "3月", "4月", "5月", "6月",
+		"7月", "8月", "9月", "10月", "11月", "12月"},
+	DayNames:         [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+	DayAbbreviations: [7]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"},
+	AM:               "上午",
+	PM:               "下午",
+	NumFmts: map[int]string{
+		5:  `"¥"#,##0;"¥"\-#,##0`,
+		6:  `"¥"#,##0;[Red]"¥"\-#,##0`,
+		7:  `"¥"#,##0.00;"¥"\-#,##0.00`,
+		8:  `"¥"#,##0.00;[Red]"¥"\-#,##0.00`,
+		27: `yyyy"年"m"月"`,
+		28: `m"月"d"日"`,
+		29: `m"月"d"日"`,
+		30: `m-d-yy`,
+		31: `yyyy"年"m"月"d"日"`,
+		32: `h"时"mm"分"`,
+		33: `h"时"mm"分"ss"秒"`,
+		34: `上午/下午h"时"mm"分"`,
+		35: `上午/下午h"时"mm"分"ss"秒"`,
+		36: `yyyy"年"m"月"`,
+		50: `yyyy"年"m"月"`,
+		51: `m"月"d"日"`,
+		52: `yyyy"年"m"月"`,
+		53: `m"月"d"日"`,
+		54: `m"月"d"日"`,
+		55: `上午/下午h"时"mm"分"`,
+		56: `上午/下午h"时"mm"分"ss"秒"`,
+		57: `yyyy"年"m"月"`,
+		58: `m"月"d"日"`,
+	},
+}
+
+// LocaleZhTW is Chinese as written in Taiwan.
+var LocaleZhTW = &Locale{
+	Name:               "zh-TW",
+	LCID:               0x404,
+	DecimalSeparator:   ".",
+	GroupSeparator:     ",",
+	MonthNames:         LocaleZhCN.MonthNames,
+	MonthAbbreviations: LocaleZhCN.MonthAbbreviations,
+	DayNames:           LocaleZhCN.DayNames,
+	DayAbbreviations:   [7]string{"週日", "週一", "週二", "週三", "週四", "週五", "週六"},
+	AM:                 "上午",
+	PM:                 "下午",
+	Eras: []Era{
+		{time.Date(1912, time.January, 1, 0, 0, 0, 0, time.UTC), "中華民國", "民國", "民國"},
+	},
+	NumFmts: map[int]string{
+		5:  `"NT$"#,##0_);\("NT$"#,##0\)`,
+		6:  `"NT$"#,##0_);[Red]\("NT$"#,##0\)`,
+		7:  `"NT$"#,##0.00_);\("NT$"#,##0.00\)`,
+		8:  `"NT$"#,##0.00_);[Red]\("NT$"#,##0.00\)`,
+		27: `[$-404]e/m/d`,
+		28: `[$-404]e"年"m"月"d"日"`,
+		29: `[$-404]e"年"m"月"d"日"`,
+		30: `m/d/yy`,
+		31: `yyyy"年"m"月"d"日"`,
+		32: `hh"時"mm"分"`,
+		33: `hh"時"mm"分"ss"秒"`,
+		34: `上午/下午hh"時"mm"分"`,
+		35: `上午/下午hh"時"mm"分"ss"秒"`,
+		36: `[$-404]e/m/d`,
+		50: `[$-404]e/m/d`,
+		51: `[$-404]e"年"m"月"d"日"`,
+		52: `上午/下午hh"時"mm"分"`,
+		53: `上午/下午hh"時"mm"分"ss"秒"`,
+		54: `[$-404]e"年"m"月"d"日"`,
+		55: `上午/下午hh"時"mm"分"`,
+		56: `上午/下午hh"時"mm"分"ss"秒"`,
+		57: `[$-404]e/m/d`,
+		58: `[$-404]e"年"m"月"d"日"`,
+	},
+}
+
+// LocaleKoKR is Korean as written in South Korea.
+var LocaleKoKR = &Locale{
+	Name:             "ko-KR",
+	LCID:             0x412,
+	DecimalSeparator: ".",
+	GroupSeparator:   ",",
+	MonthNames: [12]string{"1월", "2월", "3월", "4월", "5월", "6월",
+		"7월", "8월", "9월", "10월", "11월", "12월"},
+	MonthAbbreviations: [12]string{"1월", "2월", "3월", "4월", "5월", "6월",
+		"7월", "8월", "9월", "10월", "11월", "12월"},
+	DayNames:         [7]string{"일요일", "월요일", "화요일", "수요일", "목요일", "금요일", "토요일"},
+	DayAbbreviations: [7]string{"일", "월", "화", "수", "목", "금", "토"},
+	AM:               "오전",
+	PM:               "오후",
+	NumFmts: map[int]string{
+		5:  `"₩"#,##0;"₩"\-#,##0`,
+		6:  `"₩"#,##0;[Red]"₩"\-#,##0`,
+		7:  `"₩"#,##0.00;"₩"\-#,##0.00`,
+		8:  `"₩"#,##0.00;[Red]"₩"\-#,##0.00`,
+		27: `yyyy"年" mm"月" dd"日"`,
+		28: `mm-dd`,
+		29: `mm-dd`,
+		30: `mm-dd-yy`,
+		31: `yyyy"년" mm"월" dd"일"`,
+		32: `h"시" mm"분"`,
+		33: `h"시" mm"분" ss"초"`,
+		34: `yyyy-mm-dd`,
+		35: `yyyy-mm-dd`,
+		36: `yyyy"年" mm"月" dd"日"`,
+		50: `yyyy"年" mm"月" dd"日"`,
+		51: `mm-dd`,
+		52: `yyyy-mm-dd`,
+		53: `yyyy-mm-dd`,
+		54: `mm-dd`,
+		55: `yyyy-mm-dd`,
+		56: `yyyy-mm-dd`,
+		57: `yyyy"年" mm"月" dd"日"`,
+		58: `mm-dd`,
+	},
+}
+
+// LocaleThTH is Thai as written in Thailand.  Its built-in number
+// formats 59 to 81 use Thai digits and date codes, and years of the
+// Buddhist era.
+var LocaleThTH = &Locale{
+	Name:             "th-TH",
+	LCID:             0x41E,
+	DecimalSeparator: ".",
+	GroupSeparator:   ",",
+	MonthNames: [12]string{"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน",
+		"กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม"},
+	MonthAbbreviations: [12]string{"ม.ค.", "ก.พ.", "มี.ค.", "เม.ย.", "พ.ค.", "มิ.ย.",
+		"ก.ค.", "ส.ค.", "ก.ย.", "ต.ค.", "พ.ย.", "ธ.ค."},
+	DayNames:         [7]string{"อาทิตย์", "จันทร์", "อังคาร", "พุธ", "พฤหัสบดี", "ศุกร์", "เสาร์"},
+	DayAbbreviations: [7]string{"อา.", "จ.", "อ.", "พ.", "พฤ.", "ศ.", "ส."},
+	AM:               "AM",
+	PM:               "PM",
+	NumFmts: map[int]string{
+		5:  `"฿"#,##0_);\("฿"#,##0\)`,
+		6:  `"฿"#,##0_);[Red]\("฿"#,##0\)`,
+		7:  `"฿"#,##0.00_);\("฿"#,##0.00\)`,
+		8:  `"฿"#,##0.00_);[Red]\("฿"#,##0.00\)`,
+		59: `t0`,
+		60: `t0.00`,
+		61: `t#,##0`,
+		62: `t#,##0.00`,
+		67: `t0%`,
+		68: `t0.00%`,
+		69: `t# ?/?`,
+		70: `t# ??/??`,
+		71: `ว/ด/ปปปป`,
+		72: `ว-ดดด-ปป`,
+		73: `ว-ดดด`,
+		74: `ดดด-ปป`,
+		75: `ช:นน`,
+		76: `ช:นน:ทท`,
+		77: `ว/ด/ปปปป ช:นน`,
+		78: `นน:ทท`,
+		79: `[ช]:นน:ทท`,
+		80: `นน:ทท.0`,
+		81: `d/m/bb`,
+	},
+}
+
+var (
+	localesLock sync.RWMutex
+	locales     = []*Locale{LocaleEnUS, LocaleEnGB, LocaleDeDE, LocaleFrFR, LocaleJaJP,
+		LocaleZhCN, LocaleZhTW, LocaleKoKR, LocaleThTH}
+)
+
+// RegisterLocale makes locale known to number formats that name it in
+// a prefix, by its LCID or its Name, such as [$-410] or [$-it-IT].  It
+// replaces any locale registered before with the same LCID.
+func RegisterLocale(locale *Locale) {
+	localesLock.Lock()
+	defer localesLock.Unlock()
+	for i, l := range locales {
+		if l.LCID == locale.LCID {
+			locales[i] = locale
+			return
+		}
+	}
+	locales = append(locales, locale)
+}
+
+// lookupLocale returns the registered locale named by the locale part
+// of a [$-...] prefix, which is either a hexadecimal LCID or a
+// language tag.  Only the low 16 bits of an LCID name the locale; the
+// rest choose a calendar and digits.  It returns nil for locales that
+// aren't known.
+func lookupLocale(id string) *Locale {
+	localesLock.RLock()
+	defer localesLock.RUnlock()
+	if n, err := strconv.ParseUint(id, 16, 32); err == nil {
+		lcid := int(n & 0xFFFF)
+		for _, l := range locales {
+			if l.LCID == lcid {
+				return l
+			}
+		}
+		return nil
+	}
+	for _, l := range locales {
+		if strings.EqualFold(l.Name, id) {
+			return l
+		}
+	}
+	return nil
+}
+
+// withNames returns the locale with its names of months and days and
+// its designators taken from names, if that isn't nil.  Separators
+// are left alone, as Excel takes those from the reader's settings
+// rather than from the format.
+func (l *Locale) withNames(names *Locale) *Locale {
+	if names == nil || names == l {
+		return l
+	}
+	merged := *names
+	merged.DecimalSeparator = l.DecimalSeparator
+	merged.GroupSeparator = l.GroupSeparator
+	return &merged
+}
+
+// localizeNumber replaces the decimal point of a number written with
+// strconv by the locale's decimal separator.
+func (l *Locale) localizeNumber(s string) string {
+	if l.DecimalSeparator == "." {
+		return s
+	}
+	return strings.Replace(s, ".", l.DecimalSeparator, 1)
+}
+
+// era returns the era of the locale that a date falls in, and the
+// year of the date in that era.  Dates before the first era, and every
+// date of a locale without eras, are in no era and keep their year.
+func (l *Locale) era(year int, month time.Month, day int) (*Era, int) {
+	for i := len(l.Eras) - 1; i >= 0; i-- {
+		era := &l.Eras[i]
+		y, m, d := era.Start.Date()
+		if year > y || year == y && (month > m || month == m && day >= d) {
+			return era, year - y + 1
+		}
+	}
+	return nil, year
+}
+
+// thaiDigits replaces the digits of s by Thai digits, as a t prefix or
+// a Thai date code asks for.
+func thaiDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r - '0' + '๐'
+		}
+		return r
+	}, s)
+}
+
+// firstLetter returns the first character of name, as shown by an
+// mmmmm code.
+func firstLetter(name string) string {
+	_, size := utf8.DecodeRuneInString(name)
+	return name[:size]
+}
@@ -0,0 +1,129 @@
+package xlsx
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type LocaleSuite struct{}
+
+var _ = Suite(&LocaleSuite{})
+
+func formatWithLocale(c *C, value float64, format string, locale *Locale) string {
+	cell := Cell{}
+	cell.SetFloatWithFormat(value, format)
+	val, err := cell.FormattedValueWithLocale(locale)
+	c.Assert(err, IsNil)
+	return val
+}
+
+// Numbers are written with the separators of the locale.
+func (s *LocaleSuite) TestSeparators(c *C) {
+	c.Assert(formatWithLocale(c, 1234567.891, "#,##0.00", LocaleDeDE), Equals, "1.234.567,89")
+	c.Assert(formatWithLocale(c, 1234567.891, "#,##0.00", LocaleFrFR), Equals, "1\u00a0234\u00a0567,89")
+	c.Assert(formatWithLocale(c, 1234567.891, "#,##0.00", LocaleJaJP), Equals, "1,234,567.89")
+	c.Assert(formatWithLocale(c, 0.5, "General", LocaleDeDE), Equals, "0,5")
+	c.Assert(formatWithLocale(c, 12345, "0.00E+00", LocaleDeDE), Equals, "1,23E+04")
+	c.Assert(formatWithLocale(c, 1234.5, "#,##0.00", nil), Equals, "1,234.50")
+}
+
+// Dates are written with the names and designators of the locale.
+func (s *LocaleSuite) TestDates(c *C) {
+	value := 37947.75 + 1.5/86400 // Saturday 22 November 2003, 18:00:01.5
+	c.Assert(formatWithLocale(c, value, "dddd, d. mmmm yyyy", LocaleDeDE), Equals, "Samstag, 22. November 2003")
+	c.Assert(formatWithLocale(c, value, "ddd d mmm", LocaleFrFR), Equals, "sam. 22 nov.")
+	c.Assert(formatWithLocale(c, value, "yyyy\"年\"mmmm d\"日\"", LocaleJaJP), Equals, "2003年11月 22日")
+	c.Assert(formatWithLocale(c, value, "AM/PM h:mm", LocaleJaJP), Equals, "午後 6:00")
+	c.Assert(formatWithLocale(c, value, "ss.0", LocaleDeDE), Equals, "01,5")
+}
+
+// A locale prefix in the format picks the names of months and days,
+// while the separators stay those of the reader's locale.
+func (s *LocaleSuite) TestLocalePrefix(c *C) {
+	c.Assert(formatWithLocale(c, 37947.75, "[$-407]mmmm yyyy", LocaleEnUS), Equals, "November 2003")
+	c.Assert(formatWithLocale(c, 37947.25, "[$-407]dddd", LocaleEnUS), Equals, "Samstag")
+	c.Assert(formatWithLocale(c, 37947.25, "[$-ja-JP]dddd", LocaleEnUS), Equals, "土曜日")
+	c.Assert(formatWithLocale(c, 37947.25, "[$-1010411]h:mm AM/PM", LocaleEnUS), Equals, "6:00 午前")
+	c.Assert(formatWithLocale(c, 37947.25, "[$-F800]dddd", LocaleDeDE), Equals, "Samstag")
+	c.Assert(formatWithLocale(c, 1234.5, "[$€-407] #,##0.00", LocaleDeDE), Equals, "€ 1.234,50")
+	c.Assert(formatWithLocale(c, 1234.5, "[$€-407] #,##0.00", LocaleEnUS), Equals, "€ 1,234.50")
+	c.Assert(formatWithLocale(c, 1234.5, "[$USD] #,##0", LocaleEnUS), Equals, "USD 1,235")
+}
+
+// Registered locales can be named in prefixes.
+func (s *LocaleSuite) TestRegisterLocale(c *C) {
+	c.Assert(lookupLocale("410"), IsNil)
+	italian := *LocaleEnUS
+	italian.Name = "it-IT"
+	italian.LCID = 0x410
+	italian.DayNames = [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"}
+	RegisterLocale(&italian)
+	defer func() {
+		localesLock.Lock()
+		locales = locales[:len(locales)-1]
+		localesLock.Unlock()
+	}()
+	c.Assert(lookupLocale("410"), Equals, &italian)
+	c.Assert(lookupLocale("IT-it"), Equals, &italian)
+	c.Assert(formatWithLocale(c, 37947.25, "[$-410]dddd", LocaleEnUS), Equals, "sabato")
+}
+
+// Every built-in format of every locale is rendered, with no code left
+// over as a literal.
+func (s *LocaleSuite) TestBuiltinFormats(c *C) {
+	for _, locale := range locales {
+		for id, format := range locale.NumFmts {
+			comment := Commentf("%s format %d: %s", locale.Name, id, format)
+			for _, section := range parseNumberFormat(format).sections {
+				if !section.isDate {
+					continue
+				}
+				for _, tok := range parseDateFormat(section.raw) {
+					if tok.kind == dateLiteral {
+						c.Check(strings.ContainsAny(strings.ToLower(tok.text), "abdeghmsyวดปชนท"), Equals, false, comment)
+					}
+				}
+			}
+			formatWithLocale(c, 44198.5, format, locale)
+		}
+	}
+
+	value := 44198.5 // Saturday 2 January 2021, 12:00
+	cases := []struct {
+		locale *Locale
+		id     int
+		want   string
+	}{
+		{LocaleEnUS, 5, "$44,199 "},
+		{LocaleEnGB, 7, "£44,198.50"},
+		{LocaleDeDE, 8, "44.198,50 €"},
+		{LocaleFrFR, 5, "44\u00a0199 €"},
+		{LocaleJaJP, 27, "R3.1.2"},
+		{LocaleJaJP, 28, "令和3年1月2日"},
+		{LocaleJaJP, 30, "1/2/21"},
+		{LocaleZhCN, 31, "2021年1月2日"},
+		{LocaleZhTW, 27, "110/1/2"},
+		{LocaleZhTW, 28, "110年1月2日"},
+		{LocaleKoKR, 31, "2021년 01월 02일"},
+		{LocaleThTH, 59, "๔๔๑๙๙"},
+		{LocaleThTH, 62, "๔๔,๑๙๘.๕๐"},
+		{LocaleThTH, 71, "๒/๑/๒๕๖๔"},
+		{LocaleThTH, 72, "๒-ม.ค.-๖๔"},
+		{LocaleThTH, 76, "๑๒:๐๐:๐๐"},
+		{LocaleThTH, 79, "๑๐๖๐๗๖๔:๐๐:๐๐"},
+		{LocaleThTH, 81, "2/1/64"},
+	}
+	for _, t := range cases {
+		c.Check(formatWithLocale(c, value, t.locale.NumFmts[t.id], t.locale), Equals, t.want, Commentf("%s format %d", t.locale.Name, t.id))
+	}
+}
+
+// Dates before the first era of a locale keep their year.
+func (s *LocaleSuite) TestEras(c *C) {
+	c.Assert(formatWithLocale(c, 32515, "[$-411]ggge\"年\"", LocaleEnUS), Equals, "昭和64年")
+	c.Assert(formatWithLocale(c, 32516, "[$-411]gge\"年\"", LocaleEnUS), Equals, "平1年")
+	c.Assert(formatWithLocale(c, 1, "[$-404]e", LocaleEnUS), Equals, "1900")
+	c.Assert(formatWithLocale(c, 4384, "[$-404]ee", LocaleEnUS), Equals, "01")
+	c.Assert(formatWithLocale(c, 44198, "yyyy bbbb", LocaleEnUS), Equals, "2021 2564")
+}
@@ -0,0 +1,1012 @@
+package xlsx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A number format code has up to four sections, separated by
+// semicolons.  Without conditions they apply to positive numbers,
+// negative numbers, zero and text, in that order.  Each section is a
+// run of tokens: digit placeholders, the decimal point, commas,
+// literal text and so on.
+//
+// See "Review guidelines for customizing a number format" in the
+// Excel documentation, and ECMA-376 Part 1, 18.8.31.
+
+type formatTokenKind int
+
+const (
+	formatLiteral  formatTokenKind = iota // text shown as it is
+	formatDigit                           // one of the digit placeholders 0, # and ?
+	formatPoint                           // the decimal point
+	formatComma                           // a thousands separator, or scaling by a thousand
+	formatPercent                         // multiplies by a hundred, and is shown
+	formatExponent                        // E+, E-, e+ or e-
+	formatSlash                           // the bar of a fraction
+	formatText                            // @, the text of the cell
+	formatGeneral                         // General
+	formatPad                             // _x, a space the width of x
+	formatFill                            // *x, x repeated to fill the cell
+)
+
+type formatToken struct {
+	kind formatTokenKind
+	text string
+}
+
+// formatCondition is the condition of a section, such as [>=100].
+type formatCondition struct {
+	op    string
+	value float64
+}
+
+// formatSection is one section of a number format code.
+type formatSection struct {
+	raw       string // the section without its colour and condition
+	tokens    []formatToken
+	color     string
+	condition *formatCondition
+	isDate    bool
+	thai      bool    // a t prefix shows digits in Thai
+	locale    *Locale // the locale named by a [$-409] prefix, if known
+}
+
+// numberFormat is a number format code, split into its sections.
+type numberFormat struct {
+	sections []*formatSection
+	text     *formatSection // the section for text, if there is one
+}
+
+// parseNumberFormat splits the format code format into sections and
+// the sections into tokens.  An empty format is taken to be General.
+func parseNumberFormat(format string) *numberFormat {
+	f := &numberFormat{}
+	if strings.TrimSpace(format) == "" {
+		format = "General"
+	}
+	section := &formatSection{}
+	var raw strings.Builder
+	literal := func(s string) {
+		section.tokens = append(section.tokens, formatToken{kind: formatLiteral, text: s})
+	}
+	for i := 0; i < len(format); {
+		r, size := utf8.DecodeRuneInString(format[i:])
+		start := i
+		i += size
+		switch r {
+		case ';':
+			section.raw = raw.String()
+			f.sections = append(f.sections, section)
+			section = &formatSection{}
+			raw.Reset()
+			continue
+		case '"':
+			end := strings.IndexByte(format[i:], '"')
+			if end < 0 {
+				end = len(format) - i
+			}
+			literal(format[i : i+end])
+			i += end
+			if i < len(format) {
+				i++
+			}
+		case '\\', '_', '*':
+			if i < len(format) {
+				_, size = utf8.DecodeRuneInString(format[i:])
+				text := format[i : i+size]
+				i += size
+				switch r {
+				case '\\':
+					literal(text)
+				case '_':
+					section.tokens = append(section.tokens, formatToken{kind: formatPad, text: text})
+				case '*':
+					section.tokens = append(section.tokens, formatToken{kind: formatFill, text: text})
+				}
+			}
+		case '[':
+			end := strings.IndexByte(format[i:], ']')
+			if end < 0 {
+				end = len(format) - i
+			}
+			content := format[i : i+end]
+			i += end
+			if i < len(format) {
+				i++
+			}
+			if section.parseBracket(content) {
+				// Colours, conditions and locales are left out of
+				// the raw section; elapsed times stay in it.
+				continue
+			}
+		case '0', '#', '?':
+			section.tokens = append(section.tokens, formatToken{kind: formatDigit, text: string(r)})
+		case '.':
+			section.tokens = append(section.tokens, formatToken{kind: formatPoint, text: "."})
+		case ',':
+			section.tokens = append(section.tokens, formatToken{kind: formatComma, text: ","})
+		case '%':
+			section.tokens = append(section.tokens, formatToken{kind: formatPercent, text: "%"})
+		case '/':
+			section.tokens = append(section.tokens, formatToken{kind: formatSlash, text: "/"})
+		case '@':
+			section.tokens = append(section.tokens, formatToken{kind: formatText, text: "@"})
+		case 'E', 'e':
+			if i < len(format) && (format[i] == '+' || format[i] == '-') {
+				i++
+				section.tokens = append(section.tokens, formatToken{kind: formatExponent, text: format[start:i]})
+			} else {
+				// The year of an era, as in ge.m.d.
+				section.isDate = true
+				literal(string(r))
+			}
+		default:
+			switch {
+			case (r == 't' || r == 'T') && raw.Len() == 0:
+				// Thai digits, as in t#,##0 or tว/ด/ปปปป.
+				section.thai = true
+			case (r == 'G' || r == 'g') && len(format)-start >= 7 && strings.EqualFold(format[start:start+7], "General"):
+				i = start + 7
+				section.tokens = append(section.tokens, formatToken{kind: formatGeneral, text: "General"})
+			case (r == 'A' || r == 'a') && (hasPrefixFold(format[start:], "AM/PM") || hasPrefixFold(format[start:], "A/P")):
+				section.isDate = true
+				if hasPrefixFold(format[start:], "AM/PM") {
+					i = start + 5
+				} else {
+					i = start + 3
+				}
+				literal(format[start:i])
+			default:
+				if strings.ContainsRune("yYmMdDhHsSgGbB", r) || thaiDateCodes[r] != 0 {
+					section.isDate = true
+				}
+				literal(string(r))
+			}
+		}
+		raw.WriteString(format[start:i])
+	}
+	section.raw = raw.String()
+	f.sections = append(f.sections, section)
+
+	// Any section holding @ makes the last section the one for text.
+	for _, s := range f.sections {
+		if s.has(formatText) {
+			f.text = f.sections[len(f.sections)-1]
+			f.sections = f.sections[:len(f.sections)-1]
+			break
+		}
+	}
+	if f.text == nil && len(f.sections) > 3 {
+		f.text = f.sections[3]
+		f.sections = f.sections[:3]
+	}
+	return f
+}
+
+// parseBracket handles the content of a [...] in a section.  It
+// reports whether the brackets should be left out of the raw section.
+func (s *formatSection) parseBracket(content string) bool {
+	lower := strings.ToLower(content)
+	switch {
+	case lower == "":
+		return true
+	case strings.ContainsRune("<>=", rune(content[0])):
+		op := content[:1]
+		if len(content) > 1 && strings.ContainsRune("<>=", rune(content[1])) {
+			op = content[:2]
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(content[len(op):]), 64)
+		if err == nil {
+			s.condition = &formatCondition{op: op, value: value}
+		}
+		return true
+	case content[0] == '$':
+		// A currency symbol and locale, such as [$€-407].  The
+		// symbol is shown, and the names of months and days come
+		// from the locale.
+		symbol := content[1:]
+		if dash := strings.IndexByte(symbol, '-'); dash >= 0 {
+			s.locale = lookupLocale(symbol[dash+1:])
+			symbol = symbol[:dash]
+		}
+		if symbol != "" {
+			s.tokens = append(s.tokens, formatToken{kind: formatLiteral, text: symbol})
+		}
+		return true
+	case isElapsed(lower):
+		// An elapsed time, such as [h].
+		s.isDate = true
+		return false
+	case isFormatColor(lower):
+		s.color = content
+		return true
+	}
+	return true
+}
+
+// formatColors are the colours that can be named in a section.
+var formatColors = map[string]bool{
+	"black": true, "blue": true, "cyan": true, "green": true,
+	"magenta": true, "red": true, "white": true, "yellow": true,
+}
+
+func isFormatColor(name string) bool {
+	if formatColors[name] {
+		return true
+	}
+	if strings.HasPrefix(name, "color") {
+		n, err := strconv.Atoi(name[len("color"):])
+		return err == nil && n >= 1 && n <= 56
+	}
+	return false
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// has reports whether the section has a token of the given kind.
+func (s *formatSection) has(kind formatTokenKind) bool {
+	for _, t := range s.tokens {
+		if t.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether v meets the condition.
+func (c *formatCondition) matches(v float64) bool {
+	switch c.op {
+	case "<":
+		return v < c.value
+	case "<=", "=<":
+		return v <= c.value
+	case ">":
+		return v > c.value
+	case ">=", "=>":
+		return v >= c.value
+	case "=", "==":
+		return v == c.value
+	case "<>", "><":
+		return v != c.value
+	}
+	return false
+}
+
+// negativeOnly reports whether the condition can only be met by
+// numbers that aren't positive, in which case the section is expected
+// to show its own sign.
+func (c *formatCondition) negativeOnly() bool {
+	switch c.op {
+	case "<", "<=", "=<":
+		return c.value <= 0
+	}
+	return false
+}
+
+// section returns the section that formats the number v, and whether
+// the number should be shown without its minus sign.
+func (f *numberFormat) section(v float64) (*formatSection, bool) {
+	sections := f.sections
+	if len(sections) == 0 {
+		return nil, false
+	}
+	conditional := false
+	for i := 0; i < len(sections) && i < 2; i++ {
+		if c := sections[i].condition; c != nil {
+			conditional = true
+			if c.matches(v) {
+				return sections[i], v < 0 && c.negativeOnly()
+			}
+		}
+	}
+	if conditional {
+		for _, s := range sections {
+			if s.condition == nil {
+				return s, false
+			}
+		}
+		return sections[len(sections)-1], false
+	}
+	switch {
+	case v < 0 && len(sections) > 1:
+		return sections[1], true
+	case v == 0 && len(sections) > 2:
+		return sections[2], false
+	}
+	return sections[0], false
+}
+
+// formatText renders the text s with the format.  Without a section
+// for text, the text is shown as it is.
+func (f *numberFormat) formatText(s string) string {
+	if f.text == nil {
+		return s
+	}
+	var b strings.Builder
+	for _, t := range f.text.tokens {
+		switch t.kind {
+		case formatText:
+			b.WriteString(s)
+		case formatPad:
+			b.WriteByte(' ')
+		case formatFill:
+		default:
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
+
+// isTextOnly reports whether numbers are shown as they are stored by
+// the format, that is whether it is General or @.
+func (f *numberFormat) isTextOnly() bool {
+	if len(f.sections) == 0 {
+		return true
+	}
+	if len(f.sections) > 1 {
+		return false
+	}
+	tokens := f.sections[0].tokens
+	return len(tokens) == 1 && tokens[0].kind == formatGeneral && f.sections[0].condition == nil
+}
+
+// formatNumber renders the number v with the section, using the
+// decimal and thousands separators of locale.  Date and time sections
+// aren't handled here.
+func (s *formatSection) formatNumber(v float64, dropSign bool, locale *Locale) string {
+	negative := v < 0 && !dropSign
+	v = math.Abs(v)
+	var body string
+	switch {
+	case s.has(formatGeneral):
+		body = s.render(func(t formatToken) string {
+			if t.kind == formatGeneral {
+				return locale.localizeNumber(formatGeneralNumber(v))
+			}
+			return ""
+		})
+	case s.has(formatExponent):
+		body = s.formatScientific(v, locale)
+	case s.isFraction():
+		body = s.formatFraction(v)
+	default:
+		body = s.formatFixed(v, locale)
+	}
+	if s.thai {
+		body = thaiDigits(body)
+	}
+	if negative {
+		return "-" + body
+	}
+	return body
+}
+
+// render writes out the tokens of the section, using placeholder for
+// everything that isn't a literal.
+func (s *formatSection) render(placeholder func(formatToken) string) string {
+	var b strings.Builder
+	for _, t := range s.tokens {
+		switch t.kind {
+		case formatLiteral:
+			b.WriteString(t.text)
+		case formatPad:
+			b.WriteByte(' ')
+		case formatFill:
+			// A cell has no width here, so there is nothing to fill.
+		case formatPercent:
+			b.WriteString("%")
+		default:
+			b.WriteString(placeholder(t))
+		}
+	}
+	return b.String()
+}
+
+// scale returns v multiplied by a hundred for every percent sign, and
+// divided by a thousand for every comma that follows the last digit
+// placeholder of the integer or decimal part.
+func (s *formatSection) scale(v float64) float64 {
+	for i, t := range s.tokens {
+		switch t.kind {
+		case formatPercent:
+			v *= 100
+		case formatComma:
+			if s.commaScales(i) {
+				v /= 1000
+			}
+		}
+	}
+	return v
+}
+
+// commaScales reports whether the comma at i scales the number by a
+// thousand: it follows a digit placeholder, and the next token that
+// isn't a comma isn't a digit placeholder.
+func (s *formatSection) commaScales(i int) bool {
+	if !s.digitBefore(i) {
+		return false
+	}
+	for _, t := range s.tokens[i+1:] {
+		if t.kind != formatComma {
+			return t.kind != formatDigit
+		}
+	}
+	return true
+}
+
+// commaGroups reports whether the comma at i separates thousands.
+func (s *formatSection) commaGroups(i int) bool {
+	return s.digitBefore(i) && !s.commaScales(i)
+}
+
+func (s *formatSection) digitBefore(i int) bool {
+	for _, t := range s.tokens[:i] {
+		if t.kind == formatDigit {
+			return true
+		}
+	}
+	return false
+}
+
+// grouping reports whether the integer part is shown with thousands
+// separators.
+func (s *formatSection) grouping() bool {
+	for i, t := range s.tokens {
+		if t.kind == formatPoint || t.kind == formatExponent {
+			break
+		}
+		if t.kind == formatComma && s.commaGroups(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatFixed renders v, which isn't negative, in a section without
+// an exponent or fraction.
+func (s *formatSection) formatFixed(v float64, locale *Locale) string {
+	v = s.scale(v)
+	point := len(s.tokens)
+	decimals := 0
+	for i, t := range s.tokens {
+		if t.kind == formatPoint && point == len(s.tokens) {
+			point = i
+		} else if t.kind == formatDigit && i > point {
+			decimals++
+		}
+	}
+	integer, fraction := roundDecimal(v, decimals)
+	separator := ""
+	if s.grouping() {
+		separator = locale.GroupSeparator
+	}
+	intDigits := s.integerDigits(integer, 0, point, separator)
+	fracDigits := decimalDigits(s.tokens, point, fraction)
+	return s.renderNumber(point, intDigits, fracDigits, locale.DecimalSeparator)
+}
+
+// renderNumber writes out the section with the digits filled in, and
+// decimal as the decimal point.
+func (s *formatSection) renderNumber(point int, intDigits, fracDigits map[int]string, decimal string) string {
+	var b strings.Builder
+	for i, t := range s.tokens {
+		switch t.kind {
+		case formatLiteral:
+			b.WriteString(t.text)
+		case formatPad:
+			b.WriteByte(' ')
+		case formatPercent:
+			b.WriteString("%")
+		case formatPoint:
+			if i == point {
+				b.WriteString(decimal)
+			}
+		case formatComma:
+			if !s.digitBefore(i) {
+				b.WriteString(",")
+			}
+		case formatDigit:
+			if i < point {
+				b.WriteString(intDigits[i])
+			} else {
+				b.WriteString(fracDigits[i])
+			}
+		}
+	}
+	return b.String()
+}
+
+// integerDigits assigns the digits of integer to the digit
+// placeholders between from and to, right to left.  The leftmost
+// placeholder takes any digits left over.  Thousands are separated
+// with separator, if it isn't empty.
+func (s *formatSection) integerDigits(integer string, from, to int, separator string) map[int]string {
+	if integer == "0" {
+		integer = ""
+	}
+	var placeholders []int
+	for i := from; i < to; i++ {
+		if s.tokens[i].kind == formatDigit {
+			placeholders = append(placeholders, i)
+		}
+	}
+	digits := make(map[int]string, len(placeholders))
+	written := 0
+	withSeparator := func(d byte) string {
+		written++
+		if separator != "" && written > 1 && (written-1)%3 == 0 {
+			return string(d) + separator
+		}
+		return string(d)
+	}
+	remaining := integer
+	for j := len(placeholders) - 1; j >= 0; j-- {
+		i := placeholders[j]
+		if j == 0 && len(remaining) > 1 {
+			var b []string
+			for k := len(remaining) - 1; k >= 0; k-- {
+				b = append(b, withSeparator(remaining[k]))
+			}
+			var out strings.Builder
+			for k := len(b) - 1; k >= 0; k-- {
+				out.WriteString(b[k])
+			}
+			digits[i] = out.String()
+			remaining = ""
+			continue
+		}
+		if remaining != "" {
+			digits[i] = withSeparator(remaining[len(remaining)-1])
+			remaining = remaining[:len(remaining)-1]
+			continue
+		}
+		switch s.tokens[i].text {
+		case "0":
+			digits[i] = withSeparator('0')
+		case "?":
+			digits[i] = " "
+		}
+	}
+	return digits
+}
+
+// decimalDigits assigns the digits of fraction to the digit
+// placeholders after point, left to right.  Trailing zeros are left
+// out for # and shown as spaces for ?.
+func decimalDigits(tokens []formatToken, point int, fraction string) map[int]string {
+	var placeholders []int
+	for i := point + 1; i < len(tokens); i++ {
+		if tokens[i].kind == formatExponent {
+			break
+		}
+		if tokens[i].kind == formatDigit {
+			placeholders = append(placeholders, i)
+		}
+	}
+	digits := make(map[int]string, len(placeholders))
+	for j, i := range placeholders {
+		if j < len(fraction) {
+			digits[i] = fraction[j : j+1]
+		}
+	}
+	for j := len(placeholders) - 1; j >= 0; j-- {
+		i := placeholders[j]
+		if digits[i] != "0" && digits[i] != "" {
+			break
+		}
+		switch tokens[i].text {
+		case "#":
+			digits[i] = ""
+		case "?":
+			digits[i] = " "
+		case "0":
+			digits[i] = "0"
+			return digits
+		}
+	}
+	return digits
+}
+
+// formatScientific renders v, which isn't negative, in a section with
+// an exponent.  With more than one integer placeholder and a # among
+// them, the exponent is a multiple of their number, as in ##0.0E+0.
+func (s *formatSection) formatScientific(v float64, locale *Locale) string {
+	v = s.scale(v)
+	exponentAt := 0
+	for i, t := range s.tokens {
+		if t.kind == formatExponent {
+			exponentAt = i
+			break
+		}
+	}
+	point := exponentAt
+	intPlaces, decimals := 0, 0
+	engineering := false
+	for i, t := range s.tokens[:exponentAt] {
+		switch {
+		case t.kind == formatPoint && point == exponentAt:
+			point = i
+		case t.kind == formatDigit && point == exponentAt:
+			intPlaces++
+			engineering = engineering || t.text == "#"
+		case t.kind == formatDigit:
+			decimals++
+		}
+	}
+	if intPlaces == 0 {
+		intPlaces = 1
+	}
+	exponent := 0
+	if v != 0 {
+		exponent = int(math.Floor(math.Log10(v)))
+		if engineering && intPlaces > 1 {
+			exponent = int(math.Floor(float64(exponent)/float64(intPlaces))) * intPlaces
+		} else {
+			exponent -= intPlaces - 1
+		}
+	}
+	integer, fraction := roundDecimal(v/math.Pow(10, float64(exponent)), decimals)
+	period := 1
+	if engineering && intPlaces > 1 {
+		period = intPlaces
+	}
+	if v != 0 && len(integer) > intPlaces {
+		// Rounding carried into another integer digit.
+		exponent += period
+		integer, fraction = roundDecimal(v/math.Pow(10, float64(exponent)), decimals)
+	}
+	intDigits := s.integerDigits(integer, 0, point, "")
+	if integer == "0" {
+		// A zero mantissa still shows its integer digit.
+		for i := point - 1; i >= 0; i-- {
+			if s.tokens[i].kind == formatDigit {
+				intDigits[i] = "0"
+				break
+			}
+		}
+	}
+	fracDigits := decimalDigits(s.tokens, point, fraction)
+
+	var b strings.Builder
+	mantissa := &formatSection{tokens: s.tokens[:exponentAt]}
+	b.WriteString(mantissa.renderNumber(point, intDigits, fracDigits, locale.DecimalSeparator))
+
+	sign := s.tokens[exponentAt].text
+	b.WriteByte(sign[0])
+	switch {
+	case exponent < 0:
+		b.WriteByte('-')
+	case sign[1] == '+':
+		b.WriteByte('+')
+	}
+	places := 0
+	for _, t := range s.tokens[exponentAt+1:] {
+		if t.kind == formatDigit {
+			places++
+		}
+	}
+	digits := strconv.Itoa(abs(exponent))
+	for len(digits) < places {
+		digits = "0" + digits
+	}
+	written := false
+	for _, t := range s.tokens[exponentAt+1:] {
+		switch t.kind {
+		case formatDigit:
+			if !written {
+				b.WriteString(digits)
+				written = true
+			}
+		case formatLiteral:
+			b.WriteString(t.text)
+		case formatPad:
+			b.WriteByte(' ')
+		case formatPercent:
+			b.WriteString("%")
+		}
+	}
+	return b.String()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// fractionLayout finds the parts of a fraction in a section: the
+// integer placeholders, the numerator and the denominator.  The
+// numerator is the run of digit placeholders just before the slash,
+// and the denominator the run of placeholders or digits just after.
+func (s *formatSection) fractionLayout() (slash, numStart, denEnd int, ok bool) {
+	slash = -1
+	for i, t := range s.tokens {
+		if t.kind == formatSlash {
+			slash = i
+			break
+		}
+	}
+	if slash <= 0 {
+		return 0, 0, 0, false
+	}
+	numStart = slash
+	for numStart > 0 && s.tokens[numStart-1].kind == formatDigit {
+		numStart--
+	}
+	denEnd = slash + 1
+	for denEnd < len(s.tokens) && isDenominatorToken(s.tokens[denEnd]) {
+		denEnd++
+	}
+	return slash, numStart, denEnd, numStart < slash && denEnd > slash+1
+}
+
+func isDenominatorToken(t formatToken) bool {
+	return t.kind == formatDigit || (t.kind == formatLiteral && len(t.text) == 1 && t.text[0] >= '1' && t.text[0] <= '9')
+}
+
+// isFraction reports whether the section shows a fraction.
+func (s *formatSection) isFraction() bool {
+	_, _, _, ok := s.fractionLayout()
+	return ok
+}
+
+// formatFraction renders v, which isn't negative, as a fraction such
+// as "# ?/?" or "?/4".
+func (s *formatSection) formatFraction(v float64) string {
+	v = s.scale(v)
+	slash, numStart, denEnd, _ := s.fractionLayout()
+	hasInteger := false
+	for _, t := range s.tokens[:numStart] {
+		if t.kind == formatDigit {
+			hasInteger = true
+			break
+		}
+	}
+
+	fixed := false
+	denText := ""
+	for _, t := range s.tokens[slash+1 : denEnd] {
+		if t.kind == formatLiteral {
+			fixed = true
+		}
+		denText += t.text
+	}
+	whole, part := 0.0, v
+	if hasInteger {
+		whole = math.Floor(v)
+		part = v - whole
+	}
+	var num, den int
+	if fixed {
+		den, _ = strconv.Atoi(strings.NewReplacer("#", "0", "?", "0").Replace(denText))
+		if den == 0 {
+			den = 1
+		}
+		num = int(math.Floor(part*float64(den) + 0.5))
+	} else {
+		num, den = approximateFraction(part, int(math.Pow(10, float64(denEnd-slash-1)))-1)
+	}
+	if hasInteger && num == den && !fixed {
+		whole++
+		num = 0
+	}
+
+	integer, _ := roundDecimal(whole, 0)
+	if !hasInteger || (whole == 0 && num != 0) {
+		integer = "0"
+	}
+	intDigits := s.integerDigits(integer, 0, numStart, "")
+	if hasInteger && whole == 0 && num == 0 {
+		for i := numStart - 1; i >= 0; i-- {
+			if s.tokens[i].kind == formatDigit {
+				intDigits[i] = "0"
+				break
+			}
+		}
+	}
+
+	numText := strconv.Itoa(num)
+	denShown := strconv.Itoa(den)
+	if fixed {
+		denShown = denText
+	}
+	blank := hasInteger && num == 0
+	var b strings.Builder
+	for i, t := range s.tokens {
+		switch {
+		case i < numStart:
+			switch t.kind {
+			case formatDigit:
+				b.WriteString(intDigits[i])
+			case formatLiteral:
+				b.WriteString(t.text)
+			case formatPad:
+				b.WriteByte(' ')
+			case formatPercent:
+				b.WriteString("%")
+			}
+		case i == numStart:
+			width := slash - numStart
+			text := padLeft(numText, s.tokens[numStart:slash])
+			if blank {
+				text = strings.Repeat(" ", width)
+			}
+			b.WriteString(text)
+		case i < slash:
+		case i == slash:
+			if blank {
+				b.WriteString(" ")
+			} else {
+				b.WriteString("/")
+			}
+		case i == slash+1:
+			text := denShown
+			if !fixed {
+				text = padRight(denShown, s.tokens[slash+1:denEnd])
+			}
+			if blank {
+				text = strings.Repeat(" ", len(text))
+			}
+			b.WriteString(text)
+		case i < denEnd:
+		default:
+			switch t.kind {
+			case formatLiteral:
+				b.WriteString(t.text)
+			case formatPad:
+				b.WriteByte(' ')
+			case formatPercent:
+				b.WriteString("%")
+			}
+		}
+	}
+	return b.String()
+}
+
+// padLeft pads digits on the left for the placeholders it doesn't
+// fill: ? with a space, 0 with a zero, # with nothing.
+func padLeft(digits string, placeholders []formatToken) string {
+	var pad strings.Builder
+	for j := 0; j < len(placeholders)-len(digits); j++ {
+		switch placeholders[j].text {
+		case "?":
+			pad.WriteByte(' ')
+		case "0":
+			pad.WriteByte('0')
+		}
+	}
+	return pad.String() + digits
+}
+
+// padRight pads digits on the right with a space for every ?
+// placeholder it doesn't fill.
+func padRight(digits string, placeholders []formatToken) string {
+	for j := len(digits); j < len(placeholders); j++ {
+		if placeholders[j].text == "?" {
+			digits += " "
+		}
+	}
+	return digits
+}
+
+// approximateFraction returns the fraction closest to v with a
+// denominator no bigger than maxDen, preferring the smallest
+// denominator.
+func approximateFraction(v float64, maxDen int) (num, den int) {
+	if maxDen < 1 {
+		maxDen = 1
+	}
+	num, den = int(math.Floor(v+0.5)), 1
+	best := math.Abs(v - float64(num))
+	for d := 2; d <= maxDen && best > 0; d++ {
+		n := int(math.Floor(v*float64(d) + 0.5))
+		if diff := math.Abs(v - float64(n)/float64(d)); diff < best-1e-12 {
+			num, den, best = n, d, diff
+		}
+	}
+	return num, den
+}
+
+// roundDecimal returns the integer and decimal digits of v, which
+// mustn't be negative, rounded half away from zero to places decimal
+// places.  Like Excel, it works from the first 15 significant digits
+// of v, so that 1.005 rounds up to 1.01.
+func roundDecimal(v float64, places int) (integer, fraction string) {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return strconv.FormatFloat(v, 'f', -1, 64), ""
+	}
+	s := strconv.FormatFloat(v, 'e', 14, 64)
+	e := strings.IndexByte(s, 'e')
+	exp, _ := strconv.Atoi(s[e+1:])
+	digits := []byte(s[:1] + s[2:e])
+	point := exp + 1 // the number of digits before the decimal point
+	keep := point + places
+	switch {
+	case keep < 0:
+		digits = nil
+		point = -places
+	case keep >= len(digits):
+		for len(digits) < keep {
+			digits = append(digits, '0')
+		}
+	default:
+		roundUp := digits[keep] >= '5'
+		digits = digits[:keep]
+		if roundUp {
+			j := len(digits) - 1
+			for ; j >= 0 && digits[j] == '9'; j-- {
+				digits[j] = '0'
+			}
+			if j >= 0 {
+				digits[j]++
+			} else {
+				digits = append([]byte{'1'}, digits...)
+				point++
+			}
+		}
+	}
+	if point <= 0 {
+		return "0", strings.Repeat("0", -point) + string(digits)
+	}
+	integer = strings.TrimLeft(string(digits[:point]), "0")
+	if integer == "" {
+		integer = "0"
+	}
+	return integer, string(digits[point:])
+}
+
+// formatGeneralNumber renders v as the General format does: as many
+// digits as fit in eleven characters, switching to scientific
+// notation for very large and very small numbers.
+func formatGeneralNumber(v float64) string {
+	if v == 0 {
+		return "0"
+	}
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	exponent := int(math.Floor(math.Log10(v)))
+	if exponent >= -4 && exponent <= 10 {
+		intDigits := exponent + 1
+		if intDigits < 1 {
+			intDigits = 1
+		}
+		decimals := 10 - intDigits
+		if decimals < 0 {
+			decimals = 0
+		}
+		integer, fraction := roundDecimal(v, decimals)
+		if len(integer) <= 11 {
+			if fraction = strings.TrimRight(fraction, "0"); fraction == "" {
+				return sign + integer
+			}
+			return sign + integer + "." + fraction
+		}
+	}
+	integer, fraction := roundDecimal(v/math.Pow(10, float64(exponent)), 5)
+	if integer == "10" {
+		exponent++
+		integer, fraction = roundDecimal(v/math.Pow(10, float64(exponent)), 5)
+	}
+	mantissa := integer
+	if fraction = strings.TrimRight(fraction, "0"); fraction != "" {
+		mantissa += "." + fraction
+	}
+	exp := "E+"
+	if exponent < 0 {
+		exp = "E-"
+	}
+	digits := strconv.Itoa(abs(exponent))
+	if len(digits) < 2 {
+		digits = "0" + digits
+	}
+	return sign + mantissa + exp + digits
+}
@@ -0,0 +1,174 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type NumberFormatSuite struct{}
+
+var _ = Suite(&NumberFormatSuite{})
+
+type formatCase struct {
+	format   string
+	value    float64
+	expected string
+}
+
+func checkFormatCases(c *C, cases []formatCase) {
+	for _, fc := range cases {
+		cell := Cell{}
+		cell.SetFloatWithFormat(fc.value, fc.format)
+		val, err := cell.FormattedValue()
+		c.Assert(err, IsNil)
+		c.Assert(val, Equals, fc.expected, Commentf("%q %v", fc.format, fc.value))
+	}
+}
+
+// Up to four sections, picked by sign or by condition.
+func (s *NumberFormatSuite) TestSections(c *C) {
+	checkFormatCases(c, []formatCase{
+		{"0.00;(0.00)", 1.5, "1.50"},
+		{"0.00;(0.00)", -1.5, "(1.50)"},
+		{"0.00;(0.00)", 0, "0.00"},
+		{"0;-0;\"zero\"", 0, "zero"},
+		{"0;[Red]-0;\"zero\"", -3, "-3"},
+		{"0", -3, "-3"},
+		{"[>=100][Blue]\"big \"0;[<0]\"minus \"0;0", 150, "big 150"},
+		{"[>=100][Blue]\"big \"0;[<0]\"minus \"0;0", -7, "minus 7"},
+		{"[>=100][Blue]\"big \"0;[<0]\"minus \"0;0", 7, "7"},
+		{"[<=9999999]###-####;(###) ###-####", 5551234, "555-1234"},
+		{"[<=9999999]###-####;(###) ###-####", 2125551234, "(212) 555-1234"},
+		{"[=1]\"one\";0", 2, "2"},
+	})
+}
+
+// Thousands separators, scaling commas, percentages and rounding.
+func (s *NumberFormatSuite) TestDigits(c *C) {
+	checkFormatCases(c, []formatCase{
+		{"#,##0", 1234567.5, "1,234,568"},
+		{"#,##0.00", -1234.565, "-1,234.57"},
+		{"#,##0,", 1234567, "1,235"},
+		{"0.0,,\" M\"", 1234567, "1.2 M"},
+		{"0.00%", 0.12345, "12.35%"},
+		{"0.0#", 2, "2.0"},
+		{"#.##", 0.5, ".5"},
+		{"000", 7, "007"},
+		{"??0.0?", 1.5, "  1.5 "},
+		{"0", 2.5, "3"},
+		{"0.00", 1.005, "1.01"},
+		{"000-00-0000", 123456789, "123-45-6789"},
+	})
+}
+
+// Quoted and escaped literals, and padding.  A cell has no width
+// here, so fill characters are left out.
+func (s *NumberFormatSuite) TestLiterals(c *C) {
+	checkFormatCases(c, []formatCase{
+		{"\"Total: \"0.0", 12.34, "Total: 12.3"},
+		{"0\\ \\k\\g", 5, "5 kg"},
+		{"$#,##0.00", 1234.5, "$1,234.50"},
+		{"[$€-407] #,##0.00", 1234.5, "€ 1,234.50"},
+		{"#,##0.00_);(#,##0.00)", 12, "12.00 "},
+		{"#,##0.00_);(#,##0.00)", -12, "(12.00)"},
+		{"_(\"$\"* #,##0_)", 42, " $42 "},
+	})
+}
+
+// Fractions, with a fixed or a largest denominator.
+func (s *NumberFormatSuite) TestFractions(c *C) {
+	checkFormatCases(c, []formatCase{
+		{"# ?/?", 1.5, "1 1/2"},
+		{"# ?/?", 2, "2    "},
+		{"# ??/??", 3.14159, "3 14/99"},
+		{"# ??/??", 0.75, "  3/4 "},
+		{"# ???/???", 3.14159, "3  16/113"},
+		{"?/?", 1.25, "5/4"},
+		{"# ?/4", 1.3, "1 1/4"},
+		{"0/100", 0.25, "25/100"},
+		{"# ?/?", -0.5, "- 1/2"},
+	})
+}
+
+// Scientific and engineering notation.
+func (s *NumberFormatSuite) TestScientific(c *C) {
+	checkFormatCases(c, []formatCase{
+		{"0.00E+00", 12345, "1.23E+04"},
+		{"0.00E+00", 0.00012345, "1.23E-04"},
+		{"0.00E-00", 12345, "1.23E04"},
+		{"0.00E+00", 9.999, "1.00E+01"},
+		{"0.00E+00", 0, "0.00E+00"},
+		{"##0.0E+0", 12345, "12.3E+3"},
+		{"##0.0E+0", 0.00012345, "123.5E-6"},
+	})
+}
+
+// General shows up to eleven characters, switching to scientific
+// notation for very large and very small numbers.
+func (s *NumberFormatSuite) TestGeneral(c *C) {
+	checkFormatCases(c, []formatCase{
+		{"General", 0, "0"},
+		{"General", 1234, "1234"},
+		{"General", 0.1 + 0.2, "0.3"},
+		{"General", 1.0 / 3, "0.333333333"},
+		{"General", -2.0 / 3, "-0.666666667"},
+		{"General", 123456.7890123, "123456.789"},
+		{"General", 12345678901, "12345678901"},
+		{"General", 123456789012, "1.23457E+11"},
+		{"General", 0.0001234, "0.0001234"},
+		{"General", 0.00001234, "1.234E-05"},
+		{"", 42.5, "42.5"},
+		{"\"Qty: \"General", 3, "Qty: 3"},
+	})
+}
+
+// Text is shown by the section for text, if there is one, and as it
+// is otherwise.
+func (s *NumberFormatSuite) TestText(c *C) {
+	cases := []struct{ format, expected string }{
+		{"@", "abc"},
+		{"\"<\"@\">\"", "<abc>"},
+		{"0.00;-0.00;0;\"text: \"@", "text: abc"},
+		{"General", "abc"},
+	}
+	for _, tc := range cases {
+		cell := Cell{}
+		cell.SetString("abc")
+		cell.NumFmt = tc.format
+		val, err := cell.FormattedValue()
+		c.Assert(err, IsNil)
+		c.Assert(val, Equals, tc.expected, Commentf(tc.format))
+	}
+
+	// A number is shown by the number sections, and as General if
+	// there are none.
+	checkFormatCases(c, []formatCase{
+		{"0.00;-0.00;0;\"text: \"@", 1.5, "1.50"},
+		{"@", 1.5, "1.5"},
+	})
+
+	// A string that looks like a number is still text.
+	cell := Cell{}
+	cell.SetString("00123")
+	cell.NumFmt = "General"
+	val, err := cell.FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(val, Equals, "00123")
+}
+
+// Sections are split at semicolons outside quotes and brackets, and
+// sections with date and time codes are recognised.
+func (s *NumberFormatSuite) TestParseNumberFormat(c *C) {
+	f := parseNumberFormat(`[Red][<0]"a;b"0;[Color10]0.0;"x"\;"y";@`)
+	c.Assert(f.sections, HasLen, 3)
+	c.Assert(f.sections[0].color, Equals, "Red")
+	c.Assert(f.sections[0].condition, DeepEquals, &formatCondition{op: "<", value: 0})
+	c.Assert(f.sections[1].color, Equals, "Color10")
+	c.Assert(f.sections[2].tokens, DeepEquals, []formatToken{{formatLiteral, "x"}, {formatLiteral, ";"}, {formatLiteral, "y"}})
+	c.Assert(f.text, NotNil)
+
+	c.Assert(parseNumberFormat("[h]:mm:ss").sections[0].isDate, Equals, true)
+	c.Assert(parseNumberFormat("[$-409]h:mm AM/PM").sections[0].raw, Equals, "h:mm AM/PM")
+	c.Assert(parseNumberFormat(`0.00" days"`).sections[0].isDate, Equals, false)
+	c.Assert(parseNumberFormat(`0.00\ \p\c\s`).sections[0].isDate, Equals, false)
+	c.Assert(parseNumberFormat("General").isTextOnly(), Equals, true)
+}
@@ -0,0 +1,301 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+const (
+	relTypeOfficeDocument = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"
+	relTypeCoreProperties = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	relTypeExtendedProps  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+	relTypeWorksheet      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+	relTypeSharedStrings  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"
+	relTypeStyles         = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	relTypeTheme          = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+	relTypeCalcChain      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/calcChain"
+	relTypeHyperlink      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+)
+
+// modelledParts are the parts of a package that Write produces
+// itself.  Every other part of an opened file is kept as it was read.
+// The calculation chain is dropped rather than kept, because it lists
+// formula cells and goes stale as soon as a sheet is edited; Excel
+// rebuilds it when it is missing.
+var modelledParts = map[string]bool{
+	"[Content_Types].xml":        true,
+	"_rels/.rels":                true,
+	"docProps/app.xml":           true,
+	"xl/workbook.xml":            true,
+	"xl/_rels/workbook.xml.rels": true,
+	"xl/sharedStrings.xml":       true,
+	"xl/styles.xml":              true,
+	"xl/calcChain.xml":           true,
+}
+
+// modelledRelTypes are the types of the package and workbook
+// relationships that Write produces itself.
+var modelledRelTypes = map[string]bool{
+	relTypeOfficeDocument: true,
+	relTypeCoreProperties: true,
+	relTypeExtendedProps:  true,
+	relTypeWorksheet:      true,
+	relTypeSharedStrings:  true,
+	relTypeStyles:         true,
+	relTypeTheme:          true,
+	relTypeCalcChain:      true,
+}
+
+// packageParts holds everything in an opened XLSX package that the
+// library does not model, such as drawings, charts, comments, VBA
+// projects and custom XML, so that Write can put it back unchanged.
+type packageParts struct {
+	files              map[string][]byte // raw content, by zip path
+	contentTypes       xlsxTypes
+	rootRels           []xlsxWorkbookRelation
+	workbookRels       []xlsxWorkbookRelation
+	sheets             []preservedSheet // chartsheets and other sheets that are not worksheets
+	externalReferences *xlsxExternalReferences
+	pivotCaches        *xlsxPivotCaches
+}
+
+// preservedSheet is a sheet of an opened workbook that is not a
+// worksheet, along with its index among the sheets of the workbook, so
+// that it keeps its place among them when the workbook is written.
+type preservedSheet struct {
+	xlsxSheet
+	index int
+}
+
+// worksheetParts holds the relationships of an opened worksheet, and
+// the elements of its XML that refer to them, so that the drawings,
+// comments, tables and so on of the sheet survive a save.
+type worksheetParts struct {
+	relationships   []xlsxWorkbookRelation
+	hyperlinks      *xlsxHyperlinks
+	pageSetUpRID    string
+	drawing         *xlsxPartRef
+	legacyDrawing   *xlsxPartRef
+	legacyDrawingHF *xlsxPartRef
+	picture         *xlsxPartRef
+	tableParts      *xlsxTableParts
+}
+
+// readPackageParts keeps the content of every part of r that the
+// library does not model, along with the content types and the
+// package and workbook relationships that go with them.
+func readPackageParts(r *zip.Reader) (*packageParts, error) {
+	parts := &packageParts{files: make(map[string][]byte)}
+	for _, f := range r.File {
+		var err error
+		switch {
+		case strings.HasSuffix(f.Name, "/"):
+			// A directory entry.
+		case f.Name == "[Content_Types].xml":
+			err = readXMLPart(f, &parts.contentTypes)
+		case f.Name == "_rels/.rels":
+			parts.rootRels, err = readPreservedRels(f)
+		case f.Name == "xl/_rels/workbook.xml.rels":
+			parts.workbookRels, err = readPreservedRels(f)
+		case modelledParts[f.Name] || strings.HasPrefix(f.Name, "xl/worksheets/"):
+		default:
+			parts.files[f.Name], err = readRawPart(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// readXMLPart decodes the XML part f into v.
+func readXMLPart(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// readRawPart returns the content of the part f.
+func readRawPart(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// readPreservedRels returns the relationships of the relationships
+// part f that Write does not produce itself.
+func readPreservedRels(f *zip.File) ([]xlsxWorkbookRelation, error) {
+	rels := new(xlsxWorkbookRels)
+	if err := readXMLPart(f, rels); err != nil {
+		return nil, err
+	}
+	var preserved []xlsxWorkbookRelation
+	for _, rel := range rels.Relationships {
+		if !modelledRelTypes[rel.Type] {
+			preserved = append(preserved, rel)
+		}
+	}
+	return preserved, nil
+}
+
+// hasWorkbookRel reports whether id is one of the preserved workbook
+// relationships.
+func (p *packageParts) hasWorkbookRel(id string) bool {
+	for _, rel := range p.workbookRels {
+		if rel.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// readWorksheetParts returns the relationships of a worksheet, read
+// from relsFile if it isn't nil, and the elements of the worksheet
+// that refer to them.
+func readWorksheetParts(worksheet *xlsxWorksheet, relsFile *zip.File) (*worksheetParts, error) {
+	parts := &worksheetParts{
+		hyperlinks:      worksheet.Hyperlinks,
+		pageSetUpRID:    worksheet.PageSetUp.RID,
+		drawing:         worksheet.Drawing,
+		legacyDrawing:   worksheet.LegacyDrawing,
+		legacyDrawingHF: worksheet.LegacyDrawingHF,
+		picture:         worksheet.Picture,
+		tableParts:      worksheet.TableParts,
+	}
+	if relsFile != nil {
+		rels := new(xlsxWorkbookRels)
+		if err := readXMLPart(relsFile, rels); err != nil {
+			return nil, err
+		}
+		parts.relationships = rels.Relationships
+	}
+	return parts, nil
+}
+
+// worksheetRelsName returns the name of the relationships part of the
+// worksheet part called name.
+func worksheetRelsName(name string) string {
+	dir, file := path.Split(name)
+	return dir + "_rels/" + file + ".rels"
+}
+
+// apply copies the elements of an opened worksheet that refer to
+// other parts into worksheet.
+func (p *worksheetParts) apply(worksheet *xlsxWorksheet) {
+	worksheet.Hyperlinks = p.hyperlinks
+	worksheet.PageSetUp.RID = p.pageSetUpRID
+	worksheet.Drawing = p.drawing
+	worksheet.LegacyDrawing = p.legacyDrawing
+	worksheet.LegacyDrawingHF = p.legacyDrawingHF
+	worksheet.Picture = p.picture
+	worksheet.TableParts = p.tableParts
+}
+
+// marshalRels renders relationships as a relationships part.
+func marshalRels(relationships []xlsxWorkbookRelation) (string, error) {
+	return marshalPart(xlsxWorkbookRels{Relationships: relationships})
+}
+
+// addRootRels adds the preserved package relationships to the
+// relationships written for every file.  Relationship ids of the
+// package are not referred to from anywhere else, so the preserved
+// ones are simply numbered after the standard three.
+func (p *packageParts) addRootRels(parts map[string]string) error {
+	if len(p.rootRels) == 0 {
+		return nil
+	}
+	rels := []xlsxWorkbookRelation{
+		{Id: "rId1", Type: relTypeOfficeDocument, Target: "xl/workbook.xml"},
+		{Id: "rId2", Type: relTypeCoreProperties, Target: "docProps/core.xml"},
+		{Id: "rId3", Type: relTypeExtendedProps, Target: "docProps/app.xml"},
+	}
+	for _, rel := range p.rootRels {
+		rel.Id = fmt.Sprintf("rId%d", len(rels)+1)
+		rels = append(rels, rel)
+	}
+	var err error
+	parts["_rels/.rels"], err = marshalRels(rels)
+	return err
+}
+
+// addWorkbookRels adds the preserved workbook relationships to
+// xWRel, numbering them after the relationships that Write produces,
+// and points the workbook elements that refer to them at their new
+// ids.  Preserved sheets, such as chartsheets, go back to the places
+// they had among the sheets of the workbook as it was read.
+func (p *packageParts) addWorkbookRels(xWRel *xlsxWorkbookRels, workbook *xlsxWorkbook) {
+	ids := make(map[string]string, len(p.workbookRels))
+	for _, rel := range p.workbookRels {
+		id := fmt.Sprintf("rId%d", len(xWRel.Relationships)+1)
+		ids[rel.Id] = id
+		rel.Id = id
+		xWRel.Relationships = append(xWRel.Relationships, rel)
+	}
+	for _, preserved := range p.sheets {
+		sheet := preserved.xlsxSheet
+		sheet.Id = ids[sheet.Id]
+		sheet.SheetId = fmt.Sprint(len(workbook.Sheets.Sheet) + 1)
+		sheets := workbook.Sheets.Sheet
+		i := preserved.index
+		if i > len(sheets) {
+			i = len(sheets)
+		}
+		workbook.Sheets.Sheet = append(sheets[:i:i], append([]xlsxSheet{sheet}, sheets[i:]...)...)
+	}
+	if p.externalReferences != nil {
+		refs := &xlsxExternalReferences{}
+		for _, ref := range p.externalReferences.ExternalReference {
+			refs.ExternalReference = append(refs.ExternalReference, xlsxExternalReference{RID: ids[ref.RID]})
+		}
+		workbook.ExternalReferences = refs
+	}
+	if p.pivotCaches != nil {
+		caches := &xlsxPivotCaches{}
+		for _, cache := range p.pivotCaches.PivotCache {
+			cache.RID = ids[cache.RID]
+			caches.PivotCache = append(caches.PivotCache, cache)
+		}
+		workbook.PivotCaches = caches
+	}
+}
+
+// addContentTypes adds the original content types of the preserved
+// parts to types.  The workbook keeps its original content type, so
+// that a macro enabled workbook stays one.
+func (p *packageParts) addContentTypes(types *xlsxTypes) {
+	extensions := make(map[string]bool, len(types.Defaults))
+	for _, d := range types.Defaults {
+		extensions[strings.ToLower(d.Extension)] = true
+	}
+	for _, d := range p.contentTypes.Defaults {
+		if !extensions[strings.ToLower(d.Extension)] {
+			extensions[strings.ToLower(d.Extension)] = true
+			types.Defaults = append(types.Defaults, d)
+		}
+	}
+	overridden := make(map[string]int, len(types.Overrides))
+	for i, o := range types.Overrides {
+		overridden[o.PartName] = i
+	}
+	for _, o := range p.contentTypes.Overrides {
+		if i, ok := overridden[o.PartName]; ok {
+			if o.PartName == "/xl/workbook.xml" {
+				types.Overrides[i].ContentType = o.ContentType
+			}
+			continue
+		}
+		if _, ok := p.files[strings.TrimPrefix(o.PartName, "/")]; ok {
+			types.Overrides = append(types.Overrides, o)
+		}
+	}
+}
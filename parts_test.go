@@ -0,0 +1,146 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+
+	. "gopkg.in/check.v1"
+)
+
+type PartsSuite struct{}
+
+var _ = Suite(&PartsSuite{})
+
+// readZipParts returns the content of every part of an XLSX file.
+func readZipParts(c *C, data []byte) map[string]string {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	parts := make(map[string]string, len(r.File))
+	for _, f := range r.File {
+		content, err := readRawPart(f)
+		c.Assert(err, IsNil)
+		parts[f.Name] = string(content)
+	}
+	return parts
+}
+
+// resave opens the named test document, saves it, and returns the
+// parts of both the original and the saved file.
+func resave(c *C, name string) (original, saved map[string]string) {
+	data, err := ioutil.ReadFile("./testdocs/" + name)
+	c.Assert(err, IsNil)
+	f, err := OpenBinary(data)
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	_, err = OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	return readZipParts(c, data), readZipParts(c, buf.Bytes())
+}
+
+// A drawing on a worksheet survives, along with the worksheet's
+// relationships and its reference to the drawing.
+func (s *PartsSuite) TestSaveKeepsWorksheetDrawing(c *C) {
+	original, saved := resave(c, "googleDocsTest.xlsx")
+	c.Assert(saved["xl/drawings/drawing1.xml"], Equals, original["xl/drawings/drawing1.xml"])
+	c.Assert(saved["xl/worksheets/_rels/sheet1.xml.rels"], Matches, `(?s).*<Relationship Id="rId1" Target="../drawings/drawing1.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"></Relationship>.*`)
+	c.Assert(saved["xl/worksheets/sheet1.xml"], Matches, `(?s)<\?xml.*<worksheet xmlns="[^"]*" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">.*<drawing r:id="rId1"></drawing></worksheet>`)
+	c.Assert(saved["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/drawings/drawing1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing\+xml"></Override>.*`)
+}
+
+// A chartsheet, its drawing and chart survive, and the chartsheet
+// keeps its place in the workbook under a new relationship id.
+func (s *PartsSuite) TestSaveKeepsChartsheet(c *C) {
+	original, saved := resave(c, "testchartsheet.xlsx")
+	for _, name := range []string{
+		"xl/chartsheets/sheet1.xml",
+		"xl/chartsheets/_rels/sheet1.xml.rels",
+		"xl/drawings/drawing1.xml",
+		"xl/drawings/_rels/drawing1.xml.rels",
+		"xl/charts/chart1.xml",
+		"xl/theme/theme1.xml",
+		"docProps/core.xml",
+		"docProps/thumbnail.jpeg",
+	} {
+		c.Assert(saved[name], Equals, original[name], Commentf(name))
+	}
+	c.Assert(saved["xl/_rels/workbook.xml.rels"], Matches, `(?s).*<Relationship Id="rId5" Target="chartsheets/sheet1.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/chartsheet"></Relationship>.*`)
+	c.Assert(saved["xl/workbook.xml"], Matches, `(?s).*<sheets><sheet name="Chart1" sheetId="2" r:id="rId5"></sheet><sheet name="Sheet1" sheetId="1" r:id="rId1" state="visible"></sheet></sheets>.*`)
+	c.Assert(saved["_rels/.rels"], Matches, `(?s).*<Relationship Id="rId4" Target="docProps/thumbnail.jpeg" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail"></Relationship>.*`)
+	types := saved["[Content_Types].xml"]
+	c.Assert(types, Matches, `(?s).*<Override PartName="/xl/chartsheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.chartsheet\+xml"></Override>.*`)
+	c.Assert(types, Matches, `(?s).*<Default Extension="jpeg" ContentType="image/jpeg"></Default>.*`)
+}
+
+// Package relationships such as custom properties survive.
+func (s *PartsSuite) TestSaveKeepsCustomProperties(c *C) {
+	original, saved := resave(c, "wpsBlankLineTest.xlsx")
+	c.Assert(saved["docProps/custom.xml"], Equals, original["docProps/custom.xml"])
+	c.Assert(saved["_rels/.rels"], Matches, `(?s).*<Relationship Id="rId4" Target="docProps/custom.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/custom-properties"></Relationship>.*`)
+}
+
+// The calculation chain is dropped, along with its relationship and
+// content type.
+func (s *PartsSuite) TestSaveDropsCalcChain(c *C) {
+	original, saved := resave(c, "testcelltypes.xlsx")
+	c.Assert(original["xl/calcChain.xml"], Not(Equals), "")
+	_, ok := saved["xl/calcChain.xml"]
+	c.Assert(ok, Equals, false)
+	c.Assert(saved["xl/_rels/workbook.xml.rels"], Not(Matches), `(?s).*calcChain.*`)
+	c.Assert(saved["[Content_Types].xml"], Not(Matches), `(?s).*calcChain.*`)
+}
+
+// The workbook keeps the content type it was read with, and workbook
+// elements that refer to preserved relationships follow their new ids.
+func (s *PartsSuite) TestAddWorkbookRels(c *C) {
+	parts := &packageParts{
+		contentTypes: xlsxTypes{Overrides: []xlsxOverride{
+			{PartName: "/xl/workbook.xml", ContentType: "application/vnd.ms-excel.sheet.macroEnabled.main+xml"},
+			{PartName: "/xl/vbaProject.bin", ContentType: "application/vnd.ms-office.vbaProject"},
+			{PartName: "/xl/calcChain.xml", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.calcChain+xml"},
+		}},
+		files: map[string][]byte{"xl/vbaProject.bin": []byte("VBA")},
+		workbookRels: []xlsxWorkbookRelation{
+			{Id: "rId2", Type: "http://schemas.microsoft.com/office/2006/relationships/vbaProject", Target: "vbaProject.bin"},
+			{Id: "rId9", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/externalLink", Target: "externalLinks/externalLink1.xml"},
+		},
+		externalReferences: &xlsxExternalReferences{ExternalReference: []xlsxExternalReference{{RID: "rId9"}}},
+	}
+	f := NewFile()
+	_, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	f.parts = parts
+	output, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(output["xl/vbaProject.bin"], Equals, "VBA")
+	c.Assert(output["xl/_rels/workbook.xml.rels"], Matches, `(?s).*<Relationship Id="rId5" Target="vbaProject.bin" Type="http://schemas.microsoft.com/office/2006/relationships/vbaProject"></Relationship><Relationship Id="rId6" Target="externalLinks/externalLink1.xml" Type="[^"]*"></Relationship>.*`)
+	c.Assert(output["xl/workbook.xml"], Matches, `(?s).*</sheets><externalReferences><externalReference r:id="rId6"></externalReference></externalReferences>.*`)
+	types := output["[Content_Types].xml"]
+	c.Assert(types, Matches, `(?s).*<Override PartName="/xl/workbook.xml" ContentType="application/vnd.ms-excel.sheet.macroEnabled.main\+xml"></Override>.*`)
+	c.Assert(types, Matches, `(?s).*<Override PartName="/xl/vbaProject.bin" ContentType="application/vnd.ms-office.vbaProject"></Override>.*`)
+	c.Assert(types, Not(Matches), `(?s).*calcChain.*`)
+}
+
+// Preserved sheets go back to their places among the worksheets.
+func (s *PartsSuite) TestAddWorkbookRelsKeepsSheetOrder(c *C) {
+	parts := &packageParts{
+		workbookRels: []xlsxWorkbookRelation{
+			{Id: "rId7", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/chartsheet", Target: "chartsheets/sheet1.xml"},
+			{Id: "rId8", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/chartsheet", Target: "chartsheets/sheet2.xml"},
+		},
+		sheets: []preservedSheet{
+			{xlsxSheet{Name: "Chart1", SheetId: "4", Id: "rId7"}, 1},
+			{xlsxSheet{Name: "Chart2", SheetId: "5", Id: "rId8"}, 3},
+		},
+	}
+	f := NewFile()
+	for _, name := range []string{"Sheet1", "Sheet2", "Sheet3"} {
+		_, err := f.AddSheet(name)
+		c.Assert(err, IsNil)
+	}
+	f.parts = parts
+	output, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(output["xl/workbook.xml"], Matches, `(?s).*<sheets><sheet name="Sheet1" [^>]*></sheet><sheet name="Chart1" sheetId="4" r:id="rId7"></sheet><sheet name="Sheet2" [^>]*></sheet><sheet name="Chart2" sheetId="5" r:id="rId8"></sheet><sheet name="Sheet3" [^>]*></sheet></sheets>.*`)
+}
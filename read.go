@@ -0,0 +1,265 @@
+package xlsx
+
+import (
+	"database/sql"
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadStructs reads the rows of the sheet into the slice that ptr
+// points to, which holds structs or pointers to structs.  The first
+// row of the sheet is taken to be a header, and the rows below it
+// become elements of the slice, except for empty rows.  A field is
+// read from the column whose header matches its name, regardless of
+// case, or from the column its tag names; see Row.WriteStruct for the
+// tags, and Row.ReadStruct for the types of field that can be read.
+//
+// A value that can't be converted to the type of its field leaves the
+// field alone, and the reading goes on.  Every such value is reported
+// in the CellErrors returned at the end, which name their cells.
+func (s *Sheet) ReadStructs(ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.New("xlsx: ReadStructs needs a pointer to a slice")
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return errors.New("xlsx: ReadStructs needs a slice of structs or of pointers to structs")
+	}
+	fields, err := structFields(structType)
+	if err != nil {
+		return err
+	}
+	if err := s.Load(); err != nil {
+		return err
+	}
+
+	slice.Set(slice.Slice(0, 0))
+	if len(s.Rows) == 0 {
+		return nil
+	}
+	headers := make(map[string]int)
+	if s.Rows[0] != nil {
+		for x, cell := range s.Rows[0].Cells {
+			header := strings.ToLower(strings.TrimSpace(cell.Value))
+			if _, ok := headers[header]; !ok && header != "" {
+				headers[header] = x
+			}
+		}
+	}
+	cols := make([]int, len(fields))
+	for i, field := range fields {
+		cols[i] = field.col
+		if field.col < 0 {
+			if x, ok := headers[strings.ToLower(field.name)]; ok {
+				cols[i] = x
+			}
+		}
+	}
+
+	var errs CellErrors
+	for y, row := range s.Rows[1:] {
+		if row == nil || row.isEmpty() {
+			continue
+		}
+		elem := reflect.New(structType)
+		errs = append(errs, row.readStruct(elem.Elem(), fields, cols, y+1)...)
+		if elemType.Kind() == reflect.Ptr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// ReadStruct reads the row into the struct that ptr points to.  Fields
+// are read by position, as Row.WriteStruct writes them, unless their
+// tag names a column.  Use Sheet.ReadStructs to match fields with
+// headers.
+//
+// Fields of string, bool, integer and floating point types can be
+// read, as can time.Time, time.Duration, types that implement
+// encoding.TextUnmarshaler or sql.Scanner, such as sql.NullInt64, and
+// pointers to any of those.  Times are read from dates, or from text
+// in ISO 8601 form, in the location of the cell (see Cell.GetTime).
+// Nested structs are read as Row.WriteStruct flattens them.  Empty
+// cells leave their fields alone, as do values that can't be read,
+// which are reported together in CellErrors.
+func (r *Row) ReadStruct(ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("xlsx: ReadStruct needs a pointer to a struct")
+	}
+	fields, err := structFields(v.Elem().Type())
+	if err != nil {
+		return err
+	}
+	cols := positionalColumns(fields, 0)
+	y := -1
+	if r.Sheet != nil {
+		for i, row := range r.Sheet.Rows {
+			if row == r {
+				y = i
+				break
+			}
+		}
+	}
+	if errs := r.readStruct(v.Elem(), fields, cols, y); errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// readStruct reads the fields of v from the columns cols of the row,
+// which is row y of its sheet, or -1 if that isn't known.  It returns
+// an error for each cell that can't be read.
+func (r *Row) readStruct(v reflect.Value, fields []structField, cols []int, y int) CellErrors {
+	var errs CellErrors
+	for i, field := range fields {
+		x := cols[i]
+		if x < 0 || x >= len(r.Cells) || r.Cells[x] == nil || r.Cells[x].Value == "" {
+			continue
+		}
+		fv, _ := fieldValue(v, field.index, true)
+		if err := r.Cells[x].readValue(fv); err != nil {
+			ref := numericToLetters(x)
+			if y >= 0 {
+				ref = getCellIDStringFromCoords(x, y)
+			}
+			errs = append(errs, newCellError(r.Sheet, ref, fmt.Errorf("field %s: %v", field.goName, err)))
+		}
+	}
+	return errs
+}
+
+// isEmpty reports whether none of the row's cells has a value.
+func (r *Row) isEmpty() bool {
+	for _, cell := range r.Cells {
+		if cell != nil && cell.Value != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// readValue converts the value of the cell to the type of v, and sets
+// v to it.  An empty cell leaves v alone.
+func (c *Cell) readValue(v reflect.Value) error {
+	if c.Value == "" {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return c.readValue(v.Elem())
+	}
+
+	switch {
+	case v.Type() == timeType:
+		t, err := c.readTime()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := c.GetDuration()
+		if err != nil {
+			return fmt.Errorf("cannot read '%s' as a duration", c.Value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case reflect.PtrTo(v.Type()).Implements(unmarshaler):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(c.Value))
+	case reflect.PtrTo(v.Type()).Implements(scannerType):
+		scanner := v.Addr().Interface().(sql.Scanner)
+		if err := scanner.Scan(c.Value); err != nil {
+			// Such as sql.NullTime, which scans only times.
+			t, terr := c.readTime()
+			if terr != nil || scanner.Scan(t) != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value := strings.TrimSpace(c.Value)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(c.Value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("cannot read '%s' as a bool", c.Value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			// Integers may be stored as 1E+20 or 3.0.
+			f, ferr := strconv.ParseFloat(value, 64)
+			if ferr != nil || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return fmt.Errorf("cannot read '%s' as an integer", c.Value)
+			}
+			n = int64(f)
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("%s overflows %s", c.Value, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(value, 64)
+			if ferr != nil || f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return fmt.Errorf("cannot read '%s' as an unsigned integer", c.Value)
+			}
+			n = uint64(f)
+		}
+		if v.OverflowUint(n) {
+			return fmt.Errorf("%s overflows %s", c.Value, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot read '%s' as a number", c.Value)
+		}
+		v.SetFloat(f)
+	case reflect.Struct:
+		// Written as text, such as a fmt.Stringer, that can't be
+		// read back.
+	default:
+		return fmt.Errorf("cannot read a cell into a %s", v.Type())
+	}
+	return nil
+}
+
+// readTime returns the time held by the cell, either as a date or as
+// text in ISO 8601 form.
+func (c *Cell) readTime() (time.Time, error) {
+	f, err := c.Float()
+	if err != nil {
+		f, _, err = excelTimeFromISO8601(strings.TrimSpace(c.Value), c.usesDate1904())
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot read '%s' as a time", c.Value)
+		}
+	}
+	return c.timeIn(f, nil), nil
+}
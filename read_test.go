@@ -0,0 +1,207 @@
+package xlsx
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ReadSuite struct{}
+
+var _ = Suite(&ReadSuite{})
+
+// testLevel is read with encoding.TextUnmarshaler.
+type testLevel int
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+type testAddress struct {
+	City string
+}
+
+type testCustomer struct {
+	testAddress
+	Name     string `xlsx:"Customer Name"`
+	Code     string `xlsx:"col=D"`
+	Age      int
+	Balance  *float64
+	Active   bool
+	Since    time.Time
+	Level    testLevel
+	Notes    string `xlsx:"-"`
+	internal string
+}
+
+func addTestRow(sheet *Sheet, values ...interface{}) {
+	row := sheet.AddRow()
+	for _, value := range values {
+		row.AddCell().SetValue(value)
+	}
+}
+
+func (s *ReadSuite) TestReadStructs(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Customers")
+	c.Assert(err, IsNil)
+	since := time.Date(2013, 1, 1, 9, 30, 0, 0, time.UTC)
+	addTestRow(sheet, "AGE", "Customer Name", "Balance", "", "Active", "Since", "Level", "City", "Notes")
+	addTestRow(sheet, 42, "Ada", 12.5, "C-1", "TRUE", since, "high", "London", "skipped")
+	addTestRow(sheet)
+	addTestRow(sheet, "7.0", "Bob", "", "C-2", true, "2013-01-01T09:30:00", "Low", "Paris")
+
+	var customers []testCustomer
+	c.Assert(sheet.ReadStructs(&customers), IsNil)
+	c.Assert(customers, HasLen, 2)
+	balance := 12.5
+	c.Assert(customers[0], DeepEquals, testCustomer{
+		testAddress: testAddress{City: "London"},
+		Name:        "Ada",
+		Code:        "C-1",
+		Age:         42,
+		Balance:     &balance,
+		Active:      true,
+		Since:       since,
+		Level:       2,
+	})
+	c.Assert(customers[1].Age, Equals, 7)
+	c.Assert(customers[1].Balance, IsNil)
+	c.Assert(customers[1].Since, Equals, since)
+	c.Assert(customers[1].Level, Equals, testLevel(1))
+
+	var pointers []*testCustomer
+	c.Assert(sheet.ReadStructs(&pointers), IsNil)
+	c.Assert(pointers, HasLen, 2)
+	c.Assert(pointers[1].Name, Equals, "Bob")
+
+	c.Assert(sheet.ReadStructs(customers), ErrorMatches, "xlsx: ReadStructs needs a pointer to a slice")
+}
+
+// Values that don't fit their fields are reported with their cells,
+// and the rest of the rows are read.
+func (s *ReadSuite) TestReadStructsError(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Customers")
+	c.Assert(err, IsNil)
+	addTestRow(sheet, "Customer Name", "Age")
+	addTestRow(sheet, "Ada", 42)
+	addTestRow(sheet, "Bob", "forty")
+
+	var customers []testCustomer
+	err = sheet.ReadStructs(&customers)
+	cellErrs, ok := err.(CellErrors)
+	c.Assert(ok, Equals, true)
+	c.Assert(cellErrs, HasLen, 1)
+	c.Assert(cellErrs[0].Sheet, Equals, "Customers")
+	c.Assert(cellErrs[0].Cell, Equals, "B3")
+	c.Assert(err, ErrorMatches, "sheet 'Customers', cell B3: field Age: cannot read 'forty' as an integer")
+	c.Assert(customers, HasLen, 2)
+	c.Assert(customers[1].Name, Equals, "Bob")
+
+	addTestRow(sheet, "Carol", "old")
+	addTestRow(sheet, "Dan", 30)
+	err = sheet.ReadStructs(&customers)
+	c.Assert(err, ErrorMatches, "sheet 'Customers', cell B3: field Age: cannot read 'forty' as an integer; "+
+		"sheet 'Customers', cell B4: field Age: cannot read 'old' as an integer")
+	c.Assert(err.(CellErrors)[1].Cell, Equals, "B4")
+	c.Assert(customers, HasLen, 4)
+	c.Assert(customers[3].Age, Equals, 30)
+
+	sheet.Rows[2].Cells[1].SetString("300")
+	var small []struct{ Age int8 }
+	err = sheet.ReadStructs(&small)
+	c.Assert(err, ErrorMatches, "sheet 'Customers', cell B3: field Age: 300 overflows int8; .*")
+
+	var bad []struct {
+		Age int `xlsx:"col=1"`
+	}
+	c.Assert(sheet.ReadStructs(&bad), ErrorMatches, "xlsx: field Age: invalid column '1'")
+}
+
+// Rows written with Row.WriteStruct are read back by position, or by
+// column for tagged fields, and times by way of a saved file.
+func (s *ReadSuite) TestReadStruct(c *C) {
+	type e struct {
+		Name   string
+		Age    int
+		GPA    float64
+		Member bool
+		Joined time.Time
+		Code   string `xlsx:"col=H"`
+	}
+	written := e{"Eric", 20, 3.94, true, time.Date(2012, 9, 1, 0, 0, 0, 0, time.UTC), "X"}
+
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	c.Assert(row.WriteStruct(&written, -1), Equals, 6)
+	c.Assert(row.Cells, HasLen, 8)
+	xlsxPath := filepath.Join(c.MkDir(), "TestReadStruct.xlsx")
+	c.Assert(f.Save(xlsxPath), IsNil)
+
+	xlsxFile, err := OpenFile(xlsxPath)
+	c.Assert(err, IsNil)
+	var read e
+	c.Assert(xlsxFile.Sheet["Sheet1"].Rows[0].ReadStruct(&read), IsNil)
+	c.Assert(read, DeepEquals, written)
+
+	var wrong struct{ Name, Age, GPA, Member string }
+	c.Assert(xlsxFile.Sheet["Sheet1"].Rows[0].ReadStruct(&wrong), IsNil)
+	c.Assert(wrong.GPA, Equals, "3.94")
+	var bad struct{ Name, Age, GPA, Member int }
+	c.Assert(xlsxFile.Sheet["Sheet1"].Rows[0].ReadStruct(&bad), ErrorMatches,
+		"sheet 'Sheet1', cell A1: field Name: cannot read 'Eric' as an integer; "+
+			"sheet 'Sheet1', cell C1: field GPA: cannot read '3.94' as an integer")
+}
+
+type testNode struct {
+	Name string
+	Next *testNode
+}
+
+type testTree struct {
+	*testAddress
+	Name string
+	Root struct {
+		Value int
+		Left  *testTree
+	}
+}
+
+// Structs nested in themselves and pointers to unexported embedded
+// structs are left out.
+func (s *ReadSuite) TestReadStructsSkippedFields(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Nodes")
+	c.Assert(err, IsNil)
+	addTestRow(sheet, "Name", "City", "Root.Value", "Next.Name")
+	addTestRow(sheet, "Ada", "London", 3, "Bob")
+
+	var nodes []testNode
+	c.Assert(sheet.ReadStructs(&nodes), IsNil)
+	c.Assert(nodes, DeepEquals, []testNode{{Name: "Ada"}})
+
+	var trees []testTree
+	c.Assert(sheet.ReadStructs(&trees), IsNil)
+	c.Assert(trees, HasLen, 1)
+	c.Assert(trees[0].testAddress, IsNil)
+	c.Assert(trees[0].Name, Equals, "Ada")
+	c.Assert(trees[0].Root.Value, Equals, 3)
+	c.Assert(trees[0].Root.Left, IsNil)
+
+	row := sheet.AddRow()
+	c.Assert(row.WriteStruct(&testTree{Name: "Carol"}, -1), Equals, 2)
+}
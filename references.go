@@ -0,0 +1,147 @@
+package xlsx
+
+import (
+	"strings"
+
+	"github.com/structer/xlsx/formula"
+)
+
+// rewriteFormula returns f with its references rewritten by fn, as
+// formula.Rewrite does, or f as it is if it can't be parsed.
+func rewriteFormula(f string, fn func(formula.Node) formula.Node) string {
+	res, err := formula.Rewrite(f, fn)
+	if err != nil {
+		return f
+	}
+	return res
+}
+
+// rewriteFormulas rewrites with fn the references in the formulas of
+// the cells and conditional formats of the sheet.
+func (s *Sheet) rewriteFormulas(fn func(formula.Node) formula.Node) {
+	for _, row := range s.Rows {
+		if row == nil {
+			continue
+		}
+		for _, cell := range row.Cells {
+			if cell != nil && cell.formula != "" {
+				cell.formula = rewriteFormula(cell.formula, fn)
+			}
+		}
+	}
+	for _, cf := range s.ConditionalFormatting {
+		for _, rule := range cf.Rules {
+			for i, text := range rule.Formula {
+				rule.Formula[i] = rewriteFormula(text, fn)
+			}
+		}
+	}
+}
+
+// rewriteFormulas rewrites with fn the references in the formulas of
+// every sheet of the file, as Sheet.rewriteFormulas does, and in its
+// defined names.  fn is given the sheet of the formula, or nil for a
+// defined name.  The sheets are loaded first.
+func (f *File) rewriteFormulas(fn func(home *Sheet, n formula.Node) formula.Node) error {
+	for _, sheet := range f.Sheets {
+		if err := sheet.Load(); err != nil {
+			return err
+		}
+	}
+	for _, sheet := range f.Sheets {
+		home := sheet
+		sheet.rewriteFormulas(func(n formula.Node) formula.Node {
+			return fn(home, n)
+		})
+	}
+	for _, dn := range f.DefinedNames {
+		dn.Data = rewriteFormula(dn.Data, func(n formula.Node) formula.Node {
+			return fn(nil, n)
+		})
+	}
+	return nil
+}
+
+// sheetNamed returns the sheet of the given name, which is matched
+// without regard to case as Excel does, or nil.
+func (f *File) sheetNamed(name string) *Sheet {
+	for _, sheet := range f.Sheets {
+		if strings.EqualFold(sheet.Name, name) {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// refersTo reports whether ref, in a formula of home, is to cells of
+// sheet.  References to other workbooks and to ranges of sheets are
+// not.
+func refersTo(ref *formula.Ref, home, sheet *Sheet) bool {
+	if ref.Book != "" || ref.LastSheet != "" {
+		return false
+	}
+	if ref.Sheet == "" {
+		return home == sheet
+	}
+	return strings.EqualFold(ref.Sheet, sheet.Name)
+}
+
+// parseRange parses a reference to a cell or a range of cells, such as
+// "B2" or "A1:C3".
+func parseRange(s string) (*formula.Ref, bool) {
+	n, err := formula.Parse(s)
+	if err != nil {
+		return nil, false
+	}
+	ref, ok := n.(*formula.Ref)
+	return ref, ok && !ref.Invalid
+}
+
+// adjustRanges applies fn to each range of a space separated list such
+// as the Sqref of a ConditionalFormat, leaving out those that become
+// invalid.
+func adjustRanges(sqref string, fn func(formula.Ref) formula.Ref) string {
+	var ranges []string
+	for _, s := range strings.Fields(sqref) {
+		ref, ok := parseRange(s)
+		if !ok {
+			ranges = append(ranges, s)
+			continue
+		}
+		if adjusted := fn(*ref); !adjusted.Invalid {
+			ranges = append(ranges, adjusted.String())
+		}
+	}
+	return strings.Join(ranges, " ")
+}
+
+// renameSheet returns a function for rewriteFormula that points the
+// references to the sheet called old, and the names of that sheet, at
+// the sheet called name instead.
+func renameSheet(old, name string) func(formula.Node) formula.Node {
+	return func(n formula.Node) formula.Node {
+		switch n := n.(type) {
+		case *formula.Ref:
+			if n.Book != "" {
+				return nil
+			}
+			ref, renamed := *n, false
+			if strings.EqualFold(ref.Sheet, old) {
+				ref.Sheet, renamed = name, true
+			}
+			if ref.LastSheet != "" && strings.EqualFold(ref.LastSheet, old) {
+				ref.LastSheet, renamed = name, true
+			}
+			if renamed {
+				return &ref
+			}
+		case *formula.Name:
+			if n.Book == "" && n.Sheet != "" && strings.EqualFold(n.Sheet, old) {
+				renamed := *n
+				renamed.Sheet = name
+				return &renamed
+			}
+		}
+		return nil
+	}
+}
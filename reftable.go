@@ -2,6 +2,7 @@ package xlsx
 
 type RefTable struct {
 	indexedStrings []string
+	richTexts      map[int][]RichTextRun
 	knownStrings   map[string]int
 	isWrite        bool
 }
@@ -10,6 +11,7 @@ type RefTable struct {
 func NewSharedStringRefTable() *RefTable {
 	rt := RefTable{}
 	rt.knownStrings = make(map[string]int)
+	rt.richTexts = make(map[int][]RichTextRun)
 	return &rt
 }
 
@@ -22,11 +24,7 @@ func MakeSharedStringRefTable(source *xlsxSST) *RefTable {
 	reftable.isWrite = false
 	for _, si := range source.SI {
 		if len(si.R) > 0 {
-			newString := ""
-			for j := 0; j < len(si.R); j++ {
-				newString = newString + si.R[j].T
-			}
-			reftable.AddString(newString)
+			reftable.AddRichText(makeRichTextRuns(si.R))
 		} else {
 			reftable.AddString(si.T)
 		}
@@ -40,9 +38,13 @@ func (rt *RefTable) makeXLSXSST() xlsxSST {
 	sst := xlsxSST{}
 	sst.Count = len(rt.indexedStrings)
 	sst.UniqueCount = sst.Count
-	for _, ref := range rt.indexedStrings {
+	for i, ref := range rt.indexedStrings {
 		si := xlsxSI{}
-		si.T = ref
+		if runs, ok := rt.richTexts[i]; ok {
+			si.R = makeXLSXRuns(runs)
+		} else {
+			si.T = ref
+		}
 		sst.SI = append(sst.SI, si)
 	}
 	return sst
@@ -72,6 +74,24 @@ func (rt *RefTable) AddString(str string) int {
 	return index
 }
 
+// ResolveRichText looks up the rich text runs of a string by numeric
+// index.  It returns nil if the string at that index is a plain
+// string.
+func (rt *RefTable) ResolveRichText(index int) []RichTextRun {
+	return rt.richTexts[index]
+}
+
+// AddRichText adds a rich text string, made of the given runs, to the
+// reference table and returns its numeric index.  Rich text strings
+// are never shared with plain strings, even when their text is the
+// same.
+func (rt *RefTable) AddRichText(runs []RichTextRun) int {
+	rt.indexedStrings = append(rt.indexedStrings, richTextString(runs))
+	index := len(rt.indexedStrings) - 1
+	rt.richTexts[index] = runs
+	return index
+}
+
 func (rt *RefTable) Length() int {
 	return len(rt.indexedStrings)
 }
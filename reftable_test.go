@@ -123,3 +123,90 @@ func (s *RefTableSuite) TestRefTableWriteAddString(c *C) {
 	c.Assert(index2, Equals, 0)
 	c.Assert(refTable.ResolveSharedString(0), Equals, "Foo")
 }
+
+// Rich text strings keep their runs, and resolve to their plain text.
+func (s *RefTableSuite) TestMakeSharedStringRefTableWithRichText(c *C) {
+	sharedStringsXML := bytes.NewBufferString(
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+        <sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+             count="2"
+             uniqueCount="2">
+          <si>
+            <t>Foo</t>
+          </si>
+          <si>
+            <r><t>Bar</t></r>
+            <r>
+              <rPr><b/><sz val="11"/><color rgb="FFFF0000"/><rFont val="Calibri"/><family val="2"/></rPr>
+              <t>Baz</t>
+            </r>
+          </si>
+        </sst>`)
+	sst := new(xlsxSST)
+	err := xml.NewDecoder(sharedStringsXML).Decode(sst)
+	c.Assert(err, IsNil)
+	reftable := MakeSharedStringRefTable(sst)
+	c.Assert(reftable.ResolveRichText(0), IsNil)
+	c.Assert(reftable.ResolveSharedString(1), Equals, "BarBaz")
+	runs := reftable.ResolveRichText(1)
+	c.Assert(runs, HasLen, 2)
+	c.Assert(runs[0], DeepEquals, RichTextRun{Text: "Bar"})
+	c.Assert(runs[1].Text, Equals, "Baz")
+	c.Assert(*runs[1].Font, DeepEquals, Font{Size: 11, Name: "Calibri", Family: 2, Color: "FFFF0000", Bold: true})
+}
+
+// The properties of a run that a Font doesn't model are written back
+// as they were read, unless the fields of the Font closest to them
+// are changed.
+func (s *RefTableSuite) TestRichTextRunPropertiesRoundTrip(c *C) {
+	sharedStringsXML := bytes.NewBufferString(
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+        <sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="1" uniqueCount="1">
+          <si>
+            <r><rPr><strike/><sz val="10.5"/><color theme="1" tint="0.5"/><rFont val="Calibri"/><scheme val="minor"/></rPr><t>Foo</t></r>
+            <r><rPr><color indexed="10"/></rPr><t>Bar</t></r>
+          </si>
+        </sst>`)
+	sst := new(xlsxSST)
+	c.Assert(xml.NewDecoder(sharedStringsXML).Decode(sst), IsNil)
+	runs := MakeSharedStringRefTable(sst).ResolveRichText(0)
+	c.Assert(runs, HasLen, 2)
+	c.Assert(runs[0].Font.Size, Equals, 10)
+	c.Assert(runs[0].Font.Color, Equals, "")
+	c.Assert(runs[0].Font.Name, Equals, "Calibri")
+
+	refTable := NewSharedStringRefTable()
+	refTable.isWrite = true
+	refTable.AddRichText(runs)
+	body, err := xml.Marshal(refTable.makeXLSXSST())
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="1" uniqueCount="1"><si><r><rPr><strike></strike><sz val="10.5"></sz><color theme="1" tint="0.5"></color><rFont val="Calibri"></rFont><scheme val="minor"></scheme></rPr><t>Foo</t></r><r><rPr><color indexed="10"></color></rPr><t>Bar</t></r></si></sst>`)
+
+	runs[0].Font.Bold = true
+	runs[0].Font.Size = 12
+	runs[1].Font.Color = "FFFF0000"
+	refTable = NewSharedStringRefTable()
+	refTable.isWrite = true
+	refTable.AddRichText(runs)
+	body, err = xml.Marshal(refTable.makeXLSXSST())
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="1" uniqueCount="1"><si><r><rPr><b></b><strike></strike><sz val="12"></sz><color theme="1" tint="0.5"></color><rFont val="Calibri"></rFont><scheme val="minor"></scheme></rPr><t>Foo</t></r><r><rPr><color rgb="FFFF0000"></color></rPr><t>Bar</t></r></si></sst>`)
+}
+
+// Rich text strings are written as runs, and are not shared with
+// plain strings of the same text.
+func (s *RefTableSuite) TestMakeXLSXSSTWithRichText(c *C) {
+	refTable := NewSharedStringRefTable()
+	refTable.isWrite = true
+	refTable.AddString("FooBar")
+	index := refTable.AddRichText([]RichTextRun{
+		{Text: "Foo"},
+		{Font: &Font{Bold: true, Color: "FF0000FF"}, Text: "Bar"},
+	})
+	c.Assert(index, Equals, 1)
+	c.Assert(refTable.AddString("FooBar"), Equals, 0)
+
+	body, err := xml.Marshal(refTable.makeXLSXSST())
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2" uniqueCount="2"><si><t>FooBar</t></si><si><r><t>Foo</t></r><r><rPr><b></b><color rgb="FF0000FF"></color></rPr><t>Bar</t></r></si></sst>`)
+}
@@ -0,0 +1,144 @@
+package xlsx
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// RichTextRun is a fragment of the text of a rich text cell, together
+// with the font it is displayed in.  A nil Font means the run is
+// displayed in the font of the cell's style.
+//
+// The properties of a run read from a file that the Font type doesn't
+// model, such as strike-through, a themed colour or a size of 10.5,
+// are written back with it as long as the fields of the Font that come
+// closest to them, such as Color and Size, are left as they were read.
+type RichTextRun struct {
+	Font *Font
+	Text string
+}
+
+// richTextString returns the plain text of a list of runs.
+func richTextString(runs []RichTextRun) string {
+	var b strings.Builder
+	for _, run := range runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// makeRichTextRuns converts the runs of a shared string item into
+// RichTextRuns.
+func makeRichTextRuns(xRuns []xlsxR) []RichTextRun {
+	runs := make([]RichTextRun, len(xRuns))
+	for i, xRun := range xRuns {
+		runs[i].Text = xRun.T
+		if xRun.RPr != nil {
+			runs[i].Font = xRun.RPr.font()
+		}
+	}
+	return runs
+}
+
+// makeXLSXRuns converts RichTextRuns into the runs of a shared string
+// item.
+func makeXLSXRuns(runs []RichTextRun) []xlsxR {
+	xRuns := make([]xlsxR, len(runs))
+	for i, run := range runs {
+		xRuns[i].T = run.Text
+		if run.Font != nil {
+			xRuns[i].RPr = makeXLSXRPr(run.Font)
+		}
+	}
+	return xRuns
+}
+
+// font returns the Font described by the run properties.
+func (rPr *xlsxRPr) font() *Font {
+	font := &Font{}
+	if rPr.Sz != nil {
+		size, _ := strconv.ParseFloat(rPr.Sz.Val, 64)
+		font.Size = int(size)
+	}
+	if rPr.RFont != nil {
+		font.Name = rPr.RFont.Val
+	}
+	if rPr.Family != nil {
+		font.Family, _ = strconv.Atoi(rPr.Family.Val)
+	}
+	if rPr.Charset != nil {
+		font.Charset, _ = strconv.Atoi(rPr.Charset.Val)
+	}
+	if rPr.Color != nil {
+		font.Color = rPr.Color.RGB
+	}
+	font.Bold = rPr.B != nil && rPr.B.Val != "0" && rPr.B.Val != "false"
+	font.Italic = rPr.I != nil && rPr.I.Val != "0" && rPr.I.Val != "false"
+	font.Underline = rPr.U != nil && rPr.U.Val != "none"
+	if !reflect.DeepEqual(makeXLSXRPr(font), rPr) {
+		font.source = &fontSource{read: *font, rPr: rPr}
+	}
+	return font
+}
+
+// makeXLSXRPr returns the run properties describing font.  Zero
+// valued fields of the Font are left out.  A Font read from a file
+// starts from the run properties it was read from, and only the fields
+// changed since are written anew.
+func makeXLSXRPr(font *Font) *xlsxRPr {
+	rPr, read := &xlsxRPr{}, Font{}
+	if font.source != nil && font.source.rPr != nil {
+		copied := *font.source.rPr
+		rPr, read = &copied, font.source.read
+	}
+	if font.Bold != read.Bold {
+		rPr.B = nil
+		if font.Bold {
+			rPr.B = &xlsxVal{}
+		}
+	}
+	if font.Italic != read.Italic {
+		rPr.I = nil
+		if font.Italic {
+			rPr.I = &xlsxVal{}
+		}
+	}
+	if font.Underline != read.Underline {
+		rPr.U = nil
+		if font.Underline {
+			rPr.U = &xlsxVal{}
+		}
+	}
+	if font.Size != read.Size {
+		rPr.Sz = nil
+		if font.Size > 0 {
+			rPr.Sz = &xlsxVal{Val: strconv.Itoa(font.Size)}
+		}
+	}
+	if font.Color != read.Color {
+		rPr.Color = nil
+		if font.Color != "" {
+			rPr.Color = &xlsxColor{RGB: font.Color}
+		}
+	}
+	if font.Name != read.Name {
+		rPr.RFont = nil
+		if font.Name != "" {
+			rPr.RFont = &xlsxVal{Val: font.Name}
+		}
+	}
+	if font.Family != read.Family {
+		rPr.Family = nil
+		if font.Family > 0 {
+			rPr.Family = &xlsxVal{Val: strconv.Itoa(font.Family)}
+		}
+	}
+	if font.Charset != read.Charset {
+		rPr.Charset = nil
+		if font.Charset > 0 {
+			rPr.Charset = &xlsxVal{Val: strconv.Itoa(font.Charset)}
+		}
+	}
+	return rPr
+}
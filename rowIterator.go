@@ -0,0 +1,260 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// RowIterator provides pull-style access to the rows of a single
+// worksheet.  Rather than decoding the whole worksheet into memory,
+// it walks the sheet XML token by token and builds one Row at a time,
+// which makes it suitable for very large sheets.
+//
+// A typical loop looks like this:
+//
+//	rows, err := file.OpenSheetRows("Sheet1")
+//	if err != nil {
+//	    ...
+//	}
+//	defer rows.Close()
+//	for rows.Next() {
+//	    row := rows.Row()
+//	    ...
+//	}
+//	if err := rows.Err(); err != nil {
+//	    ...
+//	}
+//
+// Rows are yielded in sheet order, and rows that are missing from the
+// stored data are yielded as empty Rows, so that the n-th call to
+// Next always positions the iterator on the n-th row of the sheet.
+// As the iterator can't go back, a row stored out of order or a second
+// time is an error, where reading the whole sheet would put it in its
+// place.  Merged cell extents are stored after the sheet data and so are not
+// available to a RowIterator.
+type RowIterator struct {
+	file           *File
+	sheet          *Sheet
+	rc             io.ReadCloser
+	decoder        *xml.Decoder
+	sharedFormulas map[int]sharedFormula
+	cols           []*Col
+	pending        *xlsxRow
+	row            *Row
+	index          int
+	err            error
+	done           bool
+}
+
+// OpenSheetRows returns a RowIterator over the rows of the named
+// sheet.  The File must still have access to its zip data, which is
+// the case for files opened with OpenFileForStreaming, OpenBinary or
+// OpenReaderAt.  The caller should Close the iterator when done with
+// it.
+func (f *File) OpenSheetRows(sheetName string) (*RowIterator, error) {
+	var rawsheet *xlsxSheet
+	for i := range f.workbookSheets {
+		if f.workbookSheets[i].Name == sheetName {
+			rawsheet = &f.workbookSheets[i]
+			break
+		}
+	}
+	if rawsheet == nil {
+		return nil, fmt.Errorf("Unable to find sheet '%s'", sheetName)
+	}
+	zf := worksheetFileForSheet(*rawsheet, f.worksheets, f.sheetXMLMap)
+	if zf == nil {
+		return nil, fmt.Errorf("Unable to find worksheet for sheet '%s'", sheetName)
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	sheet, ok := f.Sheet[sheetName]
+	if !ok {
+		sheet = &Sheet{Name: sheetName, File: f}
+	}
+	return &RowIterator{
+		file:           f,
+		sheet:          sheet,
+		rc:             rc,
+		decoder:        xml.NewDecoder(rc),
+		sharedFormulas: map[int]sharedFormula{},
+		index:          -1,
+	}, nil
+}
+
+// Next advances the iterator to the next row, which will then be
+// available through Row.  It returns false when there are no more
+// rows or when an error has occurred; the two cases can be told apart
+// by calling Err.
+func (it *RowIterator) Next() bool {
+	if it.err != nil || it.done {
+		it.row = nil
+		return false
+	}
+	it.index++
+	if it.pending == nil {
+		rawrow, err := it.nextRawRow()
+		if err != nil {
+			it.err = err
+			it.row = nil
+			return false
+		}
+		if rawrow == nil {
+			it.done = true
+			it.row = nil
+			return false
+		}
+		it.pending = rawrow
+	}
+	// Some spreadsheets will omit blank rows from the stored data.
+	// Rows without an explicit number simply follow the previous one.
+	if it.pending.R > it.index+1 {
+		it.row = makeEmptyRow(it.sheet)
+		return true
+	}
+	row, err := it.makeRow(*it.pending)
+	it.pending = nil
+	if err != nil {
+		it.err = err
+		it.row = nil
+		return false
+	}
+	it.row = row
+	return true
+}
+
+// Row returns the row the iterator is currently positioned on.
+func (it *RowIterator) Row() *Row {
+	return it.row
+}
+
+// Index returns the zero based index of the current row in the sheet.
+func (it *RowIterator) Index() int {
+	return it.index
+}
+
+// Err returns the first error encountered while iterating, if any.
+func (it *RowIterator) Err() error {
+	return it.err
+}
+
+// Close releases the worksheet reader held by the iterator.
+func (it *RowIterator) Close() error {
+	it.done = true
+	if it.rc == nil {
+		return nil
+	}
+	err := it.rc.Close()
+	it.rc = nil
+	return err
+}
+
+// nextRawRow decodes tokens until the next row element and returns
+// it, or nil once the end of the sheet data has been reached.  Column
+// definitions, which precede the sheet data, are picked up on the way.
+func (it *RowIterator) nextRawRow() (*xlsxRow, error) {
+	for {
+		token, err := it.decoder.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "cols":
+				rawcols := new(xlsxCols)
+				if err := it.decoder.DecodeElement(rawcols, &t); err != nil {
+					return nil, err
+				}
+				it.readCols(rawcols)
+			case "row":
+				rawrow := new(xlsxRow)
+				if err := it.decoder.DecodeElement(rawrow, &t); err != nil {
+					return nil, err
+				}
+				if rawrow.R == 0 {
+					rawrow.R = it.index + 1
+				}
+				if rawrow.R <= it.index {
+					return nil, fmt.Errorf("xlsx: row %d of sheet '%s' is stored after row %d", rawrow.R, it.sheet.Name, it.index)
+				}
+				return rawrow, nil
+			}
+		case xml.EndElement:
+			if t.Name.Local == "sheetData" {
+				return nil, nil
+			}
+		}
+	}
+}
+
+// readCols expands the column definitions of the sheet into
+// individual Col values, in the same way as readRowsFromSheet.
+func (it *RowIterator) readCols(rawcols *xlsxCols) {
+	for _, rawcol := range rawcols.Col {
+		for len(it.cols) < rawcol.Max {
+			it.cols = append(it.cols, &Col{Hidden: false})
+		}
+		for i := rawcol.Min; i > 0 && i <= rawcol.Max; i++ {
+			col := &Col{
+				Min:          rawcol.Min,
+				Max:          rawcol.Max,
+				Hidden:       rawcol.Hidden,
+				Width:        rawcol.Width,
+				OutlineLevel: rawcol.OutlineLevel}
+			if it.file.styles != nil {
+				col.style = it.file.styles.getStyle(rawcol.Style)
+				col.numFmt = it.file.styles.getNumberFormat(rawcol.Style)
+			}
+			it.cols[i-1] = col
+		}
+	}
+}
+
+// makeRow builds a Row from a decoded xlsxRow, resolving shared
+// strings, styles and shared formulas.  Malformed cell data results
+// in a *CellError.
+func (it *RowIterator) makeRow(rawrow xlsxRow) (*Row, error) {
+	file := it.file
+	row, err := makeRowFromRaw(rawrow, it.sheet)
+	if err != nil {
+		return nil, err
+	}
+	row.Hidden = rawrow.Hidden
+	height, err := strconv.ParseFloat(rawrow.Ht, 64)
+	if err == nil {
+		row.Height = height
+	}
+	row.isCustom = rawrow.CustomHeight
+
+	insertColIndex := 0
+	for _, rawcell := range rawrow.C {
+		if rawcell.R != "" {
+			insertColIndex, _, _ = getCoordsFromCellIDString(rawcell.R)
+		}
+		cellX := insertColIndex
+		cell := row.Cells[cellX]
+		if file.styles != nil {
+			cell.style = file.styles.getStyle(rawcell.S)
+			cell.NumFmt = file.styles.getNumberFormat(rawcell.S)
+		}
+		cell.date1904 = file.Date1904
+		if err := fillCellData(rawcell, file.referenceTable, it.sharedFormulas, cell); err != nil {
+			return nil, newCellError(it.sheet, rawcell.R, err)
+		}
+		if file.trimSpace {
+			trimCellValue(cell)
+		}
+		// Cell is considered hidden if the row or the column of this cell is hidden
+		cell.Hidden = rawrow.Hidden || (len(it.cols) > cellX && it.cols[cellX].Hidden)
+		insertColIndex++
+	}
+	return row, nil
+}
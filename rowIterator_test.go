@@ -0,0 +1,95 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type RowIteratorSuite struct{}
+
+var _ = Suite(&RowIteratorSuite{})
+
+// Test we can stream the rows of a sheet without loading them into
+// the File first.
+func (s *RowIteratorSuite) TestOpenSheetRows(c *C) {
+	f, err := OpenFileForStreaming("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	defer f.Close()
+	c.Assert(len(f.Sheets), Equals, 3)
+	c.Assert(f.Sheets[0].Rows, IsNil)
+
+	rows, err := f.OpenSheetRows("Tabelle1")
+	c.Assert(err, IsNil)
+	defer rows.Close()
+	var output [][]string
+	for rows.Next() {
+		r := []string{}
+		for _, cell := range rows.Row().Cells {
+			str, err := cell.String()
+			c.Assert(err, IsNil)
+			r = append(r, str)
+		}
+		output = append(output, r)
+	}
+	c.Assert(rows.Err(), IsNil)
+	c.Assert(output, DeepEquals, [][]string{{"Foo", "Bar"}, {"Baz", "Quuk"}})
+	c.Assert(rows.Next(), Equals, false)
+}
+
+// Rows omitted from the stored data are yielded as empty rows.
+func (s *RowIteratorSuite) TestOpenSheetRowsWithEmptyRows(c *C) {
+	loaded, err := OpenFile("./testdocs/empty_rows.xlsx")
+	c.Assert(err, IsNil)
+	sheet := loaded.Sheet["EmptyRows"]
+
+	f, err := OpenFileForStreaming("./testdocs/empty_rows.xlsx")
+	c.Assert(err, IsNil)
+	defer f.Close()
+	rows, err := f.OpenSheetRows("EmptyRows")
+	c.Assert(err, IsNil)
+	defer rows.Close()
+	for rows.Next() {
+		row := rows.Row()
+		c.Assert(row.Sheet, Equals, f.Sheet["EmptyRows"])
+		expected := sheet.Rows[rows.Index()]
+		c.Assert(len(row.Cells), Equals, len(expected.Cells))
+		for i, cell := range row.Cells {
+			c.Assert(cell.Value, Equals, expected.Cells[i].Value)
+		}
+	}
+	c.Assert(rows.Err(), IsNil)
+	c.Assert(rows.Index(), Equals, len(sheet.Rows))
+}
+
+// Rows stored out of order, or twice, are an error rather than being
+// yielded out of place.
+func (s *RowIteratorSuite) TestOpenSheetRowsOutOfOrder(c *C) {
+	for _, test := range []struct {
+		old, new string
+		rows     int
+		message  string
+	}{
+		{`outlineLevel="0" r="1"`, `outlineLevel="0" r="3"`, 3, `xlsx: row 2 of sheet 'Tabelle1' is stored after row 3`},
+		{`outlineLevel="0" r="2"`, `outlineLevel="0" r="1"`, 1, `xlsx: row 1 of sheet 'Tabelle1' is stored after row 1`},
+	} {
+		data := rewriteTestFile(c, "./testdocs/testfile.xlsx", "xl/worksheets/sheet1.xml", test.old, test.new)
+		f, err := OpenBinary(data)
+		c.Assert(err, IsNil)
+		rows, err := f.OpenSheetRows("Tabelle1")
+		c.Assert(err, IsNil)
+		count := 0
+		for rows.Next() {
+			count++
+		}
+		c.Assert(count, Equals, test.rows)
+		c.Assert(rows.Err(), ErrorMatches, test.message)
+		c.Assert(rows.Close(), IsNil)
+	}
+}
+
+func (s *RowIteratorSuite) TestOpenSheetRowsUnknownSheet(c *C) {
+	f, err := OpenFileForStreaming("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	defer f.Close()
+	_, err = f.OpenSheetRows("NoSuchSheet")
+	c.Assert(err, NotNil)
+}
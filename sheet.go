@@ -8,35 +8,44 @@ import (
 // Sheet is a high level structure intended to provide user access to
 // the contents of a particular sheet within an XLSX file.
 type Sheet struct {
-	Name        string
-	File        *File
-	Rows        []*Row
-	Cols        []*Col
-	MaxRow      int
-	MaxCol      int
-	Hidden      bool
-	Selected    bool
-	SheetViews  []SheetView
-	SheetFormat SheetFormat
-	ConditionalFormatting []conditionalFormatting
+	Name                  string
+	File                  *File
+	Rows                  []*Row // nil until a lazily read sheet is loaded
+	Cols                  []*Col
+	MaxRow                int
+	MaxCol                int
+	Hidden                bool
+	Selected              bool
+	SheetViews            []SheetView
+	SheetFormat           SheetFormat
+	ConditionalFormatting []ConditionalFormat
+	rawSheet              *xlsxSheet // set until a lazily read sheet is loaded
+	parts                 *worksheetParts
+	loadErr               error
 }
 
-type conditionalFormatting struct {
-	CfRule cfRule
-	Sqref  string
-}
-
-type cfRule struct {
-	Formula 	formulaCF
-	Type   		string
-	DxfId   	string
-	Priority   	string
-	Operator   	string
-	Text  	 	string
-}
-
-type formulaCF struct {
-	Value string
+// Load decodes the worksheet of a Sheet that was read lazily (see
+// ReadOptions).  Sheet methods such as Cell and AddRow load the
+// sheet automatically, but code that reads the Rows or Cols fields of
+// a lazy sheet directly must call Load first.  Calling Load on a
+// sheet that is already loaded does nothing.
+//
+// If the worksheet can't be decoded, the sheet is left empty and the
+// error is kept: Load returns it every time it is called, and so do
+// File.Write and the Sheet methods that return an error.  Cell, Col
+// and AddRow have no error to return, so check Load before relying on
+// what they find in a lazy sheet.
+func (s *Sheet) Load() error {
+	if s.rawSheet == nil {
+		return s.loadErr
+	}
+	rsheet := *s.rawSheet
+	s.rawSheet = nil
+	sc := make(chan *indexedSheet, 1)
+	readSheetFromFile(sc, 0, rsheet, s.File, s.File.sheetXMLMap, s)
+	result := <-sc
+	s.loadErr = result.Error
+	return s.loadErr
 }
 
 type SheetView struct {
@@ -60,6 +69,7 @@ type SheetFormat struct {
 
 // Add a new Row to a Sheet
 func (s *Sheet) AddRow() *Row {
+	s.Load()
 	row := &Row{Sheet: s}
 	s.Rows = append(s.Rows, row)
 	if len(s.Rows) > s.MaxRow {
@@ -84,6 +94,7 @@ func (s *Sheet) maybeAddCol(cellCount int) {
 
 // Make sure we always have as many Cols as we do cells.
 func (s *Sheet) Col(idx int) *Col {
+	s.Load()
 	s.maybeAddCol(idx + 1)
 	return s.Cols[idx]
 }
@@ -93,11 +104,12 @@ func (s *Sheet) Col(idx int) *Col {
 //
 // For example:
 //
-//    cell := sheet.Cell(0,0)
+//	cell := sheet.Cell(0,0)
 //
 // ... would set the variable "cell" to contain a Cell struct
 // containing the data from the field "A1" on the spreadsheet.
 func (sh *Sheet) Cell(row, col int) *Cell {
+	sh.Load()
 
 	// If the user requests a row beyond what we have, then extend.
 	for len(sh.Rows) <= row {
@@ -112,11 +124,14 @@ func (sh *Sheet) Cell(row, col int) *Cell {
 	return r.Cells[col]
 }
 
-//Set the width of a single column or multiple columns.
+// Set the width of a single column or multiple columns.
 func (s *Sheet) SetColWidth(startcol, endcol int, width float64) error {
 	if startcol > endcol {
 		return fmt.Errorf("Could not set width for range %d-%d: startcol must be less than endcol.", startcol, endcol)
 	}
+	if err := s.Load(); err != nil {
+		return err
+	}
 	col := &Col{
 		style:     NewStyle(),
 		Min:       startcol + 1,
@@ -228,7 +243,9 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 		}
 		style := col.GetStyle()
 		//col's style always not nil
-		if style != nil {
+		if xfIndex, ok := style.readXfIndex(styles, col.numFmt); ok {
+			XfId = xfIndex
+		} else if style != nil {
 			xNumFmt := styles.newNumFmt(col.numFmt)
 			XfId = handleStyleForXLSX(style, xNumFmt.NumFmtId, styles)
 		}
@@ -273,11 +290,11 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 		for c, cell := range row.Cells {
 			XfId := colsXfIdList[c]
 
-			// generate NumFmtId and add new NumFmt
-			xNumFmt := styles.newNumFmt(cell.NumFmt)
-
 			style := cell.style
-			if style != nil {
+			if xfIndex, ok := style.readXfIndex(styles, cell.NumFmt); ok {
+				// The cell keeps the format it was read with.
+				XfId = xfIndex
+			} else if xNumFmt := styles.newNumFmt(cell.NumFmt); style != nil {
 				XfId = handleStyleForXLSX(style, xNumFmt.NumFmtId, styles)
 			} else if len(cell.NumFmt) > 0 && s.Cols[c].numFmt != cell.NumFmt {
 				XfId = handleNumFmtIdForXLSX(xNumFmt.NumFmtId, styles)
@@ -286,38 +303,7 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 			if c > maxCell {
 				maxCell = c
 			}
-			xC := xlsxC{}
-			xC.R = fmt.Sprintf("%s%d", numericToLetters(c), r+1)
-			switch cell.cellType {
-			case CellTypeString:
-				if len(cell.Value) > 0 {
-					xC.V = strconv.Itoa(refTable.AddString(cell.Value))
-				}
-				xC.T = "s"
-				xC.S = XfId
-			case CellTypeBool:
-				xC.V = cell.Value
-				xC.T = "b"
-				xC.S = XfId
-			case CellTypeNumeric:
-				xC.V = cell.Value
-				xC.S = XfId
-			case CellTypeDate:
-				xC.V = cell.Value
-				xC.S = XfId
-			case CellTypeFormula:
-				xC.V = cell.Value
-				xC.F = &xlsxF{Content: cell.formula}
-				xC.S = XfId
-			case CellTypeError:
-				xC.V = cell.Value
-				xC.F = &xlsxF{Content: cell.formula}
-				xC.T = "e"
-				xC.S = XfId
-			case CellTypeGeneral:
-				xC.V = cell.Value
-				xC.S = XfId
-			}
+			xC := makeXLSXCell(cell, fmt.Sprintf("%s%d", numericToLetters(c), r+1), XfId, refTable)
 
 			xRow.C = append(xRow.C, xC)
 
@@ -358,29 +344,96 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 	}
 	worksheet.Dimension = dimension
 
-	SheetConditionalFormattings := new([]xlsxConditionalFormatting)
-	for _, condFormat := range s.ConditionalFormatting{
-		FormulaCF := new(xlsxFormula)
-		CfRule := new(xlsxCfRule)
-		SheetConditionalFormatting := new(xlsxConditionalFormatting)		
-		
-		FormulaCF.Value = condFormat.CfRule.Formula.Value
-		CfRule.Formula = *FormulaCF
-		CfRule.Type = condFormat.CfRule.Type
-		
-		CfRule.DxfId = condFormat.CfRule.DxfId
-		CfRule.Priority = condFormat.CfRule.Priority
-		CfRule.Operator = condFormat.CfRule.Operator
-		CfRule.Text = condFormat.CfRule.Text
-		
-		SheetConditionalFormatting.CfRule = *CfRule
-		SheetConditionalFormatting.Sqref = condFormat.Sqref
-		*SheetConditionalFormattings = append(*SheetConditionalFormattings, *SheetConditionalFormatting)		
+	priorities := s.cfPriorities()
+	for i, condFormat := range s.ConditionalFormatting {
+		xCondFormat := xlsxConditionalFormatting{Sqref: condFormat.Sqref}
+		for j, rule := range condFormat.Rules {
+			if rule != nil {
+				xCondFormat.CfRule = append(xCondFormat.CfRule, rule.makeXLSXCfRule(condFormat.Sqref, priorities[i][j], styles))
+			}
+		}
+		if len(xCondFormat.CfRule) > 0 {
+			worksheet.ConditionalFormatting = append(worksheet.ConditionalFormatting, xCondFormat)
+		}
+	}
+	if s.parts != nil {
+		s.parts.apply(worksheet)
 	}
-	worksheet.ConditionalFormatting = *SheetConditionalFormattings
 	return worksheet
 }
 
+// makeXLSXCell returns the XML representation of a Cell located at
+// the cell reference ref and using the cell format XfId.  String
+// values are added to refTable, or written inline if refTable is nil.
+func makeXLSXCell(cell *Cell, ref string, XfId int, refTable *RefTable) xlsxC {
+	xC := xlsxC{}
+	xC.R = ref
+	switch cell.cellType {
+	case CellTypeInline:
+		xC.Is = makeXLSXInlineString(cell)
+		xC.T = "inlineStr"
+		xC.S = XfId
+	case CellTypeString:
+		if refTable == nil {
+			xC.Is = makeXLSXInlineString(cell)
+			xC.T = "inlineStr"
+		} else {
+			if runs := cell.RichText(); runs != nil {
+				xC.V = strconv.Itoa(refTable.AddRichText(runs))
+			} else if len(cell.Value) > 0 {
+				xC.V = strconv.Itoa(refTable.AddString(cell.Value))
+			}
+			xC.T = "s"
+		}
+		xC.S = XfId
+	case CellTypeBool:
+		xC.V = cell.Value
+		if cell.formula != "" {
+			xC.F = &xlsxF{Content: cell.formula}
+		}
+		xC.T = "b"
+		xC.S = XfId
+	case CellTypeNumeric:
+		xC.V = cell.Value
+		xC.S = XfId
+	case CellTypeDate:
+		xC.V = cell.Value
+		xC.S = XfId
+	case CellTypeFormula:
+		xC.V = cell.Value
+		xC.F = &xlsxF{Content: cell.formula}
+		if _, err := strconv.ParseFloat(cell.Value, 64); err != nil && len(cell.Value) > 0 {
+			xC.T = "str"
+		}
+		xC.S = XfId
+	case CellTypeStringFormula:
+		xC.V = cell.Value
+		xC.F = &xlsxF{Content: cell.formula}
+		xC.T = "str"
+		xC.S = XfId
+	case CellTypeError:
+		xC.V = cell.Value
+		if cell.formula != "" {
+			xC.F = &xlsxF{Content: cell.formula}
+		}
+		xC.T = "e"
+		xC.S = XfId
+	case CellTypeGeneral:
+		xC.V = cell.Value
+		xC.S = XfId
+	}
+	return xC
+}
+
+// makeXLSXInlineString returns the inline string element holding the
+// value of a string cell.
+func makeXLSXInlineString(cell *Cell) *xlsxSI {
+	if runs := cell.RichText(); runs != nil {
+		return &xlsxSI{R: makeXLSXRuns(runs)}
+	}
+	return &xlsxSI{T: cell.Value}
+}
+
 func handleStyleForXLSX(style *Style, NumFmtId int, styles *xlsxStyleSheet) (XfId int) {
 	xFont, xFill, xBorder, xCellXf := style.makeXLSXStyleElements()
 	fontId := styles.addFont(xFont)
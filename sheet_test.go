@@ -228,6 +228,31 @@ func (s *SheetSuite) TestMarshalSheetWithMultipleCells(c *C) {
 	c.Assert(output.String(), Equals, expectedXLSXSheet)
 }
 
+// String cells are written inline when there is no RefTable, and
+// formulas with a string result are marked as such.
+func (s *SheetSuite) TestMakeXLSXCellWithInlineStrings(c *C) {
+	cell := &Cell{}
+	cell.SetString("Inline")
+	xC := makeXLSXCell(cell, "A1", 0, nil)
+	c.Assert(xC.T, Equals, "inlineStr")
+	c.Assert(xC.V, Equals, "")
+	body, err := xml.Marshal(xC)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<xlsxC r="A1" t="inlineStr"><is><t>Inline</t></is></xlsxC>`)
+
+	refTable := NewSharedStringRefTable()
+	xC = makeXLSXCell(cell, "A1", 0, refTable)
+	c.Assert(xC.T, Equals, "s")
+	c.Assert(xC.Is, IsNil)
+
+	cell.SetFormula(`"In"&"line"`)
+	xC = makeXLSXCell(cell, "A1", 0, refTable)
+	c.Assert(xC.T, Equals, "str")
+	cell.Value = "42"
+	xC = makeXLSXCell(cell, "A1", 0, refTable)
+	c.Assert(xC.T, Equals, "")
+}
+
 func (s *SheetSuite) TestSetColWidth(c *C) {
 	file := NewFile()
 	sheet, _ := file.AddSheet("Sheet1")
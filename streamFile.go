@@ -0,0 +1,246 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamFile writes an XLSX file to an io.Writer without building the
+// in-memory File model.  Rows are encoded straight into the zip entry
+// of the current sheet as they are written, so the memory used does
+// not grow with the number of rows.  Only the shared strings and the
+// styles are collected on the side, and they are written out by
+// Close.
+//
+// Sheets are written one after the other: calling AddSheet finishes
+// the current sheet and starts a new one.
+//
+//	sf := xlsx.NewStreamFile(w)
+//	if err := sf.AddSheet("Sheet1"); err != nil {
+//	    ...
+//	}
+//	for _, record := range records {
+//	    if err := sf.Write(record); err != nil {
+//	        ...
+//	    }
+//	}
+//	if err := sf.Close(); err != nil {
+//	    ...
+//	}
+type StreamFile struct {
+	// InlineStrings makes string cells be written inline in the
+	// sheet rather than collected as shared strings, so that memory
+	// use doesn't grow with the number of distinct strings.
+	InlineStrings bool
+
+	file         *File
+	zipWriter    *zip.Writer
+	refTable     *RefTable
+	workbook     xlsxWorkbook
+	workbookRels WorkBookRels
+	types        xlsxTypes
+	sheet        *streamSheet
+	err          error
+	closed       bool
+}
+
+// streamSheet holds the state of the sheet currently being written by
+// a StreamFile.
+type streamSheet struct {
+	writer   *bufio.Writer
+	encoder  *xml.Encoder
+	suffix   string
+	rowCount int
+}
+
+// ErrStreamFileClosed is returned when writing to a StreamFile after
+// Close has been called.
+var ErrStreamFileClosed = errors.New("xlsx: stream file is closed")
+
+// NewStreamFile returns a StreamFile that writes an XLSX file to w.
+func NewStreamFile(w io.Writer) *StreamFile {
+	file := NewFile()
+	file.styles = newXlsxStyleSheet(nil)
+	file.styles.reset()
+	refTable := NewSharedStringRefTable()
+	refTable.isWrite = true
+	return &StreamFile{
+		file:         file,
+		zipWriter:    zip.NewWriter(w),
+		refTable:     refTable,
+		workbookRels: make(WorkBookRels),
+		types:        MakeDefaultContentTypes(),
+	}
+}
+
+// AddSheet finishes the sheet currently being written, if any, and
+// starts a new sheet with the provided name.  Subsequent rows are
+// written to the new sheet.
+func (sf *StreamFile) AddSheet(sheetName string) error {
+	if err := sf.checkWritable(); err != nil {
+		return err
+	}
+	if _, err := sf.file.AddSheet(sheetName); err != nil {
+		return err
+	}
+	if err := sf.finishSheet(); err != nil {
+		return sf.fail(err)
+	}
+
+	sheetIndex := len(sf.file.Sheets)
+	sf.workbook.Sheets.Sheet = append(sf.workbook.Sheets.Sheet, xlsxSheet{})
+	partName := addSheetToWorkbook(sheetIndex, sheetName, &sf.workbook, sf.workbookRels, &sf.types)
+	w, err := sf.zipWriter.Create(partName)
+	if err != nil {
+		return sf.fail(err)
+	}
+
+	// The worksheet is rendered with empty sheet data, and the rows
+	// are streamed in between the two halves of the result.
+	worksheet := newXlsxWorksheet()
+	worksheet.SheetViews.SheetView[0].TabSelected = sheetIndex == 1
+	body, err := xml.Marshal(worksheet)
+	if err != nil {
+		return sf.fail(err)
+	}
+	// The dimension of the sheet isn't known until all of the rows
+	// have been written, and it is optional, so we leave it out.
+	parts := strings.SplitN(strings.Replace(string(body), `<dimension ref=""></dimension>`, "", 1), "<sheetData></sheetData>", 2)
+	if len(parts) != 2 {
+		return sf.fail(fmt.Errorf("xlsx: unable to render worksheet '%s'", sheetName))
+	}
+	sheet := &streamSheet{writer: bufio.NewWriter(w), suffix: "</sheetData>" + parts[1]}
+	sheet.encoder = xml.NewEncoder(sheet.writer)
+	if _, err := sheet.writer.WriteString(xml.Header + parts[0] + "<sheetData>"); err != nil {
+		return sf.fail(err)
+	}
+	sf.sheet = sheet
+	return nil
+}
+
+// Write appends a row of string values to the current sheet.
+func (sf *StreamFile) Write(record []string) error {
+	cells := make([]*Cell, len(record))
+	for i, value := range record {
+		cells[i] = &Cell{}
+		cells[i].SetString(value)
+	}
+	return sf.WriteRow(cells)
+}
+
+// WriteRow appends a row made of the given cells to the current
+// sheet.  The cell values, types, number formats and styles are
+// written; a nil cell leaves a gap in the row.
+func (sf *StreamFile) WriteRow(cells []*Cell) error {
+	if err := sf.checkWritable(); err != nil {
+		return err
+	}
+	if sf.sheet == nil {
+		return errors.New("xlsx: AddSheet must be called before writing rows")
+	}
+	styles := sf.file.styles
+	sheet := sf.sheet
+	refTable := sf.refTable
+	if sf.InlineStrings {
+		refTable = nil
+	}
+	xRow := xlsxRow{R: sheet.rowCount + 1}
+	for c, cell := range cells {
+		if cell == nil {
+			continue
+		}
+		XfId := 0
+		xNumFmt := styles.newNumFmt(cell.NumFmt)
+		if cell.style != nil {
+			XfId = handleStyleForXLSX(cell.style, xNumFmt.NumFmtId, styles)
+		} else if len(cell.NumFmt) > 0 {
+			XfId = handleNumFmtIdForXLSX(xNumFmt.NumFmtId, styles)
+		}
+		ref := fmt.Sprintf("%s%d", numericToLetters(c), xRow.R)
+		xRow.C = append(xRow.C, makeXLSXCell(cell, ref, XfId, refTable))
+	}
+	err := sheet.encoder.EncodeElement(xRow, xml.StartElement{Name: xml.Name{Local: "row"}})
+	if err != nil {
+		return sf.fail(err)
+	}
+	sheet.rowCount++
+	return nil
+}
+
+// Close finishes the current sheet, writes the shared strings, the
+// styles and the remaining workbook parts and closes the zip archive.
+// It does not close the underlying io.Writer.
+func (sf *StreamFile) Close() error {
+	if sf.closed {
+		return sf.err
+	}
+	sf.closed = true
+	if sf.err != nil {
+		return sf.err
+	}
+	if len(sf.file.Sheets) == 0 {
+		sf.err = errors.New("xlsx: a stream file must contain at least one sheet")
+		return sf.err
+	}
+	if err := sf.finishSheet(); err != nil {
+		sf.err = err
+		return err
+	}
+
+	workbook := sf.file.makeWorkbook()
+	workbook.Sheets = sf.workbook.Sheets
+	parts := make(map[string]string)
+	err := sf.file.marshallWorkbookParts(parts, workbook, sf.workbookRels, sf.types, sf.refTable)
+	if err != nil {
+		sf.err = err
+		return err
+	}
+	for partName, part := range parts {
+		w, err := sf.zipWriter.Create(partName)
+		if err != nil {
+			sf.err = err
+			return err
+		}
+		if _, err = io.WriteString(w, part); err != nil {
+			sf.err = err
+			return err
+		}
+	}
+	sf.err = sf.zipWriter.Close()
+	return sf.err
+}
+
+// finishSheet completes the XML of the sheet currently being written.
+func (sf *StreamFile) finishSheet() error {
+	sheet := sf.sheet
+	if sheet == nil {
+		return nil
+	}
+	sf.sheet = nil
+	if err := sheet.encoder.Flush(); err != nil {
+		return err
+	}
+	if _, err := sheet.writer.WriteString(sheet.suffix); err != nil {
+		return err
+	}
+	return sheet.writer.Flush()
+}
+
+func (sf *StreamFile) checkWritable() error {
+	if sf.closed {
+		return ErrStreamFileClosed
+	}
+	return sf.err
+}
+
+// fail records err as the sticky error of the StreamFile, after which
+// every further write fails.
+func (sf *StreamFile) fail(err error) error {
+	sf.err = err
+	return err
+}
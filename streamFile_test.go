@@ -0,0 +1,101 @@
+package xlsx
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type StreamFileSuite struct{}
+
+var _ = Suite(&StreamFileSuite{})
+
+// Test that a streamed file can be read back, with its sheets, values
+// and cell types intact.
+func (s *StreamFileSuite) TestWriteAndReadBack(c *C) {
+	var buf bytes.Buffer
+	sf := NewStreamFile(&buf)
+	c.Assert(sf.AddSheet("First"), IsNil)
+	c.Assert(sf.Write([]string{"Name", "Age"}), IsNil)
+	name := &Cell{}
+	name.SetString("Eric")
+	age := &Cell{}
+	age.SetInt(42)
+	price := &Cell{}
+	price.SetFloatWithFormat(1.5, "0.00")
+	c.Assert(sf.WriteRow([]*Cell{name, age, price}), IsNil)
+	c.Assert(sf.AddSheet("Second"), IsNil)
+	c.Assert(sf.Write([]string{"Eric"}), IsNil)
+	c.Assert(sf.Close(), IsNil)
+
+	f, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(len(f.Sheets), Equals, 2)
+	c.Assert(f.Sheets[0].Name, Equals, "First")
+	c.Assert(f.Sheets[1].Name, Equals, "Second")
+
+	output, err := f.ToSlice()
+	c.Assert(err, IsNil)
+	c.Assert(output[0], DeepEquals, [][]string{{"Name", "Age"}, {"Eric", "42", "1.50"}})
+	c.Assert(output[1], DeepEquals, [][]string{{"Eric"}})
+
+	first := f.Sheet["First"]
+	c.Assert(first.Cell(1, 0).Type(), Equals, CellTypeString)
+	c.Assert(first.Cell(1, 1).Type(), Equals, CellTypeNumeric)
+	c.Assert(first.Cell(1, 2).GetNumberFormat(), Equals, "0.00")
+}
+
+// Test that a streamed file can be saved to disk and opened with
+// OpenFile.
+func (s *StreamFileSuite) TestWriteToDisk(c *C) {
+	xlsxPath := filepath.Join(c.MkDir(), "TestStreamFile.xlsx")
+	target, err := os.Create(xlsxPath)
+	c.Assert(err, IsNil)
+	sf := NewStreamFile(target)
+	c.Assert(sf.AddSheet("Data"), IsNil)
+	for i := 0; i < 100; i++ {
+		c.Assert(sf.Write([]string{"a", "b", "c"}), IsNil)
+	}
+	c.Assert(sf.Close(), IsNil)
+	c.Assert(target.Close(), IsNil)
+
+	f, err := OpenFile(xlsxPath)
+	c.Assert(err, IsNil)
+	c.Assert(len(f.Sheet["Data"].Rows), Equals, 100)
+	c.Assert(f.Sheet["Data"].Cell(99, 2).Value, Equals, "c")
+}
+
+// With InlineStrings set, no shared strings are collected, and the
+// values still read back.
+func (s *StreamFileSuite) TestWriteInlineStrings(c *C) {
+	var buf bytes.Buffer
+	sf := NewStreamFile(&buf)
+	sf.InlineStrings = true
+	c.Assert(sf.AddSheet("Data"), IsNil)
+	c.Assert(sf.Write([]string{"a", "b"}), IsNil)
+	c.Assert(sf.Write([]string{"a", "c"}), IsNil)
+	c.Assert(sf.Close(), IsNil)
+	c.Assert(sf.refTable.Length(), Equals, 0)
+
+	f, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	output, err := f.ToSlice()
+	c.Assert(err, IsNil)
+	c.Assert(output[0], DeepEquals, [][]string{{"a", "b"}, {"a", "c"}})
+	c.Assert(f.Sheet["Data"].Cell(1, 1).Type(), Equals, CellTypeInline)
+}
+
+func (s *StreamFileSuite) TestErrors(c *C) {
+	var buf bytes.Buffer
+	sf := NewStreamFile(&buf)
+	c.Assert(sf.Write([]string{"a"}), NotNil)
+	c.Assert(sf.AddSheet("Data"), IsNil)
+	c.Assert(sf.AddSheet("Data"), NotNil)
+	c.Assert(sf.Close(), IsNil)
+	c.Assert(sf.Write([]string{"a"}), Equals, ErrStreamFileClosed)
+
+	sf = NewStreamFile(&buf)
+	c.Assert(sf.Close(), NotNil)
+}
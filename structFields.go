@@ -0,0 +1,195 @@
+package xlsx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	stringerType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	marshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	unmarshaler   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	valuerType    = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType   = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// structField is a field of a struct that is held in a cell, as
+// described by its xlsx tag.
+type structField struct {
+	index     []int  // the path to the field, through nested structs
+	goName    string // the name of the field in the struct
+	name      string // the header of the field's column
+	col       int    // the zero based column of the field, or -1
+	format    string // the number format of the field's cells
+	omitEmpty bool   // leave the cell empty for a zero value
+}
+
+// structFields returns the fields of the struct type t that are held
+// in cells, in order.
+//
+// The tag of a field starts with the header of its column, such as
+// `xlsx:"Customer Name"`, and may go on with options: col=C for the
+// column of the field, format=0.00% for the number format of its
+// cells, and omitempty to leave zero values out.  A format runs to the
+// next option, so it may hold commas.  Without a header, the field's
+// own name is used.  Fields tagged `xlsx:"-"`, unexported fields and
+// fields of types that can't be held in a cell are left out.
+//
+// The fields of nested structs are taken as the struct's own.  Those
+// of embedded structs keep their names, and those of other nested
+// structs are named after the struct field, as in "Address.City".
+// Pointers to unexported embedded structs are left out, as they can't
+// be allocated, and so are nested structs of a type that is already
+// being flattened, such as the Next of a linked list.
+func structFields(t reflect.Type) ([]structField, error) {
+	return nestedStructFields(t, nil)
+}
+
+// nestedStructFields returns the fields of t, which is nested in the
+// struct types of path.
+func nestedStructFields(t reflect.Type, path []reflect.Type) ([]structField, error) {
+	path = append(path, t)
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("xlsx")
+		if tag == "-" {
+			continue
+		}
+		if sf.PkgPath != "" && (!sf.Anonymous || sf.Type.Kind() == reflect.Ptr) {
+			continue
+		}
+		field := structField{index: []int{i}, goName: sf.Name, name: sf.Name, col: -1}
+		if err := field.parseTag(tag); err != nil {
+			return nil, err
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && !isCellType(ft) {
+			if inPath(ft, path) {
+				continue
+			}
+			nested, err := nestedStructFields(ft, path[:len(path):len(path)])
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range nested {
+				f.index = append([]int{i}, f.index...)
+				if !sf.Anonymous || tag != "" {
+					f.name = field.name + "." + f.name
+				}
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if sf.PkgPath != "" || !isCellType(ft) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// inPath reports whether t is one of the types of path.
+func inPath(t reflect.Type, path []reflect.Type) bool {
+	for _, p := range path {
+		if p == t {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTag sets the header and the options of the field from its
+// xlsx tag.
+func (f *structField) parseTag(tag string) error {
+	if tag == "" {
+		return nil
+	}
+	inFormat := false
+	for i, option := range strings.Split(tag, ",") {
+		switch {
+		case option == "omitempty":
+			f.omitEmpty = true
+			inFormat = false
+		case strings.HasPrefix(option, "col="):
+			letters := option[len("col="):]
+			if letters == "" || strings.Map(letterOnlyMapF, letters) != letters {
+				return fmt.Errorf("xlsx: field %s: invalid column '%s'", f.goName, letters)
+			}
+			f.col = lettersToNumeric(letters)
+			inFormat = false
+		case strings.HasPrefix(option, "format="):
+			f.format = option[len("format="):]
+			inFormat = true
+		case inFormat:
+			f.format += "," + option
+		case i == 0:
+			if option != "" {
+				f.name = option
+			}
+		default:
+			return fmt.Errorf("xlsx: field %s: unknown tag option '%s'", f.goName, option)
+		}
+	}
+	return nil
+}
+
+// isCellType reports whether values of type t can be held in a single
+// cell.
+func isCellType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	pt := reflect.PtrTo(t)
+	return t == timeType || pt.Implements(stringerType) || pt.Implements(marshalerType) ||
+		pt.Implements(unmarshaler) || pt.Implements(valuerType) || pt.Implements(scannerType)
+}
+
+// positionalColumns returns the columns of fields, laid out in order
+// from the column start, except for those whose tags name a column.
+func positionalColumns(fields []structField, start int) []int {
+	cols := make([]int, len(fields))
+	next := start
+	for i, field := range fields {
+		cols[i] = field.col
+		if field.col < 0 {
+			cols[i] = next
+			next++
+		}
+	}
+	return cols
+}
+
+// fieldValue returns the field of the struct v at index.  Nil pointers
+// to nested structs are allocated if alloc is true; otherwise the
+// field is reported as missing.
+func fieldValue(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
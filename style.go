@@ -1,6 +1,9 @@
 package xlsx
 
-import "strconv"
+import (
+	"reflect"
+	"strconv"
+)
 
 // Style is a high level structure intended to provide user access to
 // the contents of Style within an XLSX file.
@@ -15,6 +18,37 @@ type Style struct {
 	Alignment       Alignment
 	NamedStyleIndex *int
 	Dxfs      Dxfs
+	read      *readStyle // where the style was read from, if it was
+}
+
+// readStyle records the cell format a Style was read from, and the
+// Style as it was read.
+type readStyle struct {
+	styles  *xlsxStyleSheet
+	xfIndex int
+	numFmt  string
+	style   Style
+}
+
+// readXfIndex returns the index of the cell format that the style
+// was read from, provided that the style is being written back to the
+// same style sheet, and that neither the style nor the number format
+// has changed since.  The original cell format can then be used as it
+// is, with whatever it holds that Style doesn't model.
+func (style *Style) readXfIndex(styles *xlsxStyleSheet, numFmt string) (int, bool) {
+	if style == nil {
+		return 0, false
+	}
+	read := style.read
+	if read == nil || read.styles != styles || read.numFmt != numFmt {
+		return 0, false
+	}
+	current := *style
+	current.read = nil
+	if !reflect.DeepEqual(current, read.style) {
+		return 0, false
+	}
+	return read.xfIndex, true
 }
 
 type Dxfs struct {
@@ -147,6 +181,20 @@ type Font struct {
 	Bold      bool
 	Italic    bool
 	Underline bool
+
+	source *fontSource // the XML a font was read from, if the fields above don't tell all of it
+}
+
+// fontSource keeps the XML that a Font of a rich text run or of a
+// differential style was read from, together with the Font as it was
+// read.  The properties that
+// the Font doesn't model, such as strike-through, a themed colour or a
+// size of 10.5, are written back from the XML for as long as the
+// fields of the Font that stand for them are unchanged.
+type fontSource struct {
+	read  Font
+	rPr   *xlsxRPr
+	xFont *xlsxFont
 }
 
 func NewFont(size int, name string) *Font {
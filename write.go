@@ -1,8 +1,12 @@
 package xlsx
 
 import (
+	"database/sql/driver"
+	"encoding"
+	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"time"
 )
 
@@ -66,47 +70,168 @@ func (r *Row) WriteSlice(e interface{}, cols int) int {
 // Writes a struct to row r. Accepts a pointer to struct type 'e',
 // and the number of columns to write, `cols`. If 'cols' is < 0,
 // the entire struct will be written if possible. Returns -1 if the 'e'
-// doesn't point to a struct, otherwise the number of columns written
+// doesn't point to a struct, otherwise the number of columns written.
+//
+// Fields are written in order, after the cells the row already has.
+// A field's xlsx tag can change that: `xlsx:"-"` leaves the field out,
+// and otherwise the tag starts with the header of the field's column
+// (see Sheet.WriteStructs) and goes on with options.  The option
+// col=C puts the field in column C, format=0.00% gives its cell a
+// number format, which may hold commas, and omitempty leaves the cell
+// empty for a zero value, as in `xlsx:"Share,format=0.00%,omitempty"`.
+//
+// Strings, bools, numbers, times, durations, fmt.Stringer and
+// encoding.TextMarshaler types, and driver.Valuer types such as
+// sql.NullString can be written, as can pointers to them; nil
+// pointers and invalid sql.Null values leave their cells empty.  The
+// fields of nested structs are written as the struct's own, and
+// unexported fields and fields of other types are left out.
 func (r *Row) WriteStruct(e interface{}, cols int) int {
 	if cols == 0 {
 		return cols
 	}
 
-	v := reflect.ValueOf(e).Elem()
-	if v.Kind() != reflect.Struct {
+	v := reflect.ValueOf(e)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
 		return -1 // bail if it's not a struct
 	}
-
-	n := v.NumField() // number of fields in struct
-	if cols < n && cols > 0 {
-		n = cols
+	fields, err := structFields(v.Elem().Type())
+	if err != nil {
+		return -1
+	}
+	if cols < len(fields) && cols > 0 {
+		fields = fields[:cols]
 	}
 
-	var k int
-	for i := 0; i < n; i, k = i+1, k+1 {
-		f := v.Field(i)
+	r.writeStruct(v.Elem(), fields, positionalColumns(fields, len(r.Cells)))
+	return len(fields)
+}
 
-		switch t := f.Interface().(type) {
-		case time.Time:
-			cell := r.AddCell()
-			cell.SetValue(t)
-		case fmt.Stringer: // check Stringer first
-			cell := r.AddCell()
-			cell.SetString(t.String())
-		default:
-			switch f.Kind() {
-			case reflect.String, reflect.Int, reflect.Int8,
-				reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float64, reflect.Float32:
-				cell := r.AddCell()
-				cell.SetValue(f.Interface())
-			case reflect.Bool:
-				cell := r.AddCell()
-				cell.SetBool(t.(bool))
-			default:
-				k-- // nothing set so reset to previous
+// WriteStructs adds a header row to the sheet, and then a row for each
+// element of slice, which holds structs or pointers to structs.  The
+// headers, in bold, are the names of the fields or the names their
+// tags give them; see Row.WriteStruct for the tags and the types of
+// field that can be written.  Sheet.ReadStructs reads the rows back.
+func (s *Sheet) WriteStructs(slice interface{}) error {
+	v := reflect.ValueOf(slice)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return errors.New("xlsx: WriteStructs needs a slice")
+	}
+	structType := v.Type().Elem()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return errors.New("xlsx: WriteStructs needs a slice of structs or of pointers to structs")
+	}
+	fields, err := structFields(structType)
+	if err != nil {
+		return err
+	}
+	cols := positionalColumns(fields, 0)
+
+	style := NewStyle()
+	style.Font.Bold = true
+	style.ApplyFont = true
+	header := s.AddRow()
+	for i, field := range fields {
+		cell := header.cellAt(cols[i])
+		cell.SetString(field.name)
+		cell.SetStyle(style)
+	}
+	for i := 0; i < v.Len(); i++ {
+		row := s.AddRow()
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
 			}
+			elem = elem.Elem()
+		}
+		row.writeStruct(elem, fields, cols)
+	}
+	return nil
+}
+
+// writeStruct writes the fields of the struct v to the columns cols of
+// the row.
+func (r *Row) writeStruct(v reflect.Value, fields []structField, cols []int) {
+	for i, field := range fields {
+		cell := r.cellAt(cols[i])
+		fv, ok := fieldValue(v, field.index, false)
+		if !ok || field.omitEmpty && fv.IsZero() {
+			continue
+		}
+		cell.writeValue(fv)
+		if field.format != "" {
+			cell.NumFmt = field.format
 		}
 	}
+}
 
-	return k
+// cellAt returns the cell of the row in column x, adding empty cells
+// up to it as needed.
+func (r *Row) cellAt(x int) *Cell {
+	for len(r.Cells) <= x {
+		r.AddCell()
+	}
+	return r.Cells[x]
+}
+
+// writeValue sets the cell to v, as Row.WriteStruct writes fields.
+func (c *Cell) writeValue(v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if !v.CanAddr() {
+		addressable := reflect.New(v.Type()).Elem()
+		addressable.Set(v)
+		v = addressable
+	}
+
+	switch t := v.Addr().Interface().(type) {
+	case *time.Time:
+		c.SetDateTime(*t)
+		return
+	case *time.Duration:
+		c.SetDuration(*t)
+		return
+	case driver.Valuer:
+		value, err := t.Value()
+		if err == nil && value != nil {
+			c.writeValue(reflect.ValueOf(value))
+		}
+		return
+	case fmt.Stringer: // check Stringer first
+		c.SetString(t.String())
+		return
+	case encoding.TextMarshaler:
+		if text, err := t.MarshalText(); err == nil {
+			c.SetString(string(text))
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		c.SetString(v.String())
+	case reflect.Bool:
+		c.SetBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.setGeneral(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		c.setGeneral(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		c.setGeneral(strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			c.SetString(string(v.Bytes()))
+		}
+	}
 }
@@ -1,7 +1,10 @@
 package xlsx
 
 import (
+	"database/sql"
 	"math"
+	"path/filepath"
+	"reflect"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -182,3 +185,112 @@ func (r *RowSuite) TestWriteSlice(c *C) {
 	s7_ret = row7.WriteSlice([]string{s7}, -1)
 	c.Assert(s7_ret, Equals, -1)
 }
+
+type testAmounts struct {
+	Net   float64 `xlsx:"Net Amount,format=#,##0.00"`
+	Share float64 `xlsx:"format=0.00%,omitempty"`
+}
+
+type testOrder struct {
+	ID       int `xlsx:"Order"`
+	Customer *testCustomerRef
+	Amounts  testAmounts `xlsx:"Total"`
+	Note     sql.NullString
+	Quantity sql.NullInt64
+	Shipped  *time.Time
+	Secret   string `xlsx:"-"`
+	Tags     []string
+	internal int
+}
+
+type testCustomerRef struct {
+	Name string
+}
+
+// Tags name, place and format the fields, and leave some out.
+func (r *RowSuite) TestWriteStructTags(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Orders")
+	c.Assert(err, IsNil)
+	row := sheet.AddRow()
+	row.AddCell().SetString("before")
+	type e struct {
+		Name    string  `xlsx:"Name,omitempty"`
+		Ratio   float32 `xlsx:",format=0.0%"`
+		Code    string  `xlsx:"col=F"`
+		Skipped string  `xlsx:"-"`
+		Level   uint8
+	}
+	c.Assert(row.WriteStruct(&e{Ratio: 0.25, Code: "X", Skipped: "no", Level: 3}, -1), Equals, 4)
+	c.Assert(row.Cells, HasLen, 6)
+	c.Assert(row.Cells[1].Value, Equals, "")
+	c.Assert(row.Cells[2].Value, Equals, "0.25")
+	c.Assert(row.Cells[2].NumFmt, Equals, "0.0%")
+	c.Assert(row.Cells[3].Value, Equals, "3")
+	c.Assert(row.Cells[5].Value, Equals, "X")
+
+	var bad struct {
+		Name string `xlsx:"Name,sideways"`
+	}
+	c.Assert(sheet.AddRow().WriteStruct(&bad, -1), Equals, -1)
+	_, err = structFields(reflect.TypeOf(bad))
+	c.Assert(err, ErrorMatches, "xlsx: field Name: unknown tag option 'sideways'")
+}
+
+// WriteStructs writes a header and flattens nested structs, and the
+// rows read back with ReadStructs.
+func (r *RowSuite) TestWriteStructs(c *C) {
+	shipped := time.Date(2013, 1, 2, 15, 4, 0, 0, time.UTC)
+	orders := []*testOrder{
+		{ID: 1, Customer: &testCustomerRef{"Ada"}, Amounts: testAmounts{1234.5, 0.25},
+			Note: sql.NullString{String: "rush", Valid: true}, Quantity: sql.NullInt64{Int64: 3, Valid: true},
+			Shipped: &shipped, Secret: "x", Tags: []string{"a"}},
+		nil,
+		{ID: 2, Amounts: testAmounts{Net: 10}},
+	}
+	f := NewFile()
+	sheet, err := f.AddSheet("Orders")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.WriteStructs(orders), IsNil)
+	c.Assert(sheet.WriteStructs(42), ErrorMatches, "xlsx: WriteStructs needs a slice")
+
+	c.Assert(sheet.Rows, HasLen, 4)
+	var headers []string
+	for _, cell := range sheet.Rows[0].Cells {
+		headers = append(headers, cell.Value)
+		c.Assert(cell.GetStyle().Font.Bold, Equals, true)
+	}
+	c.Assert(headers, DeepEquals, []string{"Order", "Customer.Name", "Total.Net Amount", "Total.Share", "Note", "Quantity", "Shipped"})
+	first := sheet.Rows[1]
+	c.Assert(first.Cells[1].Value, Equals, "Ada")
+	c.Assert(first.Cells[2].NumFmt, Equals, "#,##0.00")
+	val, err := first.Cells[2].FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(val, Equals, "1,234.50")
+	c.Assert(first.Cells[4].Value, Equals, "rush")
+	c.Assert(first.Cells[5].Value, Equals, "3")
+	c.Assert(sheet.Rows[2].Cells, HasLen, 0)
+	third := sheet.Rows[3]
+	c.Assert(third.Cells[1].Value, Equals, "")
+	c.Assert(third.Cells[3].Value, Equals, "")
+	c.Assert(third.Cells[4].Value, Equals, "")
+	c.Assert(third.Cells[6].Value, Equals, "")
+
+	xlsxPath := filepath.Join(c.MkDir(), "TestWriteStructs.xlsx")
+	c.Assert(f.Save(xlsxPath), IsNil)
+	xlsxFile, err := OpenFile(xlsxPath)
+	c.Assert(err, IsNil)
+	var read []testOrder
+	c.Assert(xlsxFile.Sheet["Orders"].ReadStructs(&read), IsNil)
+	c.Assert(read, HasLen, 2)
+	c.Assert(read[0].ID, Equals, 1)
+	c.Assert(read[0].Customer, DeepEquals, &testCustomerRef{"Ada"})
+	c.Assert(read[0].Amounts, Equals, testAmounts{1234.5, 0.25})
+	c.Assert(read[0].Note, Equals, sql.NullString{String: "rush", Valid: true})
+	c.Assert(read[0].Quantity, Equals, sql.NullInt64{Int64: 3, Valid: true})
+	c.Assert(*read[0].Shipped, Equals, shipped)
+	c.Assert(read[0].Secret, Equals, "")
+	c.Assert(read[1].Customer, IsNil)
+	c.Assert(read[1].Note.Valid, Equals, false)
+	c.Assert(read[1].Shipped, IsNil)
+}
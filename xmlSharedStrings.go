@@ -2,6 +2,7 @@ package xlsx
 
 import (
 	"encoding/xml"
+	"strings"
 )
 
 // xlsxSST directly maps the sst element from the namespace
@@ -23,10 +24,74 @@ type xlsxSI struct {
 	R []xlsxR `xml:"r"`
 }
 
+// MarshalXML writes the si element.  A string item holds either plain
+// text or a list of rich text runs, never both, so the t element is
+// only written when there are no runs.
+func (si xlsxSI) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(si.R) > 0 {
+		return e.EncodeElement(struct {
+			R []xlsxR `xml:"r"`
+		}{si.R}, start)
+	}
+	return e.EncodeElement(struct {
+		T xlsxT `xml:"t"`
+	}{makeXLSXT(si.T)}, start)
+}
+
 // xlsxR directly maps the r element from the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked this for completeness - it does as
 // much as I need.
 type xlsxR struct {
-	T string `xml:"t"`
+	RPr *xlsxRPr `xml:"rPr"`
+	T   string   `xml:"t"`
+}
+
+// MarshalXML writes the r element, preserving the whitespace of its
+// text.
+func (r xlsxR) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		RPr *xlsxRPr `xml:"rPr"`
+		T   xlsxT    `xml:"t"`
+	}{r.RPr, makeXLSXT(r.T)}, start)
+}
+
+// xlsxT is the t element, as written.  Spreadsheet applications
+// discard leading and trailing whitespace, and may collapse the rest,
+// unless the element is marked with xml:space="preserve".
+type xlsxT struct {
+	Space string `xml:"http://www.w3.org/XML/1998/namespace space,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+// makeXLSXT returns the t element holding text, marked to preserve
+// whitespace where that matters.
+func makeXLSXT(text string) xlsxT {
+	t := xlsxT{Text: text}
+	if strings.TrimSpace(text) != text || strings.ContainsAny(text, "\t\n\r") || strings.Contains(text, "  ") {
+		t.Space = "preserve"
+	}
+	return t
+}
+
+// xlsxRPr directly maps the rPr element from the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main - the
+// font properties of a rich text run.  The elements are listed in the
+// order Excel writes them.
+type xlsxRPr struct {
+	B         *xlsxVal   `xml:"b"`
+	I         *xlsxVal   `xml:"i"`
+	Strike    *xlsxVal   `xml:"strike"`
+	Condense  *xlsxVal   `xml:"condense"`
+	Extend    *xlsxVal   `xml:"extend"`
+	Outline   *xlsxVal   `xml:"outline"`
+	Shadow    *xlsxVal   `xml:"shadow"`
+	U         *xlsxVal   `xml:"u"`
+	VertAlign *xlsxVal   `xml:"vertAlign"`
+	Sz        *xlsxVal   `xml:"sz"`
+	Color     *xlsxColor `xml:"color"`
+	RFont     *xlsxVal   `xml:"rFont"`
+	Family    *xlsxVal   `xml:"family"`
+	Charset   *xlsxVal   `xml:"charset"`
+	Scheme    *xlsxVal   `xml:"scheme"`
 }
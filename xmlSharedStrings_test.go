@@ -46,3 +46,16 @@ func (s *SharedStringsSuite) TestUnmarshallSharedStrings(c *C) {
 	si := sst.SI[0]
 	c.Assert(si.T, Equals, "Foo")
 }
+
+// Text with significant whitespace is marked to be preserved, in
+// plain strings and in rich text runs alike.
+func (s *SharedStringsSuite) TestMarshalSharedStringsPreservesWhitespace(c *C) {
+	sst := xlsxSST{SI: []xlsxSI{
+		{T: "Foo"},
+		{T: " Bar\n"},
+		{R: []xlsxR{{T: "Baz"}, {T: "\tQuuk"}}},
+	}}
+	body, err := xml.Marshal(sst)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="0" uniqueCount="0"><si><t>Foo</t></si><si><t xml:space="preserve"> Bar&#xA;</t></si><si><r><t>Baz</t></r><r><t xml:space="preserve">&#x9;Quuk</t></r></si></sst>`)
+}
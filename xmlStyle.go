@@ -10,6 +10,7 @@ package xlsx
 import (
 	"encoding/xml"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,8 +21,8 @@ import (
 const builtinNumFmtsCount = 163
 
 // Excel styles can reference number formats that are built-in, all of which
-// have an id less than 164. This is a possibly incomplete list comprised of as
-// many of them as I could find.
+// have an id less than 164. These are the ones that are the same in every
+// locale; the others are in Locale.NumFmts.
 var builtInNumFmt = map[int]string{
 	0:  "general",
 	1:  "0",
@@ -57,6 +58,54 @@ var builtInNumFmt = map[int]string{
 	49: "@",
 }
 
+// fallbackNumFmts holds the codes Excel uses for the locale dependent
+// built-in number formats that aren't defined for the reader's locale,
+// which is the nearest format of the same kind in builtInNumFmt.
+var fallbackNumFmts = map[int]string{
+	23: "general",
+	24: "general",
+	25: "general",
+	26: "general",
+	27: "mm-dd-yy",
+	28: "mm-dd-yy",
+	29: "mm-dd-yy",
+	30: "mm-dd-yy",
+	31: "mm-dd-yy",
+	32: "h:mm:ss",
+	33: "h:mm:ss",
+	34: "h:mm:ss",
+	35: "h:mm:ss",
+	36: "mm-dd-yy",
+	50: "mm-dd-yy",
+	51: "mm-dd-yy",
+	52: "mm-dd-yy",
+	53: "mm-dd-yy",
+	54: "mm-dd-yy",
+	55: "mm-dd-yy",
+	56: "mm-dd-yy",
+	57: "mm-dd-yy",
+	58: "mm-dd-yy",
+	59: "0",
+	60: "0.00",
+	61: "#,##0",
+	62: "#,##0.00",
+	67: "0%",
+	68: "0.00%",
+	69: "# ?/?",
+	70: "# ??/??",
+	71: "mm-dd-yy",
+	72: "d-mmm-yy",
+	73: "d-mmm",
+	74: "mmm-yy",
+	75: "h:mm",
+	76: "h:mm:ss",
+	77: "m/d/yy h:mm",
+	78: "mm:ss",
+	79: "[h]:mm:ss",
+	80: "mm:ss.0",
+	81: "mm-dd-yy",
+}
+
 const (
 	builtInNumFmtIndex_GENERAL = int(0)
 	builtInNumFmtIndex_INT     = int(1)
@@ -82,6 +131,16 @@ type xlsxStyleSheet struct {
 	Dxfs      dxfs       `xml:"dxfs,omitempty"`
 
 	theme *theme
+	// locale picks the codes of the locale dependent built-in
+	// number formats; nil means LocaleEnUS.
+	locale *Locale
+
+	// For a style sheet read from a file, the start tag of the
+	// original styleSheet element and the elements after the dxfs,
+	// which are written back as they were read.
+	fromFile bool
+	rawRoot  string
+	rawTail  string
 
 	sync.RWMutex   // protects the following
 	styleCache     map[int]*Style
@@ -90,11 +149,17 @@ type xlsxStyleSheet struct {
 type dxfs struct {
 	Count 	string 		`xml:"count,attr,omitempty"`
 	Dxf 	[]xlsxDxf 	`xml:"dxf,omitempty"`
+	raw   []string  // original XML of the entries read from a file
 }
 
+// xlsxDxf directly maps the dxf element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main - a
+// differential format, as used by conditional formatting.
 type xlsxDxf struct {
-	Font xlsxFont `xml:"font,omitempty"`
-	Fill xlsxFill `xml:"fill,omitempty"`
+	Font   xlsxFont    `xml:"font,omitempty"`
+	NumFmt *xlsxNumFmt `xml:"numFmt"`
+	Fill   xlsxFill    `xml:"fill,omitempty"`
+	Border *xlsxBorder `xml:"border"`
 }
 
 func newXlsxStyleSheet(t *theme) *xlsxStyleSheet {
@@ -104,7 +169,60 @@ func newXlsxStyleSheet(t *theme) *xlsxStyleSheet {
 	}
 }
 
+// readRaw keeps the original XML of every entry of the style sheet
+// data, which has already been decoded into styles, so that Marshal
+// can write the entries back exactly as they were read, with any new
+// entries after them.
+func (styles *xlsxStyleSheet) readRaw(data []byte) error {
+	raw := new(xlsxRawStyleSheet)
+	if err := xml.Unmarshal(data, raw); err != nil {
+		return err
+	}
+	prefixes := map[string]string{"http://www.w3.org/XML/1998/namespace": "xml"}
+	for _, attr := range raw.Attrs {
+		if attr.Name.Space == "xmlns" {
+			prefixes[attr.Value] = attr.Name.Local
+		}
+	}
+	render := func(elements []xlsxRawElement) []string {
+		rendered := make([]string, len(elements))
+		for i, element := range elements {
+			rendered[i] = element.render(prefixes)
+		}
+		return rendered
+	}
+	styles.fromFile = true
+	styles.rawRoot = "<styleSheet" + renderAttrs(raw.Attrs, prefixes) + ">"
+	tail := append(append(raw.TableStyles, raw.Colors...), raw.ExtLst...)
+	styles.rawTail = strings.Join(render(tail), "")
+	styles.Fonts.raw = render(raw.Fonts)
+	styles.Fonts.Count = len(styles.Fonts.Font)
+	styles.Fills.raw = render(raw.Fills)
+	styles.Fills.Count = len(styles.Fills.Fill)
+	styles.Borders.raw = render(raw.Borders)
+	styles.Borders.Count = len(styles.Borders.Border)
+	if styles.CellStyleXfs != nil {
+		styles.CellStyleXfs.raw = render(raw.CellStyleXfs)
+		styles.CellStyleXfs.Count = len(styles.CellStyleXfs.Xf)
+	}
+	styles.CellXfs.raw = render(raw.CellXfs)
+	styles.CellXfs.Count = len(styles.CellXfs.Xf)
+	if styles.CellStyles != nil {
+		styles.CellStyles.raw = render(raw.CellStyles)
+		styles.CellStyles.Count = len(styles.CellStyles.CellStyle)
+	}
+	styles.Dxfs.raw = render(raw.Dxfs)
+	styles.Dxfs.Count = strconv.Itoa(len(styles.Dxfs.Dxf))
+	return nil
+}
+
+// reset empties the style sheet, ready for the styles of the cells to
+// be added to it as a file is written.  The style sheet of an opened
+// file is not reset, so that its entries, used or not, are kept.
 func (styles *xlsxStyleSheet) reset() {
+	if styles.fromFile {
+		return
+	}
 	styles.Fonts = xlsxFonts{}
 	styles.Fills = xlsxFills{}
 	styles.Borders = xlsxBorders{}
@@ -138,10 +256,10 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
 	var namedStyleXf xlsxXf
 
 	xfCount := styles.CellXfs.Count
-	if styleIndex > -1 && xfCount > 0 && styleIndex <= xfCount {
+	if styleIndex > -1 && xfCount > 0 && styleIndex < xfCount && styleIndex < len(styles.CellXfs.Xf) {
 		xf := styles.CellXfs.Xf[styleIndex]
 
-		if xf.XfId != nil && styles.CellStyleXfs != nil {
+		if xf.XfId != nil && styles.CellStyleXfs != nil && *xf.XfId > -1 && *xf.XfId < len(styles.CellStyleXfs.Xf) {
 			namedStyleXf = styles.CellStyleXfs.Xf[*xf.XfId]
 			style.NamedStyleIndex = xf.XfId
 		} else {
@@ -153,7 +271,7 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
 		style.ApplyFont = xf.ApplyFont || namedStyleXf.ApplyFont
 		style.ApplyAlignment = xf.ApplyAlignment || namedStyleXf.ApplyAlignment
 
-		if xf.BorderId > -1 && xf.BorderId < styles.Borders.Count {
+		if xf.BorderId > -1 && xf.BorderId < len(styles.Borders.Border) {
 			var border xlsxBorder
 			border = styles.Borders.Border[xf.BorderId]
 			style.Border.Left = border.Left.Style
@@ -166,14 +284,14 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
 			style.Border.BottomColor = border.Bottom.Color.RGB
 		}
 
-		if xf.FillId > -1 && xf.FillId < styles.Fills.Count {
+		if xf.FillId > -1 && xf.FillId < len(styles.Fills.Fill) {
 			xFill := styles.Fills.Fill[xf.FillId]
 			style.Fill.PatternType = xFill.PatternFill.PatternType
 			style.Fill.FgColor = styles.argbValue(xFill.PatternFill.FgColor)
 			style.Fill.BgColor = styles.argbValue(xFill.PatternFill.BgColor)
 		}
 
-		if xf.FontId > -1 && xf.FontId < styles.Fonts.Count {
+		if xf.FontId > -1 && xf.FontId < len(styles.Fonts.Font) {
 			xfont := styles.Fonts.Font[xf.FontId]
 			style.Font.Size, _ = strconv.Atoi(xfont.Sz.Val)
 			style.Font.Name = xfont.Name.Val
@@ -198,6 +316,14 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
 		if xf.Alignment.Vertical != "" {
 			style.Alignment.Vertical = xf.Alignment.Vertical
 		}
+		if styles.fromFile {
+			style.read = &readStyle{
+				styles:  styles,
+				xfIndex: styleIndex,
+				numFmt:  styles.getNumberFormat(styleIndex),
+				style:   *style,
+			}
+		}
 		styles.Lock()
 		styles.styleCache[styleIndex] = style
 		styles.Unlock()
@@ -212,11 +338,22 @@ func (styles *xlsxStyleSheet) argbValue(color xlsxColor) string {
 	return color.RGB
 }
 
-// Excel styles can reference number formats that are built-in, all of which
-// have an id less than 164. This is a possibly incomplete list comprised of as
-// many of them as I could find.
-func getBuiltinNumberFormat(numFmtId int) string {
-	return builtInNumFmt[numFmtId]
+// builtinNumberFormat returns the code of a built-in number format,
+// as Excel in the style sheet's locale reads it, or "" if numFmtId
+// isn't built in.
+func (styles *xlsxStyleSheet) builtinNumberFormat(numFmtId int) string {
+	if code, ok := builtInNumFmt[numFmtId]; ok {
+		return code
+	}
+	for _, locale := range []*Locale{styles.locale, LocaleEnUS} {
+		if locale == nil {
+			continue
+		}
+		if code, ok := locale.NumFmts[numFmtId]; ok {
+			return code
+		}
+	}
+	return fallbackNumFmts[numFmtId]
 }
 
 func (styles *xlsxStyleSheet) getNumberFormat(styleIndex int) string {
@@ -224,9 +361,9 @@ func (styles *xlsxStyleSheet) getNumberFormat(styleIndex int) string {
 		return ""
 	}
 	var numberFormat string = ""
-	if styleIndex > -1 && styleIndex <= styles.CellXfs.Count {
+	if styleIndex > -1 && styleIndex < styles.CellXfs.Count && styleIndex < len(styles.CellXfs.Xf) {
 		xf := styles.CellXfs.Xf[styleIndex]
-		if builtin := getBuiltinNumberFormat(xf.NumFmtId); builtin != "" {
+		if builtin := styles.builtinNumberFormat(xf.NumFmtId); builtin != "" {
 			return builtin
 		}
 		if styles.numFmtRefTable != nil {
@@ -234,7 +371,7 @@ func (styles *xlsxStyleSheet) getNumberFormat(styleIndex int) string {
 			numberFormat = numFmt.FormatCode
 		}
 	}
-	return strings.ToLower(numberFormat)
+	return numberFormat
 }
 
 func (styles *xlsxStyleSheet) addFont(xFont xlsxFont) (index int) {
@@ -310,6 +447,20 @@ func (styles *xlsxStyleSheet) addCellXf(xCellXf xlsxXf) (index int) {
 	return
 }
 
+// addDxf adds a differential format to the style sheet, unless an
+// identical one is already there, and returns its index.
+func (styles *xlsxStyleSheet) addDxf(xDxf xlsxDxf) (index int) {
+	var dxf xlsxDxf
+	for index, dxf = range styles.Dxfs.Dxf {
+		if reflect.DeepEqual(dxf, xDxf) {
+			return index
+		}
+	}
+	styles.Dxfs.Dxf = append(styles.Dxfs.Dxf, xDxf)
+	styles.Dxfs.Count = strconv.Itoa(len(styles.Dxfs.Dxf))
+	return len(styles.Dxfs.Dxf) - 1
+}
+
 // newNumFmt generate a xlsxNumFmt according the format code. When the FormatCode is built in, it will return a xlsxNumFmt with the NumFmtId defined in ECMA document, otherwise it will generate a new NumFmtId greater than 164.
 func (styles *xlsxStyleSheet) newNumFmt(formatCode string) xlsxNumFmt {
 	if formatCode == "" {
@@ -325,7 +476,8 @@ func (styles *xlsxStyleSheet) newNumFmt(formatCode string) xlsxNumFmt {
 		return xlsxNumFmt{NumFmtId: numFmtId, FormatCode: formatCode}
 	}
 
-	// find the exist xlsxNumFmt
+	// find the exist xlsxNumFmt.  Quoted text is shown as it is
+	// written, so formats that differ only in case are different.
 	for _, numFmt := range styles.NumFmts.NumFmt {
 		if formatCode == numFmt.FormatCode {
 			return numFmt
@@ -367,6 +519,9 @@ func (styles *xlsxStyleSheet) addNumFmt(xNumFmt xlsxNumFmt) {
 
 func (styles *xlsxStyleSheet) Marshal() (string, error) {
 	result := xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`
+	if styles.rawRoot != "" {
+		result = xml.Header + styles.rawRoot
+	}
 
 	xNumFmts, err := styles.NumFmts.Marshal()
 	if err != nil {
@@ -423,7 +578,66 @@ func (styles *xlsxStyleSheet) Marshal() (string, error) {
 		}
 		result += xDxfs
 	}
-	return result + "</styleSheet>", nil
+	return result + styles.rawTail + "</styleSheet>", nil
+}
+
+// xlsxRawStyleSheet captures the entries of a styleSheet element as
+// raw XML, alongside the decoded xlsxStyleSheet, so that the style
+// sheet of an opened file can be written back without losing the
+// parts of it that the library does not model.
+type xlsxRawStyleSheet struct {
+	Attrs        []xml.Attr       `xml:",any,attr"`
+	Fonts        []xlsxRawElement `xml:"fonts>font"`
+	Fills        []xlsxRawElement `xml:"fills>fill"`
+	Borders      []xlsxRawElement `xml:"borders>border"`
+	CellStyleXfs []xlsxRawElement `xml:"cellStyleXfs>xf"`
+	CellXfs      []xlsxRawElement `xml:"cellXfs>xf"`
+	CellStyles   []xlsxRawElement `xml:"cellStyles>cellStyle"`
+	Dxfs         []xlsxRawElement `xml:"dxfs>dxf"`
+	TableStyles  []xlsxRawElement `xml:"tableStyles"`
+	Colors       []xlsxRawElement `xml:"colors"`
+	ExtLst       []xlsxRawElement `xml:"extLst"`
+}
+
+// xlsxRawElement is an element, with its attributes and content, as
+// it was read.
+type xlsxRawElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+// render returns the element as XML.  Namespaced attributes are
+// written with the prefixes they were declared with on the root
+// element.
+func (element *xlsxRawElement) render(prefixes map[string]string) string {
+	result := "<" + element.XMLName.Local + renderAttrs(element.Attrs, prefixes)
+	if element.InnerXML == "" {
+		return result + "/>"
+	}
+	return result + ">" + element.InnerXML + "</" + element.XMLName.Local + ">"
+}
+
+// renderAttrs returns attrs as they appear in a start tag, mapping
+// the namespace of each attribute back to its prefix.
+func renderAttrs(attrs []xml.Attr, prefixes map[string]string) string {
+	var b strings.Builder
+	for _, attr := range attrs {
+		b.WriteByte(' ')
+		switch {
+		case attr.Name.Space == "":
+		case attr.Name.Space == "xmlns":
+			b.WriteString("xmlns:")
+		case prefixes[attr.Name.Space] != "":
+			b.WriteString(prefixes[attr.Name.Space] + ":")
+		default:
+			b.WriteString(attr.Name.Space + ":")
+		}
+		b.WriteString(attr.Name.Local + `="`)
+		xml.EscapeText(&b, []byte(attr.Value))
+		b.WriteByte('"')
+	}
+	return b.String()
 }
 
 // xlsxDxfs directly maps the Dxfs element in the namespace
@@ -436,62 +650,70 @@ type xlsxDxfs struct {
 }
 
 func (dxfsElement *dxfs) Marshal() (result string, err error) {
-	DxfCount, err := strconv.Atoi(dxfsElement.Count)
-	if err != nil{
-		return "", err
+	if len(dxfsElement.Dxf) == 0 {
+		return
 	}
-	if DxfCount > 0 {
-		//result = `<dxfs count="4"><dxf><font><color rgb="FF006100"/></font><fill><patternFill><bgColor rgb="FFC6EFCE"/></patternFill></fill></dxf><dxf><font><color rgb="FF006100"/></font><fill><patternFill><bgColor rgb="FFC6EFCE"/></patternFill></fill></dxf><dxf><font><color rgb="FF9C0006"/></font><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf><dxf><font><color rgb="FF9C0006"/></font><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf></dxfs>`
-		
-		resultBegin := fmt.Sprintf(`<dxfs count="%d">`, DxfCount)
-		countDxf := 0
-		for _, Dxf := range dxfsElement.Dxf {
-			var xxf string
-			xxf, err = Dxf.Marshal()
-			if err != nil {
-				return
-			}
-			result += xxf			
-			countDxf++
-			if countDxf == len(dxfsElement.Dxf){
-				result += xxf
-			}
+	result = fmt.Sprintf(`<dxfs count="%d">`, len(dxfsElement.Dxf))
+	for i, Dxf := range dxfsElement.Dxf {
+		if i < len(dxfsElement.raw) {
+			result += dxfsElement.raw[i]
+			continue
 		}
-		if DxfCount != countDxf {
-			resultBegin = fmt.Sprintf(`<dxfs count="%d">`, countDxf)
+		var xxf string
+		xxf, err = Dxf.Marshal()
+		if err != nil {
+			return
 		}
-		result = resultBegin + result
-		result += `</dxfs>`
+		result += xxf
 	}
+	result += `</dxfs>`
 	return
 }
 
-func (Dxf *xlsxDxf) Marshal() (result string, err error){
+func (Dxf *xlsxDxf) Marshal() (result string, err error) {
 	result = `<dxf>`
 	xxFont, err := Dxf.Font.Marshal()
-	if err != nil{
-		return 
+	if err != nil {
+		return
 	}
-	if xxFont != `<font></font>`{
-		result += xxFont	
+	if xxFont != `<font></font>` {
+		result += xxFont
 	}
-	
+
+	if Dxf.NumFmt != nil {
+		xxNumFmt, err := Dxf.NumFmt.Marshal()
+		if err != nil {
+			return "", err
+		}
+		result += xxNumFmt
+	}
+
 	xxFill, err := Dxf.Fill.MarshalDxfFill()
-	if err != nil{
-		return 
+	if err != nil {
+		return
+	}
+	if xxFill != `<fill><patternFill></patternFill></fill>` {
+		result += xxFill
+	}
+
+	if Dxf.Border != nil {
+		xxBorder, err := Dxf.Border.MarshalDxfBorder()
+		if err != nil {
+			return "", err
+		}
+		result += xxBorder
 	}
-	
-	if xxFill != `<patternFill></patternFill>`{
-		result += xxFill 	
-	}	
 
 	result += `</dxf>`
 
-	return 
-} 
+	return
+}
 
-func (dxfFill *xlsxFill) MarshalDxfFill()(result string, err error){	
+func (dxfFill *xlsxFill) MarshalDxfFill() (result string, err error) {
 	result = `<fill><patternFill>`
+	if dxfFill.PatternFill.PatternType != "" {
+		result = fmt.Sprintf(`<fill><patternFill patternType="%s">`, dxfFill.PatternFill.PatternType)
+	}
 	subparts := ""
 
 	if dxfFill.PatternFill.FgColor.RGB != "" {
@@ -499,12 +721,39 @@ func (dxfFill *xlsxFill) MarshalDxfFill()(result string, err error){
 	}
 	if dxfFill.PatternFill.BgColor.RGB != "" {
 		subparts += fmt.Sprintf(`<bgColor rgb="%s"/>`, dxfFill.PatternFill.BgColor.RGB)
-	}	
-	result += subparts	
+	}
+	result += subparts
 	result += `</patternFill></fill>`
 	return
 }
 
+// MarshalDxfBorder renders a border for use in a differential format.
+// Unlike the borders of cell formats, only the sides that have a
+// style are written, so the other sides of the cell are left alone.
+func (dxfBorder *xlsxBorder) MarshalDxfBorder() (result string, err error) {
+	sides := []struct {
+		name string
+		line xlsxLine
+	}{
+		{"left", dxfBorder.Left},
+		{"right", dxfBorder.Right},
+		{"top", dxfBorder.Top},
+		{"bottom", dxfBorder.Bottom},
+	}
+	result = `<border>`
+	for _, side := range sides {
+		if side.line.Style == "" {
+			continue
+		}
+		result += fmt.Sprintf(`<%s style="%s">`, side.name, side.line.Style)
+		if side.line.Color.RGB != "" {
+			result += fmt.Sprintf(`<color rgb="%s"/>`, side.line.Color.RGB)
+		}
+		result += fmt.Sprintf(`</%s>`, side.name)
+	}
+	result += `</border>`
+	return
+}
 
 // xlsxNumFmts directly maps the numFmts element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
@@ -541,7 +790,11 @@ type xlsxNumFmt struct {
 }
 
 func (numFmt *xlsxNumFmt) Marshal() (result string, err error) {
-	return fmt.Sprintf(`<numFmt numFmtId="%d" formatCode="%s"/>`, numFmt.NumFmtId, numFmt.FormatCode), nil
+	var formatCode strings.Builder
+	if err = xml.EscapeText(&formatCode, []byte(numFmt.FormatCode)); err != nil {
+		return
+	}
+	return fmt.Sprintf(`<numFmt numFmtId="%d" formatCode="%s"/>`, numFmt.NumFmtId, formatCode.String()), nil
 }
 
 // xlsxFonts directly maps the fonts element in the namespace
@@ -553,6 +806,7 @@ type xlsxFonts struct {
 
 	Count int        `xml:"count,attr"`
 	Font  []xlsxFont `xml:"font,omitempty"`
+	raw   []string   // original XML of the entries read from a file
 }
 
 func (fonts *xlsxFonts) Marshal(outputFontMap map[int]int) (result string, err error) {
@@ -561,8 +815,9 @@ func (fonts *xlsxFonts) Marshal(outputFontMap map[int]int) (result string, err e
 
 	for i, font := range fonts.Font {
 		var xfont string
-		xfont, err = font.Marshal()
-		if err != nil {
+		if i < len(fonts.raw) {
+			xfont = fonts.raw[i]
+		} else if xfont, err = font.Marshal(); err != nil {
 			return
 		}
 		if xfont != "" {
@@ -591,6 +846,7 @@ type xlsxFont struct {
 	Color   xlsxColor `xml:"color,omitempty"`
 	B       *xlsxVal  `xml:"b,omitempty"`
 	I       *xlsxVal  `xml:"i,omitempty"`
+	Strike  *xlsxVal  `xml:"strike,omitempty"`
 	U       *xlsxVal  `xml:"u,omitempty"`
 }
 
@@ -601,6 +857,9 @@ func (font *xlsxFont) Equals(other xlsxFont) bool {
 	if (font.I == nil && other.I != nil) || (font.I != nil && other.I == nil) {
 		return false
 	}
+	if (font.Strike == nil && other.Strike != nil) || (font.Strike != nil && other.Strike == nil) {
+		return false
+	}
 	if (font.U == nil && other.U != nil) || (font.U != nil && other.U == nil) {
 		return false
 	}
@@ -621,8 +880,21 @@ func (font *xlsxFont) Marshal() (result string, err error) {
 	if font.Charset.Val != "" {
 		result += fmt.Sprintf(`<charset val="%s"/>`, font.Charset.Val)
 	}
-	if font.Color.RGB != "" {
-		result += fmt.Sprintf(`<color rgb="%s"/>`, font.Color.RGB)
+	if font.Color.RGB != "" || font.Color.Indexed != nil || font.Color.Theme != nil {
+		result += "<color"
+		if font.Color.RGB != "" {
+			result += fmt.Sprintf(` rgb="%s"`, font.Color.RGB)
+		}
+		if font.Color.Indexed != nil {
+			result += fmt.Sprintf(` indexed="%d"`, *font.Color.Indexed)
+		}
+		if font.Color.Theme != nil {
+			result += fmt.Sprintf(` theme="%d"`, *font.Color.Theme)
+		}
+		if font.Color.Tint != 0 {
+			result += fmt.Sprintf(` tint="%s"`, strconv.FormatFloat(font.Color.Tint, 'f', -1, 64))
+		}
+		result += "/>"
 	}
 	if font.B != nil {
 		result += "<b/>"
@@ -630,6 +902,9 @@ func (font *xlsxFont) Marshal() (result string, err error) {
 	if font.I != nil {
 		result += "<i/>"
 	}
+	if font.Strike != nil {
+		result += "<strike/>"
+	}
 	if font.U != nil {
 		result += "<u/>"
 	}
@@ -655,14 +930,18 @@ func (val *xlsxVal) Equals(other xlsxVal) bool {
 type xlsxFills struct {
 	Count int        `xml:"count,attr"`
 	Fill  []xlsxFill `xml:"fill,omitempty"`
+	raw   []string   // original XML of the entries read from a file
 }
 
 func (fills *xlsxFills) Marshal(outputFillMap map[int]int) (string, error) {
 	var subparts string
 	var emittedCount int
 	for i, fill := range fills.Fill {
-		xfill, err := fill.Marshal()
-		if err != nil {
+		var xfill string
+		var err error
+		if i < len(fills.raw) {
+			xfill = fills.raw[i]
+		} else if xfill, err = fill.Marshal(); err != nil {
 			return "", err
 		}
 		if xfill != "" {
@@ -748,13 +1027,18 @@ func (patternFill *xlsxPatternFill) Marshal() (result string, err error) {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxColor struct {
-	RGB   string  `xml:"rgb,attr,omitempty"`
-	Theme *int    `xml:"theme,attr,omitempty"`
-	Tint  float64 `xml:"tint,attr,omitempty"`
+	RGB     string  `xml:"rgb,attr,omitempty"`
+	Indexed *int    `xml:"indexed,attr,omitempty"`
+	Theme   *int    `xml:"theme,attr,omitempty"`
+	Tint    float64 `xml:"tint,attr,omitempty"`
 }
 
 func (color *xlsxColor) Equals(other xlsxColor) bool {
-	return color.RGB == other.RGB
+	return color.RGB == other.RGB && color.Tint == other.Tint &&
+		(color.Theme == other.Theme ||
+			(color.Theme != nil && other.Theme != nil && *color.Theme == *other.Theme)) &&
+		(color.Indexed == other.Indexed ||
+			(color.Indexed != nil && other.Indexed != nil && *color.Indexed == *other.Indexed))
 }
 
 // xlsxBorders directly maps the borders element in the namespace
@@ -764,6 +1048,7 @@ func (color *xlsxColor) Equals(other xlsxColor) bool {
 type xlsxBorders struct {
 	Count  int          `xml:"count,attr"`
 	Border []xlsxBorder `xml:"border"`
+	raw    []string     // original XML of the entries read from a file
 }
 
 func (borders *xlsxBorders) Marshal(outputBorderMap map[int]int) (result string, err error) {
@@ -772,8 +1057,9 @@ func (borders *xlsxBorders) Marshal(outputBorderMap map[int]int) (result string,
 	subparts := ""
 	for i, border := range borders.Border {
 		var xborder string
-		xborder, err = border.Marshal()
-		if err != nil {
+		if i < len(borders.raw) {
+			xborder = borders.raw[i]
+		} else if xborder, err = border.Marshal(); err != nil {
 			return
 		}
 		if xborder != "" {
@@ -858,12 +1144,17 @@ type xlsxCellStyles struct {
 	XMLName   xml.Name        `xml:"cellStyles"`
 	Count     int             `xml:"count,attr"`
 	CellStyle []xlsxCellStyle `xml:"cellStyle,omitempty"`
+	raw       []string        // original XML of the entries read from a file
 }
 
 func (cellStyles *xlsxCellStyles) Marshal() (result string, err error) {
 	if cellStyles.Count > 0 {
 		result = fmt.Sprintf(`<cellStyles count="%d">`, cellStyles.Count)
-		for _, cellStyle := range cellStyles.CellStyle {
+		for i, cellStyle := range cellStyles.CellStyle {
+			if i < len(cellStyles.raw) {
+				result += cellStyles.raw[i]
+				continue
+			}
 			var xCellStyle []byte
 			xCellStyle, err = xml.Marshal(cellStyle)
 			if err != nil {
@@ -882,7 +1173,7 @@ type xlsxCellStyle struct {
 	BuiltInId     *int     `xml:"builtInId,attr,omitempty"`
 	CustomBuiltIn *bool    `xml:"customBuiltIn,attr,omitempty"`
 	Hidden        *bool    `xml:"hidden,attr,omitempty"`
-	ILevel        *bool    `xml:"iLevel,attr,omitempty"`
+	ILevel        *int     `xml:"iLevel,attr,omitempty"`
 	Name          string   `xml:"name,attr"`
 	XfId          int      `xml:"xfId,attr"`
 }
@@ -894,12 +1185,17 @@ type xlsxCellStyle struct {
 type xlsxCellStyleXfs struct {
 	Count int      `xml:"count,attr"`
 	Xf    []xlsxXf `xml:"xf,omitempty"`
+	raw   []string // original XML of the entries read from a file
 }
 
 func (cellStyleXfs *xlsxCellStyleXfs) Marshal(outputBorderMap, outputFillMap, outputFontMap map[int]int) (result string, err error) {
 	if cellStyleXfs.Count > 0 {
 		result = fmt.Sprintf(`<cellStyleXfs count="%d">`, cellStyleXfs.Count)
-		for _, xf := range cellStyleXfs.Xf {
+		for i, xf := range cellStyleXfs.Xf {
+			if i < len(cellStyleXfs.raw) {
+				result += cellStyleXfs.raw[i]
+				continue
+			}
 			var xxf string
 			xxf, err = xf.Marshal(outputBorderMap, outputFillMap, outputFontMap)
 			if err != nil {
@@ -919,12 +1215,17 @@ func (cellStyleXfs *xlsxCellStyleXfs) Marshal(outputBorderMap, outputFillMap, ou
 type xlsxCellXfs struct {
 	Count int      `xml:"count,attr"`
 	Xf    []xlsxXf `xml:"xf,omitempty"`
+	raw   []string // original XML of the entries read from a file
 }
 
 func (cellXfs *xlsxCellXfs) Marshal(outputBorderMap, outputFillMap, outputFontMap map[int]int) (result string, err error) {
 	if cellXfs.Count > 0 {
 		result = fmt.Sprintf(`<cellXfs count="%d">`, cellXfs.Count)
-		for _, xf := range cellXfs.Xf {
+		for i, xf := range cellXfs.Xf {
+			if i < len(cellXfs.raw) {
+				result += cellXfs.raw[i]
+				continue
+			}
 			var xxf string
 			xxf, err = xf.Marshal(outputBorderMap, outputFillMap, outputFontMap)
 			if err != nil {
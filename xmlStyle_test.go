@@ -1,6 +1,10 @@
 package xlsx
 
 import (
+	"bytes"
+	"regexp"
+	"strings"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -349,6 +353,37 @@ func (s *CellSuite) TestNewNumFmt(c *C) {
 	c.Assert(styles.newNumFmt("mm-dd-yy"), DeepEquals, xlsxNumFmt{14, "mm-dd-yy"})
 	c.Assert(styles.newNumFmt("hh:mm:ss"), DeepEquals, xlsxNumFmt{164, "hh:mm:ss"})
 	c.Assert(len(styles.NumFmts.NumFmt), Equals, 1)
+
+	// Quoted text keeps its case, so formats that differ only in
+	// case are kept apart.
+	c.Assert(styles.newNumFmt(`"Kg"0`), DeepEquals, xlsxNumFmt{165, `"Kg"0`})
+	c.Assert(styles.newNumFmt(`"kg"0`), DeepEquals, xlsxNumFmt{166, `"kg"0`})
+	c.Assert(styles.newNumFmt(`"Kg"0`), DeepEquals, xlsxNumFmt{165, `"Kg"0`})
+}
+
+// Custom number formats are read as they are written, so that they
+// survive a load and save cycle.
+func (x *XMLStyleSuite) TestSaveKeepsNumberFormatCase(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetFloatWithFormat(1, `"Kg"0`)
+	sheet.Cell(0, 1).SetFloatWithFormat(2, `"kg"0`)
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	read, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	sheet = read.Sheets[0]
+	c.Assert(sheet.Cell(0, 0).NumFmt, Equals, `"Kg"0`)
+	c.Assert(sheet.Cell(0, 1).NumFmt, Equals, `"kg"0`)
+	value, err := sheet.Cell(0, 0).FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "Kg1")
+
+	buf.Reset()
+	c.Assert(read.Write(&buf), IsNil)
+	saved := readZipParts(c, buf.Bytes())
+	c.Assert(saved["xl/styles.xml"], Matches, `(?s).*<numFmts count="2"><numFmt numFmtId="164" formatCode="&#34;Kg&#34;0"/><numFmt numFmtId="165" formatCode="&#34;kg&#34;0"/></numFmts>.*`)
 }
 
 func (s *CellSuite) TestAddNumFmt(c *C) {
@@ -367,3 +402,85 @@ func (s *CellSuite) TestAddNumFmt(c *C) {
 	styles.addNumFmt(xlsxNumFmt{165, "yyyy/mm/dd"})
 	c.Assert(styles.NumFmts.Count, Equals, 2)
 }
+
+// Named cell styles and their formats survive a load and save cycle,
+// along with custom number formats that no cell uses.
+func (x *XMLStyleSuite) TestSaveKeepsNamedStyles(c *C) {
+	original, saved := resave(c, "wpsBlankLineTest.xlsx")
+	for _, section := range []string{`<cellStyleXfs .*</cellStyleXfs>`, `<cellStyles .*</cellStyles>`} {
+		match := regexp.MustCompile(section).FindString(original["xl/styles.xml"])
+		c.Assert(match, Not(Equals), "")
+		c.Assert(strings.Contains(saved["xl/styles.xml"], match), Equals, true, Commentf(section))
+	}
+	c.Assert(saved["xl/styles.xml"], Matches, `(?s).*<numFmts count="4"><numFmt numFmtId="43" .*<numFmt numFmtId="42" .*`)
+}
+
+// Cells whose style is unchanged keep the cell format they were read
+// with, and a new style is added after the original cell formats.
+func (x *XMLStyleSuite) TestSaveKeepsCellFormats(c *C) {
+	f, err := OpenFile("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	style := NewStyle()
+	style.Font.Bold = true
+	sheet.Cell(2, 0).SetStyle(style)
+	sheet.Cell(2, 0).SetString("new")
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	cells := regexp.MustCompile(`<c r="([A-Z]+[0-9]+)" s="([0-9]+)"`).FindAllStringSubmatch(parts["xl/worksheets/sheet1.xml"], -1)
+	styleOf := make(map[string]string, len(cells))
+	for _, cell := range cells {
+		styleOf[cell[1]] = cell[2]
+	}
+	c.Assert(styleOf["A1"], Equals, "1")
+	// Cell format 0 is the default, and isn't written.
+	c.Assert(styleOf["B1"], Equals, "")
+	c.Assert(styleOf["A2"], Equals, "")
+	c.Assert(styleOf["B2"], Equals, "2")
+	c.Assert(styleOf["A3"], Equals, "3")
+	c.Assert(parts["xl/styles.xml"], Matches, `(?s).*<cellXfs count="4">.*`)
+}
+
+// Built-in number formats that differ between locales are read as
+// Excel in the file's locale shows them.
+func (x *XMLStyleSuite) TestBuiltinNumberFormats(c *C) {
+	styles := newXlsxStyleSheet(nil)
+	c.Assert(styles.builtinNumberFormat(14), Equals, "mm-dd-yy")
+	c.Assert(styles.builtinNumberFormat(5), Equals, "$#,##0_);($#,##0)")
+	c.Assert(styles.builtinNumberFormat(31), Equals, "mm-dd-yy")
+	c.Assert(styles.builtinNumberFormat(23), Equals, "general")
+	c.Assert(styles.builtinNumberFormat(164), Equals, "")
+
+	styles.locale = LocaleJaJP
+	c.Assert(styles.builtinNumberFormat(31), Equals, `yyyy"年"m"月"d"日"`)
+	c.Assert(styles.builtinNumberFormat(27), Equals, "[$-411]ge.m.d")
+	c.Assert(styles.builtinNumberFormat(7), Equals, `"¥"#,##0.00;"¥"\-#,##0.00`)
+	c.Assert(styles.builtinNumberFormat(59), Equals, "0")
+
+	styles.locale = LocaleZhCN
+	c.Assert(styles.builtinNumberFormat(32), Equals, `h"时"mm"分"`)
+	styles.locale = LocaleZhTW
+	c.Assert(styles.builtinNumberFormat(5), Equals, `"NT$"#,##0_);\("NT$"#,##0\)`)
+	c.Assert(formatWithLocale(c, 44198, styles.builtinNumberFormat(5), LocaleZhTW), Equals, "NT$44,198 ")
+	styles.locale = LocaleKoKR
+	c.Assert(styles.builtinNumberFormat(31), Equals, `yyyy"년" mm"월" dd"일"`)
+	styles.locale = LocaleThTH
+	c.Assert(styles.builtinNumberFormat(76), Equals, "ช:นน:ทท")
+
+	// A locale without a table of its own falls back to Excel's
+	// defaults, with the currency of the United States.
+	styles.locale = &Locale{Name: "xx"}
+	c.Assert(styles.builtinNumberFormat(6), Equals, "$#,##0_);[Red]($#,##0)")
+	c.Assert(styles.builtinNumberFormat(75), Equals, "h:mm")
+
+	styles.CellXfs = xlsxCellXfs{Count: 1, Xf: []xlsxXf{{NumFmtId: 35}}}
+	styles.locale = LocaleJaJP
+	c.Assert(styles.getNumberFormat(0), Equals, `m"月"d"日"`)
+	styles.locale = nil
+	c.Assert(styles.getNumberFormat(0), Equals, "h:mm:ss")
+
+	// Written formats never take a locale dependent id, as the
+	// file could be opened anywhere.
+	numFmt := styles.newNumFmt(`yyyy"年"m"月"d"日"`)
+	c.Assert(numFmt.NumFmtId > builtinNumFmtsCount, Equals, true)
+}
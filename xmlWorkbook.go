@@ -24,9 +24,10 @@ type xlsxWorkbookRels struct {
 
 // xmlxWorkbookRelation maps sheet id and xl/worksheets/sheet%d.xml
 type xlsxWorkbookRelation struct {
-	Id     string `xml:",attr"`
-	Target string `xml:",attr"`
-	Type   string `xml:",attr"`
+	Id         string `xml:",attr"`
+	Target     string `xml:",attr"`
+	Type       string `xml:",attr"`
+	TargetMode string `xml:",attr,omitempty"`
 }
 
 // xlsxWorkbook directly maps the workbook element from the namespace
@@ -34,14 +35,16 @@ type xlsxWorkbookRelation struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxWorkbook struct {
-	XMLName            xml.Name               `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main workbook"`
-	FileVersion        xlsxFileVersion        `xml:"fileVersion"`
-	WorkbookPr         xlsxWorkbookPr         `xml:"workbookPr"`
-	WorkbookProtection xlsxWorkbookProtection `xml:"workbookProtection"`
-	BookViews          xlsxBookViews          `xml:"bookViews"`
-	Sheets             xlsxSheets             `xml:"sheets"`
-	DefinedNames       xlsxDefinedNames       `xml:"definedNames"`
-	CalcPr             xlsxCalcPr             `xml:"calcPr"`
+	XMLName            xml.Name                `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main workbook"`
+	FileVersion        xlsxFileVersion         `xml:"fileVersion"`
+	WorkbookPr         xlsxWorkbookPr          `xml:"workbookPr"`
+	WorkbookProtection xlsxWorkbookProtection  `xml:"workbookProtection"`
+	BookViews          xlsxBookViews           `xml:"bookViews"`
+	Sheets             xlsxSheets              `xml:"sheets"`
+	ExternalReferences *xlsxExternalReferences `xml:"externalReferences,omitempty"`
+	DefinedNames       xlsxDefinedNames        `xml:"definedNames"`
+	CalcPr             xlsxCalcPr              `xml:"calcPr"`
+	PivotCaches        *xlsxPivotCaches        `xml:"pivotCaches,omitempty"`
 }
 
 // xlsxWorkbookProtection directly maps the workbookProtection element from the
@@ -118,6 +121,41 @@ type xlsxSheet struct {
 	State   string `xml:"state,attr,omitempty"`
 }
 
+// xlsxExternalReferences directly maps the externalReferences element
+// from the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxExternalReferences struct {
+	ExternalReference []xlsxExternalReference `xml:"externalReference"`
+}
+
+// xlsxExternalReference directly maps the externalReference element
+// from the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxExternalReference struct {
+	RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+// xlsxPivotCaches directly maps the pivotCaches element from the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotCaches struct {
+	PivotCache []xlsxPivotCache `xml:"pivotCache"`
+}
+
+// xlsxPivotCache directly maps the pivotCache element from the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotCache struct {
+	CacheId string `xml:"cacheId,attr"`
+	RID     string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
 // xlsxDefinedNames directly maps the definedNames element from the
 // namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
 // - currently I have not checked it for completeness - it does as
@@ -141,7 +179,7 @@ type xlsxDefinedName struct {
 	Help              string `xml:"help,attr,omitempty"`
 	ShortcutKey       string `xml:"shortcutKey,attr,omitempty"`
 	StatusBar         string `xml:"statusBar,attr,omitempty"`
-	LocalSheetID      int    `xml:"localSheetId,attr,omitempty"`
+	LocalSheetID      *int   `xml:"localSheetId,attr"`
 	FunctionGroupID   int    `xml:"functionGroupId,attr,omitempty"`
 	Function          bool   `xml:"function,attr,omitempty"`
 	Hidden            bool   `xml:"hidden,attr,omitempty"`
@@ -151,6 +189,23 @@ type xlsxDefinedName struct {
 	Xlm               bool   `xml:"xml,attr,omitempty"`
 }
 
+// isLocalTo reports whether the name belongs to the sheet at index,
+// counting from 0, rather than to the workbook or another sheet.
+func (dn *xlsxDefinedName) isLocalTo(index int) bool {
+	return dn.LocalSheetID != nil && *dn.LocalSheetID == index
+}
+
+// isGlobal reports whether the name belongs to the workbook rather
+// than to one of its sheets.
+func (dn *xlsxDefinedName) isGlobal() bool {
+	return dn.LocalSheetID == nil
+}
+
+// setLocalSheet makes the name belong to the sheet at index.
+func (dn *xlsxDefinedName) setLocalSheet(index int) {
+	dn.LocalSheetID = &index
+}
+
 // xlsxCalcPr directly maps the calcPr element from the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -19,10 +19,54 @@ type xlsxWorksheet struct {
 	SheetData     xlsxSheetData     `xml:"sheetData"`
 	ConditionalFormatting []xlsxConditionalFormatting `xml:"conditionalFormatting"`
 	MergeCells    *xlsxMergeCells   `xml:"mergeCells,omitempty"`
+	Hyperlinks    *xlsxHyperlinks   `xml:"hyperlinks,omitempty"`
 	PrintOptions  xlsxPrintOptions  `xml:"printOptions"`
 	PageMargins   xlsxPageMargins   `xml:"pageMargins"`
 	PageSetUp     xlsxPageSetUp     `xml:"pageSetup"`
 	HeaderFooter  xlsxHeaderFooter  `xml:"headerFooter"`	
+	Drawing         *xlsxPartRef    `xml:"drawing,omitempty"`
+	LegacyDrawing   *xlsxPartRef    `xml:"legacyDrawing,omitempty"`
+	LegacyDrawingHF *xlsxPartRef    `xml:"legacyDrawingHF,omitempty"`
+	Picture         *xlsxPartRef    `xml:"picture,omitempty"`
+	TableParts      *xlsxTableParts `xml:"tableParts,omitempty"`
+}
+
+// xlsxHyperlinks directly maps the hyperlinks element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxHyperlinks struct {
+	Hyperlink []xlsxHyperlink `xml:"hyperlink"`
+}
+
+// xlsxHyperlink directly maps the hyperlink element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxHyperlink struct {
+	Ref      string `xml:"ref,attr"`
+	RID      string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr,omitempty"`
+	Location string `xml:"location,attr,omitempty"`
+	Tooltip  string `xml:"tooltip,attr,omitempty"`
+	Display  string `xml:"display,attr,omitempty"`
+}
+
+// xlsxPartRef maps the drawing, legacyDrawing, legacyDrawingHF and
+// picture elements in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main, each of
+// which refers to another part of the package through a relationship
+// of the worksheet.
+type xlsxPartRef struct {
+	RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+// xlsxTableParts directly maps the tableParts element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxTableParts struct {
+	Count     int           `xml:"count,attr,omitempty"`
+	TablePart []xlsxPartRef `xml:"tablePart"`
 }
 
 // xlsxConditionalFormatting directly maps the ConditionalFormatting element in the namespace
@@ -30,29 +74,76 @@ type xlsxWorksheet struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxConditionalFormatting struct {
-	CfRule xlsxCfRule 		`xml:"cfRule"`
-	Sqref  string			`xml:"sqref,attr"`
+	CfRule []xlsxCfRule `xml:"cfRule"`
+	Sqref  string       `xml:"sqref,attr"`
 }
+
 // xlsxCfRule directly maps the xlsxCfRule element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxCfRule struct {
-	Formula 	xlsxFormula 	`xml:"formula"`
-	Type   		string         	`xml:"type,attr"`
-	DxfId   	string         	`xml:"dxfId,attr"`
-	Priority   	string      	`xml:"priority,attr"`
-	Operator   	string         	`xml:"operator,attr,omitempty"`
-	Text  	 	string         	`xml:"text,attr,omitempty"`
+	Type         string          `xml:"type,attr,omitempty"`
+	DxfId        *int            `xml:"dxfId,attr,omitempty"`
+	Priority     int             `xml:"priority,attr"`
+	StopIfTrue   bool            `xml:"stopIfTrue,attr,omitempty"`
+	AboveAverage *bool           `xml:"aboveAverage,attr,omitempty"`
+	Percent      bool            `xml:"percent,attr,omitempty"`
+	Bottom       bool            `xml:"bottom,attr,omitempty"`
+	Operator     string          `xml:"operator,attr,omitempty"`
+	Text         string          `xml:"text,attr,omitempty"`
+	TimePeriod   string          `xml:"timePeriod,attr,omitempty"`
+	Rank         int             `xml:"rank,attr,omitempty"`
+	StdDev       int             `xml:"stdDev,attr,omitempty"`
+	EqualAverage bool            `xml:"equalAverage,attr,omitempty"`
+	Formula      []string        `xml:"formula"`
+	ColorScale   *xlsxColorScale `xml:"colorScale"`
+	DataBar      *xlsxDataBar    `xml:"dataBar"`
+	IconSet      *xlsxIconSet    `xml:"iconSet"`
 }
 
-// xlsxFormula directly maps the xlsxFormula element in the namespace
+// xlsxColorScale directly maps the colorScale element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
 // as I need.
-type xlsxFormula struct {
-	Value string `xml:",chardata"`
+type xlsxColorScale struct {
+	Cfvo  []xlsxCfvo  `xml:"cfvo"`
+	Color []xlsxColor `xml:"color"`
 }
+
+// xlsxDataBar directly maps the dataBar element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxDataBar struct {
+	MinLength int        `xml:"minLength,attr,omitempty"`
+	MaxLength int        `xml:"maxLength,attr,omitempty"`
+	ShowValue *bool      `xml:"showValue,attr,omitempty"`
+	Cfvo      []xlsxCfvo `xml:"cfvo"`
+	Color     xlsxColor  `xml:"color"`
+}
+
+// xlsxIconSet directly maps the iconSet element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxIconSet struct {
+	IconSet   string     `xml:"iconSet,attr,omitempty"`
+	ShowValue *bool      `xml:"showValue,attr,omitempty"`
+	Reverse   bool       `xml:"reverse,attr,omitempty"`
+	Cfvo      []xlsxCfvo `xml:"cfvo"`
+}
+
+// xlsxCfvo directly maps the cfvo element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxCfvo struct {
+	Type string `xml:"type,attr"`
+	Val  string `xml:"val,attr,omitempty"`
+	Gte  *bool  `xml:"gte,attr,omitempty"`
+}
+
 // xlsxHeaderFooter directly maps the headerFooter element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -100,6 +191,7 @@ type xlsxPageSetUp struct {
 	HorizontalDPI      float32 `xml:"horizontalDpi,attr"`
 	VerticalDPI        float32 `xml:"verticalDpi,attr"`
 	Copies             int     `xml:"copies,attr"`
+	RID                string  `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr,omitempty"`
 }
 
 // xlsxPrintOptions directly maps the printOptions element in the namespace
@@ -303,11 +395,12 @@ func (mc *xlsxMergeCells) getExtent(cellRef string) (int, int, error) {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxC struct {
-	R string `xml:"r,attr"`           // Cell ID, e.g. A1
-	S int    `xml:"s,attr,omitempty"` // Style reference.
-	T string `xml:"t,attr,omitempty"` // Type.
-	F *xlsxF `xml:"f,omitempty"`      // Formula
-	V string `xml:"v,omitempty"`      // Value
+	R  string  `xml:"r,attr"`           // Cell ID, e.g. A1
+	S  int     `xml:"s,attr,omitempty"` // Style reference.
+	T  string  `xml:"t,attr,omitempty"` // Type.
+	F  *xlsxF  `xml:"f,omitempty"`      // Formula
+	V  string  `xml:"v,omitempty"`      // Value
+	Is *xlsxSI `xml:"is,omitempty"`     // Inline string
 }
 
 // xlsxF directly maps the f element in the namespace